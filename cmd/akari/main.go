@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Cell is a cell of the grid, by row and column counting from 0
+type Cell struct {
+	Row, Col int
+}
+
+// Puzzle is an akari grid a row per line: . for a white cell, # for a
+// black one, or a digit for a black one counting the bulbs next to it
+type Puzzle []string
+
+var (
+	// the puzzle solved when none is given
+	Example = Puzzle{
+		"#....1.",
+		"0.2....",
+		"......1",
+		"..#00..",
+		"1......",
+		"....2.#",
+		".2....#",
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: akari [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve an akari, or light up: place bulbs in white cells so that every\n")
+		fmt.Fprintf(os.Stderr, "white cell is lit, by a bulb in its row or column with no black cell\n")
+		fmt.Fprintf(os.Stderr, "between, no bulb lights another, and each numbered black cell has as\n")
+		fmt.Fprintf(os.Stderr, "many bulbs next to it as its number. a file lays out the grid a row\n")
+		fmt.Fprintf(os.Stderr, "per line, with . for a white cell, # for a black one, or a digit for a\n")
+		fmt.Fprintf(os.Stderr, "numbered black one\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+func (p Puzzle) white(c Cell) bool {
+	return c.Row >= 0 && c.Row < len(p) && c.Col >= 0 && c.Col < len(p[c.Row]) && p[c.Row][c.Col] == '.'
+}
+
+// the runs of white cells between black cells or the edge of the grid,
+// along the rows and then down the columns: no two bulbs may share one,
+// and a bulb lights every cell of those it's in
+func (p Puzzle) segments() [][]Cell {
+	var out [][]Cell
+	for _, step := range []Cell{{0, 1}, {1, 0}} {
+		for r := range p {
+			for c := range p[r] {
+				start := Cell{r, c}
+				if !p.white(start) || p.white(Cell{r - step.Row, c - step.Col}) {
+					continue
+				}
+				var segment []Cell
+				for cell := start; p.white(cell); cell = (Cell{cell.Row + step.Row, cell.Col + step.Col}) {
+					segment = append(segment, cell)
+				}
+				out = append(out, segment)
+			}
+		}
+	}
+	return out
+}
+
+// model the puzzle with a boolean variable per white cell for whether it
+// holds a bulb. an Among constraint per run of white cells lets it hold
+// one bulb at most, and another per white cell requires at least one
+// bulb in the runs across it, so that it's lit. a Count constraint per
+// numbered black cell sets the bulbs next to it
+func NewProblem(p Puzzle) csp.Problem[Cell, int] {
+	domain := map[Cell][]int{}
+	for r := range p {
+		for c := range p[r] {
+			if p.white(Cell{r, c}) {
+				domain[Cell{r, c}] = []int{0, 1}
+			}
+		}
+	}
+
+	problem := csp.New[Cell, int](domain, nil)
+	seen := map[Cell][]Cell{}
+	for _, segment := range p.segments() {
+		if len(segment) > 1 {
+			problem.AddConstraint(csp.Among(segment, []int{1}, 0, 1))
+		}
+		for _, cell := range segment {
+			seen[cell] = append(seen[cell], segment...)
+		}
+	}
+	for r := range p {
+		for c := range p[r] {
+			cell := Cell{r, c}
+			if p.white(cell) {
+				// the cell is in both the runs across it, but counts once
+				lighting := []Cell{cell}
+				for _, other := range seen[cell] {
+					if other != cell {
+						lighting = append(lighting, other)
+					}
+				}
+				problem.AddConstraint(csp.Among(lighting, []int{1}, 1, len(lighting)))
+				continue
+			}
+
+			if ch := p[r][c]; ch >= '0' && ch <= '4' {
+				var around []Cell
+				for _, n := range []Cell{{r - 1, c}, {r + 1, c}, {r, c - 1}, {r, c + 1}} {
+					if p.white(n) {
+						around = append(around, n)
+					}
+				}
+				problem.AddConstraint(csp.Count(around, 1, int(ch-'0')))
+			}
+		}
+	}
+	return problem
+}
+
+// read a puzzle a row per line, as described by the usage
+func ReadPuzzle(name string) (Puzzle, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var p Puzzle
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p = append(p, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, p.check()
+}
+
+// report whether the puzzle is a rectangle of valid cells
+func (p Puzzle) check() error {
+	if len(p) == 0 {
+		return fmt.Errorf("no rows")
+	}
+	for row, line := range p {
+		if len(line) != len(p[0]) {
+			return fmt.Errorf("row %d has %d cells, not %d", row+1, len(line), len(p[0]))
+		}
+		for _, ch := range line {
+			if ch != '.' && ch != '#' && (ch < '0' || ch > '4') {
+				return fmt.Errorf("row %d: invalid cell %q", row+1, ch)
+			}
+		}
+	}
+	return nil
+}
+
+// draw the grid with the bulbs as @ in yellow, the lit cells as . and
+// the black cells as # or their number, inverted
+func drawGrid(p Puzzle, result map[Cell]int) string {
+	return render.Grid{
+		Rows: len(p),
+		Cols: len(p[0]),
+		Cell: func(row, col int) render.Cell {
+			cell := Cell{row, col}
+			switch {
+			case !p.white(cell):
+				return render.Cell{Text: string(p[row][col]), Color: render.White}
+			case result[cell] == 1:
+				return render.Cell{Text: "@", Color: render.Yellow}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// model akari using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	p := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		var err error
+		if p, err = ReadPuzzle(flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", flag.Arg(0), err)
+			os.Exit(2)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	found := 0
+	bt.Observe(csp.Hooks[Cell, int]{
+		OnSolution: func(map[Cell]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Cell]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,63 @@
+package main
+
+//go:generate go run ../cspgen -package main plan.yaml
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+var (
+	// the least number of batches of cakes to bake
+	Cakes int
+
+	// the most hours the ovens may run
+	Hours int
+)
+
+func init() {
+	flag.IntVar(&Cakes, "cakes", 2, "the least number of batches of cakes to bake")
+	flag.IntVar(&Hours, "hours", 12, "the most hours the ovens may run")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bakery [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "plan a day's baking from the model in plan.yaml, which cspgen compiles\n")
+		fmt.Fprintf(os.Stderr, "into plan_gen.go, and the orders of the day posted on top of it\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// solve the generated model, with the day's orders posted in terms of its
+// typed variables, and read the plan off the typed Solution
+func main() {
+	flag.Parse()
+
+	m := Model()
+	m.Post(
+		VarCakes.In(m).Ge(csp.Const(Cakes)),
+		VarOvenHours.In(m).Le(csp.Const(Hours)),
+	)
+	problem, err := m.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = csp.MaintainArcConsistency(problem)
+	result := bt.Solve(map[string]int{})
+	if result == nil {
+		fmt.Println("No solution found")
+		os.Exit(1)
+	}
+
+	plan := SolutionOf(result)
+	fmt.Println("Solution:")
+	fmt.Printf("%d batches of bread, %d of croissants and %d of cakes, in %d oven hours\n",
+		plan.Bread, plan.Croissants, plan.Cakes, plan.OvenHours)
+	stats := bt.Stats()
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,100 @@
+// Code generated by cspgen from plan.yaml. DO NOT EDIT.
+
+package main
+
+import "github.com/elireisman/generic-csp-go/pkg/csp"
+
+// Variable is a variable of the model, by name
+type Variable string
+
+// the model's variables
+const (
+	VarBread      Variable = "bread"
+	VarCroissants Variable = "croissants"
+	VarCakes      Variable = "cakes"
+	VarOvenHours  Variable = "oven_hours"
+)
+
+// the expression of the variable in a Model, to Post more about it
+func (v Variable) In(m *csp.Model) csp.Expr {
+	return m.Var(string(v))
+}
+
+// Constraint is a named constraint of the model, as Labels show it
+type Constraint string
+
+// the model's named constraints
+const (
+	ConstraintOven     Constraint = "oven"
+	ConstraintFlour    Constraint = "flour"
+	ConstraintRegulars Constraint = "regulars"
+)
+
+// the model plan.yaml describes, to Post more to and Build
+func Model() *csp.Model {
+	return &csp.Model{
+		Variables: []csp.ModelVariable{
+			{Name: "bread", Min: cspgenInt(0), Max: cspgenInt(12)},
+			{Name: "croissants", Min: cspgenInt(0), Max: cspgenInt(12)},
+			{Name: "cakes", Min: cspgenInt(0), Max: cspgenInt(6)},
+			{Name: "oven_hours", Min: cspgenInt(0), Max: cspgenInt(16)},
+		},
+		Constraints: []csp.ModelConstraint{
+			{Type: "linear", Name: "oven", Variables: []string{"bread", "croissants", "cakes", "oven_hours"}, Coefficients: []int{2, 1, 4, -2}, Operator: "=="},
+			{Type: "linear", Name: "flour", Variables: []string{"bread", "croissants", "cakes"}, Coefficients: []int{5, 3, 2}, Operator: "<=", Constant: 60},
+			{Type: "linear", Name: "regulars", Variables: []string{"bread"}, Coefficients: []int{1}, Operator: ">=", Constant: 4},
+		},
+	}
+}
+
+// build the Problem of the model
+func Build() (csp.Problem[string, int], error) {
+	return Model().Build()
+}
+
+// Solution holds a value for each of the model's variables
+type Solution struct {
+	Bread      int
+	Croissants int
+	Cakes      int
+	OvenHours  int
+}
+
+// the Solution of a solution of the model's Problem
+func SolutionOf(solution map[string]int) Solution {
+	return Solution{
+		Bread:      solution["bread"],
+		Croissants: solution["croissants"],
+		Cakes:      solution["cakes"],
+		OvenHours:  solution["oven_hours"],
+	}
+}
+
+// the Solution as an assignment of the model's Problem, e.g. to Verify
+func (s Solution) Assignment() map[string]int {
+	return map[string]int{
+		"bread":      s.Bread,
+		"croissants": s.Croissants,
+		"cakes":      s.Cakes,
+		"oven_hours": s.OvenHours,
+	}
+}
+
+// the value of a variable in the Solution
+func (s Solution) Value(v Variable) int {
+	switch v {
+	case VarBread:
+		return s.Bread
+	case VarCroissants:
+		return s.Croissants
+	case VarCakes:
+		return s.Cakes
+	case VarOvenHours:
+		return s.OvenHours
+	}
+	panic("unknown variable " + string(v))
+}
+
+func cspgenInt(n int) *int {
+	return &n
+}
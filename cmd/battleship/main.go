@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Ship is one of the fleet, the Nth of its Length counting from 0
+type Ship struct {
+	Length, Nth int
+}
+
+// Placement is where a ship lies: its top or left end, and whether it
+// runs down from there or right
+type Placement struct {
+	Row, Col int
+	Down     bool
+}
+
+// the cells a ship of the given length covers placed here
+func (pl Placement) cells(length int) [][2]int {
+	out := make([][2]int, length)
+	for ndx := range out {
+		if pl.Down {
+			out[ndx] = [2]int{pl.Row + ndx, pl.Col}
+		} else {
+			out[ndx] = [2]int{pl.Row, pl.Col + ndx}
+		}
+	}
+	return out
+}
+
+// whether one placement comes before another, reading the grid a row at a
+// time
+func (pl Placement) before(other Placement) bool {
+	if pl.Row != other.Row {
+		return pl.Row < other.Row
+	}
+	if pl.Col != other.Col {
+		return pl.Col < other.Col
+	}
+	return !pl.Down && other.Down
+}
+
+// Puzzle is a battleship solitaire: the number of ship cells in each row
+// and column, and the cells given as water, ~, or part of a ship, o, with
+// the rest . for unknown
+type Puzzle struct {
+	RowCounts, ColCounts []int
+	Cells                []string
+}
+
+var (
+	// the puzzle solved when none is given, its first line holding the
+	// column counts and each of the others a row count and then the row
+	Example = []string{
+		".0513102314",
+		"3.......~..",
+		"2.~........",
+		"0..........",
+		"2....o....o",
+		"2......~...",
+		"0..........",
+		"2..........",
+		"3..........",
+		"3...o......",
+		"3...~......",
+	}
+
+	// the lengths of the ships of the fleet
+	Fleet string
+)
+
+func init() {
+	flag.StringVar(&Fleet, "fleet", "4,3,3,2,2,2,1,1,1,1", "the lengths of the ships of the fleet")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: battleship [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a battleship solitaire: place the fleet in the grid, each ship\n")
+		fmt.Fprintf(os.Stderr, "across or down, so that no two ships touch, not even at a corner, and\n")
+		fmt.Fprintf(os.Stderr, "each row and column has as many ship cells as its count. a file holds\n")
+		fmt.Fprintf(os.Stderr, "a line of . and the column counts, and then a line per row of its count\n")
+		fmt.Fprintf(os.Stderr, "and its cells, each ~ for water, o for part of a ship, or . if unknown\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// parse a puzzle as described by the usage: the counts are digits, and
+// every line must be as long as the first
+func ParsePuzzle(lines []string) (Puzzle, error) {
+	var p Puzzle
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(p.ColCounts) > 0 && len(line) != len(p.ColCounts)+1 {
+			return p, fmt.Errorf("line %d has %d characters, not %d", len(p.Cells)+2, len(line), len(p.ColCounts)+1)
+		}
+		if len(p.ColCounts) == 0 {
+			for _, ch := range line[1:] {
+				if ch < '0' || ch > '9' {
+					return p, fmt.Errorf("line 1: invalid column count %q", ch)
+				}
+				p.ColCounts = append(p.ColCounts, int(ch-'0'))
+			}
+			continue
+		}
+		if ch := line[0]; ch < '0' || ch > '9' {
+			return p, fmt.Errorf("line %d: invalid row count %q", len(p.Cells)+2, ch)
+		}
+		for _, ch := range line[1:] {
+			if ch != '.' && ch != '~' && ch != 'o' {
+				return p, fmt.Errorf("line %d: invalid cell %q", len(p.Cells)+2, ch)
+			}
+		}
+		p.RowCounts = append(p.RowCounts, int(line[0]-'0'))
+		p.Cells = append(p.Cells, line[1:])
+	}
+	if len(p.Cells) == 0 || len(p.ColCounts) == 0 {
+		return p, fmt.Errorf("no rows")
+	}
+	return p, nil
+}
+
+// parse the lengths of the ships of a fleet, such as 4,3,3,2
+func ParseFleet(lengths string) ([]Ship, error) {
+	var fleet []Ship
+	nth := map[int]int{}
+	for _, field := range strings.Split(lengths, ",") {
+		length, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("invalid ship length %q", field)
+		}
+		fleet = append(fleet, Ship{length, nth[length]})
+		nth[length]++
+	}
+	return fleet, nil
+}
+
+// constraint: two ships don't overlap or touch, not even at a corner,
+// and if they're the same length the first lies before the second, so
+// that swapping them doesn't make another solution
+func NewApart(a, b Ship) csp.Constraint[Ship] {
+	return csp.Constraint[Ship]{Variables: []Ship{a, b}}
+}
+
+// constraint: the ships cover as many cells of each row and column as its
+// count and every cell given as part of a ship, where the Variables are
+// the whole fleet
+func NewCounts(fleet []Ship) csp.Constraint[Ship] {
+	return csp.Constraint[Ship]{Variables: fleet}
+}
+
+// check the constraints of the puzzle. the ships placed so far must keep
+// apart, and cover no more cells of a row or column than its count. once
+// the whole fleet is placed, they must cover exactly as many, and every
+// cell given as part of a ship
+func satisfies(p Puzzle, fleet []Ship) csp.Satisfied[Ship, Placement] {
+	return func(constraint csp.Constraint[Ship], candidate map[Ship]Placement) bool {
+		// a fleet of two is kept apart by the same constraint that counts
+		// its cells, so both checks apply
+		if len(constraint.Variables) == 2 && !apart(constraint.Variables[0], constraint.Variables[1], candidate) {
+			return false
+		}
+		if len(constraint.Variables) != len(fleet) {
+			return true
+		}
+
+		rows, cols := make([]int, len(p.RowCounts)), make([]int, len(p.ColCounts))
+		covered := map[[2]int]bool{}
+		placed := 0
+		for _, ship := range constraint.Variables {
+			pl, found := candidate[ship]
+			if !found {
+				continue
+			}
+			placed++
+			for _, cell := range pl.cells(ship.Length) {
+				rows[cell[0]]++
+				cols[cell[1]]++
+				covered[cell] = true
+			}
+		}
+		complete := placed == len(constraint.Variables)
+		for ndx, count := range rows {
+			if count > p.RowCounts[ndx] || complete && count != p.RowCounts[ndx] {
+				return false
+			}
+		}
+		for ndx, count := range cols {
+			if count > p.ColCounts[ndx] || complete && count != p.ColCounts[ndx] {
+				return false
+			}
+		}
+		if complete {
+			for r, line := range p.Cells {
+				for c, ch := range line {
+					if ch == 'o' && !covered[[2]int{r, c}] {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+}
+
+// report whether two ships placed so far keep apart, and if they're the
+// same length, lie in order
+func apart(a, b Ship, candidate map[Ship]Placement) bool {
+	pa, foundA := candidate[a]
+	pb, foundB := candidate[b]
+	if !foundA || !foundB {
+		return true
+	}
+	if a.Length == b.Length && (a.Nth < b.Nth) != pa.before(pb) {
+		return false
+	}
+	for _, ca := range pa.cells(a.Length) {
+		for _, cb := range pb.cells(b.Length) {
+			if dr, dc := ca[0]-cb[0], ca[1]-cb[1]; dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// report whether a ship of the given length could lie here: inside the
+// grid, off the water, and in rows and columns with room for it
+func (p Puzzle) fits(pl Placement, length int) bool {
+	if !pl.Down && p.RowCounts[pl.Row] < length || pl.Down && p.ColCounts[pl.Col] < length {
+		return false
+	}
+	for _, cell := range pl.cells(length) {
+		if cell[0] >= len(p.RowCounts) || cell[1] >= len(p.ColCounts) || p.Cells[cell[0]][cell[1]] == '~' ||
+			p.RowCounts[cell[0]] == 0 || p.ColCounts[cell[1]] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// model the puzzle with a variable per ship for where it lies, each domain
+// the places it fits in the grid, off the water and in rows and columns
+// with room for it, much as the word placement example places its words.
+// a constraint per pair of ships keeps them apart, and one over the whole
+// fleet counts the cells they cover
+func NewProblem(p Puzzle, fleet []Ship) csp.Problem[Ship, Placement] {
+	rows, cols := len(p.RowCounts), len(p.ColCounts)
+	domain := map[Ship][]Placement{}
+	for _, ship := range fleet {
+		domain[ship] = []Placement{}
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				for _, down := range []bool{false, true} {
+					// a ship of one cell lies the same either way
+					if pl := (Placement{r, c, down}); p.fits(pl, ship.Length) && (!down || ship.Length > 1) {
+						domain[ship] = append(domain[ship], pl)
+					}
+				}
+			}
+		}
+	}
+
+	problem := csp.New(domain, satisfies(p, fleet))
+	for i := range fleet {
+		for j := i + 1; j < len(fleet); j++ {
+			problem.AddConstraint(NewApart(fleet[i], fleet[j]))
+		}
+	}
+	if len(fleet) != 2 {
+		problem.AddConstraint(NewCounts(fleet))
+	}
+	return problem
+}
+
+// place the longest ships first, the hardest to find room for
+func longestFirst(fleet []Ship) csp.VariableOrder[Ship, Placement] {
+	ordered := append([]Ship{}, fleet...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Length > ordered[j].Length })
+	return func(assignment map[Ship]Placement) Ship {
+		for _, ship := range ordered {
+			if _, found := assignment[ship]; !found {
+				return ship
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// draw the grid with the counts in cyan above and left of it, the ships
+// in blue, their ends pointing outward, and the water as ~
+func drawGrid(p Puzzle, fleet []Ship, result map[Ship]Placement) string {
+	rows, cols := len(p.RowCounts), len(p.ColCounts)
+	shape := map[[2]int]string{}
+	for _, ship := range fleet {
+		pl := result[ship]
+		cells := pl.cells(ship.Length)
+		for ndx, cell := range cells {
+			switch {
+			case ship.Length == 1:
+				shape[cell] = "o"
+			case ndx == 0 && pl.Down:
+				shape[cell] = "^"
+			case ndx == 0:
+				shape[cell] = "<"
+			case ndx == len(cells)-1 && pl.Down:
+				shape[cell] = "v"
+			case ndx == len(cells)-1:
+				shape[cell] = ">"
+			default:
+				shape[cell] = "#"
+			}
+		}
+	}
+
+	return render.Grid{
+		Rows: rows + 1,
+		Cols: cols + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{Text: " "}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprint(p.ColCounts[col-1]), Color: render.Cyan}
+			case col == 0:
+				return render.Cell{Text: fmt.Sprint(p.RowCounts[row-1]), Color: render.Cyan}
+			}
+			if text, found := shape[[2]int{row - 1, col - 1}]; found {
+				return render.Cell{Text: text, Color: render.Blue}
+			}
+			return render.Cell{Text: "~"}
+		},
+	}.String()
+}
+
+// model battleship solitaire using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	lines := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		lines = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := ParsePuzzle(lines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	fleet, err := ParseFleet(Fleet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p, fleet)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = longestFirst(fleet)
+	found := 0
+	bt.Observe(csp.Hooks[Ship, Placement]{
+		OnSolution: func(map[Ship]Placement) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Ship]Placement{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, fleet, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+var (
+	// CSP variables: the bin each item goes in, the items given by size
+	Sizes = []int{
+		42, 63, 67, 57, 93, 90, 38, 36, 45, 42,
+		33, 79, 27, 57, 44, 84, 86, 92, 46, 38,
+		85, 33, 82, 73, 49, 70, 59, 23, 57, 72,
+	}
+
+	// the size of every bin
+	Capacity int
+
+	// give up on proving the fewest bins after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.IntVar(&Capacity, "capacity", 150, "the size of every bin")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on proving the fewest bins after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bin_packing [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "pack items of the given sizes into as few bins of the same capacity\n")
+		fmt.Fprintf(os.Stderr, "as possible\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the items by decreasing size, so that the hardest to fit are packed
+// first, and the number of bins the sizes can't be packed in fewer than
+func order(sizes []int, capacity int) ([]int, int) {
+	items := make([]int, len(sizes))
+	total := 0
+	for ndx, size := range sizes {
+		items[ndx] = ndx
+		total += size
+	}
+	sort.SliceStable(items, func(i, j int) bool { return sizes[items[i]] > sizes[items[j]] })
+	return items, (total + capacity - 1) / capacity
+}
+
+// model the packing with a variable per item for the bin it goes in, and
+// a BinPacking constraint keeping each bin's load, the sum of the sizes of
+// its items, within the capacity.
+//
+// the bins are identical, so that any packing can have its bins
+// renumbered to give another just the same. so the items, by decreasing
+// size, can only go in a bin one of the items before them does, or in the
+// first empty one: the k-th item, counting from 0, no further than bin k,
+// and the rest of this is left to packInOrder
+func NewProblem(sizes []int, capacity int) csp.Problem[int, int] {
+	items, _ := order(sizes, capacity)
+	domain := map[int][]int{}
+	for k, item := range items {
+		for bin := 0; bin <= k; bin++ {
+			domain[item] = append(domain[item], bin)
+		}
+	}
+
+	problem := csp.New[int, int](domain, nil)
+	all := make([]int, len(sizes))
+	for ndx := range all {
+		all[ndx] = ndx
+	}
+	problem.AddConstraint(csp.BinPacking(all, sizes, capacity))
+	return problem
+}
+
+// pack the items in decreasing size, each into the first bin it fits,
+// which makes the first packing found that of first fit decreasing
+func biggestFirst(sizes []int, capacity int) csp.VariableOrder[int, int] {
+	items, _ := order(sizes, capacity)
+	return func(assignment map[int]int) int {
+		for _, item := range items {
+			if _, found := assignment[item]; !found {
+				return item
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// try the bins in use in turn, and then a single empty one: any other
+// empty one would only give a renumbering of the same packing
+func packInOrder(problem csp.Problem[int, int]) csp.ValueOrder[int, int] {
+	return func(item int, assignment map[int]int) []int {
+		used := 0
+		for _, bin := range assignment {
+			if bin+1 > used {
+				used = bin + 1
+			}
+		}
+		var values []int
+		for _, bin := range problem.Domain[item] {
+			if bin <= used {
+				values = append(values, bin)
+			}
+		}
+		return values
+	}
+}
+
+// the number of bins used, or for a partial packing, a lower bound on it:
+// those used so far, and as many more as the items left need beyond the
+// space free in them
+func bins(sizes []int, capacity int) csp.Cost[int, int] {
+	return func(assignment map[int]int) int {
+		used, packed, left := 0, 0, 0
+		for item, size := range sizes {
+			if bin, found := assignment[item]; found {
+				if bin+1 > used {
+					used = bin + 1
+				}
+				packed += size
+			} else {
+				left += size
+			}
+		}
+		if over := left - (used*capacity - packed); over > 0 {
+			return used + (over+capacity-1)/capacity
+		}
+		return used
+	}
+}
+
+// tabulate the packing with a row per bin, its items and its load
+func drawBins(result map[int]int, sizes []int, capacity, used int) string {
+	contents := make([][]string, used)
+	loads := make([]int, used)
+	for item, size := range sizes {
+		bin := result[item]
+		contents[bin] = append(contents[bin], fmt.Sprint(size))
+		loads[bin] += size
+	}
+
+	return render.Grid{
+		Rows: used + 1,
+		Cols: 3,
+		Cell: func(row, col int) render.Cell {
+			if row == 0 {
+				return render.Cell{Text: []string{"bin", "load", "items"}[col]}
+			}
+			switch col {
+			case 0:
+				return render.Cell{Text: fmt.Sprint(row)}
+			case 1:
+				cell := render.Cell{Text: fmt.Sprintf("%d/%d", loads[row-1], capacity)}
+				if loads[row-1] == capacity {
+					cell.Color = render.Green
+				}
+				return cell
+			}
+			return render.Cell{Text: strings.Join(contents[row-1], " ")}
+		},
+	}.String()
+}
+
+// model bin packing using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	for _, size := range Sizes {
+		if size > Capacity {
+			fmt.Fprintf(os.Stderr, "error: an item of size %d can't fit a bin of %d\n", size, Capacity)
+			os.Exit(2)
+		}
+	}
+	_, lower := order(Sizes, Capacity)
+
+	problem := NewProblem(Sizes, Capacity)
+	cost := bins(Sizes, Capacity)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = biggestFirst(Sizes, Capacity)
+	bt.OrderValues = packInOrder(problem)
+	bt.Timeout = Timeout
+	bt.Observe(csp.Hooks[int, int]{
+		OnSolution: func(solution map[int]int) {
+			fmt.Printf("Found a packing into %d bins after %d nodes\n", cost(solution), bt.Stats().Nodes)
+		},
+	})
+	result, used := bt.Minimize(map[int]int{}, cost)
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No packing found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d items in %d bins of %d, no fewer than %d would hold\n", len(Sizes), used, Capacity, lower)
+	fmt.Print(drawBins(result, Sizes, Capacity, used))
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the fewest bins, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The fewest bins possible, as no packing uses fewer")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
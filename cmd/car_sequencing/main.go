@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+//go:embed instances/*.txt
+var instances embed.FS
+
+// Option is an option fitted to some of the cars, e.g. a sunroof, and the
+// capacity of the station fitting it: no more than P of any Q cars in a
+// row down the line
+type Option struct {
+	P, Q int
+}
+
+// Class is a kind of car to build: how many of them, and which options
+// each has
+type Class struct {
+	Cars    int
+	Options []bool
+}
+
+// Instance is a day's production: the options and the classes of car
+type Instance struct {
+	Options []Option
+	Classes []Class
+}
+
+var (
+	// the bundled instance to sequence, if no file is given
+	InstanceName string
+
+	// give up after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.StringVar(&InstanceName, "instance", "line40", "the bundled instance to sequence: csplib or line40")
+	flag.DurationVar(&Timeout, "timeout", 30*time.Second, "give up after this long, or 0 for no limit")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: car_sequencing [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "order the cars down a production line so that no station fitting an\n")
+		fmt.Fprintf(os.Stderr, "option is given more cars needing it than it can keep up with. a file\n")
+		fmt.Fprintf(os.Stderr, "is in the format of CSPLib problem 1: a line of the numbers of cars,\n")
+		fmt.Fprintf(os.Stderr, "options and classes, a line of each option's p and another of its q,\n")
+		fmt.Fprintf(os.Stderr, "for at most p cars in q, then a line per class of its number, its cars\n")
+		fmt.Fprintf(os.Stderr, "and a 0 or 1 per option. lines starting with # are comments\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read an instance in the format described by the usage
+func ReadInstance(r io.Reader) (Instance, error) {
+	var in Instance
+	var lines [][]int
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var numbers []int
+		for _, field := range strings.Fields(line) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 {
+				return in, fmt.Errorf("line %d: invalid number %q", lineNo, field)
+			}
+			numbers = append(numbers, n)
+		}
+		lines = append(lines, numbers)
+	}
+	if err := scanner.Err(); err != nil {
+		return in, err
+	}
+
+	if len(lines) < 3 || len(lines[0]) != 3 {
+		return in, fmt.Errorf("want a line of the numbers of cars, options and classes, then the options' p and q")
+	}
+	cars, options, classes := lines[0][0], lines[0][1], lines[0][2]
+	if len(lines[1]) != options || len(lines[2]) != options {
+		return in, fmt.Errorf("want a p and a q for each of %d options", options)
+	}
+	for o := 0; o < options; o++ {
+		p, q := lines[1][o], lines[2][o]
+		if p < 1 || q < p {
+			return in, fmt.Errorf("option %d: can't fit %d cars in %d", o, p, q)
+		}
+		in.Options = append(in.Options, Option{P: p, Q: q})
+	}
+
+	if len(lines)-3 != classes {
+		return in, fmt.Errorf("want %d classes, got %d", classes, len(lines)-3)
+	}
+	total := 0
+	for ndx, numbers := range lines[3:] {
+		if len(numbers) != 2+options || numbers[0] != ndx {
+			return in, fmt.Errorf("class %d: want its number, its cars and a 0 or 1 per option", ndx)
+		}
+		class := Class{Cars: numbers[1]}
+		for _, has := range numbers[2:] {
+			class.Options = append(class.Options, has == 1)
+		}
+		in.Classes = append(in.Classes, class)
+		total += class.Cars
+	}
+	if total != cars {
+		return in, fmt.Errorf("want %d cars, the classes have %d", cars, total)
+	}
+	return in, nil
+}
+
+// the number of cars to build
+func (in Instance) Cars() int {
+	n := 0
+	for _, class := range in.Classes {
+		n += class.Cars
+	}
+	return n
+}
+
+// model the line with a variable per position down it for the class of
+// car built there. a GlobalCardinality constraint builds each class's
+// cars, and a Sequence constraint per option keeps every window of Q cars
+// to no more than P of the classes with the option
+func NewProblem(in Instance) csp.Problem[int, int] {
+	cars := in.Cars()
+	var classes, counts []int
+	for c, class := range in.Classes {
+		classes = append(classes, c)
+		counts = append(counts, class.Cars)
+	}
+	domain := map[int][]int{}
+	for slot := 0; slot < cars; slot++ {
+		domain[slot] = classes
+	}
+
+	problem := csp.New[int, int](domain, nil)
+	line := make([]int, cars)
+	for slot := range line {
+		line[slot] = slot
+	}
+	problem.AddConstraint(csp.GlobalCardinality(line, classes, counts, counts))
+	for o, option := range in.Options {
+		var fitted []int
+		for c, class := range in.Classes {
+			if class.Options[o] {
+				fitted = append(fitted, c)
+			}
+		}
+		problem.AddConstraint(csp.Sequence(line, fitted, option.Q, 0, option.P))
+	}
+	return problem
+}
+
+// assign the positions down the line in order, so that each Sequence
+// window is checked as soon as its cars are all placed
+func downTheLine(cars int) csp.VariableOrder[int, int] {
+	return func(assignment map[int]int) int {
+		for slot := 0; slot < cars; slot++ {
+			if _, found := assignment[slot]; !found {
+				return slot
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// try the classes hardest to place first, those whose options most load
+// their stations over the day, so that they're spread along the line
+// before the easy ones fill it
+func hardestFirst(in Instance) csp.ValueOrder[int, int] {
+	cars := float64(in.Cars())
+	load := make([]float64, len(in.Options))
+	for o, option := range in.Options {
+		for _, class := range in.Classes {
+			if class.Options[o] {
+				load[o] += float64(class.Cars)
+			}
+		}
+		// the share of the station's capacity over the day it needs
+		load[o] /= cars * float64(option.P) / float64(option.Q)
+	}
+
+	order := make([]int, len(in.Classes))
+	difficulty := make([]float64, len(in.Classes))
+	for c, class := range in.Classes {
+		order[c] = c
+		for o, has := range class.Options {
+			if has {
+				difficulty[c] += load[o]
+			}
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return difficulty[order[i]] > difficulty[order[j]] })
+
+	return func(_ int, _ map[int]int) []int {
+		return order
+	}
+}
+
+// tabulate the line with a row per car and a column per option, marking
+// the options it's fitted with
+func drawLine(in Instance, result map[int]int) string {
+	return render.Grid{
+		Rows: in.Cars() + 1,
+		Cols: len(in.Options) + 2,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col < 2:
+				return render.Cell{Text: []string{"car", "class"}[col]}
+			case row == 0:
+				option := in.Options[col-2]
+				return render.Cell{Text: fmt.Sprintf("%d/%d", option.P, option.Q)}
+			case col == 0:
+				return render.Cell{Text: strconv.Itoa(row)}
+			case col == 1:
+				return render.Cell{Text: strconv.Itoa(result[row-1])}
+			}
+			if in.Classes[result[row-1]].Options[col-2] {
+				return render.Cell{Text: "#", Color: render.Yellow}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// model car sequencing using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in Instance
+	var err error
+	name := InstanceName
+	switch flag.NArg() {
+	case 0:
+		var src []byte
+		if src, err = instances.ReadFile("instances/" + InstanceName + ".txt"); err != nil {
+			fmt.Fprintf(os.Stderr, "error: no bundled instance %q\n", InstanceName)
+			os.Exit(2)
+		}
+		in, err = ReadInstance(strings.NewReader(string(src)))
+	case 1:
+		name = flag.Arg(0)
+		var file *os.File
+		if file, err = os.Open(name); err == nil {
+			in, err = ReadInstance(file)
+			file.Close()
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+
+	problem := NewProblem(in)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = downTheLine(in.Cars())
+	bt.OrderValues = hardestFirst(in)
+	bt.Timeout = Timeout
+	result := bt.Solve(map[int]int{})
+	stats := bt.Stats()
+	if result == nil {
+		reason := "none exists"
+		if stats.TimedOut {
+			reason = "timed out"
+		}
+		fmt.Printf("No sequence found for %s, %s (%d nodes, %d backtracks in %s)\n", name, reason, stats.Nodes, stats.Backtracks, stats.Duration)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d cars of %d classes with %d options\n", in.Cars(), len(in.Classes), len(in.Options))
+	fmt.Print(drawLine(in, result))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
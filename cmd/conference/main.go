@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Plan is the day of a one-track conference, and its csp tags are the
+// whole model: the hour each session starts, from 9 to 16, no two at
+// once, and the three workshops after lunch, run in parallel
+type Plan struct {
+	Keynote int `csp:"min=9,max=16,alldifferent=hours,le=10"`
+	Panel   int `csp:"min=9,max=16,alldifferent=hours,gt=Keynote,lt=Lunch"`
+	// lunch is at noon or at 1
+	Lunch     int `csp:"domain=12|13,alldifferent=hours"`
+	Workshops int `csp:"min=9,max=16,alldifferent=hours,gt=Lunch,lt=Closing"`
+	Closing   int `csp:"min=9,max=16,alldifferent=hours,ge=16"`
+	// the rooms the workshops take, and the seats each has
+	Rooms [3]int `csp:"domain=1|2|3|4,alldifferent=rooms"`
+	Seats [3]int `csp:"domain=20|30|40|60,alldifferent=seats"`
+	// the seats of the workshops together, fewer than the 150 attending
+	Capacity int `csp:"min=0,max=149,sum=Seats,gt=110"`
+}
+
+// model the plan from the tags of its struct, solve it, and fill the
+// struct in with the solution
+func main() {
+	var plan Plan
+	m, err := csp.ModelOfStruct(&plan)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	problem, err := m.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	result := bt.Solve(map[string]int{})
+	if result == nil {
+		fmt.Println("No solution found")
+		os.Exit(1)
+	}
+	if err := csp.FillStruct(&plan, result); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Printf("keynote at %d, panel at %d, lunch at %d, workshops at %d, closing at %d\n",
+		plan.Keynote, plan.Panel, plan.Lunch, plan.Workshops, plan.Closing)
+	for ndx, room := range plan.Rooms {
+		fmt.Printf("workshop %d in room %d, %d seats\n", ndx+1, room, plan.Seats[ndx])
+	}
+	fmt.Printf("%d seats in all\n", plan.Capacity)
+	stats := bt.Stats()
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
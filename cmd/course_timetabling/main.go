@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+//go:embed instances/*.ctt
+var instances embed.FS
+
+// Part is which of a lecture's two decisions a variable holds
+type Part int
+
+const (
+	Period Part = iota
+	Room
+)
+
+// Var is the period or the room of the Lecture'th lecture of a course,
+// counting from 0. periods are numbered through the week, day by day
+type Var struct {
+	Course  int
+	Lecture int
+	Part    Part
+}
+
+// Course is a course of lectures, all given by its teacher
+type Course struct {
+	Name     string
+	Teacher  string
+	Lectures int
+	// the fewest days the lectures should be spread over
+	MinDays  int
+	Students int
+}
+
+// Curriculum is a group of courses taken by the same students, so none of
+// their lectures may be at the same time
+type Curriculum struct {
+	Name    string
+	Courses []int
+}
+
+// Instance is a curriculum-based course timetabling problem, in the
+// format of the second International Timetabling Competition
+type Instance struct {
+	Name          string
+	Days          int
+	PeriodsPerDay int
+	Courses       []Course
+	Rooms         []struct {
+		Name     string
+		Capacity int
+	}
+	Curricula []Curriculum
+	// by course, the periods its lectures can't be in
+	Unavailable map[int]map[int]bool
+}
+
+// the penalty per unit of each kind of soft constraint violation, as the
+// competition weighs them
+const (
+	// per student over a room's capacity
+	CapacityWeight = 1
+	// per day short of a course's minimum
+	MinDaysWeight = 5
+	// per lecture of a curriculum with none of its others next to it
+	CompactnessWeight = 2
+	// per room a course uses beyond the first
+	StabilityWeight = 1
+)
+
+var (
+	// the bundled instance to timetable, if no file is given
+	InstanceName string
+
+	// the fraction of the lectures freed at each step
+	Relax float64
+
+	// which lectures to free at each step: random, related or window
+	Neighborhood string
+
+	// give up on improving the timetable after this long
+	Timeout time.Duration
+
+	// seed the choice of lectures to free, or the clock if zero
+	Seed int64
+)
+
+func init() {
+	flag.StringVar(&InstanceName, "instance", "small", "the bundled instance to timetable")
+	flag.Float64Var(&Relax, "relax", 0.2, "the fraction of the lectures freed at each step of the search")
+	flag.StringVar(&Neighborhood, "neighborhood", "random", "which lectures to free at each step: random, related ones sharing constraints, or a window of consecutive periods")
+	flag.DurationVar(&Timeout, "timeout", 5*time.Second, "give up on improving the timetable after this long")
+	flag.Int64Var(&Seed, "seed", 1, "seed the choice of lectures to free, or the clock if 0")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: course_timetabling [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "timetable the lectures of a university's courses into periods and rooms,\n")
+		fmt.Fprintf(os.Stderr, "so that no room, teacher or curriculum has two lectures at once, and\n")
+		fmt.Fprintf(os.Stderr, "then improve it by large neighborhood search against the penalties for\n")
+		fmt.Fprintf(os.Stderr, "overfull rooms, courses crammed into too few days, isolated lectures\n")
+		fmt.Fprintf(os.Stderr, "and courses moving between rooms. a file is in the .ctt format of the\n")
+		fmt.Fprintf(os.Stderr, "curriculum-based track of the International Timetabling Competition\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read an instance in the .ctt format: a header of counts, then sections
+// of COURSES, ROOMS, CURRICULA and UNAVAILABILITY_CONSTRAINTS, and END.
+func ReadInstance(r io.Reader) (Instance, error) {
+	in := Instance{Unavailable: map[int]map[int]bool{}}
+	courses := map[string]int{}
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if strings.HasSuffix(fields[0], ":") && len(fields) == 1 {
+			section = fields[0]
+			continue
+		}
+		if fields[0] == "END." {
+			break
+		}
+
+		numbers := func(from int) ([]int, error) {
+			var out []int
+			for _, field := range fields[from:] {
+				n, err := strconv.Atoi(field)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("line %d: invalid number %q", lineNo, field)
+				}
+				out = append(out, n)
+			}
+			return out, nil
+		}
+
+		switch section {
+		case "":
+			if len(fields) != 2 {
+				return in, fmt.Errorf("line %d: want a header field and its value", lineNo)
+			}
+			if fields[0] == "Name:" {
+				in.Name = fields[1]
+				continue
+			}
+			n, err := numbers(1)
+			if err != nil {
+				return in, err
+			}
+			switch fields[0] {
+			case "Days:":
+				in.Days = n[0]
+			case "Periods_per_day:":
+				in.PeriodsPerDay = n[0]
+			}
+
+		case "COURSES:":
+			n, err := numbers(2)
+			if err != nil {
+				return in, err
+			}
+			if len(n) != 3 {
+				return in, fmt.Errorf("line %d: want a course, teacher, lectures, minimum days and students", lineNo)
+			}
+			courses[fields[0]] = len(in.Courses)
+			in.Courses = append(in.Courses, Course{Name: fields[0], Teacher: fields[1], Lectures: n[0], MinDays: n[1], Students: n[2]})
+
+		case "ROOMS:":
+			n, err := numbers(1)
+			if err != nil {
+				return in, err
+			}
+			if len(n) != 1 {
+				return in, fmt.Errorf("line %d: want a room and its capacity", lineNo)
+			}
+			in.Rooms = append(in.Rooms, struct {
+				Name     string
+				Capacity int
+			}{fields[0], n[0]})
+
+		case "CURRICULA:":
+			if len(fields) < 2 {
+				return in, fmt.Errorf("line %d: want a curriculum, its number of courses and the courses", lineNo)
+			}
+			c := Curriculum{Name: fields[0]}
+			for _, name := range fields[2:] {
+				course, found := courses[name]
+				if !found {
+					return in, fmt.Errorf("line %d: no course %q", lineNo, name)
+				}
+				c.Courses = append(c.Courses, course)
+			}
+			if strconv.Itoa(len(c.Courses)) != fields[1] {
+				return in, fmt.Errorf("line %d: want %s courses, got %d", lineNo, fields[1], len(c.Courses))
+			}
+			in.Curricula = append(in.Curricula, c)
+
+		case "UNAVAILABILITY_CONSTRAINTS:":
+			course, found := courses[fields[0]]
+			if !found {
+				return in, fmt.Errorf("line %d: no course %q", lineNo, fields[0])
+			}
+			n, err := numbers(1)
+			if err != nil {
+				return in, err
+			}
+			if len(n) != 2 || n[0] >= in.Days || n[1] >= in.PeriodsPerDay {
+				return in, fmt.Errorf("line %d: want a course, a day and a period of the day", lineNo)
+			}
+			if in.Unavailable[course] == nil {
+				in.Unavailable[course] = map[int]bool{}
+			}
+			in.Unavailable[course][n[0]*in.PeriodsPerDay+n[1]] = true
+
+		default:
+			return in, fmt.Errorf("line %d: unknown section %q", lineNo, section)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return in, err
+	}
+	if in.Days == 0 || in.PeriodsPerDay == 0 || len(in.Courses) == 0 || len(in.Rooms) == 0 {
+		return in, fmt.Errorf("want days, periods, courses and rooms")
+	}
+	return in, nil
+}
+
+// model the timetable with a period and a room variable per lecture, the
+// periods without those a course is unavailable in. the lectures of a
+// course, of a teacher's courses and of a curriculum's courses are each
+// AllDifferent in their periods, and no two lectures share a room at the
+// same time, by a single occupancy constraint over all the variables
+func NewProblem(in Instance) csp.Problem[Var, int] {
+	domain := map[Var][]int{}
+	var all []Var
+	for course, c := range in.Courses {
+		for lecture := 0; lecture < c.Lectures; lecture++ {
+			period, room := Var{course, lecture, Period}, Var{course, lecture, Room}
+			domain[period] = []int{}
+			for p := 0; p < in.Days*in.PeriodsPerDay; p++ {
+				if !in.Unavailable[course][p] {
+					domain[period] = append(domain[period], p)
+				}
+			}
+			for r := range in.Rooms {
+				domain[room] = append(domain[room], r)
+			}
+			all = append(all, period, room)
+		}
+	}
+
+	problem := csp.New(domain, occupancy)
+	teachers := map[string][]int{}
+	for course, c := range in.Courses {
+		teachers[c.Teacher] = append(teachers[c.Teacher], course)
+	}
+	groups := [][]int{}
+	for course := range in.Courses {
+		groups = append(groups, []int{course})
+	}
+	for _, courses := range teachers {
+		if len(courses) > 1 {
+			groups = append(groups, courses)
+		}
+	}
+	for _, c := range in.Curricula {
+		groups = append(groups, c.Courses)
+	}
+	for _, courses := range groups {
+		var periods []Var
+		for _, course := range courses {
+			for lecture := 0; lecture < in.Courses[course].Lectures; lecture++ {
+				periods = append(periods, Var{course, lecture, Period})
+			}
+		}
+		if len(periods) > 1 {
+			problem.AddConstraint(csp.AllDifferent(periods...))
+		}
+	}
+	problem.AddConstraint(csp.Constraint[Var]{Variables: all})
+	return problem
+}
+
+// constraint: no two of the lectures placed so far are in the same room
+// at the same time
+func occupancy(constraint csp.Constraint[Var], candidate map[Var]int) bool {
+	taken := map[[2]int]bool{}
+	for ndx := 0; ndx < len(constraint.Variables); ndx += 2 {
+		v := constraint.Variables[ndx]
+		period, foundPeriod := candidate[v]
+		room, foundRoom := candidate[Var{v.Course, v.Lecture, Room}]
+		if !foundPeriod || !foundRoom {
+			continue
+		}
+		if taken[[2]int{period, room}] {
+			return false
+		}
+		taken[[2]int{period, room}] = true
+	}
+	return true
+}
+
+// Penalty is a timetable's soft constraint violations, weighed
+type Penalty struct {
+	Capacity, MinDays, Compactness, Stability int
+}
+
+func (p Penalty) Total() int {
+	return p.Capacity + p.MinDays + p.Compactness + p.Stability
+}
+
+// weigh the soft constraint violations of a timetable, complete or not.
+// for a partial one, each is a lower bound on what every completion of
+// it incurs: students over a room's capacity, and rooms a course has
+// moved between, only add up as lectures are placed; a course can still
+// reach its minimum days with its unplaced lectures; and a curriculum's
+// isolated lectures are only counted once all its lectures are placed
+func (in Instance) Penalize(assignment map[Var]int) Penalty {
+	var p Penalty
+	for course, c := range in.Courses {
+		days, rooms := map[int]bool{}, map[int]bool{}
+		unplaced := 0
+		for lecture := 0; lecture < c.Lectures; lecture++ {
+			if period, found := assignment[Var{course, lecture, Period}]; found {
+				days[period/in.PeriodsPerDay] = true
+			} else {
+				unplaced++
+			}
+			if room, found := assignment[Var{course, lecture, Room}]; found {
+				rooms[room] = true
+				if over := c.Students - in.Rooms[room].Capacity; over > 0 {
+					p.Capacity += over * CapacityWeight
+				}
+			}
+		}
+		if short := c.MinDays - len(days) - unplaced; short > 0 {
+			p.MinDays += short * MinDaysWeight
+		}
+		if len(rooms) > 1 {
+			p.Stability += (len(rooms) - 1) * StabilityWeight
+		}
+	}
+
+	for _, cur := range in.Curricula {
+		var periods []int
+		complete := true
+		for _, course := range cur.Courses {
+			for lecture := 0; lecture < in.Courses[course].Lectures; lecture++ {
+				period, found := assignment[Var{course, lecture, Period}]
+				if !found {
+					complete = false
+				}
+				periods = append(periods, period)
+			}
+		}
+		if !complete {
+			continue
+		}
+
+		at := map[int]bool{}
+		for _, period := range periods {
+			at[period] = true
+		}
+		for _, period := range periods {
+			first, last := period%in.PeriodsPerDay == 0, period%in.PeriodsPerDay == in.PeriodsPerDay-1
+			if (first || !at[period-1]) && (last || !at[period+1]) {
+				p.Compactness += CompactnessWeight
+			}
+		}
+	}
+	return p
+}
+
+// assign a lecture's room straight after its period, and otherwise the
+// period of the lecture with the fewest periods left, as far as the
+// lectures it can't share a period with go, ties going to the lecture
+// with the most of those
+func periodThenRoom(in Instance, problem csp.Problem[Var, int]) csp.VariableOrder[Var, int] {
+	var periods []Var
+	clashes := map[Var][]Var{}
+	for course, c := range in.Courses {
+		for lecture := 0; lecture < c.Lectures; lecture++ {
+			periods = append(periods, Var{course, lecture, Period})
+		}
+	}
+	for v, constraints := range problem.Constraints {
+		for _, constraint := range constraints {
+			if constraint.Relation != csp.RelationAllDifferent {
+				continue
+			}
+			for _, other := range constraint.Variables {
+				if other != v {
+					clashes[v] = append(clashes[v], other)
+				}
+			}
+		}
+	}
+
+	return func(assignment map[Var]int) Var {
+		var best Var
+		bestLeft := -1
+		for _, v := range periods {
+			if _, found := assignment[v]; found {
+				room := Var{v.Course, v.Lecture, Room}
+				if _, found := assignment[room]; !found {
+					return room
+				}
+				continue
+			}
+
+			taken := map[int]bool{}
+			for _, other := range clashes[v] {
+				if period, found := assignment[other]; found {
+					taken[period] = true
+				}
+			}
+			left := 0
+			for _, period := range problem.Domain[v] {
+				if !taken[period] {
+					left++
+				}
+			}
+			if bestLeft < 0 || left < bestLeft || (left == bestLeft && len(clashes[v]) > len(clashes[best])) {
+				best, bestLeft = v, left
+			}
+		}
+		return best
+	}
+}
+
+// tabulate the timetable with a row per period and a column per room,
+// each lecture drawn as its course, in red if the room is too small
+func drawTimetable(in Instance, result map[Var]int) string {
+	at := map[[2]int]int{}
+	for v, period := range result {
+		if v.Part == Period {
+			at[[2]int{period, result[Var{v.Course, v.Lecture, Room}]}] = v.Course + 1
+		}
+	}
+	days := []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+	return render.Grid{
+		Rows:    in.Days*in.PeriodsPerDay + 1,
+		Cols:    len(in.Rooms) + 1,
+		BoxRows: in.PeriodsPerDay,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprintf("%s (%d)", in.Rooms[col-1].Name, in.Rooms[col-1].Capacity)}
+			case col == 0:
+				day, period := (row-1)/in.PeriodsPerDay, (row-1)%in.PeriodsPerDay
+				if day < len(days) {
+					return render.Cell{Text: fmt.Sprintf("%s %d", days[day], period+1)}
+				}
+				return render.Cell{Text: fmt.Sprintf("Day %d %d", day+1, period+1)}
+			}
+
+			course := at[[2]int{row - 1, col - 1}] - 1
+			if course < 0 {
+				return render.Cell{Text: "-"}
+			}
+			if in.Courses[course].Students > in.Rooms[col-1].Capacity {
+				return render.Cell{Text: in.Courses[course].Name, Color: render.Red}
+			}
+			return render.Cell{Text: in.Courses[course].Name}
+		},
+	}.String()
+}
+
+// model course timetabling using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in Instance
+	var err error
+	name := InstanceName
+	switch flag.NArg() {
+	case 0:
+		var src []byte
+		if src, err = instances.ReadFile("instances/" + InstanceName + ".ctt"); err != nil {
+			fmt.Fprintf(os.Stderr, "error: no bundled instance %q\n", InstanceName)
+			os.Exit(2)
+		}
+		in, err = ReadInstance(strings.NewReader(string(src)))
+	case 1:
+		name = flag.Arg(0)
+		var file *os.File
+		if file, err = os.Open(name); err == nil {
+			in, err = ReadInstance(file)
+			file.Close()
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	if Seed == 0 {
+		Seed = time.Now().UnixNano()
+	}
+
+	// find a timetable meeting the hard constraints, and then improve it
+	// by freeing a part of it at a time, as the -neighborhood picks, and
+	// searching that part again for a completion with less penalty
+	problem := NewProblem(in)
+	cost := func(assignment map[Var]int) int {
+		return in.Penalize(assignment).Total()
+	}
+	lns := csp.NewLNS(problem)
+	lns.Relax = Relax
+	switch Neighborhood {
+	case "random":
+	case "related":
+		lns.Neighborhood = csp.RelatedNeighborhood(problem)
+	case "window":
+		// a lecture's room goes with its period
+		lns.Neighborhood = csp.TimeWindowNeighborhood(func(v Var, solution map[Var]int) int {
+			return solution[Var{v.Course, v.Lecture, Period}]
+		})
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown neighborhood %q\n", Neighborhood)
+		os.Exit(2)
+	}
+	lns.StepTimeout = Timeout / 20
+	lns.Timeout = Timeout
+	lns.Rand = rand.New(rand.NewSource(Seed))
+	lns.SelectVariable = periodThenRoom(in, problem)
+	lns.OrderValues = csp.CheapestValue(problem, cost)
+	start := time.Now()
+	lns.Observe(csp.Hooks[Var, int]{
+		OnSolution: func(solution map[Var]int) {
+			fmt.Printf("Found a timetable with penalty %d after %s\n", cost(solution), time.Since(start))
+		},
+	})
+	result, _ := lns.Minimize(map[Var]int{}, cost)
+	stats := lns.Stats()
+	if result == nil {
+		fmt.Printf("No timetable found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		if stats.TimedOut {
+			fmt.Println("The search timed out")
+		}
+		os.Exit(1)
+	}
+
+	p := in.Penalize(result)
+	fmt.Printf("Solution: %s, %d courses in %d rooms over %d days, with penalty %d\n", in.Name, len(in.Courses), len(in.Rooms), in.Days, p.Total())
+	fmt.Print(drawTimetable(in, result))
+	fmt.Printf("Room capacity %d, minimum working days %d, curriculum compactness %d, room stability %d\n", p.Capacity, p.MinDays, p.Compactness, p.Stability)
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the least penalty possible, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The least penalty possible, as there's none left")
+	}
+	fmt.Printf("%d improvements, %d nodes, %d backtracks in %s\n", stats.Solutions, stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+type Word string
+
+type Point struct {
+	Row int
+	Col int
+}
+
+// Slot is a run of open cells across or down the grid that takes a word
+type Slot struct {
+	Start  Point
+	Down   bool
+	Length int
+}
+
+// the cell holding the slot's letter at ndx
+func (s Slot) At(ndx int) Point {
+	if s.Down {
+		return Point{s.Start.Row + ndx, s.Start.Col}
+	}
+	return Point{s.Start.Row, s.Start.Col + ndx}
+}
+
+// Crossing is where two slots share a cell: the letter at A of one slot
+// must be the letter at B of the other
+type Crossing struct {
+	A, B int
+}
+
+// Grid is a crossword to fill: its open cells, blocks, and any letters
+// already filled in
+type Grid struct {
+	Name    string
+	Rows    [][]rune
+	Letters map[Point]byte
+}
+
+// the block in a grid file
+const Block = '#'
+
+//go:embed grids/*.txt words.txt
+var bundled embed.FS
+
+var (
+	// fill this bundled grid, when no file is given
+	Name string
+
+	// the dictionary to fill the grid from, one word per line; the
+	// bundled one if empty
+	Dictionary string
+
+	// animate the search in the terminal
+	TUI bool
+)
+
+func init() {
+	flag.StringVar(&Name, "grid", "7x7", "the bundled grid to fill: 4x4, 5x5, 7x7 or 9x9")
+	flag.StringVar(&Dictionary, "words", "", "fill the grid from this dictionary, one word per line, rather than the bundled one")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: crossword [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "fill a crossword grid with words from a dictionary, so that every run of\n")
+		fmt.Fprintf(os.Stderr, "two or more open cells across and down is a word, and no word is used\n")
+		fmt.Fprintf(os.Stderr, "twice. a grid file has a line per row with . for an open cell, # for a\n")
+		fmt.Fprintf(os.Stderr, "block, or a letter already filled in, such as cmd/crossword/grids/7x7.txt\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read a grid in the format of the bundled ones. lines starting with ; are
+// comments, since # is a block
+func ReadGrid(name string, r io.Reader) (Grid, error) {
+	g := Grid{Name: name, Letters: map[Point]byte{}}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if len(g.Rows) > 0 && len(line) != len(g.Rows[0]) {
+			return g, fmt.Errorf("line %d: want %d cells, got %d", lineNo, len(g.Rows[0]), len(line))
+		}
+
+		row := []rune(strings.ToUpper(line))
+		for col, c := range row {
+			switch {
+			case c == '.' || c == Block:
+			case c >= 'A' && c <= 'Z':
+				g.Letters[Point{len(g.Rows), col}] = byte(c)
+			default:
+				return g, fmt.Errorf("line %d: unexpected %q", lineNo, c)
+			}
+		}
+		g.Rows = append(g.Rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return g, err
+	}
+	if len(g.Rows) == 0 {
+		return g, fmt.Errorf("empty grid")
+	}
+	return g, nil
+}
+
+// read a dictionary of one word per line, in upper case and without
+// duplicates. words with anything but letters are skipped
+func ReadWords(r io.Reader) ([]Word, error) {
+	seen := map[Word]bool{}
+	var out []Word
+
+	scanner := bufio.NewScanner(r)
+next:
+	for scanner.Scan() {
+		word := Word(strings.ToUpper(strings.TrimSpace(scanner.Text())))
+		for _, c := range word {
+			if c < 'A' || c > 'Z' {
+				continue next
+			}
+		}
+		if word != "" && !seen[word] {
+			seen[word] = true
+			out = append(out, word)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// whether the cell is inside the grid and not a block
+func (g Grid) open(p Point) bool {
+	return p.Row >= 0 && p.Row < len(g.Rows) && p.Col >= 0 && p.Col < len(g.Rows[p.Row]) && g.Rows[p.Row][p.Col] != Block
+}
+
+// find the slots of the grid, in the order crosswords number them: by
+// their first cell, reading the grid left to right and top to bottom
+func (g Grid) Slots() []Slot {
+	var out []Slot
+	for row := range g.Rows {
+		for col := range g.Rows[row] {
+			start := Point{row, col}
+			if !g.open(start) {
+				continue
+			}
+			for _, down := range []bool{false, true} {
+				slot := Slot{Start: start, Down: down}
+				if g.open(slot.At(-1)) {
+					continue
+				}
+				for g.open(slot.At(slot.Length)) {
+					slot.Length++
+				}
+				if slot.Length > 1 {
+					out = append(out, slot)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// model the grid with a variable per slot, whose domain is the words of
+// its length that agree with the letters already filled in. each pair of
+// crossing slots must agree on the letter they share, and the slots of
+// each length must all take different words
+func NewProblem(g Grid, words []Word) (csp.Problem[Slot, Word], []Slot) {
+	slots := g.Slots()
+	domain := map[Slot][]Word{}
+	for _, slot := range slots {
+		domain[slot] = []Word{}
+	next:
+		for _, word := range words {
+			if len(word) != slot.Length {
+				continue
+			}
+			for ndx := range word {
+				if letter, found := g.Letters[slot.At(ndx)]; found && letter != word[ndx] {
+					continue next
+				}
+			}
+			domain[slot] = append(domain[slot], word)
+		}
+	}
+
+	// the crossing of each binary constraint, by its identity; the
+	// constraints without one are the different-words ones
+	crossings := map[*Slot]Crossing{}
+
+	problem := csp.New(domain, func(constraint csp.Constraint[Slot], candidate map[Slot]Word) bool {
+		if crossing, found := crossings[&constraint.Variables[0]]; found {
+			a, assignedA := candidate[constraint.Variables[0]]
+			b, assignedB := candidate[constraint.Variables[1]]
+			return !assignedA || !assignedB || a[crossing.A] == b[crossing.B]
+		}
+		return differentWords(constraint.Variables, candidate)
+	})
+
+	cells := map[Point][]Slot{}
+	byLength := map[int][]Slot{}
+	for _, slot := range slots {
+		for ndx := 0; ndx < slot.Length; ndx++ {
+			cells[slot.At(ndx)] = append(cells[slot.At(ndx)], slot)
+		}
+		byLength[slot.Length] = append(byLength[slot.Length], slot)
+	}
+	for _, slot := range slots {
+		// add each crossing once, from its across slot
+		if slot.Down {
+			continue
+		}
+		for ndx := 0; ndx < slot.Length; ndx++ {
+			for _, other := range cells[slot.At(ndx)] {
+				if other.Down {
+					crossing := csp.Constraint[Slot]{Variables: []Slot{slot, other}}
+					crossings[&crossing.Variables[0]] = Crossing{ndx, slot.Start.Row - other.Start.Row}
+					problem.AddConstraint(crossing)
+				}
+			}
+		}
+	}
+	for _, same := range byLength {
+		if len(same) > 1 {
+			problem.AddConstraint(csp.Constraint[Slot]{Variables: same})
+		}
+	}
+
+	return problem, slots
+}
+
+// report whether the slots assigned so far all hold different words
+func differentWords(slots []Slot, candidate map[Slot]Word) bool {
+	seen := map[Word]bool{}
+	for _, slot := range slots {
+		if word, found := candidate[slot]; found {
+			if seen[word] {
+				return false
+			}
+			seen[word] = true
+		}
+	}
+	return true
+}
+
+// draw the grid with the letters filled in so far, picking out those the
+// search found from those the grid gave
+func drawGrid(g Grid, candidate map[Slot]Word) string {
+	letters := map[Point]byte{}
+	for slot, word := range candidate {
+		for ndx := range word {
+			letters[slot.At(ndx)] = word[ndx]
+		}
+	}
+
+	return render.Grid{
+		Rows: len(g.Rows),
+		Cols: len(g.Rows[0]),
+		Cell: func(row, col int) render.Cell {
+			p := Point{row, col}
+			switch {
+			case !g.open(p):
+				return render.Cell{Text: " ", Color: render.White}
+			case g.Letters[p] != 0:
+				return render.Cell{Text: string(g.Letters[p])}
+			case letters[p] != 0:
+				return render.Cell{Text: string(letters[p]), Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// list the words of the solution under their clue numbers
+func printWords(slots []Slot, result map[Slot]Word) {
+	numbers := map[Point]int{}
+	for _, slot := range slots {
+		if _, found := numbers[slot.Start]; !found {
+			numbers[slot.Start] = len(numbers) + 1
+		}
+	}
+
+	for _, down := range []bool{false, true} {
+		var lines []string
+		for _, slot := range slots {
+			if slot.Down == down {
+				lines = append(lines, fmt.Sprintf("  %2d %s", numbers[slot.Start], result[slot]))
+			}
+		}
+		if down {
+			fmt.Println("Down:")
+		} else {
+			fmt.Println("Across:")
+		}
+		fmt.Println(strings.Join(lines, "\n"))
+	}
+}
+
+func solve(g Grid, words []Word) bool {
+	problem, slots := NewProblem(g, words)
+	bt := csp.NewBacktracker(problem)
+	// the slot with the fewest words left that fit its crossings is the
+	// likeliest to fail, so it's the one to fill next
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+
+	var result map[Slot]Word
+	if TUI {
+		draw := func(candidate map[Slot]Word) string { return drawGrid(g, candidate) }
+		var err error
+		if result, err = tui.New("Crossword: "+g.Name, draw).Run(bt, map[Slot]Word{}); err != nil {
+			panic(err)
+		}
+	} else {
+		result = bt.Solve(map[Slot]Word{})
+	}
+
+	fmt.Printf("Grid %s, with %d slots to fill from %d words:\n", g.Name, len(slots), len(words))
+	fmt.Print(drawGrid(g, nil))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(g, result))
+	printWords(slots, result)
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
+
+// open the named file, or else the bundled one
+func open(path, bundledPath string) (io.ReadCloser, error) {
+	if path != "" {
+		return os.Open(path)
+	}
+	in, err := bundled.Open(bundledPath)
+	if err != nil {
+		return nil, fmt.Errorf("no bundled %s", strings.TrimSuffix(bundledPath, ".txt"))
+	}
+	return in, nil
+}
+
+// model crossword filling using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	in, err := open(name, "grids/"+Name+".txt")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	if name == "" {
+		name = Name
+	}
+	g, err := ReadGrid(name, in)
+	in.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+
+	in, err = open(Dictionary, "words.txt")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	words, err := ReadWords(in)
+	in.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", Dictionary, err)
+		os.Exit(2)
+	}
+
+	if !solve(g, words) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/dashboard"
+	"github.com/elireisman/generic-csp-go/pkg/report"
+)
+
+const usage = `usage: csp [flags] MODEL
+       csp [flags] repl [MODEL]
+
+solve a model described in JSON or YAML (see csp.Model), or in XCSP3,
+printing its solutions and the work the search took. the repl builds up
+a model interactively, starting from MODEL if given; enter help there
+for its commands
+
+flags:
+`
+
+// Options select how a model is read and solved
+type Options struct {
+	Format    string
+	Heuristic string
+	Values    string
+	Probe     bool
+	Shave     bool
+	Timeout   time.Duration
+	All       bool
+	Limit     int
+	Diverse   int
+	Canonical bool
+	Sample    int
+	Seed      int64
+	JSON      bool
+	Trace     string
+	Report    string
+	Profile   bool
+	Dashboard string
+}
+
+var options Options
+
+func init() {
+	flag.StringVar(&options.Format, "format", "", "model format: json, yaml, or xcsp3 (default by file extension)")
+	flag.StringVar(&options.Heuristic, "heuristic", "first", "variable ordering: first, lex, mrv, degree, or impact, which probes")
+	flag.BoolVar(&options.Shave, "shave", false, "shave the bounds of each variable before searching, ruling out those that fail at once")
+	flag.BoolVar(&options.Probe, "probe", false, "probe each value at the root before searching, ruling out those that fail at once")
+	flag.StringVar(&options.Values, "values", "domain", "value ordering: domain or lcv")
+	flag.DurationVar(&options.Timeout, "timeout", 0, "give up on the search after this long (0 for no limit)")
+	flag.BoolVar(&options.All, "all", false, "find every solution rather than the first")
+	flag.IntVar(&options.Limit, "limit", 0, "with -all, stop after this many solutions (0 for no limit)")
+	flag.IntVar(&options.Diverse, "diverse", 0, "find this many solutions, each as different from the others as possible")
+	flag.BoolVar(&options.Canonical, "canonical", false, "find the lexicographically least solution, by variable name and value, the same from run to run")
+	flag.IntVar(&options.Sample, "sample", 0, "draw this many solutions near uniformly at random")
+	flag.Int64Var(&options.Seed, "seed", 0, "with -sample, seed the draws, or the clock if 0")
+	flag.BoolVar(&options.JSON, "json", false, "print each solution as a line of JSON, with the search statistics")
+	flag.StringVar(&options.Trace, "trace", "", "record the search to this file, for csp_trace")
+	flag.StringVar(&options.Report, "report", "", "write an HTML report of the solve to this file")
+	flag.BoolVar(&options.Profile, "profile", false, "write CPU and allocation profiles of the solve alongside the -report, or else as csp-cpu.pprof and csp-allocs.pprof")
+	flag.StringVar(&options.Dashboard, "dashboard", "", "serve a live progress dashboard on this address, e.g. :8081")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+}
+
+// fail with a message, and the conventional exit status for a bad invocation
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(2)
+}
+
+func main() {
+	flag.Parse()
+
+	// allow flags after the model path too, e.g. `csp model.json --all`
+	args := flag.Args()
+	if len(args) > 1 {
+		if err := flag.CommandLine.Parse(args[1:]); err != nil {
+			os.Exit(2)
+		}
+		args = append(args[:1], flag.Args()...)
+	}
+	if len(args) > 0 && args[0] == "repl" {
+		if len(args) > 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := runREPL(os.Stdin, os.Stdout, args[1:], options); err != nil {
+			fail("%s", err)
+		}
+		return
+	}
+	if len(args) != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problem, err := load(args[0], options.Format)
+	if err != nil {
+		fail("%s", err)
+	}
+
+	found, err := solve(problem, map[string]int{}, options, os.Stdout)
+	if err != nil {
+		fail("%s", err)
+	}
+	if !found {
+		os.Exit(1)
+	}
+}
+
+// read a model file, in the given format or else the one its extension implies
+func load(path, format string) (csp.Problem[string, int], error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return csp.Problem[string, int]{}, err
+	}
+	defer in.Close()
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	var problem csp.Problem[string, int]
+	switch format {
+	case "json":
+		problem, err = csp.LoadJSON(in)
+	case "yaml", "yml":
+		problem, err = csp.LoadYAML(in)
+	case "xcsp3", "xml":
+		problem, err = csp.LoadXCSP3(in)
+	default:
+		return problem, fmt.Errorf("%s: unknown model format %q, choose one with -format", path, format)
+	}
+	if err != nil {
+		return problem, fmt.Errorf("%s: %s", path, err)
+	}
+	return problem, nil
+}
+
+// configure a Backtracker for the problem with the chosen heuristics
+func backtracker(problem csp.Problem[string, int], opts Options) (*csp.Backtracker[string, int], error) {
+	if opts.Shave {
+		shaving := problem.Shave(map[string]int{})
+		problem = shaving.Problem
+		printProbing(os.Stderr, "shaving", shaving)
+	}
+	var probing csp.Probing[string, int]
+	if opts.Probe || opts.Heuristic == "impact" {
+		probing = problem.Probe(map[string]int{})
+		problem = probing.Problem
+		if opts.Probe {
+			printProbing(os.Stderr, "probing", probing)
+		}
+	}
+
+	bt := csp.NewBacktracker(problem)
+	bt.Timeout = opts.Timeout
+
+	switch opts.Heuristic {
+	case "", "first":
+	case "lex":
+		bt.SelectVariable = csp.Lexicographic(problem)
+	case "mrv":
+		bt.SelectVariable = csp.MinRemainingValues(problem)
+	case "degree":
+		bt.SelectVariable = csp.MaxDegree(problem)
+	case "impact":
+		bt.SelectVariable = csp.HighestImpact(probing)
+	default:
+		return nil, fmt.Errorf("unknown heuristic %q", opts.Heuristic)
+	}
+
+	switch opts.Values {
+	case "", "domain":
+	case "lcv":
+		bt.OrderValues = csp.LeastConstrainingValue(problem)
+	default:
+		return nil, fmt.Errorf("unknown value ordering %q", opts.Values)
+	}
+
+	return bt, nil
+}
+
+// summarize what probing or shaving the root ruled out
+func printProbing(out io.Writer, name string, probing csp.Probing[string, int]) {
+	pruned := 0
+	for _, values := range probing.Pruned {
+		pruned += len(values)
+	}
+	fmt.Fprintf(out, "%s fixed %d variables and pruned %d values in %s\n",
+		name, len(probing.Fixed), pruned, probing.Duration)
+}
+
+// name the strategy the options select, for reports and JSON output
+func (opts Options) strategy() string {
+	name := "backtracking"
+	if opts.Shave {
+		name += "+shave"
+	}
+	if opts.Probe {
+		name += "+probe"
+	}
+	if opts.Heuristic != "" && opts.Heuristic != "first" {
+		name += "+" + opts.Heuristic
+	}
+	if opts.Values != "" && opts.Values != "domain" {
+		name += "+" + opts.Values
+	}
+	return name
+}
+
+// solve the problem as the options direct, extending the assignment, and
+// print the solutions and statistics to out. reports whether any solution
+// was found
+func solve(problem csp.Problem[string, int], assignment map[string]int, opts Options, out io.Writer) (bool, error) {
+	bt, err := backtracker(problem, opts)
+	if err != nil {
+		return false, err
+	}
+	if opts.Diverse > 0 {
+		return solveDiverse(bt, assignment, opts.Diverse, out), nil
+	}
+	if opts.Sample > 0 {
+		return sample(bt, assignment, opts, out), nil
+	}
+
+	var tracer *csp.Tracer[string, int]
+	if opts.Trace != "" {
+		f, err := os.Create(opts.Trace)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		tracer = csp.NewTracer[string, int](f)
+		tracer.Labels = problem.Labels
+		bt.Observe(tracer.Hooks())
+	}
+
+	var reporter *report.Reporter[string, int]
+	if opts.Report != "" {
+		reporter = report.New("csp", problem)
+		bt.Observe(reporter.Hooks())
+	}
+
+	if opts.Profile {
+		profile, closeProfile, err := profiles(opts.Report)
+		if err != nil {
+			return false, err
+		}
+		bt.Profile = profile
+		defer func() {
+			closeProfile()
+			if profile.Err != nil {
+				fmt.Fprintf(os.Stderr, "error: profiling: %s\n", profile.Err)
+			}
+		}()
+	}
+
+	if opts.Dashboard != "" {
+		board := dashboard.New[string, int]("csp")
+		bt.Observe(board.Hooks())
+		defer board.Finish()
+		server := &http.Server{Addr: opts.Dashboard, Handler: board}
+		go server.ListenAndServe()
+		defer server.Close()
+		fmt.Fprintf(os.Stderr, "dashboard on http://%s/\n", opts.Dashboard)
+	}
+
+	// print each solution as it's found, so that long enumerations show progress
+	var first map[string]int
+	count := 0
+	bt.Observe(csp.Hooks[string, int]{
+		OnSolution: func(solution map[string]int) {
+			count++
+			if first == nil {
+				first = map[string]int{}
+				for v, value := range solution {
+					first[v] = value
+				}
+			}
+			if opts.All && opts.Limit > 0 && count >= opts.Limit {
+				bt.Cancel()
+			}
+			if opts.JSON {
+				return
+			}
+			if opts.All {
+				fmt.Fprintf(out, "solution %d:\n", count)
+			}
+			printSolution(out, solution)
+		},
+	})
+
+	var solutions []map[string]int
+	if opts.All {
+		solutions = bt.SolveAll(assignment)
+	} else if opts.Canonical {
+		if solution := bt.SolveCanonical(assignment, nil, nil); solution != nil {
+			solutions = append(solutions, solution)
+		}
+	} else if solution := bt.Solve(assignment); solution != nil {
+		solutions = append(solutions, solution)
+	}
+	stats := bt.Stats()
+
+	if opts.JSON {
+		encoder := json.NewEncoder(out)
+		if len(solutions) == 0 {
+			solutions = append(solutions, nil)
+		}
+		for _, solution := range solutions {
+			if err := encoder.Encode(csp.Solution[string, int]{
+				Assignment: solution,
+				Stats:      stats,
+				Strategy:   opts.strategy(),
+				Timestamp:  time.Now(),
+			}); err != nil {
+				return false, err
+			}
+		}
+	} else {
+		if count == 0 {
+			fmt.Fprintln(out, "no solution")
+		}
+		fmt.Fprintf(os.Stderr, "%d solutions, %d nodes, %d backtracks, %d rejections in %s",
+			stats.Solutions, stats.Nodes, stats.Backtracks, stats.Rejections, stats.Duration)
+		switch {
+		case stats.TimedOut:
+			fmt.Fprint(os.Stderr, " (timed out)")
+		case stats.Canceled:
+			fmt.Fprint(os.Stderr, " (stopped at the limit)")
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if tracer != nil {
+		if err := tracer.Flush(); err != nil {
+			return false, err
+		}
+	}
+	if reporter != nil {
+		f, err := os.Create(opts.Report)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		if err := reporter.Write(f, csp.Solution[string, int]{Assignment: first, Stats: stats, Strategy: opts.strategy()}); err != nil {
+			return false, err
+		}
+	}
+
+	return count > 0, nil
+}
+
+// find up to k solutions, as different from each other as possible, and
+// print them with the number of variables each pair differs in
+func solveDiverse(bt *csp.Backtracker[string, int], assignment map[string]int, k int, out io.Writer) bool {
+	solutions := csp.Diverse(bt, assignment, k)
+	for ndx, solution := range solutions {
+		fmt.Fprintf(out, "solution %d:\n", ndx+1)
+		printSolution(out, solution)
+	}
+	if len(solutions) == 0 {
+		fmt.Fprintln(out, "no solution")
+	}
+
+	least := -1
+	for i := range solutions {
+		for j := i + 1; j < len(solutions); j++ {
+			distance := 0
+			for v, value := range solutions[i] {
+				if solutions[j][v] != value {
+					distance++
+				}
+			}
+			if least < 0 || distance < least {
+				least = distance
+			}
+		}
+	}
+	if least >= 0 {
+		fmt.Fprintf(out, "each differs from the others in at least %d variables\n", least)
+	}
+
+	stats := bt.Stats()
+	fmt.Fprintf(os.Stderr, "%d solutions, %d nodes, %d backtracks, %d rejections in %s",
+		len(solutions), stats.Nodes, stats.Backtracks, stats.Rejections, stats.Duration)
+	if stats.TimedOut {
+		fmt.Fprint(os.Stderr, " (timed out)")
+	}
+	fmt.Fprintln(os.Stderr)
+	return len(solutions) > 0
+}
+
+// draw solutions near uniformly at random, and print them, within the
+// -timeout for all of them
+func sample(bt *csp.Backtracker[string, int], assignment map[string]int, opts Options, out io.Writer) bool {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	sampler := csp.NewSampler(bt)
+	sampler.Rand = rand.New(rand.NewSource(seed))
+
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+	var total csp.Stats
+	drawn := 0
+	for ndx := 0; ndx < opts.Sample; ndx++ {
+		if !deadline.IsZero() {
+			if bt.Timeout = time.Until(deadline); bt.Timeout <= 0 {
+				total.TimedOut = true
+				break
+			}
+		}
+		solution := sampler.Sample(assignment)
+		stats := sampler.Stats()
+		total.Nodes += stats.Nodes
+		total.Backtracks += stats.Backtracks
+		total.Rejections += stats.Rejections
+		total.Duration += stats.Duration
+		if solution == nil {
+			total.TimedOut = stats.TimedOut
+			break
+		}
+		drawn++
+		fmt.Fprintf(out, "sample %d:\n", drawn)
+		printSolution(out, solution)
+	}
+	if drawn == 0 {
+		fmt.Fprintln(out, "no solution")
+	}
+
+	fmt.Fprintf(os.Stderr, "%d samples, %d nodes, %d backtracks, %d rejections in %s",
+		drawn, total.Nodes, total.Backtracks, total.Rejections, total.Duration)
+	if total.TimedOut {
+		fmt.Fprint(os.Stderr, " (timed out)")
+	}
+	fmt.Fprintln(os.Stderr)
+	return drawn > 0
+}
+
+// create the files to profile a solve into, named for the report they
+// accompany, e.g. out-cpu.pprof for out.html, and the func to close them
+func profiles(report string) (*csp.Profile, func(), error) {
+	base := "csp"
+	if report != "" {
+		base = strings.TrimSuffix(report, filepath.Ext(report))
+	}
+
+	cpu, err := os.Create(base + "-cpu.pprof")
+	if err != nil {
+		return nil, nil, err
+	}
+	allocs, err := os.Create(base + "-allocs.pprof")
+	if err != nil {
+		cpu.Close()
+		return nil, nil, err
+	}
+	return &csp.Profile{CPU: cpu, Allocs: allocs}, func() {
+		cpu.Close()
+		allocs.Close()
+	}, nil
+}
+
+// print an assignment one variable per line, in order of name
+func printSolution(out io.Writer, solution map[string]int) {
+	var names []string
+	for v := range solution {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+
+	for _, v := range names {
+		fmt.Fprintf(out, "  %s = %d\n", v, solution[v])
+	}
+}
@@ -0,0 +1,864 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"gopkg.in/yaml.v3"
+)
+
+const replHelp = `commands:
+  var NAME... VALUES           declare variables, e.g. var x y 1..9 or var z 1 3 5
+  alldiff NAME...              require the variables to take different values
+  alldiff NAME... offsets N... ... once each is shifted by its offset
+  EXPR OP EXPR                 a linear constraint, e.g. x != y, x + y <= 10, 2*x - y == 3
+  table NAME... : TUPLE, ...   allow only the tuples listed, e.g. table x y : 1 2, 2 3
+  absdiff A B OP N             compare |A - B| to N, e.g. absdiff x y != 1
+  product NAME... = N          require the variables to multiply to N
+  quotient A B = N             require A / B or B / A to be exactly N
+  circuit NAME...              require the variables, each the position of its
+                               successor from 0, to form a single cycle
+  disjunctive NAME... durations N...
+                               require the tasks starting at the variables, each
+                               lasting its duration, to never overlap
+  list                         show the variables, the constraints and the fixed values
+  del N | NAME                 remove constraint N, or a variable and its constraints
+  fix NAME VALUE               fix a variable's value for the solves that follow
+  unfix [NAME]                 unfix a variable, or every one
+  check                        report the constraints the fixed values violate
+  solve [all [N]]              find a solution extending the fixed values, or all of them
+  core                         find a minimal set of constraints that can't be satisfied together
+  set heuristic|values|timeout VALUE
+                               change the search options, as for the flags of the same names
+  load FILE, save FILE         read or write the model as JSON or YAML
+  help, quit
+
+OP is one of == != < <= > >=
+`
+
+// repl holds the model being built and the values fixed within it
+type repl struct {
+	model csp.Model
+	fixed map[string]int
+	opts  Options
+	out   io.Writer
+}
+
+// read commands from in until it ends or the user quits, starting from
+// the model file in args if given
+func runREPL(in io.Reader, out io.Writer, args []string, opts Options) error {
+	r := &repl{fixed: map[string]int{}, opts: opts, out: out}
+	if len(args) > 0 {
+		if err := r.load(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "loaded %d variables and %d constraints from %s\n",
+			len(r.model.Variables), len(r.model.Constraints), args[0])
+	}
+
+	// only prompt at a terminal, so that scripts piped in read cleanly
+	prompt := false
+	if f, ok := in.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			prompt = true
+			fmt.Fprintln(out, "enter help for the commands")
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		if prompt {
+			fmt.Fprint(out, "csp> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if line == "quit" || line == "exit" {
+			return nil
+		}
+		if err := r.exec(line); err != nil {
+			fmt.Fprintf(out, "error: %s\n", err)
+		}
+	}
+	if prompt {
+		fmt.Fprintln(out)
+	}
+	return scanner.Err()
+}
+
+// run a single command
+func (r *repl) exec(line string) error {
+	fields := strings.Fields(line)
+	args := fields[1:]
+
+	switch fields[0] {
+	case "help":
+		fmt.Fprint(r.out, replHelp)
+	case "var":
+		return r.declare(args)
+	case "alldiff", "alldifferent":
+		mc := csp.ModelConstraint{Type: "alldifferent", Variables: args}
+		for ndx, arg := range args {
+			if arg != "offsets" {
+				continue
+			}
+			mc.Variables = args[:ndx]
+			mc.Offsets = []int{}
+			for _, o := range args[ndx+1:] {
+				n, err := strconv.Atoi(o)
+				if err != nil {
+					return fmt.Errorf("offset %q isn't an integer", o)
+				}
+				mc.Offsets = append(mc.Offsets, n)
+			}
+			break
+		}
+		return r.add(mc)
+	case "circuit":
+		return r.add(csp.ModelConstraint{Type: "circuit", Variables: args})
+	case "disjunctive":
+		mc := csp.ModelConstraint{Type: "disjunctive", Variables: args}
+		for ndx, arg := range args {
+			if arg != "durations" {
+				continue
+			}
+			mc.Variables = args[:ndx]
+			for _, d := range args[ndx+1:] {
+				n, err := strconv.Atoi(d)
+				if err != nil {
+					return fmt.Errorf("duration %q isn't an integer", d)
+				}
+				mc.Durations = append(mc.Durations, n)
+			}
+			break
+		}
+		return r.add(mc)
+	case "table":
+		mc, err := parseTable(strings.TrimSpace(strings.TrimPrefix(line, fields[0])))
+		if err != nil {
+			return err
+		}
+		return r.add(mc)
+	case "absdiff":
+		if len(args) != 4 {
+			return fmt.Errorf("usage: absdiff A B OP N")
+		}
+		n, err := strconv.Atoi(args[3])
+		if err != nil {
+			return fmt.Errorf("invalid constant %q", args[3])
+		}
+		return r.add(csp.ModelConstraint{Type: "absdiff", Variables: args[:2], Operator: csp.Operator(args[2]), Constant: n})
+	case "product", "quotient":
+		if len(args) < 3 || args[len(args)-2] != "=" {
+			return fmt.Errorf("usage: product NAME... = N, or quotient A B = N")
+		}
+		n, err := strconv.Atoi(args[len(args)-1])
+		if err != nil {
+			return fmt.Errorf("invalid constant %q", args[len(args)-1])
+		}
+		return r.add(csp.ModelConstraint{Type: fields[0], Variables: args[:len(args)-2], Constant: n})
+	case "list", "ls":
+		r.list()
+	case "del", "rm":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: del N | NAME")
+		}
+		return r.del(args[0])
+	case "fix":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: fix NAME VALUE")
+		}
+		return r.fix(args[0], args[1])
+	case "unfix":
+		if len(args) == 0 {
+			r.fixed = map[string]int{}
+			return nil
+		}
+		for _, name := range args {
+			if _, found := r.fixed[name]; !found {
+				return fmt.Errorf("%s isn't fixed", name)
+			}
+			delete(r.fixed, name)
+		}
+	case "check":
+		return r.check()
+	case "solve":
+		return r.solve(args)
+	case "core":
+		return r.core()
+	case "set":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: set heuristic|values|timeout VALUE")
+		}
+		return r.set(args[0], args[1])
+	case "load":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: load FILE")
+		}
+		if err := r.load(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "loaded %d variables and %d constraints\n", len(r.model.Variables), len(r.model.Constraints))
+	case "save":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: save FILE")
+		}
+		return r.save(args[0])
+	default:
+		mc, err := parseLinear(line)
+		if err != nil {
+			return fmt.Errorf("%s, or enter help for the commands", err)
+		}
+		return r.add(mc)
+	}
+	return nil
+}
+
+// declare variables sharing a domain, given as values or a range lo..hi
+func (r *repl) declare(args []string) error {
+	var names []string
+	var mv csp.ModelVariable
+	for _, arg := range args {
+		if lo, hi, found := strings.Cut(arg, ".."); found {
+			min, err1 := strconv.Atoi(lo)
+			max, err2 := strconv.Atoi(hi)
+			if err1 != nil || err2 != nil {
+				return fmt.Errorf("invalid range %q", arg)
+			}
+			if max < min {
+				return fmt.Errorf("range %q is empty", arg)
+			}
+			for value := min; value <= max; value++ {
+				mv.Domain = append(mv.Domain, value)
+			}
+		} else if value, err := strconv.Atoi(arg); err == nil {
+			mv.Domain = append(mv.Domain, value)
+		} else if len(mv.Domain) > 0 {
+			return fmt.Errorf("name the variables before their values")
+		} else {
+			names = append(names, arg)
+		}
+	}
+	if len(names) == 0 || len(mv.Domain) == 0 {
+		return fmt.Errorf("usage: var NAME... VALUES")
+	}
+
+	for _, name := range names {
+		if r.variable(name) >= 0 {
+			return fmt.Errorf("%s is already declared, del it to redeclare it", name)
+		}
+	}
+	for _, name := range names {
+		mv.Name = name
+		r.model.Variables = append(r.model.Variables, mv)
+	}
+	return nil
+}
+
+// find a variable's index in the model, or -1
+func (r *repl) variable(name string) int {
+	for ndx, mv := range r.model.Variables {
+		if mv.Name == name {
+			return ndx
+		}
+	}
+	return -1
+}
+
+// add a constraint, if the model stays valid with it
+func (r *repl) add(mc csp.ModelConstraint) error {
+	m := r.model
+	m.Constraints = append(append([]csp.ModelConstraint{}, m.Constraints...), mc)
+	if _, err := m.Build(); err != nil {
+		return err
+	}
+	r.model = m
+	fmt.Fprintf(r.out, "[%d] %s\n", len(m.Constraints), describe(mc))
+	return nil
+}
+
+func (r *repl) list() {
+	for _, mv := range r.model.Variables {
+		domain := formatDomain(mv)
+		fixed := ""
+		if value, found := r.fixed[mv.Name]; found {
+			fixed = fmt.Sprintf(" = %d", value)
+		}
+		fmt.Fprintf(r.out, "var %s %s%s\n", mv.Name, domain, fixed)
+	}
+	for ndx, mc := range r.model.Constraints {
+		fmt.Fprintf(r.out, "[%d] %s\n", ndx+1, describe(mc))
+	}
+}
+
+// remove a constraint by number, or a variable with its constraints
+func (r *repl) del(arg string) error {
+	if n, err := strconv.Atoi(arg); err == nil {
+		if n < 1 || n > len(r.model.Constraints) {
+			return fmt.Errorf("no constraint %d", n)
+		}
+		r.model.Constraints = append(r.model.Constraints[:n-1:n-1], r.model.Constraints[n:]...)
+		return nil
+	}
+
+	ndx := r.variable(arg)
+	if ndx < 0 {
+		return fmt.Errorf("no variable %s", arg)
+	}
+	r.model.Variables = append(r.model.Variables[:ndx:ndx], r.model.Variables[ndx+1:]...)
+	delete(r.fixed, arg)
+
+	var kept []csp.ModelConstraint
+	for _, mc := range r.model.Constraints {
+		mentioned := false
+		for _, v := range mc.Variables {
+			mentioned = mentioned || v == arg
+		}
+		if !mentioned {
+			kept = append(kept, mc)
+		}
+	}
+	if removed := len(r.model.Constraints) - len(kept); removed > 0 {
+		fmt.Fprintf(r.out, "removed %d constraints on %s\n", removed, arg)
+	}
+	r.model.Constraints = kept
+	return nil
+}
+
+func (r *repl) fix(name, arg string) error {
+	value, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", arg)
+	}
+	problem, err := r.model.Build()
+	if err != nil {
+		return err
+	}
+	values, found := problem.Domain[name]
+	if !found {
+		return fmt.Errorf("no variable %s", name)
+	}
+	for _, candidate := range values {
+		if candidate == value {
+			r.fixed[name] = value
+			return nil
+		}
+	}
+	return fmt.Errorf("%d is outside the domain of %s", value, name)
+}
+
+// the constraints, numbered from 1, that the fixed values already violate
+func (r *repl) violated() ([]int, error) {
+	var out []int
+	for ndx, mc := range r.model.Constraints {
+		scope := map[string]int{}
+		for _, v := range mc.Variables {
+			if value, found := r.fixed[v]; found {
+				scope[v] = value
+			}
+		}
+		if len(scope) < len(mc.Variables) {
+			continue
+		}
+
+		// check the constraint on its own, over just its variables
+		sub := csp.Model{Constraints: []csp.ModelConstraint{mc}}
+		for _, mv := range r.model.Variables {
+			if _, found := scope[mv.Name]; found {
+				sub.Variables = append(sub.Variables, mv)
+			}
+		}
+		problem, err := sub.Build()
+		if err != nil {
+			return nil, err
+		}
+		if problem.Verify(scope) != nil {
+			out = append(out, ndx+1)
+		}
+	}
+	return out, nil
+}
+
+func (r *repl) check() error {
+	violated, err := r.violated()
+	if err != nil {
+		return err
+	}
+	if len(violated) == 0 {
+		fmt.Fprintf(r.out, "the %d fixed values violate no constraint\n", len(r.fixed))
+		return nil
+	}
+	for _, n := range violated {
+		fmt.Fprintf(r.out, "violated: [%d] %s\n", n, describe(r.model.Constraints[n-1]))
+	}
+	return nil
+}
+
+func (r *repl) solve(args []string) error {
+	opts := r.opts
+	switch {
+	case len(args) == 0:
+	case args[0] == "all" && len(args) <= 2:
+		opts.All = true
+		if len(args) == 2 {
+			limit, err := strconv.Atoi(args[1])
+			if err != nil || limit < 1 {
+				return fmt.Errorf("invalid limit %q", args[1])
+			}
+			opts.Limit = limit
+		}
+	default:
+		return fmt.Errorf("usage: solve [all [N]]")
+	}
+
+	problem, err := r.model.Build()
+	if err != nil {
+		return err
+	}
+	// the search never revisits the values it starts from
+	if violated, err := r.violated(); err != nil {
+		return err
+	} else if len(violated) > 0 {
+		fmt.Fprintf(r.out, "no solution: the fixed values violate constraint [%d], see check\n", violated[0])
+		return nil
+	}
+
+	assignment := map[string]int{}
+	for v, value := range r.fixed {
+		assignment[v] = value
+	}
+	found, err := solve(problem, assignment, opts, r.out)
+	if err == nil && !found && len(r.model.Constraints) > 0 && !opts.All {
+		fmt.Fprintln(r.out, "core finds the constraints responsible")
+	}
+	return err
+}
+
+// shrink the constraints to a minimal set with no solution, given the
+// fixed values: each is dropped in turn, and kept only if the rest can
+// then be satisfied. a solve that times out keeps its constraint
+func (r *repl) core() error {
+	model := r.model
+	switch unsat := r.unsatisfiable(model); {
+	case unsat == nil:
+		fmt.Fprintln(r.out, "the search didn't finish, so no core; raise it with set timeout")
+		return nil
+	case !*unsat:
+		fmt.Fprintln(r.out, "the model has a solution, so no core")
+		return nil
+	}
+
+	var kept []csp.ModelConstraint
+	numbers := map[int]int{}
+	for ndx := range model.Constraints {
+		trial := model
+		trial.Constraints = append(append([]csp.ModelConstraint{}, kept...), model.Constraints[ndx+1:]...)
+		if unsat := r.unsatisfiable(trial); unsat != nil && *unsat {
+			continue
+		}
+		numbers[len(kept)] = ndx + 1
+		kept = append(kept, model.Constraints[ndx])
+	}
+
+	fmt.Fprintf(r.out, "these %d constraints can't be satisfied together:\n", len(kept))
+	for ndx, mc := range kept {
+		fmt.Fprintf(r.out, "[%d] %s\n", numbers[ndx], describe(mc))
+	}
+	return nil
+}
+
+// report whether the model has no solution extending the fixed values,
+// or nil if the search didn't finish
+func (r *repl) unsatisfiable(model csp.Model) *bool {
+	problem, err := model.Build()
+	if err != nil {
+		return nil
+	}
+	bt, err := backtracker(problem, r.opts)
+	if err != nil {
+		return nil
+	}
+
+	// the search never revisits the values it starts from
+	sub := repl{model: model, fixed: r.fixed}
+	if violated, _ := sub.violated(); len(violated) > 0 {
+		unsat := true
+		return &unsat
+	}
+
+	assignment := map[string]int{}
+	for v, value := range r.fixed {
+		assignment[v] = value
+	}
+	unsat := bt.Solve(assignment) == nil
+	if bt.Stats().TimedOut {
+		return nil
+	}
+	return &unsat
+}
+
+func (r *repl) set(name, value string) error {
+	opts := r.opts
+	switch name {
+	case "heuristic":
+		opts.Heuristic = value
+	case "values":
+		opts.Values = value
+	case "timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %s", err)
+		}
+		opts.Timeout = timeout
+	default:
+		return fmt.Errorf("no option %s", name)
+	}
+	if _, err := backtracker(csp.New(map[string][]int{}, nil), opts); err != nil {
+		return err
+	}
+	r.opts = opts
+	return nil
+}
+
+// replace the model with one read from a JSON or YAML file
+func (r *repl) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var m csp.Model
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	default:
+		return fmt.Errorf("%s: the repl reads JSON or YAML models", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid model: %w", path, err)
+	}
+	if _, err := m.Build(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	r.model = m
+	r.fixed = map[string]int{}
+	return nil
+}
+
+func (r *repl) save(path string) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err = json.MarshalIndent(r.model, "", "  ")
+		data = append(data, '\n')
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(r.model)
+	default:
+		return fmt.Errorf("%s: save as .json or .yaml", path)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// print a variable's domain in the syntax the repl reads, as a range
+// where it has no gaps
+func formatDomain(mv csp.ModelVariable) string {
+	if mv.Domain == nil && mv.Min != nil && mv.Max != nil {
+		return fmt.Sprintf("%d..%d", *mv.Min, *mv.Max)
+	}
+
+	contiguous := len(mv.Domain) > 1
+	for ndx := 1; ndx < len(mv.Domain); ndx++ {
+		contiguous = contiguous && mv.Domain[ndx] == mv.Domain[ndx-1]+1
+	}
+	if contiguous {
+		return fmt.Sprintf("%d..%d", mv.Domain[0], mv.Domain[len(mv.Domain)-1])
+	}
+	return strings.Trim(fmt.Sprint(mv.Domain), "[]")
+}
+
+// print a constraint in the syntax the repl reads, followed by its name
+// if it has one
+func describe(mc csp.ModelConstraint) string {
+	if mc.Name != "" {
+		return fmt.Sprintf("%s (%s)", syntax(mc), mc.Name)
+	}
+	return syntax(mc)
+}
+
+// print a constraint in the syntax the repl reads
+func syntax(mc csp.ModelConstraint) string {
+	vars := mc.Variables
+	switch mc.Type {
+	case "alldifferent":
+		if mc.Offsets != nil {
+			return fmt.Sprintf("alldiff %s offsets %s", strings.Join(vars, " "), strings.Trim(fmt.Sprint(mc.Offsets), "[]"))
+		}
+		return "alldiff " + strings.Join(vars, " ")
+	case "equal", "notequal", "less":
+		op := map[string]string{"equal": "==", "notequal": "!=", "less": "<"}[mc.Type]
+		if len(vars) == 2 {
+			return fmt.Sprintf("%s %s %s", vars[0], op, vars[1])
+		}
+	case "sum":
+		return fmt.Sprintf("%s %s %d", strings.Join(vars, " + "), mc.Operator, mc.Constant)
+	case "linear":
+		var b strings.Builder
+		for ndx, v := range vars {
+			c := mc.Coefficients[ndx]
+			switch {
+			case ndx == 0 && c < 0:
+				b.WriteString("-")
+			case ndx > 0 && c < 0:
+				b.WriteString(" - ")
+			case ndx > 0:
+				b.WriteString(" + ")
+			}
+			if c < 0 {
+				c = -c
+			}
+			if c != 1 {
+				fmt.Fprintf(&b, "%d*", c)
+			}
+			b.WriteString(v)
+		}
+		return fmt.Sprintf("%s %s %d", b.String(), mc.Operator, mc.Constant)
+	case "absdiff":
+		if len(vars) == 2 {
+			return fmt.Sprintf("absdiff %s %s %s %d", vars[0], vars[1], mc.Operator, mc.Constant)
+		}
+	case "circuit":
+		return "circuit " + strings.Join(vars, " ")
+	case "disjunctive":
+		return fmt.Sprintf("disjunctive %s durations %s", strings.Join(vars, " "), strings.Trim(fmt.Sprint(mc.Durations), "[]"))
+	case "product":
+		return fmt.Sprintf("product %s = %d", strings.Join(vars, " "), mc.Constant)
+	case "quotient":
+		if len(vars) == 2 {
+			return fmt.Sprintf("quotient %s %s = %d", vars[0], vars[1], mc.Constant)
+		}
+	case "table":
+		var tuples []string
+		for _, tuple := range mc.Tuples {
+			tuples = append(tuples, strings.Trim(fmt.Sprint(tuple), "[]"))
+		}
+		return fmt.Sprintf("table %s : %s", strings.Join(vars, " "), strings.Join(tuples, ", "))
+	}
+	return fmt.Sprintf("%s %s", mc.Type, strings.Join(vars, " "))
+}
+
+// parse `table NAME... : TUPLE, TUPLE...`, given what follows the keyword
+func parseTable(spec string) (csp.ModelConstraint, error) {
+	names, rest, found := strings.Cut(spec, ":")
+	if !found {
+		return csp.ModelConstraint{}, fmt.Errorf("usage: table NAME... : TUPLE, ...")
+	}
+
+	mc := csp.ModelConstraint{Type: "table", Variables: strings.Fields(names)}
+	for _, text := range strings.Split(rest, ",") {
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		tuple := make([]int, len(fields))
+		for ndx, field := range fields {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return mc, fmt.Errorf("invalid value %q", field)
+			}
+			tuple[ndx] = value
+		}
+		mc.Tuples = append(mc.Tuples, tuple)
+	}
+	return mc, nil
+}
+
+// split a linear constraint into names, numbers and operators
+func tokenize(line string) []string {
+	var tokens []string
+	word := ""
+	flush := func() {
+		if word != "" {
+			tokens = append(tokens, word)
+			word = ""
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			flush()
+		case strings.IndexByte("<>=!", c) >= 0:
+			flush()
+			if i+1 < len(line) && line[i+1] == '=' {
+				tokens = append(tokens, line[i:i+2])
+				i++
+			} else {
+				tokens = append(tokens, line[i:i+1])
+			}
+		case strings.IndexByte("+-*", c) >= 0:
+			flush()
+			tokens = append(tokens, line[i:i+1])
+		default:
+			word += string(c)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// parse a linear constraint `EXPR OP EXPR`, where each side sums terms
+// like x, 3, or 2*x, into the simplest model constraint that expresses it
+func parseLinear(line string) (csp.ModelConstraint, error) {
+	tokens := tokenize(line)
+	split := -1
+	for ndx, token := range tokens {
+		switch token {
+		case "==", "=", "!=", "<", "<=", ">", ">=":
+			if split >= 0 {
+				return csp.ModelConstraint{}, fmt.Errorf("more than one comparison in %q", line)
+			}
+			split = ndx
+		}
+	}
+	if split < 0 {
+		return csp.ModelConstraint{}, fmt.Errorf("unknown command %q", line)
+	}
+	op := csp.Operator(tokens[split])
+	if op == "=" {
+		op = csp.Eq
+	}
+
+	// move every variable to the left and every constant to the right
+	coefficients := map[string]int{}
+	var order []string
+	constant := 0
+	for side, part := range [][]string{tokens[:split], tokens[split+1:]} {
+		sign := 1
+		if side == 1 {
+			sign = -1
+		}
+		terms, err := parseTerms(part)
+		if err != nil {
+			return csp.ModelConstraint{}, err
+		}
+		for _, t := range terms {
+			if t.name == "" {
+				constant -= sign * t.coefficient
+				continue
+			}
+			if _, found := coefficients[t.name]; !found {
+				order = append(order, t.name)
+			}
+			coefficients[t.name] += sign * t.coefficient
+		}
+	}
+
+	mc := csp.ModelConstraint{Operator: op, Constant: constant}
+	unit := true
+	for _, name := range order {
+		if c := coefficients[name]; c != 0 {
+			mc.Variables = append(mc.Variables, name)
+			mc.Coefficients = append(mc.Coefficients, c)
+			unit = unit && c == 1
+		}
+	}
+	if len(mc.Variables) == 0 {
+		return mc, fmt.Errorf("no variables in %q", line)
+	}
+
+	// prefer the binary relations where they fit
+	if len(mc.Variables) == 2 && constant == 0 {
+		a, b := mc.Variables[0], mc.Variables[1]
+		if mc.Coefficients[0] == -1 && mc.Coefficients[1] == 1 {
+			a, b = b, a
+		}
+		if mc.Coefficients[0]*mc.Coefficients[1] == -1 {
+			binary := csp.ModelConstraint{Variables: []string{a, b}}
+			switch op {
+			case csp.Eq:
+				binary.Type = "equal"
+			case csp.Ne:
+				binary.Type = "notequal"
+			case csp.Lt:
+				binary.Type = "less"
+			case csp.Gt:
+				binary.Type, binary.Variables = "less", []string{b, a}
+			}
+			if binary.Type != "" {
+				return binary, nil
+			}
+		}
+	}
+
+	if unit {
+		mc.Type, mc.Coefficients = "sum", nil
+	} else {
+		mc.Type = "linear"
+	}
+	return mc, nil
+}
+
+type term struct {
+	name        string
+	coefficient int
+}
+
+// parse a sum of terms, each optionally signed: x, 3, 2*x
+func parseTerms(tokens []string) ([]term, error) {
+	var out []term
+	for ndx := 0; ndx < len(tokens); {
+		sign := 1
+		for ndx < len(tokens) && (tokens[ndx] == "+" || tokens[ndx] == "-") {
+			if tokens[ndx] == "-" {
+				sign = -sign
+			}
+			ndx++
+		}
+		if ndx == len(tokens) {
+			return nil, fmt.Errorf("missing term")
+		}
+
+		t := term{coefficient: sign}
+		if n, err := strconv.Atoi(tokens[ndx]); err == nil {
+			t.coefficient *= n
+			if ndx+2 < len(tokens) && tokens[ndx+1] == "*" {
+				t.name = tokens[ndx+2]
+				ndx += 2
+			}
+		} else {
+			t.name = tokens[ndx]
+		}
+		if t.name == "*" {
+			return nil, fmt.Errorf("misplaced *")
+		}
+		out = append(out, t)
+		ndx++
+
+		if ndx < len(tokens) && tokens[ndx] != "+" && tokens[ndx] != "-" {
+			return nil, fmt.Errorf("expected + or - before %q", tokens[ndx])
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("missing expression")
+	}
+	return out, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/gen"
+)
+
+// Instance is a named benchmark problem, rebuilt fresh for each run
+type Instance struct {
+	Name  string
+	Build func(rng *rand.Rand) csp.Problem[int, int]
+}
+
+// Strategy is a named way of solving a Problem, reporting
+// whether a solution was found and the work it took
+type Strategy struct {
+	Name  string
+	Solve func(problem csp.Problem[int, int], timeout time.Duration) (bool, csp.Stats)
+}
+
+var (
+	Instances  []Instance
+	Strategies []Strategy
+)
+
+func init() {
+	Instances = []Instance{
+		{Name: "queens-8", Build: queens(8)},
+		{Name: "queens-12", Build: queens(12)},
+		{Name: "modelb-20x5", Build: gen.ModelB{Variables: 20, DomainSize: 5, Density: 0.3, Tightness: 0.3}.Generate},
+		{Name: "modelb-30x6-sparse", Build: gen.ModelB{Variables: 30, DomainSize: 6, Density: 0.1, Tightness: 0.4}.Generate},
+		{Name: "modelrb-20", Build: gen.ModelRB{Variables: 20, Alpha: 0.8, R: 0.8, P: 0.25}.Generate},
+	}
+
+	Strategies = []Strategy{
+		{Name: "backtracking", Solve: backtracking},
+		{Name: "components", Solve: components},
+		{Name: "tree", Solve: tree},
+	}
+}
+
+// model N queens with one variable per row, holding that queen's
+// column, and a constraint between every pair of rows
+func queens(n int) func(*rand.Rand) csp.Problem[int, int] {
+	return func(_ *rand.Rand) csp.Problem[int, int] {
+		columns := make([]int, n)
+		for col := range columns {
+			columns[col] = col
+		}
+		domain := map[int][]int{}
+		for row := 0; row < n; row++ {
+			domain[row] = columns
+		}
+
+		problem := csp.New(domain, func(c csp.Constraint[int], candidate map[int]int) bool {
+			r1, r2 := c.Variables[0], c.Variables[1]
+			c1, found1 := candidate[r1]
+			c2, found2 := candidate[r2]
+			if !found1 || !found2 {
+				return true
+			}
+			return c1 != c2 && abs(c1-c2) != abs(r1-r2)
+		})
+		for r1 := 0; r1 < n; r1++ {
+			for r2 := r1 + 1; r2 < n; r2++ {
+				problem.AddConstraint(csp.Constraint[int]{Variables: []int{r1, r2}})
+			}
+		}
+
+		return problem
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func backtracking(problem csp.Problem[int, int], timeout time.Duration) (bool, csp.Stats) {
+	bt := csp.NewBacktracker(problem)
+	bt.Timeout = timeout
+	result := bt.Solve(map[int]int{})
+	return result != nil, bt.Stats()
+}
+
+func components(problem csp.Problem[int, int], timeout time.Duration) (bool, csp.Stats) {
+	start := time.Now()
+
+	total := csp.Stats{}
+	for _, sub := range problem.Components() {
+		bt := csp.NewBacktracker(sub)
+		if timeout > 0 {
+			// share one time budget across all the components
+			bt.Timeout = timeout - time.Since(start)
+			if bt.Timeout <= 0 {
+				total.TimedOut = true
+				total.Duration = time.Since(start)
+				return false, total
+			}
+		}
+		result := bt.Solve(map[int]int{})
+
+		stats := bt.Stats()
+		total.Nodes += stats.Nodes
+		total.Backtracks += stats.Backtracks
+		total.Rejections += stats.Rejections
+		total.TimedOut = stats.TimedOut
+		if result == nil {
+			total.Duration = time.Since(start)
+			return false, total
+		}
+	}
+
+	total.Duration = time.Since(start)
+	return true, total
+}
+
+// the tree solver doesn't backtrack, so only its runtime is
+// comparable; it also runs to completion regardless of timeout
+func tree(problem csp.Problem[int, int], _ time.Duration) (bool, csp.Stats) {
+	start := time.Now()
+	result := problem.SolveTree(map[int]int{})
+	return result != nil, csp.Stats{Nodes: -1, Backtracks: -1, Duration: time.Since(start)}
+}
+
+// render a counter, or a dash if the strategy doesn't track it
+func count(n int) string {
+	if n < 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// run every selected instance under every selected strategy,
+// and print a table comparing the effort each one took
+func main() {
+	seed := flag.Int64("seed", 1, "random seed for generated instances")
+	runs := flag.Int("runs", 3, "number of runs per instance and strategy, averaged")
+	only := flag.String("strategies", "", "comma-separated strategies to run (default all)")
+	match := flag.String("instances", "", "only run instances whose name contains this string")
+	timeout := flag.Duration("timeout", 5*time.Second, "give up on a single run after this long (0 for no limit)")
+	flag.Parse()
+
+	selected := Strategies
+	if *only != "" {
+		selected = nil
+		for _, name := range strings.Split(*only, ",") {
+			found := false
+			for _, s := range Strategies {
+				if s.Name == name {
+					selected = append(selected, s)
+					found = true
+				}
+			}
+			if !found {
+				fmt.Fprintf(os.Stderr, "unknown strategy %q\n", name)
+				os.Exit(2)
+			}
+		}
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(out, "instance\tstrategy\tsolved\ttime\tnodes\tbacktracks\ttimeouts\t")
+	for _, instance := range Instances {
+		if !strings.Contains(instance.Name, *match) {
+			continue
+		}
+
+		for _, strategy := range selected {
+			var solved, timeouts int
+			total := csp.Stats{}
+			for run := 0; run < *runs; run++ {
+				// every strategy sees the same generated instance
+				problem := instance.Build(rand.New(rand.NewSource(*seed)))
+
+				ok, stats := strategy.Solve(problem, *timeout)
+				if ok {
+					solved++
+				}
+				if stats.TimedOut {
+					timeouts++
+				}
+				total.Nodes += stats.Nodes
+				total.Backtracks += stats.Backtracks
+				total.Duration += stats.Duration
+			}
+
+			n := *runs
+			if n < 1 {
+				n = 1
+			}
+			fmt.Fprintf(out, "%s\t%s\t%d/%d\t%s\t%s\t%s\t%d\t\n",
+				instance.Name, strategy.Name, solved, *runs, total.Duration/time.Duration(n),
+				count(total.Nodes/n), count(total.Backtracks/n), timeouts)
+		}
+	}
+	out.Flush()
+}
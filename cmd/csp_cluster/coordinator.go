@@ -0,0 +1,181 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// a cube of the search, and how far along its search is
+type task struct {
+	id   int
+	cube map[string]int
+	// when the worker searching it is presumed lost, and the cube handed
+	// to another, or zero if it's not being searched
+	lease    time.Time
+	finished bool
+}
+
+// Coordinator hands the cubes of a model out to the workers that ask for
+// them and gathers what they report, until every cube is searched or, in
+// ModeFirst, one has a solution
+type Coordinator struct {
+	join joinResponse
+	// how long a worker may search a cube before it's handed to another,
+	// or zero to wait on it forever
+	lease time.Duration
+
+	mu        sync.Mutex
+	tasks     []*task
+	queue     []int
+	remaining int
+	// the solutions found: all of them, or else the first or cheapest
+	solutions [][]map[string]int
+	best      map[string]int
+	bestCost  int
+	// the cubes whose search timed out, and so may have missed solutions
+	incomplete int
+	stats      statsDocument
+	workers    map[string]bool
+	done       chan struct{}
+	closed     bool
+}
+
+// construct a Coordinator for the cubes of a model, its text and format
+// passed on to the workers as they join
+func NewCoordinator(join joinResponse, cubes []map[string]int) *Coordinator {
+	c := &Coordinator{
+		join:      join,
+		remaining: len(cubes),
+		workers:   map[string]bool{},
+		done:      make(chan struct{}),
+	}
+	// a worker that outlives the time its search may take is presumed lost
+	if join.taskTimeout() > 0 {
+		c.lease = 2*join.taskTimeout() + 10*time.Second
+	}
+	for ndx, cube := range cubes {
+		c.tasks = append(c.tasks, &task{id: ndx, cube: cube})
+		c.queue = append(c.queue, ndx)
+	}
+	if len(cubes) == 0 {
+		c.finish()
+	}
+	return c
+}
+
+// closed once the search is over
+func (c *Coordinator) Done() <-chan struct{} {
+	return c.done
+}
+
+// end the search, with the lock held
+func (c *Coordinator) finish() {
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+}
+
+// describe the model and how to search it, to a worker joining
+func (c *Coordinator) Join(worker string) joinResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.workers[worker] {
+		c.workers[worker] = true
+		log.Printf("worker %s joined", worker)
+	}
+	return c.join
+}
+
+// the next cube for a worker to search: one not yet handed out, or else
+// one whose worker seems to have been lost
+func (c *Coordinator) NextTask(worker string) taskResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return taskResponse{Done: true}
+	}
+
+	var next *task
+	if len(c.queue) > 0 {
+		next = c.tasks[c.queue[0]]
+		c.queue = c.queue[1:]
+	} else if c.lease > 0 {
+		now := time.Now()
+		for _, t := range c.tasks {
+			if !t.finished && now.After(t.lease) {
+				log.Printf("cube %d timed out on its worker, handing it to %s", t.id, worker)
+				next = t
+				break
+			}
+		}
+	}
+	if next == nil {
+		return taskResponse{Wait: true}
+	}
+
+	next.lease = time.Now().Add(c.lease)
+	resp := taskResponse{ID: next.id, Assignment: next.cube}
+	if c.best != nil && c.join.Mode == ModeMinimize {
+		bound := c.bestCost
+		resp.Bound = &bound
+	}
+	return resp
+}
+
+// record the outcome of a task, reporting whether the search is over.
+// the first report of each cube counts, and any later one is dropped
+func (c *Coordinator) Report(report reportRequest) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if report.ID < 0 || report.ID >= len(c.tasks) || c.tasks[report.ID].finished {
+		return c.closed
+	}
+
+	c.tasks[report.ID].finished = true
+	c.remaining--
+	c.stats.Nodes += report.Stats.Nodes
+	c.stats.Backtracks += report.Stats.Backtracks
+	c.stats.Rejections += report.Stats.Rejections
+	if !report.Complete {
+		c.incomplete++
+	}
+
+	switch c.join.Mode {
+	case ModeAll:
+		if len(report.Solutions) > 0 {
+			c.solutions = append(c.solutions, report.Solutions)
+		}
+	case ModeMinimize:
+		if len(report.Solutions) > 0 && (c.best == nil || report.Cost < c.bestCost) {
+			c.best, c.bestCost = report.Solutions[0], report.Cost
+			log.Printf("cube %d: a solution of cost %d", report.ID, report.Cost)
+		}
+	default:
+		if len(report.Solutions) > 0 && c.best == nil {
+			c.best = report.Solutions[0]
+			c.finish()
+		}
+	}
+
+	if c.remaining == 0 {
+		c.finish()
+	}
+	return c.closed
+}
+
+// the result of the search: every solution found, or else the first or
+// cheapest, and the work it took
+func (c *Coordinator) Result() (solutions []map[string]int, cost int, stats statsDocument, incomplete int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.join.Mode == ModeAll {
+		for _, found := range c.solutions {
+			solutions = append(solutions, found...)
+		}
+	} else if c.best != nil {
+		solutions = []map[string]int{c.best}
+	}
+	return solutions, c.bestCost, c.stats, c.incomplete + c.remaining
+}
@@ -0,0 +1,69 @@
+package main
+
+import "time"
+
+// the JSON documents the coordinator and its workers exchange
+//
+// join:    {"worker": "..."}  ->  joinResponse
+// task:    {"worker": "..."}  ->  taskResponse
+// report:  reportRequest  ->  {"done": bool}
+//
+// the model travels as the text of the file the coordinator was given,
+// in its format, so workers read it just as the coordinator did
+
+// the ways the coordinator aggregates the solutions of the cubes
+const (
+	// ModeFirst stops at the first solution of any cube
+	ModeFirst = "first"
+	// ModeAll collects the solutions of every cube
+	ModeAll = "all"
+	// ModeMinimize keeps the cheapest solution, passing its cost on to
+	// each later task as the bound to beat
+	ModeMinimize = "minimize"
+)
+
+type workerRequest struct {
+	Worker string `json:"worker"`
+}
+
+type joinResponse struct {
+	Model              string   `json:"model"`
+	Format             string   `json:"format"`
+	Mode               string   `json:"mode"`
+	Minimize           []string `json:"minimize,omitempty"`
+	TaskTimeoutSeconds float64  `json:"task_timeout_seconds,omitempty"`
+}
+
+func (r joinResponse) taskTimeout() time.Duration {
+	return time.Duration(r.TaskTimeoutSeconds * float64(time.Second))
+}
+
+// a cube to search, or else whether to wait for one or stop. Bound is
+// only set once a minimizing run has a solution
+type taskResponse struct {
+	ID         int            `json:"task_id"`
+	Assignment map[string]int `json:"assignment,omitempty"`
+	Bound      *int           `json:"bound,omitempty"`
+	Wait       bool           `json:"wait,omitempty"`
+	Done       bool           `json:"done,omitempty"`
+}
+
+// the outcome of a task: incomplete if its search timed out, and so may
+// have missed solutions
+type reportRequest struct {
+	ID        int              `json:"task_id"`
+	Complete  bool             `json:"complete"`
+	Solutions []map[string]int `json:"solutions,omitempty"`
+	Cost      int              `json:"cost,omitempty"`
+	Stats     statsDocument    `json:"stats"`
+}
+
+type statsDocument struct {
+	Nodes      int `json:"nodes"`
+	Backtracks int `json:"backtracks"`
+	Rejections int `json:"rejections"`
+}
+
+type reportResponse struct {
+	Done bool `json:"done"`
+}
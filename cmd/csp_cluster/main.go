@@ -0,0 +1,223 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+const usage = `usage: csp_cluster [flags] coordinate MODEL
+       csp_cluster [flags] work ADDRESS
+
+solve a model described in JSON or YAML (see csp.Model), or in XCSP3,
+across several processes or machines. the coordinator splits the search
+into cubes, each a consistent assignment of a few variables, and serves
+them over gRPC to the workers that join it from ADDRESS, each searching
+-procs cubes at a time, then prints the first solution, every solution
+with -all, or the one minimizing the sum of the -minimize variables
+
+flags:
+`
+
+// Options select how the coordinator splits and aggregates the search,
+// and how a worker runs
+type Options struct {
+	Format      string
+	Listen      string
+	Cubes       int
+	All         bool
+	Minimize    string
+	TaskTimeout time.Duration
+	Timeout     time.Duration
+	Procs       int
+	Name        string
+}
+
+var options Options
+
+func init() {
+	host, _ := os.Hostname()
+	flag.StringVar(&options.Format, "format", "", "model format: json, yaml, or xcsp3 (default by file extension)")
+	flag.StringVar(&options.Listen, "listen", ":50052", "coordinator: address to serve the workers on")
+	flag.IntVar(&options.Cubes, "cubes", 256, "coordinator: split the search into at least this many cubes")
+	flag.BoolVar(&options.All, "all", false, "coordinator: find every solution rather than the first")
+	flag.StringVar(&options.Minimize, "minimize", "", "coordinator: comma-separated variables whose sum to minimize")
+	flag.DurationVar(&options.TaskTimeout, "task-timeout", 0, "coordinator: give up on a single cube after this long (0 for no limit)")
+	flag.DurationVar(&options.Timeout, "timeout", 0, "coordinator: give up on the whole search after this long (0 for no limit)")
+	flag.IntVar(&options.Procs, "procs", runtime.GOMAXPROCS(0), "worker: number of cubes to search at once")
+	flag.StringVar(&options.Name, "name", fmt.Sprintf("%s-%d", host, os.Getpid()), "worker: name to report to the coordinator")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+}
+
+// fail with a message, and the conventional exit status for a bad invocation
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "error: "+format+"\n", args...)
+	os.Exit(2)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "coordinate":
+		found, err := coordinate(flag.Arg(1), options)
+		if err != nil {
+			fail("%s", err)
+		}
+		if !found {
+			os.Exit(1)
+		}
+	case "work":
+		if options.Procs < 1 {
+			options.Procs = 1
+		}
+		w, err := Dial(flag.Arg(1), options.Name, options.Procs)
+		if err != nil {
+			fail("%s", err)
+		}
+		if err := w.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// read a model in the given format or else the one its extension implies
+func parse(in io.Reader, format string) (csp.Problem[string, int], error) {
+	switch format {
+	case "json":
+		return csp.LoadJSON(in)
+	case "yaml", "yml":
+		return csp.LoadYAML(in)
+	case "xcsp3", "xml":
+		return csp.LoadXCSP3(in)
+	}
+	return csp.Problem[string, int]{}, fmt.Errorf("unknown model format %q, choose one with -format", format)
+}
+
+// split the model into cubes, serve them until the workers have searched
+// them all, and print what they found. reports whether they found any
+// solution
+func coordinate(path string, opts Options) (bool, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	format := opts.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	problem, err := parse(strings.NewReader(string(text)), format)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", path, err)
+	}
+
+	join := joinResponse{
+		Model:              string(text),
+		Format:             format,
+		Mode:               ModeFirst,
+		TaskTimeoutSeconds: opts.TaskTimeout.Seconds(),
+	}
+	if opts.All {
+		join.Mode = ModeAll
+	}
+	if opts.Minimize != "" {
+		if opts.All {
+			return false, fmt.Errorf("choose one of -all and -minimize")
+		}
+		join.Mode, join.Minimize = ModeMinimize, strings.Split(opts.Minimize, ",")
+		if _, err := objective(problem, join.Minimize); err != nil {
+			return false, err
+		}
+	}
+
+	start := time.Now()
+	cubes := problem.Cubes(map[string]int{}, opts.Cubes)
+	coordinator := NewCoordinator(join, cubes)
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return false, err
+	}
+	server := grpc.NewServer()
+	server.RegisterService(&clusterServiceDesc, &clusterService{coordinator: coordinator})
+	log.Printf("serving %d cubes to workers on %s", len(cubes), listener.Addr())
+	go server.Serve(listener)
+
+	var deadline <-chan time.Time
+	if opts.Timeout > 0 {
+		deadline = time.After(opts.Timeout)
+	}
+	timedOut := false
+	select {
+	case <-coordinator.Done():
+	case <-deadline:
+		timedOut = true
+	}
+	// give the workers a moment to hear that the search is over
+	time.Sleep(2 * pollInterval)
+	server.Stop()
+
+	solutions, cost, stats, incomplete := coordinator.Result()
+	for ndx, solution := range solutions {
+		if join.Mode == ModeAll {
+			fmt.Printf("solution %d:\n", ndx+1)
+		} else {
+			fmt.Println("Solution:")
+		}
+		printSolution(os.Stdout, solution)
+	}
+	if len(solutions) == 0 {
+		fmt.Println("no solution")
+	}
+	if join.Mode == ModeMinimize && len(solutions) > 0 {
+		fmt.Printf("cost: %d\n", cost)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d solutions, %d cubes, %d nodes, %d backtracks, %d rejections in %s",
+		len(solutions), len(cubes), stats.Nodes, stats.Backtracks, stats.Rejections, time.Since(start))
+	switch {
+	case timedOut:
+		fmt.Fprint(os.Stderr, " (timed out)")
+	case incomplete > 0 && (join.Mode != ModeFirst || len(solutions) == 0):
+		fmt.Fprintf(os.Stderr, " (%d cubes timed out)", incomplete)
+	}
+	fmt.Fprintln(os.Stderr)
+
+	return len(solutions) > 0, nil
+}
+
+// print an assignment one variable per line, in order of name
+func printSolution(out io.Writer, solution map[string]int) {
+	var names []string
+	for v := range solution {
+		names = append(names, v)
+	}
+	sort.Strings(names)
+
+	for _, v := range names {
+		fmt.Fprintf(out, "  %s = %d\n", v, solution[v])
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// the gRPC service of cluster.proto, served by the coordinator, built by
+// hand over the well-known Struct as cmd/csp_server's is
+type clusterService struct {
+	coordinator *Coordinator
+}
+
+type unaryMethod func(s *clusterService, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+
+// adapt a method to the handler signature grpc expects
+func unary(name string, method unaryMethod) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return method(srv.(*clusterService), ctx, req.(*structpb.Struct))
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/csp.v1.Cluster/" + name}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+var clusterServiceDesc = grpc.ServiceDesc{
+	ServiceName: "csp.v1.Cluster",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		unary("Join", (*clusterService).Join),
+		unary("NextTask", (*clusterService).NextTask),
+		unary("Report", (*clusterService).Report),
+	},
+	Metadata: "cluster.proto",
+}
+
+// decode a Struct into one of the documents
+func decode(in *structpb.Struct, out any) error {
+	encoded, err := protojson.Marshal(in)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// encode one of the documents as a Struct, by way of its JSON
+func encode(doc any) (*structpb.Struct, error) {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	out := new(structpb.Struct)
+	if err := protojson.Unmarshal(encoded, out); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+func (s *clusterService) Join(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req workerRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+	return encode(s.coordinator.Join(req.Worker))
+}
+
+func (s *clusterService) NextTask(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req workerRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+	return encode(s.coordinator.NextTask(req.Worker))
+}
+
+func (s *clusterService) Report(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req reportRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+	return encode(reportResponse{Done: s.coordinator.Report(req)})
+}
+
+// call a method of the coordinator's service, from a worker
+func call(ctx context.Context, conn *grpc.ClientConn, method string, req, resp any) error {
+	in, err := encode(req)
+	if err != nil {
+		return err
+	}
+	out := new(structpb.Struct)
+	if err := conn.Invoke(ctx, "/csp.v1.Cluster/"+method, in, out); err != nil {
+		return err
+	}
+	return decode(out, resp)
+}
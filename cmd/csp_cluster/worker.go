@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// how long a worker waits before asking again for a cube, when the rest
+// are all being searched
+const pollInterval = 200 * time.Millisecond
+
+// Worker searches the cubes a coordinator hands it, several at a time,
+// until there are none left
+type Worker struct {
+	Name string
+	// Procs is the number of cubes searched at once
+	Procs int
+
+	conn    *grpc.ClientConn
+	join    joinResponse
+	problem csp.Problem[string, int]
+	cost    csp.Cost[string, int]
+}
+
+// join the coordinator at the address, reading the model it's searching
+func Dial(addr, name string, procs int) (*Worker, error) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	w := &Worker{Name: name, Procs: procs, conn: conn}
+
+	if err := call(context.Background(), conn, "Join", workerRequest{Worker: name}, &w.join); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("joining %s: %w", addr, err)
+	}
+	w.problem, err = parse(bytes.NewReader([]byte(w.join.Model)), w.join.Format)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("the coordinator's model: %w", err)
+	}
+	if w.join.Mode == ModeMinimize {
+		w.cost, err = objective(w.problem, w.join.Minimize)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// search cubes until the coordinator has no more, or is gone
+func (w *Worker) Run() error {
+	defer w.conn.Close()
+
+	errs := make(chan error, w.Procs)
+	var wg sync.WaitGroup
+	for ndx := 0; ndx < w.Procs; ndx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- w.loop()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ask for cubes and report their outcomes, one at a time
+func (w *Worker) loop() error {
+	ctx := context.Background()
+	for {
+		var next taskResponse
+		if err := call(ctx, w.conn, "NextTask", workerRequest{Worker: w.Name}, &next); err != nil {
+			return gone(err)
+		}
+		if next.Done {
+			return nil
+		}
+		if next.Wait {
+			time.Sleep(pollInterval)
+			continue
+		}
+
+		report := w.search(next)
+		var resp reportResponse
+		if err := call(ctx, w.conn, "Report", report, &resp); err != nil {
+			return gone(err)
+		}
+		if resp.Done {
+			return nil
+		}
+	}
+}
+
+// search a cube as the coordinator's mode directs
+func (w *Worker) search(next taskResponse) reportRequest {
+	bt := csp.NewBacktracker(w.problem)
+	bt.SelectVariable = csp.MinRemainingValues(w.problem)
+	bt.Timeout = w.join.taskTimeout()
+
+	report := reportRequest{ID: next.ID}
+	switch w.join.Mode {
+	case ModeAll:
+		report.Solutions = bt.SolveAll(next.Assignment)
+	case ModeMinimize:
+		var best map[string]int
+		if next.Bound != nil {
+			best, report.Cost = bt.MinimizeBelow(next.Assignment, w.cost, *next.Bound)
+		} else {
+			best, report.Cost = bt.Minimize(next.Assignment, w.cost)
+		}
+		if best != nil {
+			report.Solutions = []map[string]int{best}
+		}
+	default:
+		if solution := bt.Solve(next.Assignment); solution != nil {
+			report.Solutions = []map[string]int{solution}
+		}
+	}
+
+	stats := bt.Stats()
+	report.Complete = !stats.TimedOut
+	report.Stats = statsDocument{Nodes: stats.Nodes, Backtracks: stats.Backtracks, Rejections: stats.Rejections}
+	log.Printf("cube %d: %d solutions, %d nodes in %s", next.ID, len(report.Solutions), stats.Nodes, stats.Duration)
+	return report
+}
+
+// a coordinator that has stopped serving has finished its search, and
+// isn't an error to its workers
+func gone(err error) error {
+	if status.Code(err) == codes.Unavailable {
+		log.Printf("the coordinator is gone")
+		return nil
+	}
+	return err
+}
+
+// the sum of the named variables, bounded below for a partial assignment
+// by the least value in the domain of each one unassigned
+func objective(problem csp.Problem[string, int], names []string) (csp.Cost[string, int], error) {
+	least := map[string]int{}
+	for _, name := range names {
+		values, found := problem.Domain[name]
+		if !found {
+			return nil, fmt.Errorf("-minimize: unknown variable %q", name)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		least[name] = values[0]
+		for _, value := range values {
+			if value < least[name] {
+				least[name] = value
+			}
+		}
+	}
+
+	return func(assignment map[string]int) int {
+		total := 0
+		for _, name := range names {
+			if value, found := assignment[name]; found {
+				total += value
+			} else {
+				total += least[name]
+			}
+		}
+		return total
+	}, nil
+}
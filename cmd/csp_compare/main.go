@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+const usage = `usage: csp_compare [flags] MODEL
+
+solve a model described in JSON or YAML (see csp.Model), or in XCSP3,
+under each of several strategies in turn, and print a table of the time,
+nodes and backtracks each took, to choose the one to configure csp with.
+the strategies are:
+
+  dfs            depth-first backtracking, variables and values in order
+  mrv+lcv        the variable with the fewest values left, and its least
+                 constraining values first
+  mac            mrv, trying only the values that keep arc consistency
+  parallel       mrv, searched by a worker per processor stealing work
+                 from each other
+  min-conflicts  local search, repairing a complete assignment; it can't
+                 prove there is no solution
+  breakout       min-conflicts weighing each constraint's conflicts, and
+                 raising the weights of those violated at local minima
+
+flags:
+`
+
+// Strategy is a named way of solving a Problem: an engine configured to
+// give up after the timeout, and any randomness drawn from rng
+type Strategy struct {
+	Name string
+	New  func(problem csp.Problem[string, int], timeout time.Duration, rng *rand.Rand) csp.Solver[string, int]
+	// local search has no backtracks to count; its nodes are the values it
+	// assigns, repairs included
+	Local bool
+}
+
+var Strategies = []Strategy{
+	{Name: "dfs", New: dfs},
+	{Name: "mrv+lcv", New: mrvLCV},
+	{Name: "mac", New: mac},
+	{Name: "parallel", New: parallel},
+	{Name: "min-conflicts", New: minConflicts, Local: true},
+	{Name: "breakout", New: breakout, Local: true},
+}
+
+func dfs(problem csp.Problem[string, int], timeout time.Duration, _ *rand.Rand) csp.Solver[string, int] {
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.Lexicographic(problem)
+	bt.Timeout = timeout
+	return bt
+}
+
+func mrvLCV(problem csp.Problem[string, int], timeout time.Duration, _ *rand.Rand) csp.Solver[string, int] {
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = csp.LeastConstrainingValue(problem)
+	bt.Timeout = timeout
+	return bt
+}
+
+func mac(problem csp.Problem[string, int], timeout time.Duration, _ *rand.Rand) csp.Solver[string, int] {
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = csp.MaintainArcConsistency(problem)
+	bt.Timeout = timeout
+	return bt
+}
+
+func parallel(problem csp.Problem[string, int], timeout time.Duration, _ *rand.Rand) csp.Solver[string, int] {
+	s := csp.NewParallel(problem)
+	s.SelectVariable = csp.MinRemainingValues(problem)
+	s.Timeout = timeout
+	return s
+}
+
+func minConflicts(problem csp.Problem[string, int], timeout time.Duration, rng *rand.Rand) csp.Solver[string, int] {
+	mc := csp.NewMinConflicts(problem)
+	mc.Timeout = timeout
+	mc.Rand = rng
+	return mc
+}
+
+// as minConflicts, escaping local minima by constraint weights, not noise
+func breakout(problem csp.Problem[string, int], timeout time.Duration, rng *rand.Rand) csp.Solver[string, int] {
+	mc := csp.NewMinConflicts(problem)
+	mc.Timeout = timeout
+	mc.Rand = rng
+	mc.Noise = 0
+	mc.Breakout = true
+	return mc
+}
+
+// read a model file, in the given format or else the one its extension implies
+func load(path, format string) (csp.Problem[string, int], error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return csp.Problem[string, int]{}, err
+	}
+	defer in.Close()
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	var problem csp.Problem[string, int]
+	switch format {
+	case "json":
+		problem, err = csp.LoadJSON(in)
+	case "yaml", "yml":
+		problem, err = csp.LoadYAML(in)
+	case "xcsp3", "xml":
+		problem, err = csp.LoadXCSP3(in)
+	default:
+		return problem, fmt.Errorf("%s: unknown model format %q, choose one with -format", path, format)
+	}
+	if err != nil {
+		return problem, fmt.Errorf("%s: %s", path, err)
+	}
+	return problem, nil
+}
+
+// render a counter, or a dash if the strategy doesn't track it
+func count(n int) string {
+	if n < 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// run the model under every selected strategy, and print a table
+// comparing the effort each one took
+func main() {
+	format := flag.String("format", "", "model format: json, yaml, or xcsp3 (default by file extension)")
+	runs := flag.Int("runs", 3, "number of runs per strategy, averaged")
+	only := flag.String("strategies", "", "comma-separated strategies to run (default all)")
+	timeout := flag.Duration("timeout", 10*time.Second, "give up on a single run after this long (0 for no limit)")
+	seed := flag.Int64("seed", 1, "random seed for min-conflicts")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	selected := Strategies
+	if *only != "" {
+		selected = nil
+		for _, name := range strings.Split(*only, ",") {
+			found := false
+			for _, s := range Strategies {
+				if s.Name == name {
+					selected = append(selected, s)
+					found = true
+				}
+			}
+			if !found {
+				fmt.Fprintf(os.Stderr, "unknown strategy %q\n", name)
+				os.Exit(2)
+			}
+		}
+	}
+	if _, err := load(flag.Arg(0), *format); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(out, "strategy\tsolved\ttime\tnodes\tbacktracks\ttimeouts\t")
+	for _, strategy := range selected {
+		var solved, timeouts int
+		total := csp.Stats{}
+		rng := rand.New(rand.NewSource(*seed))
+		for run := 0; run < *runs; run++ {
+			// every run starts from a freshly loaded model
+			problem, _ := load(flag.Arg(0), *format)
+
+			solver := strategy.New(problem, *timeout, rng)
+			result := solver.Solve(map[string]int{})
+			stats := solver.Stats()
+			if strategy.Local {
+				stats.Backtracks = -1
+			}
+			if result != nil {
+				solved++
+			}
+			if stats.TimedOut {
+				timeouts++
+			}
+			total.Nodes += stats.Nodes
+			total.Backtracks += stats.Backtracks
+			total.Duration += stats.Duration
+		}
+
+		n := *runs
+		if n < 1 {
+			n = 1
+		}
+		fmt.Fprintf(out, "%s\t%d/%d\t%s\t%s\t%s\t%d\t\n",
+			strategy.Name, solved, *runs, total.Duration/time.Duration(n),
+			count(total.Nodes/n), count(total.Backtracks/n), timeouts)
+	}
+	out.Flush()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// the JSON documents the API exchanges, shared by every transport
+//
+// submit:    {"model": {...}, "timeout_seconds": 30}  ->  {"job_id": "..."}
+// status:    {"job_id": "..."}  ->  statusDocument
+// solution:  {"job_id": "..."}  ->  {"job_id", "state", "solution": csp.Solution}
+// cancel:    {"job_id": "..."}  ->  {"job_id", "state"}
+//
+// models use the format read by csp.LoadJSON
+
+type submitRequest struct {
+	Model          json.RawMessage `json:"model"`
+	TimeoutSeconds float64         `json:"timeout_seconds"`
+}
+
+func (r submitRequest) timeout() time.Duration {
+	return time.Duration(r.TimeoutSeconds * float64(time.Second))
+}
+
+type jobRequest struct {
+	ID string `json:"job_id"`
+}
+
+// describe a job's status, leaving out the times of steps it hasn't reached
+func statusDocument(status JobStatus) map[string]any {
+	doc := map[string]any{
+		"job_id":    status.ID,
+		"state":     status.State,
+		"submitted": status.Submitted.Format(time.RFC3339Nano),
+	}
+	if !status.Started.IsZero() {
+		doc["started"] = status.Started.Format(time.RFC3339Nano)
+	}
+	if !status.Finished.IsZero() {
+		doc["finished"] = status.Finished.Format(time.RFC3339Nano)
+		doc["stats"] = map[string]any{
+			"nodes":      status.Stats.Nodes,
+			"backtracks": status.Stats.Backtracks,
+			"rejections": status.Stats.Rejections,
+			"seconds":    status.Stats.Duration.Seconds(),
+		}
+	}
+
+	return doc
+}
+
+// describe a job's solution, once it has finished
+func solutionDocument(id, state string, solution csp.Solution[string, int]) (map[string]any, error) {
+	doc := map[string]any{
+		"job_id": id,
+		"state":  state,
+	}
+	if state == StateQueued || state == StateRunning {
+		return doc, nil
+	}
+
+	encoded, err := json.Marshal(solution)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	doc["solution"] = decoded
+
+	return doc, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// the gRPC service of csp.proto. every message is a google.protobuf.Struct
+// holding one of the JSON documents of documents.go, so clients in any
+// language can call it using only the well-known types, with no generated
+// code beyond what their protobuf runtime already ships
+type solverService struct {
+	jobs *Jobs
+}
+
+type unaryMethod func(s *solverService, ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+
+// adapt a method to the handler signature grpc expects
+func unary(name string, method unaryMethod) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return method(srv.(*solverService), ctx, req.(*structpb.Struct))
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/csp.v1.Solver/" + name}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+var solverServiceDesc = grpc.ServiceDesc{
+	ServiceName: "csp.v1.Solver",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		unary("SubmitProblem", (*solverService).SubmitProblem),
+		unary("GetStatus", (*solverService).GetStatus),
+		unary("GetSolution", (*solverService).GetSolution),
+		unary("Cancel", (*solverService).Cancel),
+	},
+	Metadata: "csp.proto",
+}
+
+// decode a request Struct into one of the request documents
+func decode(in *structpb.Struct, out any) error {
+	encoded, err := protojson.Marshal(in)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return nil
+}
+
+// encode a response document as a Struct
+func encode(doc map[string]any) (*structpb.Struct, error) {
+	out, err := structpb.NewStruct(doc)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// translate job errors into gRPC status codes
+func jobError(err error) error {
+	if errors.Is(err, errNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (s *solverService) SubmitProblem(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req submitRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+
+	id, err := s.jobs.Submit(req.Model, req.timeout())
+	if errors.Is(err, errQueueFull) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return encode(map[string]any{"job_id": id})
+}
+
+func (s *solverService) GetStatus(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req jobRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+
+	st, err := s.jobs.Status(req.ID)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return encode(statusDocument(st))
+}
+
+func (s *solverService) GetSolution(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req jobRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+
+	state, solution, err := s.jobs.Solution(req.ID)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	doc, err := solutionDocument(req.ID, state, solution)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return encode(doc)
+}
+
+func (s *solverService) Cancel(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	var req jobRequest
+	if err := decode(in, &req); err != nil {
+		return nil, err
+	}
+
+	state, err := s.jobs.Cancel(req.ID)
+	if err != nil {
+		return nil, jobError(err)
+	}
+	return encode(map[string]any{"job_id": req.ID, "state": state})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// the states a Job moves through: queued until a worker is free, running,
+// then one of the final states
+const (
+	StateQueued        = "queued"
+	StateRunning       = "running"
+	StateSolved        = "solved"
+	StateUnsatisfiable = "unsatisfiable"
+	StateTimedOut      = "timed_out"
+	StateCanceled      = "canceled"
+)
+
+var (
+	errNotFound  = errors.New("no such job")
+	errQueueFull = errors.New("too many jobs queued, try again later")
+)
+
+// Job is a single submitted model and the progress of its solve
+type Job struct {
+	ID        string
+	State     string
+	Submitted time.Time
+	Started   time.Time
+	Finished  time.Time
+	Solution  csp.Solution[string, int]
+
+	solver *csp.Backtracker[string, int]
+	// closed once the job reaches a final state
+	done chan struct{}
+}
+
+// JobStatus is the state of a Job without its solution, as reported by GetStatus
+type JobStatus struct {
+	ID        string
+	State     string
+	Submitted time.Time
+	Started   time.Time
+	Finished  time.Time
+	// Stats is only set once the job has finished
+	Stats csp.Stats
+}
+
+// Jobs runs submitted models in the background, a limited number at a
+// time, with a limited number more waiting their turn, and keeps each
+// job for a while after it finishes, for its solution to be fetched
+type Jobs struct {
+	// Timeout bounds each solve, or zero for no limit
+	Timeout time.Duration
+	// Queue bounds the jobs waiting for a worker, past which Submit
+	// refuses more
+	Queue int
+	// TTL is how long a finished job is kept, or zero to keep it for
+	// the life of the server
+	TTL time.Duration
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	slots chan struct{}
+	// the jobs not yet finished, running or waiting for a worker
+	pending int
+}
+
+// construct an empty Jobs running up to workers solves at once
+func NewJobs(workers int, timeout time.Duration) *Jobs {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Jobs{
+		Timeout: timeout,
+		jobs:    map[string]*Job{},
+		slots:   make(chan struct{}, workers),
+	}
+}
+
+// forget the jobs that finished longer than the TTL ago. the caller
+// holds the lock
+func (js *Jobs) evict(now time.Time) {
+	if js.TTL <= 0 {
+		return
+	}
+	for id, job := range js.jobs {
+		if !job.Finished.IsZero() && now.Sub(job.Finished) > js.TTL {
+			delete(js.jobs, id)
+		}
+	}
+}
+
+// validate a JSON model document and queue it for solving. a positive
+// timeout tightens the server's own Timeout for this job
+func (js *Jobs) Submit(model []byte, timeout time.Duration) (string, error) {
+	problem, err := csp.LoadJSON(bytes.NewReader(model))
+	if err != nil {
+		return "", err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	solver := csp.NewBacktracker(problem)
+	solver.Timeout = js.Timeout
+	if timeout > 0 && (js.Timeout == 0 || timeout < js.Timeout) {
+		solver.Timeout = timeout
+	}
+	job := &Job{
+		ID:        hex.EncodeToString(id),
+		State:     StateQueued,
+		Submitted: time.Now(),
+		solver:    solver,
+		done:      make(chan struct{}),
+	}
+
+	js.mu.Lock()
+	js.evict(job.Submitted)
+	if js.pending >= cap(js.slots)+js.Queue {
+		js.mu.Unlock()
+		return "", errQueueFull
+	}
+	js.pending++
+	js.jobs[job.ID] = job
+	js.mu.Unlock()
+
+	go js.run(job)
+	return job.ID, nil
+}
+
+// wait for a free worker, then solve the job
+func (js *Jobs) run(job *Job) {
+	defer func() {
+		js.mu.Lock()
+		js.pending--
+		js.mu.Unlock()
+	}()
+	js.slots <- struct{}{}
+	defer func() { <-js.slots }()
+
+	js.mu.Lock()
+	if job.State == StateCanceled {
+		js.mu.Unlock()
+		return
+	}
+	job.State = StateRunning
+	job.Started = time.Now()
+	js.mu.Unlock()
+
+	assignment := job.solver.Solve(map[string]int{})
+	stats := job.solver.Stats()
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	defer close(job.done)
+	job.Finished = time.Now()
+	job.Solution = csp.Solution[string, int]{
+		Assignment: assignment,
+		Stats:      stats,
+		Strategy:   "backtracking",
+		Timestamp:  job.Finished,
+	}
+	switch {
+	case assignment != nil:
+		job.State = StateSolved
+	case stats.Canceled:
+		job.State = StateCanceled
+	case stats.TimedOut:
+		job.State = StateTimedOut
+	default:
+		job.State = StateUnsatisfiable
+	}
+}
+
+// report the state of a job
+func (js *Jobs) Status(id string) (JobStatus, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, found := js.jobs[id]
+	if !found {
+		return JobStatus{}, errNotFound
+	}
+	return JobStatus{
+		ID:        job.ID,
+		State:     job.State,
+		Submitted: job.Submitted,
+		Started:   job.Started,
+		Finished:  job.Finished,
+		Stats:     job.Solution.Stats,
+	}, nil
+}
+
+// obtain a job's state and, once it has finished, its solution
+func (js *Jobs) Solution(id string) (string, csp.Solution[string, int], error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, found := js.jobs[id]
+	if !found {
+		return "", csp.Solution[string, int]{}, errNotFound
+	}
+	return job.State, job.Solution, nil
+}
+
+// wait up to timeout for a job to finish, reporting whether it did
+func (js *Jobs) Wait(id string, timeout time.Duration) (bool, error) {
+	js.mu.Lock()
+	job, found := js.jobs[id]
+	js.mu.Unlock()
+	if !found {
+		return false, errNotFound
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-job.done:
+		return true, nil
+	case <-timer.C:
+		return false, nil
+	}
+}
+
+// stop a job, whether it's queued or running. canceling a job
+// that has already finished has no effect
+func (js *Jobs) Cancel(id string) (string, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	job, found := js.jobs[id]
+	if !found {
+		return "", errNotFound
+	}
+	switch job.State {
+	case StateQueued:
+		job.State = StateCanceled
+		job.Finished = time.Now()
+		close(job.done)
+	case StateRunning:
+		// the solve reports the cancellation as it returns
+		job.solver.Cancel()
+	}
+	return job.State, nil
+}
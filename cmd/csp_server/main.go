@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"runtime"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// serve the solver over gRPC and HTTP: clients submit a JSON model, poll
+// for its status, then fetch the solution or cancel the job. both APIs
+// share the same jobs
+func main() {
+	grpcAddr := flag.String("grpc", ":50051", "address to serve gRPC on (empty to disable)")
+	httpAddr := flag.String("http", ":8080", "address to serve the HTTP/JSON API on (empty to disable)")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of models to solve at once")
+	timeout := flag.Duration("timeout", 10*time.Minute, "give up on any single solve after this long (0 for no limit)")
+	queue := flag.Int("queue", 100, "number of models to hold waiting for a worker, past which more are refused")
+	ttl := flag.Duration("ttl", time.Hour, "forget a job this long after it finishes (0 to keep every job)")
+	flag.Parse()
+
+	if *grpcAddr == "" && *httpAddr == "" {
+		log.Fatal("error: nothing to serve, with both -grpc and -http disabled")
+	}
+	jobs := NewJobs(*workers, *timeout)
+	jobs.Queue, jobs.TTL = *queue, *ttl
+
+	errs := make(chan error, 2)
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("error: %s", err)
+		}
+		server := grpc.NewServer()
+		server.RegisterService(&solverServiceDesc, &solverService{jobs: jobs})
+
+		log.Printf("serving gRPC on %s", listener.Addr())
+		go func() { errs <- server.Serve(listener) }()
+	}
+	if *httpAddr != "" {
+		listener, err := net.Listen("tcp", *httpAddr)
+		if err != nil {
+			log.Fatalf("error: %s", err)
+		}
+
+		log.Printf("serving HTTP on %s", listener.Addr())
+		go func() { errs <- http.Serve(listener, restHandler{jobs: jobs}) }()
+	}
+
+	log.Fatalf("error: %s", <-errs)
+}
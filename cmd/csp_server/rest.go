@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// the largest model document accepted
+const maxModelBytes = 16 << 20
+
+// the HTTP/JSON API, for quick integrations and testing with curl:
+//
+//	POST   /solve                post a model, and get back its job ID
+//	GET    /jobs/{id}            the job's status
+//	GET    /jobs/{id}/solution   the job's solution, once it has finished
+//	DELETE /jobs/{id}            cancel the job
+//
+// POST /solve takes a timeout query parameter to bound the solve, e.g.
+// ?timeout=30s, and a wait parameter to answer with the solution if it is
+// found soon enough, e.g. ?wait=5s; otherwise it answers 202 Accepted,
+// or 429 Too Many Requests if the queue of jobs is full.
+// responses are the documents of documents.go
+type restHandler struct {
+	jobs *Jobs
+}
+
+func (h restHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "solve":
+		if r.Method != http.MethodPost {
+			h.fail(w, http.StatusMethodNotAllowed, fmt.Errorf("use POST to submit a model"))
+			return
+		}
+		h.solve(w, r)
+	case len(parts) == 2 && parts[0] == "jobs":
+		switch r.Method {
+		case http.MethodGet:
+			h.status(w, parts[1])
+		case http.MethodDelete:
+			h.cancel(w, parts[1])
+		default:
+			h.fail(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET or DELETE on a job"))
+		}
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "solution":
+		if r.Method != http.MethodGet {
+			h.fail(w, http.StatusMethodNotAllowed, fmt.Errorf("use GET to fetch a solution"))
+			return
+		}
+		h.solution(w, http.StatusOK, parts[1])
+	default:
+		h.fail(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+	}
+}
+
+func (h restHandler) solve(w http.ResponseWriter, r *http.Request) {
+	var timeout, wait time.Duration
+	for name, d := range map[string]*time.Duration{"timeout": &timeout, "wait": &wait} {
+		if value := r.URL.Query().Get(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				h.fail(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %s", name, err))
+				return
+			}
+			*d = parsed
+		}
+	}
+
+	model, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxModelBytes))
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err)
+		return
+	}
+	id, err := h.jobs.Submit(model, timeout)
+	if errors.Is(err, errQueueFull) {
+		h.fail(w, http.StatusTooManyRequests, err)
+		return
+	}
+	if err != nil {
+		h.fail(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if wait > 0 {
+		if finished, err := h.jobs.Wait(id, wait); err == nil && finished {
+			h.solution(w, http.StatusOK, id)
+			return
+		}
+	}
+	status, err := h.jobs.Status(id)
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+id)
+	h.reply(w, http.StatusAccepted, statusDocument(status))
+}
+
+func (h restHandler) status(w http.ResponseWriter, id string) {
+	status, err := h.jobs.Status(id)
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+	h.reply(w, http.StatusOK, statusDocument(status))
+}
+
+func (h restHandler) solution(w http.ResponseWriter, code int, id string) {
+	state, solution, err := h.jobs.Solution(id)
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+	doc, err := solutionDocument(id, state, solution)
+	if err != nil {
+		h.fail(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.reply(w, code, doc)
+}
+
+func (h restHandler) cancel(w http.ResponseWriter, id string) {
+	state, err := h.jobs.Cancel(id)
+	if err != nil {
+		h.jobError(w, err)
+		return
+	}
+	h.reply(w, http.StatusOK, map[string]any{"job_id": id, "state": state})
+}
+
+func (h restHandler) jobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNotFound) {
+		h.fail(w, http.StatusNotFound, err)
+		return
+	}
+	h.fail(w, http.StatusInternalServerError, err)
+}
+
+func (h restHandler) fail(w http.ResponseWriter, code int, err error) {
+	h.reply(w, code, map[string]any{"error": err.Error()})
+}
+
+func (h restHandler) reply(w http.ResponseWriter, code int, doc map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(doc)
+}
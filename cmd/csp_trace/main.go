@@ -0,0 +1,287 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+const usage = `usage:
+  csp_trace [flags] record MODEL TRACE   solve a JSON or YAML model, recording its search to TRACE
+  csp_trace [flags] summary TRACE        summarize where a recorded search spent its effort
+  csp_trace [flags] replay TRACE         print a recorded search step by step
+
+flags:
+`
+
+// report a bad invocation and exit with the conventional status for it
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
+
+func main() {
+	all := flag.Bool("all", false, "record: search for every solution rather than the first")
+	timeout := flag.Duration("timeout", 0, "record: give up on the search after this long (0 for no limit)")
+	top := flag.Int("top", 10, "summary: number of constraints and variables to rank")
+	maxDepth := flag.Int("max-depth", -1, "replay: hide steps deeper than this (-1 for no limit)")
+	limit := flag.Int("limit", 0, "replay: stop after this many steps (0 for no limit)")
+	delay := flag.Duration("delay", 0, "replay: pause between steps, to watch the search unfold")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch args[0] {
+	case "record":
+		if len(args) != 3 {
+			fail("record takes a model and a trace path")
+		}
+		err = record(args[1], args[2], *all, *timeout)
+	case "summary":
+		if len(args) != 2 {
+			fail("summary takes a trace path")
+		}
+		err = summary(args[1], *top)
+	case "replay":
+		if len(args) != 2 {
+			fail("replay takes a trace path")
+		}
+		err = replay(args[1], *maxDepth, *limit, *delay)
+	default:
+		fail("unknown command %q", args[0])
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// solve the model, writing a trace of the search
+func record(modelPath, tracePath string, all bool, timeout time.Duration) error {
+	in, err := os.Open(modelPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var problem csp.Problem[string, int]
+	switch strings.ToLower(filepath.Ext(modelPath)) {
+	case ".yaml", ".yml":
+		problem, err = csp.LoadYAML(in)
+	default:
+		problem, err = csp.LoadJSON(in)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", modelPath, err)
+	}
+
+	out, err := os.Create(tracePath)
+	if err != nil {
+		return err
+	}
+
+	tracer := csp.NewTracer[string, int](out)
+	bt := csp.NewBacktracker(problem)
+	bt.Timeout = timeout
+	bt.Observe(tracer.Hooks())
+	if all {
+		bt.SolveAll(map[string]int{})
+	} else {
+		bt.Solve(map[string]int{})
+	}
+	if err := tracer.Flush(); err != nil {
+		out.Close()
+		return err
+	}
+
+	stats := bt.Stats()
+	fmt.Printf("recorded %d nodes, %d backtracks, %d solutions in %s", stats.Nodes, stats.Backtracks, stats.Solutions, stats.Duration)
+	if stats.TimedOut {
+		fmt.Print(" (timed out)")
+	}
+	fmt.Println()
+
+	return out.Close()
+}
+
+// call visit with each event of the trace in turn, until it returns false
+func events(tracePath string, visit func(csp.TraceEvent) bool) error {
+	in, err := os.Open(tracePath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tr := csp.NewTraceReader(in)
+	for {
+		event, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", tracePath, err)
+		}
+		if !visit(event) {
+			return nil
+		}
+	}
+}
+
+// a count keyed by name, for ranking
+type ranked struct {
+	name  string
+	count int
+}
+
+// the n highest counts, breaking ties by name so output is stable
+func rank(counts map[string]int, n int) []ranked {
+	var out []ranked
+	for name, count := range counts {
+		out = append(out, ranked{name: name, count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].name < out[j].name
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+
+	return out
+}
+
+// report totals, the effort spent at each depth, and the constraints and
+// variables most responsible for failures
+func summary(tracePath string, top int) error {
+	kinds := map[csp.TraceKind]int{}
+	var nodes, rejections, backtracks []int
+	byConstraint := map[rejector]int{}
+	byVariable := map[string]int{}
+
+	grow := func(counts []int, depth int) []int {
+		for len(counts) <= depth {
+			counts = append(counts, 0)
+		}
+		return counts
+	}
+
+	err := events(tracePath, func(event csp.TraceEvent) bool {
+		kinds[event.Kind]++
+		switch event.Kind {
+		case csp.TraceAssign:
+			nodes = grow(nodes, event.Depth)
+			nodes[event.Depth]++
+		case csp.TraceReject:
+			rejections = grow(rejections, event.Depth)
+			rejections[event.Depth]++
+			byConstraint[rejector{id: event.ConstraintID, name: event.Constraint}]++
+		case csp.TraceBacktrack:
+			backtracks = grow(backtracks, event.Depth)
+			backtracks[event.Depth]++
+			byVariable[event.Variable]++
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d nodes, %d rejections, %d backtracks, %d solutions, max depth %d\n\n",
+		kinds[csp.TraceAssign], kinds[csp.TraceReject], kinds[csp.TraceBacktrack], kinds[csp.TraceSolution], len(nodes)-1)
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(out, "depth\tnodes\trejections\tbacktracks\t")
+	for depth := range nodes {
+		rejections, backtracks = grow(rejections, depth), grow(backtracks, depth)
+		fmt.Fprintf(out, "%d\t%d\t%d\t%d\t\n", depth, nodes[depth], rejections[depth], backtracks[depth])
+	}
+	out.Flush()
+
+	fmt.Println("\nConstraints by rejections:")
+	for _, r := range rank(distinguished(byConstraint), top) {
+		fmt.Printf("  %8d  %s\n", r.count, r.name)
+	}
+	fmt.Println("Variables by backtracks:")
+	for _, r := range rank(byVariable, top) {
+		fmt.Printf("  %8d  %s\n", r.count, r.name)
+	}
+
+	return nil
+}
+
+// a constraint of a trace, by its ID, and its name
+type rejector struct {
+	id   int
+	name string
+}
+
+// the counts keyed by name, suffixing the ID to any name shared by more
+// than one constraint, so that each is counted apart
+func distinguished(counts map[rejector]int) map[string]int {
+	shared := map[string]int{}
+	for r := range counts {
+		shared[r.name]++
+	}
+	out := make(map[string]int, len(counts))
+	for r, count := range counts {
+		name := r.name
+		if shared[name] > 1 {
+			name = fmt.Sprintf("%s #%d", name, r.id)
+		}
+		out[name] += count
+	}
+	return out
+}
+
+// print each step of the search, indented by its depth
+func replay(tracePath string, maxDepth, limit int, delay time.Duration) error {
+	steps := 0
+	return events(tracePath, func(event csp.TraceEvent) bool {
+		if maxDepth >= 0 && event.Depth > maxDepth {
+			return true
+		}
+
+		indent := strings.Repeat("  ", event.Depth)
+		switch event.Kind {
+		case csp.TraceAssign:
+			fmt.Printf("%s%s = %s\n", indent, event.Variable, event.Value)
+		case csp.TraceReject:
+			fmt.Printf("%s  rejected by %s\n", indent, event.Constraint)
+		case csp.TraceBacktrack:
+			fmt.Printf("%s<- backtrack from %s\n", indent, event.Variable)
+		case csp.TraceSolution:
+			var parts []string
+			for v, value := range event.Solution {
+				parts = append(parts, v+"="+value)
+			}
+			sort.Strings(parts)
+			fmt.Printf("%ssolution: %s\n", indent, strings.Join(parts, " "))
+		}
+
+		steps++
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		return limit <= 0 || steps < limit
+	})
+}
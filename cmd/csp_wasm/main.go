@@ -0,0 +1,221 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall/js"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// how many nodes the search visits between progress callbacks, by default
+const progressEvery = 10000
+
+// expose the solver to JavaScript as a global csp object:
+//
+//	csp.validate(model)           an error message, or null if the model is valid
+//	csp.solve(model, options)     a Promise of the solution
+//	csp.cancel()                  stop every running solve, resolving them with what they found
+//
+// model is a csp.Model, as an object or a JSON string. options may set all
+// (find every solution), limit (with all, stop after this many), timeout
+// (milliseconds), heuristic ("first", "lex", "mrv" or "degree"), values
+// ("domain" or "lcv"), and onProgress, a function called every
+// progressEvery nodes with {nodes, backtracks, rejections, solutions,
+// depth}. the Promise resolves with the solution as csp.Solution marshals
+// it to JSON, or an array of them with all, and rejects an invalid model
+func main() {
+	exports := js.Global().Get("Object").New()
+	exports.Set("validate", js.FuncOf(validate))
+	exports.Set("solve", js.FuncOf(solve))
+	exports.Set("cancel", js.FuncOf(cancel))
+	js.Global().Set("csp", exports)
+
+	// keep the exported functions alive
+	select {}
+}
+
+// the backtrackers of the solves in progress, for cancel
+var running = map[*csp.Backtracker[string, int]]struct{}{}
+
+// read a model from an object or a JSON string
+func load(model js.Value) (csp.Problem[string, int], error) {
+	doc := model
+	if model.Type() != js.TypeString {
+		doc = js.Global().Get("JSON").Call("stringify", model)
+	}
+	return csp.LoadJSON(strings.NewReader(doc.String()))
+}
+
+func validate(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return "no model given"
+	}
+	if _, err := load(args[0]); err != nil {
+		return err.Error()
+	}
+	return nil
+}
+
+func cancel(this js.Value, args []js.Value) any {
+	for bt := range running {
+		bt.Cancel()
+	}
+	return nil
+}
+
+func solve(this js.Value, args []js.Value) any {
+	var model, options js.Value
+	if len(args) > 0 {
+		model = args[0]
+	}
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		options = args[1]
+	}
+
+	promise := js.Global().Get("Promise")
+	return promise.New(js.FuncOf(func(this js.Value, handlers []js.Value) any {
+		resolve, reject := handlers[0], handlers[1]
+
+		// search off the caller's stack, so that the Promise returns first
+		go func() {
+			result, err := run(model, options)
+			if err != nil {
+				reject.Invoke(js.Global().Get("Error").New(err.Error()))
+				return
+			}
+			resolve.Invoke(js.Global().Get("JSON").Call("parse", string(result)))
+		}()
+		return nil
+	}))
+}
+
+// read the option by name, or undefined if there are no options
+func option(options js.Value, name string) js.Value {
+	if options.IsUndefined() || options.IsNull() {
+		return js.Undefined()
+	}
+	return options.Get(name)
+}
+
+// solve the model as the options direct, returning the JSON of the outcome
+func run(model, options js.Value) ([]byte, error) {
+	if model.IsUndefined() {
+		return nil, fmt.Errorf("no model given")
+	}
+	problem, err := load(model)
+	if err != nil {
+		return nil, err
+	}
+
+	bt := csp.NewBacktracker(problem)
+	strategy := "backtracking"
+	if heuristic := option(options, "heuristic"); heuristic.Truthy() {
+		switch heuristic.String() {
+		case "first":
+		case "lex":
+			bt.SelectVariable = csp.Lexicographic(problem)
+		case "mrv":
+			bt.SelectVariable = csp.MinRemainingValues(problem)
+		case "degree":
+			bt.SelectVariable = csp.MaxDegree(problem)
+		default:
+			return nil, fmt.Errorf("unknown heuristic %q", heuristic.String())
+		}
+		strategy += "+" + heuristic.String()
+	}
+	if values := option(options, "values"); values.Truthy() {
+		switch values.String() {
+		case "domain":
+		case "lcv":
+			bt.OrderValues = csp.LeastConstrainingValue(problem)
+		default:
+			return nil, fmt.Errorf("unknown value ordering %q", values.String())
+		}
+		strategy += "+" + values.String()
+	}
+	if timeout := option(options, "timeout"); timeout.Type() == js.TypeNumber {
+		bt.Timeout = time.Duration(timeout.Float()) * time.Millisecond
+	}
+
+	all := option(options, "all").Truthy()
+	limit := 0
+	if n := option(options, "limit"); n.Type() == js.TypeNumber {
+		limit = n.Int()
+	}
+
+	// stopping at the limit cancels the search, but isn't a cancellation
+	// to report
+	limited := false
+
+	// report progress from the hooks, since Stats is only final once the search ends
+	var progress csp.Stats
+	depth := 0
+	onProgress := option(options, "onProgress")
+	report := func() {
+		if onProgress.Type() != js.TypeFunction {
+			return
+		}
+		onProgress.Invoke(map[string]any{
+			"nodes":      progress.Nodes,
+			"backtracks": progress.Backtracks,
+			"rejections": progress.Rejections,
+			"solutions":  progress.Solutions,
+			"depth":      depth,
+		})
+	}
+	bt.Observe(csp.Hooks[string, int]{
+		OnAssign: func(variable string, value int, d int) {
+			progress.Nodes++
+			depth = d
+			if progress.Nodes%progressEvery == 0 {
+				report()
+				// yield to the JavaScript event loop, so that the page can
+				// repaint and the caller can cancel
+				time.Sleep(time.Millisecond)
+			}
+		},
+		OnReject:    func(string, int, csp.Constraint[string]) { progress.Rejections++ },
+		OnBacktrack: func(string, int) { progress.Backtracks++ },
+		OnSolution: func(map[string]int) {
+			progress.Solutions++
+			if all && limit > 0 && progress.Solutions >= limit {
+				limited = true
+				bt.Cancel()
+			}
+		},
+	})
+
+	running[bt] = struct{}{}
+	defer delete(running, bt)
+
+	var solutions []map[string]int
+	if all {
+		solutions = bt.SolveAll(map[string]int{})
+	} else {
+		solutions = []map[string]int{bt.Solve(map[string]int{})}
+	}
+	report()
+	stats := bt.Stats()
+	if limited {
+		stats.Canceled = false
+	}
+
+	docs := make([]csp.Solution[string, int], len(solutions))
+	for ndx, solution := range solutions {
+		docs[ndx] = csp.Solution[string, int]{
+			Assignment: solution,
+			Stats:      stats,
+			Strategy:   strategy,
+			Timestamp:  time.Now(),
+		}
+	}
+	if all {
+		return json.Marshal(docs)
+	}
+	return json.Marshal(docs[0])
+}
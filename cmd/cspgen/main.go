@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `usage: cspgen [flags] MODEL
+
+compile a model described in JSON or YAML (see csp.Model) into Go: a
+Variable constant for each of its variables, and a Constraint constant
+for each of its named constraints, a Model func returning the model to
+Post more to and Build, and a Solution struct with a field per variable,
+so that code using the model refers to its variables by identifiers the
+compiler checks, rather than by their names as strings. run it with
+go:generate, from the package to generate into:
+
+  //go:generate go run github.com/elireisman/generic-csp-go/cmd/cspgen model.yaml
+
+flags:
+`
+
+// Options configure the generated code
+type Options struct {
+	// the package to generate into
+	Package string
+	// the file to write
+	Out    string
+	Format string
+}
+
+func main() {
+	var options Options
+	flag.StringVar(&options.Package, "package", os.Getenv("GOPACKAGE"), "the package to generate into (default $GOPACKAGE, as go:generate sets it)")
+	flag.StringVar(&options.Out, "o", "", "the file to write (default the model's name, with _gen.go for its extension)")
+	flag.StringVar(&options.Format, "format", "", "model format: json or yaml (default by file extension)")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || options.Package == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	if options.Out == "" {
+		options.Out = strings.TrimSuffix(path, filepath.Ext(path)) + "_gen.go"
+	}
+	if err := generate(path, options); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// read the model, check that it builds, and write the code for it
+func generate(path string, options Options) error {
+	m, err := load(path, options.Format)
+	if err != nil {
+		return err
+	}
+	if _, err := m.Build(); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	src, err := compile(m, filepath.Base(path), options.Package)
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return os.WriteFile(options.Out, src, 0o644)
+}
+
+// read a model file, in the given format or else the one its extension
+// implies, without building it
+func load(path, format string) (csp.Model, error) {
+	var m csp.Model
+	in, err := os.Open(path)
+	if err != nil {
+		return m, err
+	}
+	defer in.Close()
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(in)
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&m)
+	case "yaml", "yml":
+		decoder := yaml.NewDecoder(in)
+		decoder.KnownFields(true)
+		err = decoder.Decode(&m)
+	default:
+		return m, fmt.Errorf("%s: unknown model format %q, choose one with -format", path, format)
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: invalid model: %s", path, err)
+	}
+	return m, nil
+}
+
+// the Go source of the model, formatted
+func compile(m csp.Model, source, pkg string) ([]byte, error) {
+	// the Solution's fields can't share the names of its methods
+	vars, err := identifiers(len(m.Variables), func(ndx int) string { return m.Variables[ndx].Name }, "Assignment", "Value")
+	if err != nil {
+		return nil, fmt.Errorf("variables: %s", err)
+	}
+	var named []csp.ModelConstraint
+	for _, mc := range m.Constraints {
+		if mc.Name != "" {
+			named = append(named, mc)
+		}
+	}
+	constraints, err := identifiers(len(named), func(ndx int) string { return named[ndx].Name })
+	if err != nil {
+		return nil, fmt.Errorf("constraints: %s", err)
+	}
+
+	var b bytes.Buffer
+	p := func(format string, args ...any) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	p("// Code generated by cspgen from %s. DO NOT EDIT.", source)
+	p("")
+	p("package %s", pkg)
+	p("")
+	p("import %q", "github.com/elireisman/generic-csp-go/pkg/csp")
+	p("")
+	p("// Variable is a variable of the model, by name")
+	p("type Variable string")
+	p("")
+	p("// the model's variables")
+	p("const (")
+	for ndx, mv := range m.Variables {
+		p("Var%s Variable = %q", vars[ndx], mv.Name)
+	}
+	p(")")
+	p("")
+	p("// the expression of the variable in a Model, to Post more about it")
+	p("func (v Variable) In(m *csp.Model) csp.Expr {")
+	p("return m.Var(string(v))")
+	p("}")
+	p("")
+	if len(named) > 0 {
+		p("// Constraint is a named constraint of the model, as Labels show it")
+		p("type Constraint string")
+		p("")
+		p("// the model's named constraints")
+		p("const (")
+		for ndx, mc := range named {
+			p("Constraint%s Constraint = %q", constraints[ndx], mc.Name)
+		}
+		p(")")
+		p("")
+	}
+
+	p("// the model %s describes, to Post more to and Build", source)
+	p("func Model() *csp.Model {")
+	p("return &csp.Model{")
+	p("Variables: %s,", literal(reflect.ValueOf(m.Variables), true))
+	p("Constraints: %s,", literal(reflect.ValueOf(m.Constraints), true))
+	p("}")
+	p("}")
+	p("")
+	p("// build the Problem of the model")
+	p("func Build() (csp.Problem[string, int], error) {")
+	p("return Model().Build()")
+	p("}")
+	p("")
+
+	p("// Solution holds a value for each of the model's variables")
+	p("type Solution struct {")
+	for ndx := range m.Variables {
+		p("%s int", vars[ndx])
+	}
+	p("}")
+	p("")
+	p("// the Solution of a solution of the model's Problem")
+	p("func SolutionOf(solution map[string]int) Solution {")
+	p("return Solution{")
+	for ndx, mv := range m.Variables {
+		p("%s: solution[%q],", vars[ndx], mv.Name)
+	}
+	p("}")
+	p("}")
+	p("")
+	p("// the Solution as an assignment of the model's Problem, e.g. to Verify")
+	p("func (s Solution) Assignment() map[string]int {")
+	p("return map[string]int{")
+	for ndx, mv := range m.Variables {
+		p("%q: s.%s,", mv.Name, vars[ndx])
+	}
+	p("}")
+	p("}")
+	p("")
+	p("// the value of a variable in the Solution")
+	p("func (s Solution) Value(v Variable) int {")
+	p("switch v {")
+	for ndx := range m.Variables {
+		p("case Var%s:", vars[ndx])
+		p("return s.%s", vars[ndx])
+	}
+	p("}")
+	p("panic(\"unknown variable \" + string(v))")
+	p("}")
+	if usesInt(m) {
+		p("")
+		p("func cspgenInt(n int) *int {")
+		p("return &n")
+		p("}")
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// the Go identifiers for n names, e.g. StartTime for start_time and
+// Cell_0_1 for cell[0][1], which must not collide. one that would be
+// reserved gets an underscore after it, e.g. Value_ for value
+func identifiers(n int, name func(int) string, reserved ...string) ([]string, error) {
+	out := make([]string, n)
+	seen := map[string]string{}
+	for ndx := range out {
+		id := identifier(name(ndx))
+		for _, r := range reserved {
+			if id == r {
+				id += "_"
+			}
+		}
+		if other, found := seen[id]; found {
+			return nil, fmt.Errorf("%q and %q would both be %s", other, name(ndx), id)
+		}
+		seen[id] = name(ndx)
+		out[ndx] = id
+	}
+	return out, nil
+}
+
+// the exported Go identifier for a name: its runs of letters and digits,
+// each capitalized, or after an underscore if it starts with a digit
+func identifier(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := ""
+	for _, part := range parts {
+		runes := []rune(part)
+		if unicode.IsDigit(runes[0]) {
+			out += "_" + part
+			continue
+		}
+		out += string(unicode.ToUpper(runes[0])) + string(runes[1:])
+	}
+	if out == "" || out[0] == '_' {
+		out = "V" + out
+	}
+	return out
+}
+
+// the Go literal of a model's value, leaving out the fields at their zero
+// values, and the types of elements, which the slice's implies
+func literal(v reflect.Value, typed bool) string {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return fmt.Sprintf("cspgenInt(%d)", v.Elem().Int())
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Struct:
+		var fields []string
+		for ndx := 0; ndx < v.NumField(); ndx++ {
+			if f := v.Type().Field(ndx); f.IsExported() && !v.Field(ndx).IsZero() {
+				fields = append(fields, f.Name+": "+literal(v.Field(ndx), true))
+			}
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	// a slice or array
+	elems := make([]string, v.Len())
+	for ndx := range elems {
+		elems[ndx] = literal(v.Index(ndx), false)
+	}
+	prefix := ""
+	if typed {
+		prefix = v.Type().String()
+	}
+	if len(elems) > 0 && v.Type().Elem().Kind() == reflect.Struct {
+		return prefix + "{\n" + strings.Join(elems, ",\n") + ",\n}"
+	}
+	return prefix + "{" + strings.Join(elems, ", ") + "}"
+}
+
+// whether any variable is declared with a min or max, for which the
+// generated code needs a pointer to an int
+func usesInt(m csp.Model) bool {
+	for _, mv := range m.Variables {
+		if mv.Min != nil || mv.Max != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"math/rand"
+	"os"
+
 	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
 )
 
 type Row int
 type Column int
 
+// boards wider than this are summarized rather than drawn
+const MaxDrawn = 64
+
 var (
+	// the size of the board, and the number of queens to place on it
+	N int
+
 	// CSP variables
 	Queens []Row
 
@@ -17,108 +29,130 @@ var (
 
 	// CSP constraints
 	Constraints []csp.Constraint[Row]
-)
 
-func NewQueen(row Row) csp.Constraint[Row] {
-	return csp.Constraint[Row]{
-		Variables: []Row{row},
-	}
-}
+	// repair a complete placement by local search, rather than
+	// building one up by backtracking
+	MinConflicts bool
 
-// constraint: ensure no newly-placed queen occupies a row and column
-// that can be threatened by any other already-placed queen
-func SatisfiesConstraint(queen csp.Constraint[Row], candidate map[Row]Column) bool {
-	rowOccupied := queen.Variables[0]
-	colOccupied, found := candidate[rowOccupied]
+	// seed the local search, or the clock if zero
+	Seed int64
 
-	// if no Queen has been assigned to the Row in the
-	// Constraint yet, the Constraint is satisfied
-	if !found {
-		return true
-	}
+	// animate the search in the terminal
+	TUI bool
 
-	// check if the proposed new queen satisfies the constraint that
-	// no other already placed queen can threaten it
-	return checkPosition(rowOccupied, colOccupied, candidate, 1, 0) &&
-		checkPosition(rowOccupied, colOccupied, candidate, -1, 0) &&
-		checkPosition(rowOccupied, colOccupied, candidate, 0, 1) &&
-		checkPosition(rowOccupied, colOccupied, candidate, 0, -1) &&
-		checkPosition(rowOccupied, colOccupied, candidate, 1, 1) &&
-		checkPosition(rowOccupied, colOccupied, candidate, -1, -1) &&
-		checkPosition(rowOccupied, colOccupied, candidate, 1, -1) &&
-		checkPosition(rowOccupied, colOccupied, candidate, -1, 1)
-}
+	// keep the queens off each other's diagonals with an AbsDiff
+	// constraint per pair, rather than AllDifferentOffset over them all
+	Pairwise bool
 
-func checkPosition(qRow Row, qCol Column, queens map[Row]Column, diffRow Row, diffCol Column) bool {
-	qNextRow := qRow + diffRow
-	qNextCol := qCol + diffCol
+	// find every solution, but only one of those the board's rotations
+	// and reflections turn into each other
+	Distinct bool
+)
 
-	if qNextRow < 1 || qNextRow > 8 {
-		return true
-	}
-	if qNextCol < 1 || qNextCol > 8 {
-		return true
+func init() {
+	flag.IntVar(&N, "n", 8, "the size of the board, and the number of queens to place")
+	flag.BoolVar(&MinConflicts, "min-conflicts", false, "search by min-conflicts local search, which reaches boards in the thousands")
+	flag.Int64Var(&Seed, "seed", 0, "seed the min-conflicts search, or the clock if 0")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.BoolVar(&Distinct, "distinct", false, "find every solution, one of each set the board's rotations and reflections turn into each other")
+	flag.BoolVar(&Pairwise, "pairwise", false, "state the diagonals as an AbsDiff constraint per pair of queens")
+}
+
+// place a queen per row, so no two share a row, in the column picked by
+// its variable. no two may share a column, nor a diagonal: along one way,
+// row + column is the same for all the cells of a diagonal, and along
+// the other, column - row is.
+//
+// pairwise, the diagonals are the same rule spelled out a pair of queens
+// at a time: two queens share one when their columns are as far apart as
+// their rows, so |column i - column j| != |i - j|. that's n(n-1)/2
+// constraints rather than two, each checked as soon as both of its
+// queens are placed
+func NewConstraints(queens []Row, pairwise bool) []csp.Constraint[Row] {
+	if pairwise {
+		out := []csp.Constraint[Row]{csp.AllDifferent(queens...)}
+		for i, a := range queens {
+			for _, b := range queens[i+1:] {
+				gap := int(b - a)
+				if gap < 0 {
+					gap = -gap
+				}
+				out = append(out, csp.AbsDiff(a, b, csp.Ne, gap))
+			}
+		}
+		return out
 	}
 
-	qActualCol, otherQFound := queens[qNextRow]
-	if !otherQFound || qActualCol != qNextCol {
-		return checkPosition(qNextRow, qNextCol, queens, diffRow, diffCol)
+	up := make([]int, len(queens))
+	down := make([]int, len(queens))
+	for ndx, row := range queens {
+		up[ndx] = int(row)
+		down[ndx] = -int(row)
 	}
 
-	return false
+	return []csp.Constraint[Row]{
+		csp.AllDifferent(queens...),
+		csp.AllDifferentOffset(queens, up),
+		csp.AllDifferentOffset(queens, down),
+	}
 }
 
-func init() {
-	Queens = []Row{
-		1,
-		2,
-		3,
-		4,
-		5,
-		6,
-		7,
-		8,
+// the symmetries of the board: a quarter turn, queen (r, c) moving to
+// (c, N+1-r), and a mirror, moving it to (r, N+1-c). together they make
+// the other two turns and three reflections
+func Symmetries() []csp.Symmetry[Row, Column] {
+	turn := func(solution map[Row]Column) map[Row]Column {
+		out := map[Row]Column{}
+		for row, col := range solution {
+			out[Row(col)] = Column(N + 1 - int(row))
+		}
+		return out
 	}
-
-	Columns = []Column{
-		1,
-		2,
-		3,
-		4,
-		5,
-		6,
-		7,
-		8,
+	mirror := func(solution map[Row]Column) map[Row]Column {
+		out := map[Row]Column{}
+		for row, col := range solution {
+			out[row] = Column(N + 1 - int(col))
+		}
+		return out
 	}
+	return []csp.Symmetry[Row, Column]{turn, mirror}
+}
 
-	// TODO
-	Constraints = []csp.Constraint[Row]{
-		csp.Constraint[Row]{Variables: []Row{1}},
-		csp.Constraint[Row]{Variables: []Row{2}},
-		csp.Constraint[Row]{Variables: []Row{3}},
-		csp.Constraint[Row]{Variables: []Row{4}},
-		csp.Constraint[Row]{Variables: []Row{5}},
-		csp.Constraint[Row]{Variables: []Row{6}},
-		csp.Constraint[Row]{Variables: []Row{7}},
-		csp.Constraint[Row]{Variables: []Row{8}},
-	}
+func drawBoard(result map[Row]Column) string {
+	return render.Grid{
+		Rows: N,
+		Cols: N,
+		Cell: func(row, col int) render.Cell {
+			if column, found := result[Row(row+1)]; found && column == Column(col+1) {
+				return render.Cell{Text: "Q", Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
 }
 
 func renderBoard(result map[Row]Column) {
-	for row := 1; row <= 8; row++ {
-		for col := 1; col <= 8; col++ {
-			elem := "\x1b[0;38m.\x1b[0;0m"
-			if result[Row(row)] == Column(col) {
-				elem = "\x1b[0;46mQ\x1b[0;0m"
-			}
-			fmt.Printf("%s ", elem)
-		}
-		fmt.Println()
+	if N > MaxDrawn {
+		fmt.Printf("%d queens placed, the first in column %d\n", N, result[1])
+		return
 	}
+	fmt.Print(drawBoard(result))
 }
 
-// model the 8 Queens problem using CSP framework + Go generics
+// model the N Queens problem using CSP framework + Go generics
 func main() {
+	flag.Parse()
+	if N < 1 {
+		fmt.Fprintf(os.Stderr, "error: -n must be at least 1, got %d\n", N)
+		os.Exit(2)
+	}
+
+	for i := 1; i <= N; i++ {
+		Queens = append(Queens, Row(i))
+		Columns = append(Columns, Column(i))
+	}
+	Constraints = NewConstraints(Queens, Pairwise)
+
 	// assemble mapping of variables to a set of possible
 	// values to search for a valid solution
 	domain := map[Row][]Column{}
@@ -127,20 +161,64 @@ func main() {
 	}
 
 	// create CSP framework instance, populate
-	problem := csp.New(domain, SatisfiesConstraint)
-	for _, takeable := range Constraints {
-		problem.AddConstraint(takeable)
+	problem := csp.New[Row, Column](domain, nil)
+	for _, constraint := range Constraints {
+		problem.AddConstraint(constraint)
 	}
 
 	// init empty solution to begin search through problem space
 	candidate := map[Row]Column{}
 
 	// find ONE possible solution, and display it, if it exists
-	if result := problem.Solve(candidate); result != nil {
+	var result map[Row]Column
+	var stats csp.Stats
+	switch {
+	case Distinct:
+		bt := csp.NewBacktracker(problem)
+		bt.Symmetries = Symmetries()
+		solutions := bt.SolveAll(candidate)
+		stats = bt.Stats()
+		for ndx, solution := range solutions {
+			fmt.Printf("Solution %d:\n", ndx+1)
+			renderBoard(solution)
+		}
+		fmt.Printf("%d distinct solutions of %d, %d nodes, %d backtracks in %s\n",
+			len(solutions), stats.Solutions, stats.Nodes, stats.Backtracks, stats.Duration)
+		if len(solutions) == 0 {
+			os.Exit(1)
+		}
+		return
+	case MinConflicts:
+		mc := csp.NewMinConflicts(problem)
+		if Seed != 0 {
+			mc.Rand = rand.New(rand.NewSource(Seed))
+		}
+		result = mc.Solve(candidate)
+		stats = mc.Stats()
+	case TUI:
+		var err error
+		bt := csp.NewBacktracker(problem)
+		title := fmt.Sprintf("%d Queens", N)
+		if result, err = tui.New(title, drawBoard).Run(bt, candidate); err != nil {
+			panic(err)
+		}
+		if bt.Stats().Canceled {
+			return
+		}
+		stats = bt.Stats()
+	default:
+		bt := csp.NewBacktracker(problem)
+		bt.SelectVariable = csp.MinRemainingValues(problem)
+		result = bt.Solve(candidate)
+		stats = bt.Stats()
+	}
+	if result != nil {
 		fmt.Println("Solution:")
 		renderBoard(result)
+		fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
 		return
 	}
 
-	panic("No solution found")
+	fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+	os.Exit(1)
 }
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"embed"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+//go:embed data/*.csv
+var data embed.FS
+
+// Part is which of an exam's two decisions a variable holds
+type Part int
+
+const (
+	Slot Part = iota
+	Room
+)
+
+// Var is the timeslot or the room of an exam, the slots and rooms both
+// numbered from 0
+type Var struct {
+	Exam string
+	Part Part
+}
+
+// Hall is a room exams can be sat in, by as many students at once as it
+// has seats, whatever exams they're sitting
+type Hall struct {
+	Name     string
+	Capacity int
+}
+
+// Session is an exam session: who sits which exams, and where
+type Session struct {
+	// the exams each student sits
+	Students map[string][]string
+	Rooms    []Hall
+}
+
+var (
+	// the enrollments to timetable, as a CSV of student and exam rows
+	EnrollmentsFile string
+
+	// the rooms to seat them in, as a CSV of room and capacity rows
+	RoomsFile string
+
+	// the number of timeslots in the session
+	Slots int
+
+	// the timeslots in a day, for labeling them
+	SlotsPerDay int
+
+	// give up on the timetable after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.StringVar(&EnrollmentsFile, "enrollments", "", "a CSV of student and exam rows (default: the bundled enrollments of 300 students)")
+	flag.StringVar(&RoomsFile, "rooms", "", "a CSV of room and capacity rows (default: the bundled rooms)")
+	flag.IntVar(&Slots, "slots", 8, "the number of timeslots in the session")
+	flag.IntVar(&SlotsPerDay, "per-day", 2, "the timeslots in a day, for labeling them")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on the timetable after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: exam_timetabling [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "timetable a session of exams into timeslots and rooms, so that no\n")
+		fmt.Fprintf(os.Stderr, "student has two exams at once and no room seats more students than it\n")
+		fmt.Fprintf(os.Stderr, "has seats. rooms are shared by the exams sat in them at once. both\n")
+		fmt.Fprintf(os.Stderr, "files may start with a header row, which is skipped\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read the rows of a two-column CSV, skipping a header row if the second
+// column of the first isn't a number and numeric is set
+func readCSV(r io.Reader, numeric bool) ([][2]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var out [][2]string
+	for ndx, record := range records {
+		if ndx == 0 {
+			if _, err := strconv.Atoi(record[1]); numeric && err != nil {
+				continue
+			}
+			if !numeric && record[0] == "student" {
+				continue
+			}
+		}
+		out = append(out, [2]string{strings.TrimSpace(record[0]), strings.TrimSpace(record[1])})
+	}
+	return out, nil
+}
+
+// read a session's enrollments and rooms, from the CSVs described by the
+// usage
+func ReadSession(enrollments, rooms io.Reader) (Session, error) {
+	s := Session{Students: map[string][]string{}}
+	rows, err := readCSV(enrollments, false)
+	if err != nil {
+		return s, fmt.Errorf("enrollments: %w", err)
+	}
+	for _, row := range rows {
+		s.Students[row[0]] = append(s.Students[row[0]], row[1])
+	}
+
+	if rows, err = readCSV(rooms, true); err != nil {
+		return s, fmt.Errorf("rooms: %w", err)
+	}
+	for _, row := range rows {
+		capacity, err := strconv.Atoi(row[1])
+		if err != nil || capacity <= 0 {
+			return s, fmt.Errorf("rooms: invalid capacity %q for %s", row[1], row[0])
+		}
+		s.Rooms = append(s.Rooms, Hall{Name: row[0], Capacity: capacity})
+	}
+	if len(s.Students) == 0 || len(s.Rooms) == 0 {
+		return s, fmt.Errorf("no enrollments or no rooms")
+	}
+	return s, nil
+}
+
+// the number of students sitting each exam
+func (s Session) Sizes() map[string]int {
+	sizes := map[string]int{}
+	for _, exams := range s.Students {
+		for _, exam := range exams {
+			sizes[exam]++
+		}
+	}
+	return sizes
+}
+
+// the exams in order of their names
+func (s Session) Exams() []string {
+	var exams []string
+	for exam := range s.Sizes() {
+		exams = append(exams, exam)
+	}
+	sort.Strings(exams)
+	return exams
+}
+
+// the pairs of exams some student sits both of, in order
+func (s Session) Conflicts() [][2]string {
+	seen := map[[2]string]bool{}
+	var out [][2]string
+	for _, exams := range s.Students {
+		for i := range exams {
+			for j := range exams {
+				pair := [2]string{exams[i], exams[j]}
+				if pair[0] < pair[1] && !seen[pair] {
+					seen[pair] = true
+					out = append(out, pair)
+				}
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i][0] < out[j][0] || (out[i][0] == out[j][0] && out[i][1] < out[j][1])
+	})
+	return out
+}
+
+// model the timetable with a slot and a room variable per exam. exams
+// some student sits both of take different slots, by NotEqual, and
+// every room's exams in a slot fit in it, by a single seating constraint
+// over all the variables. an exam's rooms are only those it fits in by
+// itself, from the smallest, so it's tried in the tightest first
+func NewProblem(s Session, slots int) (csp.Problem[Var, int], error) {
+	sizes := s.Sizes()
+	rooms := make([]int, len(s.Rooms))
+	for ndx := range rooms {
+		rooms[ndx] = ndx
+	}
+	sort.SliceStable(rooms, func(i, j int) bool { return s.Rooms[rooms[i]].Capacity < s.Rooms[rooms[j]].Capacity })
+
+	domain := map[Var][]int{}
+	var all []Var
+	for _, exam := range s.Exams() {
+		for slot := 0; slot < slots; slot++ {
+			domain[Var{exam, Slot}] = append(domain[Var{exam, Slot}], slot)
+		}
+		for _, room := range rooms {
+			if s.Rooms[room].Capacity >= sizes[exam] {
+				domain[Var{exam, Room}] = append(domain[Var{exam, Room}], room)
+			}
+		}
+		if len(domain[Var{exam, Room}]) == 0 {
+			return csp.Problem[Var, int]{}, fmt.Errorf("%s has %d students, more than any room seats", exam, sizes[exam])
+		}
+		all = append(all, Var{exam, Slot}, Var{exam, Room})
+	}
+
+	problem := csp.New(domain, seating(s, sizes))
+	for _, pair := range s.Conflicts() {
+		problem.AddConstraint(csp.NotEqual(Var{pair[0], Slot}, Var{pair[1], Slot}))
+	}
+	problem.AddConstraint(csp.Constraint[Var]{Variables: all})
+	return problem, nil
+}
+
+// constraint: the students of the exams placed so far fit in the seats of
+// the rooms they're placed in, at each slot. and those of the exams given
+// a slot but not yet a room fit in the seats left over, or no choice of
+// rooms will seat them
+func seating(s Session, sizes map[string]int) csp.Satisfied[Var, int] {
+	total := 0
+	for _, r := range s.Rooms {
+		total += r.Capacity
+	}
+
+	return func(constraint csp.Constraint[Var], candidate map[Var]int) bool {
+		seated := map[[2]int]int{}
+		inSlot := map[int]int{}
+		for ndx := 0; ndx < len(constraint.Variables); ndx += 2 {
+			exam := constraint.Variables[ndx].Exam
+			slot, foundSlot := candidate[Var{exam, Slot}]
+			room, foundRoom := candidate[Var{exam, Room}]
+			if !foundSlot {
+				continue
+			}
+			inSlot[slot] += sizes[exam]
+			if inSlot[slot] > total {
+				return false
+			}
+			if !foundRoom {
+				continue
+			}
+			seated[[2]int{slot, room}] += sizes[exam]
+			if seated[[2]int{slot, room}] > s.Rooms[room].Capacity {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// assign an exam's room straight after its slot, so that a slot without
+// the room to seat it is given up at once. otherwise, like DSatur, assign
+// the slot of the exam whose conflicting exams have taken the most slots
+// already, ties going to the one in the most conflicts
+func slotThenRoom(s Session) csp.VariableOrder[Var, int] {
+	exams := s.Exams()
+	neighbors := map[string][]string{}
+	for _, pair := range s.Conflicts() {
+		neighbors[pair[0]] = append(neighbors[pair[0]], pair[1])
+		neighbors[pair[1]] = append(neighbors[pair[1]], pair[0])
+	}
+
+	return func(assignment map[Var]int) Var {
+		var best string
+		bestTaken := -1
+		for _, exam := range exams {
+			if _, found := assignment[Var{exam, Slot}]; found {
+				if _, found := assignment[Var{exam, Room}]; !found {
+					return Var{exam, Room}
+				}
+				continue
+			}
+
+			taken := map[int]bool{}
+			for _, other := range neighbors[exam] {
+				if slot, found := assignment[Var{other, Slot}]; found {
+					taken[slot] = true
+				}
+			}
+			if len(taken) > bestTaken || (len(taken) == bestTaken && len(neighbors[exam]) > len(neighbors[best])) {
+				best, bestTaken = exam, len(taken)
+			}
+		}
+		return Var{best, Slot}
+	}
+}
+
+func slotName(slot int) string {
+	if SlotsPerDay <= 1 {
+		return fmt.Sprintf("Day %d", slot+1)
+	}
+	return fmt.Sprintf("Day %d/%d", slot/SlotsPerDay+1, slot%SlotsPerDay+1)
+}
+
+// tabulate the timetable with a row per slot and a column per room,
+// listing the exams sat in each and the seats they take
+func drawTimetable(s Session, result map[Var]int, slots int) string {
+	sizes := s.Sizes()
+	at := map[[2]int][]string{}
+	for _, exam := range s.Exams() {
+		key := [2]int{result[Var{exam, Slot}], result[Var{exam, Room}]}
+		at[key] = append(at[key], exam)
+	}
+
+	return render.Grid{
+		Rows: slots + 1,
+		Cols: len(s.Rooms) + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprintf("%s (%d)", s.Rooms[col-1].Name, s.Rooms[col-1].Capacity)}
+			case col == 0:
+				return render.Cell{Text: slotName(row - 1)}
+			}
+
+			exams := at[[2]int{row - 1, col - 1}]
+			seated := 0
+			for _, exam := range exams {
+				seated += sizes[exam]
+			}
+			switch {
+			case len(exams) == 0:
+				return render.Cell{Text: "-"}
+			case seated == s.Rooms[col-1].Capacity:
+				return render.Cell{Text: strings.Join(exams, "+"), Color: render.Red}
+			case seated*4 >= s.Rooms[col-1].Capacity*3:
+				return render.Cell{Text: strings.Join(exams, "+"), Color: render.Yellow}
+			}
+			return render.Cell{Text: strings.Join(exams, "+")}
+		},
+	}.String()
+}
+
+// open the named file, or the bundled one if there's no name
+func open(name, bundled string) (io.ReadCloser, error) {
+	if name == "" {
+		return data.Open("data/" + bundled)
+	}
+	return os.Open(name)
+}
+
+// model exam timetabling using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if flag.NArg() > 0 || Slots < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	enrollments, err := open(EnrollmentsFile, "enrollments.csv")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	defer enrollments.Close()
+	rooms, err := open(RoomsFile, "rooms.csv")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	defer rooms.Close()
+
+	session, err := ReadSession(enrollments, rooms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	problem, err := NewProblem(session, Slots)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = slotThenRoom(session)
+	bt.Timeout = Timeout
+	result := bt.Solve(map[Var]int{})
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No timetable in %d slots (%d nodes, %d backtracks)\n", Slots, stats.Nodes, stats.Backtracks)
+		if stats.TimedOut {
+			fmt.Println("The search timed out")
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d exams for %d students in %d slots and %d rooms, with %d conflicting pairs of exams\n",
+		len(session.Exams()), len(session.Students), Slots, len(session.Rooms), len(session.Conflicts()))
+	fmt.Print(drawTimetable(session, result, Slots))
+	fmt.Println("Rooms in red are full, and in yellow at least three quarters full")
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
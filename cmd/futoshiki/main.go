@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+type Cell struct {
+	Row int
+	Col int
+}
+
+type Digit int
+
+// Inequality says that the digit of cell Less is less than that of More
+type Inequality struct {
+	Less Cell
+	More Cell
+}
+
+// Puzzle is a futoshiki: a Size by Size Latin square with some of its
+// digits given, and inequalities between some of its neighboring cells
+type Puzzle struct {
+	Name         string
+	Size         int
+	Givens       map[Cell]Digit
+	Inequalities []Inequality
+}
+
+//go:embed puzzles/*.txt
+var bundled embed.FS
+
+var (
+	// solve this bundled puzzle, when no file is given
+	Name string
+
+	// animate the search in the terminal
+	TUI bool
+)
+
+func init() {
+	flag.StringVar(&Name, "puzzle", "5x5", "the bundled puzzle to solve: 5x5 or 7x7")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: futoshiki [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a futoshiki. a puzzle file lays out the grid with a digit or . per\n")
+		fmt.Fprintf(os.Stderr, "cell, < or > between the cells of a row, and a line of ^ or v between\n")
+		fmt.Fprintf(os.Stderr, "the rows for the cells of a column, such as cmd/futoshiki/puzzles/5x5.txt\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read a puzzle in the format of the bundled ones, where the cells of a
+// row are in the even columns of a line, with the inequalities between them
+// in the odd columns, and each line between two rows holds the ^ or v
+// under the cells it compares. the length of the first row sets the size
+// of the grid. lines starting with # are comments
+func ReadPuzzle(name string, r io.Reader) (Puzzle, error) {
+	p := Puzzle{Name: name, Givens: map[Cell]Digit{}}
+
+	// blank lines count inside the grid, as rows with no inequalities
+	var lines []string
+	var numbers []int
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "#") || (line == "" && len(lines) == 0) {
+			continue
+		}
+		lines = append(lines, line)
+		numbers = append(numbers, lineNo)
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return p, fmt.Errorf("no grid in puzzle")
+	}
+
+	p.Size = (len(lines[0]) + 1) / 2
+	if len(lines) != 2*p.Size-1 {
+		return p, fmt.Errorf("a %d by %d grid takes %d lines, got %d", p.Size, p.Size, 2*p.Size-1, len(lines))
+	}
+	if p.Size > 9 {
+		return p, fmt.Errorf("%d by %d grid is too large for single digits", p.Size, p.Size)
+	}
+
+	for ndx, line := range lines {
+		row := ndx / 2
+		if len(line) > 2*p.Size-1 {
+			return p, fmt.Errorf("line %d: want at most %d characters, got %d", numbers[ndx], 2*p.Size-1, len(line))
+		}
+		for col, c := range line {
+			var err error
+			if ndx%2 == 0 {
+				err = p.parseRow(row, col, c)
+			} else {
+				err = p.parseBetweenRows(row, col, c)
+			}
+			if err != nil {
+				return p, fmt.Errorf("line %d, column %d: %s", numbers[ndx], col+1, err)
+			}
+		}
+		if ndx%2 == 0 && len(line) < 2*p.Size-1 {
+			return p, fmt.Errorf("line %d: want %d cells", numbers[ndx], p.Size)
+		}
+	}
+	return p, nil
+}
+
+// parse the character at col of a line of cells
+func (p *Puzzle) parseRow(row, col int, c rune) error {
+	left, right := Cell{row, col / 2}, Cell{row, col/2 + 1}
+	switch {
+	case col%2 == 0 && c == '.':
+	case col%2 == 0 && c >= '1' && c <= rune('0'+p.Size):
+		p.Givens[left] = Digit(c - '0')
+	case col%2 == 1 && c == ' ':
+	case col%2 == 1 && c == '<':
+		p.Inequalities = append(p.Inequalities, Inequality{left, right})
+	case col%2 == 1 && c == '>':
+		p.Inequalities = append(p.Inequalities, Inequality{right, left})
+	default:
+		return fmt.Errorf("unexpected %q", c)
+	}
+	return nil
+}
+
+// parse the character at col of a line between the rows row and row+1
+func (p *Puzzle) parseBetweenRows(row, col int, c rune) error {
+	above, below := Cell{row, col / 2}, Cell{row + 1, col / 2}
+	switch {
+	case c == ' ':
+	case col%2 == 0 && c == '^':
+		p.Inequalities = append(p.Inequalities, Inequality{above, below})
+	case col%2 == 0 && (c == 'v' || c == 'V'):
+		p.Inequalities = append(p.Inequalities, Inequality{below, above})
+	default:
+		return fmt.Errorf("unexpected %q", c)
+	}
+	return nil
+}
+
+// model the puzzle with a variable per cell, the givens fixed by their
+// domains, all the digits of each row and column different, and a
+// LessThan per inequality
+func NewProblem(p Puzzle) csp.Problem[Cell, Digit] {
+	var digits []Digit
+	for d := Digit(1); d <= Digit(p.Size); d++ {
+		digits = append(digits, d)
+	}
+	domain := map[Cell][]Digit{}
+	for row := 0; row < p.Size; row++ {
+		for col := 0; col < p.Size; col++ {
+			if d, found := p.Givens[Cell{row, col}]; found {
+				domain[Cell{row, col}] = []Digit{d}
+			} else {
+				domain[Cell{row, col}] = digits
+			}
+		}
+	}
+
+	problem := csp.New[Cell, Digit](domain, nil)
+	for i := 0; i < p.Size; i++ {
+		var row, col []Cell
+		for j := 0; j < p.Size; j++ {
+			row = append(row, Cell{i, j})
+			col = append(col, Cell{j, i})
+		}
+		problem.AddConstraint(csp.AllDifferent(row...))
+		problem.AddConstraint(csp.AllDifferent(col...))
+	}
+	for _, in := range p.Inequalities {
+		problem.AddConstraint(csp.LessThan(in.Less, in.More))
+	}
+
+	return problem
+}
+
+// draw the grid as the puzzle file lays it out, with the digits found by
+// the search picked out from the givens
+func drawGrid(p Puzzle, candidate map[Cell]Digit) string {
+	signs := map[[2]int]string{}
+	for _, in := range p.Inequalities {
+		at := [2]int{in.Less.Row + in.More.Row, in.Less.Col + in.More.Col}
+		switch {
+		case in.Less.Col < in.More.Col:
+			signs[at] = "<"
+		case in.Less.Col > in.More.Col:
+			signs[at] = ">"
+		case in.Less.Row < in.More.Row:
+			signs[at] = "^"
+		default:
+			signs[at] = "v"
+		}
+	}
+
+	return render.Grid{
+		Rows: 2*p.Size - 1,
+		Cols: 2*p.Size - 1,
+		Cell: func(row, col int) render.Cell {
+			if row%2 == 1 || col%2 == 1 {
+				if sign, found := signs[[2]int{row, col}]; found {
+					return render.Cell{Text: sign}
+				}
+				return render.Cell{Text: " "}
+			}
+			c := Cell{row / 2, col / 2}
+			if d, found := p.Givens[c]; found {
+				return render.Cell{Text: fmt.Sprint(d)}
+			}
+			if d, found := candidate[c]; found {
+				return render.Cell{Text: fmt.Sprint(d), Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+func solve(p Puzzle) bool {
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+
+	var result map[Cell]Digit
+	if TUI {
+		draw := func(candidate map[Cell]Digit) string { return drawGrid(p, candidate) }
+		var err error
+		if result, err = tui.New("Futoshiki: "+p.Name, draw).Run(bt, map[Cell]Digit{}); err != nil {
+			panic(err)
+		}
+	} else {
+		result = bt.Solve(map[Cell]Digit{})
+	}
+
+	fmt.Printf("Puzzle %s, with %d givens and %d inequalities:\n", p.Name, len(p.Givens), len(p.Inequalities))
+	fmt.Print(drawGrid(p, nil))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, result))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
+
+// model futoshiki using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in io.ReadCloser
+	var err error
+	name := flag.Arg(0)
+	switch {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case name == "":
+		name = Name
+		in, err = bundled.Open("puzzles/" + Name + ".txt")
+		if err != nil {
+			err = fmt.Errorf("no bundled puzzle %q", Name)
+		}
+	default:
+		in, err = os.Open(name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	defer in.Close()
+
+	p, err := ReadPuzzle(name, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	if !solve(p) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Var is the distance from mark I of the ruler to mark J, for I < J, the
+// marks counting from 0. the first mark is at 0, so the distances from it
+// are the positions of the others
+type Var struct {
+	I, J int
+}
+
+// the lengths of the shortest rulers with up to 14 marks, proven by
+// exhaustive search, to check the results against
+var Optimal = []int{0, 0, 1, 3, 6, 11, 17, 25, 34, 44, 55, 72, 85, 106, 127}
+
+var (
+	// the number of marks on the ruler
+	Marks int
+
+	// the longest ruler to consider, or 0 for one long enough to hold any
+	Longest int
+
+	// give up on proving the shortest after this long
+	Timeout time.Duration
+
+	// restart the search after each shorter ruler, rather than carry on
+	// through the tree from it
+	Restarts bool
+)
+
+func init() {
+	flag.IntVar(&Marks, "marks", 8, "the number of marks on the ruler")
+	flag.IntVar(&Longest, "longest", 0, "the longest ruler to consider, or 0 for one sure to hold the marks")
+	flag.DurationVar(&Timeout, "timeout", time.Minute, "give up on proving the shortest ruler after this long")
+	flag.BoolVar(&Restarts, "restarts", false, "restart the search after each shorter ruler is found, rather than carry on through the tree")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: golomb [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "find the shortest Golomb ruler with the given number of marks: one with\n")
+		fmt.Fprintf(os.Stderr, "no two pairs of marks the same distance apart\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the length of the shortest ruler with n marks can't be less than the
+// sum of n-1 different distances between neighboring marks
+func triangle(n int) int {
+	return n * (n - 1) / 2
+}
+
+// model the ruler with a variable per pair of marks for the distance
+// between them: those from mark 0 place the marks, in increasing order,
+// and each of the others is the difference of two of them, by a Linear
+// constraint. the distances are all AllDifferent, which is what makes the
+// ruler a Golomb ruler. a ruler read backwards is a ruler too, so the
+// first distance between neighboring marks is made less than the last
+func NewProblem(marks, longest int) csp.Problem[Var, int] {
+	domain := map[Var][]int{}
+	var all []Var
+	for i := 0; i < marks; i++ {
+		for j := i + 1; j < marks; j++ {
+			// at least the distances between the marks in between, and at most
+			// the longest less those outside
+			v := Var{i, j}
+			for d := triangle(j - i + 1); d <= longest-triangle(marks-(j-i)); d++ {
+				domain[v] = append(domain[v], d)
+			}
+			all = append(all, v)
+		}
+	}
+
+	problem := csp.New[Var, int](domain, nil)
+	problem.AddConstraint(csp.AllDifferent(all...))
+	for j := 2; j < marks; j++ {
+		problem.AddConstraint(csp.LessThan(Var{0, j - 1}, Var{0, j}))
+		for i := 1; i < j; i++ {
+			problem.AddConstraint(csp.Linear([]Var{{i, j}, {0, j}, {0, i}}, []int{1, -1, 1}, csp.Eq, 0))
+		}
+	}
+	if marks > 2 {
+		problem.AddConstraint(csp.LessThan(Var{0, 1}, Var{marks - 2, marks - 1}))
+	}
+	return problem
+}
+
+// place the marks in turn, each followed by its distances from the marks
+// before it, which the Linear constraints leave a single value each
+func markByMark(marks int) csp.VariableOrder[Var, int] {
+	return func(assignment map[Var]int) Var {
+		for j := 1; j < marks; j++ {
+			for i := 0; i < j; i++ {
+				if _, found := assignment[Var{i, j}]; !found {
+					return Var{i, j}
+				}
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// try the places for a mark beyond the one before it, and for the
+// distance between two marks already placed, only the one they're apart:
+// the other values are bound to break a LessThan or a Linear constraint
+func inPlace(problem csp.Problem[Var, int]) csp.ValueOrder[Var, int] {
+	return func(v Var, assignment map[Var]int) []int {
+		if v.I > 0 {
+			return []int{assignment[Var{0, v.J}] - assignment[Var{0, v.I}]}
+		}
+		values := problem.Domain[v]
+		for len(values) > 0 && v.J > 1 && values[0] <= assignment[Var{0, v.J - 1}] {
+			values = values[1:]
+		}
+		return values
+	}
+}
+
+// the length of the ruler, or for one partly placed, a lower bound on it:
+// the last mark placed, plus the sum of as many different distances as
+// there are marks left to place after it
+func length(marks int) csp.Cost[Var, int] {
+	return func(assignment map[Var]int) int {
+		for j := marks - 1; j > 0; j-- {
+			if at, found := assignment[Var{0, j}]; found {
+				return at + triangle(marks-j)
+			}
+		}
+		return triangle(marks)
+	}
+}
+
+// draw the ruler to scale, a | for each mark
+func drawRuler(result map[Var]int, marks int) string {
+	ruler := []byte(strings.Repeat("-", result[Var{0, marks - 1}]+1))
+	ruler[0] = '|'
+	for j := 1; j < marks; j++ {
+		ruler[result[Var{0, j}]] = '|'
+	}
+	return string(ruler)
+}
+
+// model Golomb rulers using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Marks < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	// a ruler with its marks at 2^i - 1 never repeats a distance
+	if Longest == 0 {
+		Longest = 1<<(Marks-1) - 1
+	}
+
+	problem := NewProblem(Marks, Longest)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = markByMark(Marks)
+	bt.OrderValues = inPlace(problem)
+	bt.Timeout = Timeout
+	bt.Observe(csp.Hooks[Var, int]{
+		OnSolution: func(solution map[Var]int) {
+			fmt.Printf("Found a ruler of length %d after %d nodes\n", solution[Var{0, Marks - 1}], bt.Stats().Nodes)
+		},
+	})
+	minimize := bt.Minimize
+	if Restarts {
+		minimize = bt.MinimizeByRestarts
+	}
+	result, best := minimize(map[Var]int{}, length(Marks))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No ruler of %d marks no longer than %d found (%d nodes, %d backtracks)\n", Marks, Longest, stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	var at []string
+	for j := 0; j < Marks; j++ {
+		at = append(at, fmt.Sprint(result[Var{0, j}]))
+	}
+	fmt.Printf("Solution: %d marks at %s, length %d\n", Marks, strings.Join(at, ", "), best)
+	fmt.Println(drawRuler(result, Marks))
+	switch {
+	case stats.TimedOut:
+		fmt.Printf("Perhaps not the shortest, as the search timed out after %s\n", Timeout)
+	case Marks < len(Optimal) && best != Optimal[Marks]:
+		fmt.Printf("error: the shortest ruler of %d marks is known to have length %d\n", Marks, Optimal[Marks])
+		os.Exit(1)
+	default:
+		fmt.Println("The shortest possible, as no ruler is shorter")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
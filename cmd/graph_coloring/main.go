@@ -0,0 +1,625 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+//go:embed graphs/*
+var graphs embed.FS
+
+// Node is a vertex of the graph, by the name its file gives it
+type Node string
+
+// Color numbers the colors from 0; they're printed from 1
+type Color int
+
+// Graph is an undirected graph, its nodes in the order the file first
+// names them
+type Graph struct {
+	Nodes []Node
+	Edges [][2]Node
+}
+
+var (
+	// the bundled graph to color, if no file is given
+	GraphName string
+
+	// how the file is written: edges, dimacs or dot, or by its extension
+	Format string
+
+	// the number of colors to color the graph with
+	Colors int
+
+	// look for colorings with ever fewer colors, down to the fewest
+	Minimize bool
+
+	// give up on each coloring after this long, or zero for no limit
+	Timeout time.Duration
+
+	// print the colored graph in Graphviz DOT format instead
+	DOT bool
+
+	// animate the search in the terminal
+	TUI bool
+
+	// the colors nodes are drawn in, cycled through past the last
+	Palette = []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan, render.White}
+)
+
+func init() {
+	flag.StringVar(&GraphName, "graph", "myciel3", "the bundled graph to color: "+strings.Join(bundled(), ", "))
+	flag.StringVar(&Format, "format", "", "how the file is written: edges, dimacs or dot (default: by its extension)")
+	flag.IntVar(&Colors, "colors", 4, "the number of colors to use, or the most to use with -minimize")
+	flag.BoolVar(&Minimize, "minimize", false, "search for colorings with ever fewer colors, down to the fewest")
+	flag.DurationVar(&Timeout, "timeout", 0, "give up on each coloring after this long (0 for no limit)")
+	flag.BoolVar(&DOT, "dot", false, "print the colored graph in Graphviz DOT format")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: graph_coloring [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "color the nodes of a graph, so that no edge joins two of the same color.\n")
+		fmt.Fprintf(os.Stderr, "a file is one of:\n\n")
+		fmt.Fprintf(os.Stderr, "  edges   a line per edge, naming its two nodes; a line of one name is a\n")
+		fmt.Fprintf(os.Stderr, "          node without edges, and lines starting with # are comments\n")
+		fmt.Fprintf(os.Stderr, "  dimacs  the .col format of the DIMACS benchmarks: a \"p edge N M\" line,\n")
+		fmt.Fprintf(os.Stderr, "          then \"e U V\" lines over the nodes 1 to N\n")
+		fmt.Fprintf(os.Stderr, "  dot     a Graphviz graph or digraph, of node and edge statements;\n")
+		fmt.Fprintf(os.Stderr, "          attributes and subgraph braces are skipped\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the names of the bundled graphs
+func bundled() []string {
+	entries, err := graphs.ReadDir("graphs")
+	if err != nil {
+		panic(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+	}
+	return names
+}
+
+// the format a file is written in, by its extension
+func formatOf(path string) string {
+	switch filepath.Ext(path) {
+	case ".dot", ".gv":
+		return "dot"
+	case ".col":
+		return "dimacs"
+	}
+	return "edges"
+}
+
+// read a graph in the given format. self-loops are rejected, as they
+// leave no coloring, and edges given twice, or both ways, are kept once
+func ReadGraph(r io.Reader, format string) (Graph, error) {
+	g := &graphBuilder{seen: map[Node]bool{}, joined: map[[2]Node]bool{}}
+	var err error
+	switch format {
+	case "edges":
+		err = g.readEdges(r)
+	case "dimacs":
+		err = g.readDIMACS(r)
+	case "dot":
+		err = g.readDOT(r)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	return g.Graph, err
+}
+
+type graphBuilder struct {
+	Graph
+	seen   map[Node]bool
+	joined map[[2]Node]bool
+}
+
+func (g *graphBuilder) node(n Node) {
+	if !g.seen[n] {
+		g.seen[n] = true
+		g.Nodes = append(g.Nodes, n)
+	}
+}
+
+func (g *graphBuilder) edge(u, v Node) error {
+	if u == v {
+		return fmt.Errorf("node %q is joined to itself, so it can't be colored", u)
+	}
+	g.node(u)
+	g.node(v)
+	if u > v {
+		u, v = v, u
+	}
+	if !g.joined[[2]Node{u, v}] {
+		g.joined[[2]Node{u, v}] = true
+		g.Edges = append(g.Edges, [2]Node{u, v})
+	}
+	return nil
+}
+
+func (g *graphBuilder) readEdges(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 1:
+			g.node(Node(fields[0]))
+		case 2:
+			if err := g.edge(Node(fields[0]), Node(fields[1])); err != nil {
+				return fmt.Errorf("line %d: %s", lineNo, err)
+			}
+		default:
+			return fmt.Errorf("line %d: want one or two nodes, got %d fields", lineNo, len(fields))
+		}
+	}
+	return scanner.Err()
+}
+
+func (g *graphBuilder) readDIMACS(r io.Reader) error {
+	nodes := -1
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+
+		switch {
+		case fields[0] == "p" && len(fields) == 4 && nodes < 0:
+			if _, err := fmt.Sscan(fields[2], &nodes); err != nil || nodes < 0 {
+				return fmt.Errorf("line %d: invalid node count %q", lineNo, fields[2])
+			}
+			for n := 1; n <= nodes; n++ {
+				g.node(Node(fmt.Sprint(n)))
+			}
+		case fields[0] == "e" && len(fields) == 3 && nodes >= 0:
+			var u, v int
+			if _, err := fmt.Sscan(fields[1], &u); err != nil || u < 1 || u > nodes {
+				return fmt.Errorf("line %d: invalid node %q", lineNo, fields[1])
+			}
+			if _, err := fmt.Sscan(fields[2], &v); err != nil || v < 1 || v > nodes {
+				return fmt.Errorf("line %d: invalid node %q", lineNo, fields[2])
+			}
+			if err := g.edge(Node(fmt.Sprint(u)), Node(fmt.Sprint(v))); err != nil {
+				return fmt.Errorf("line %d: %s", lineNo, err)
+			}
+		default:
+			return fmt.Errorf("line %d: want a \"p edge\" line, then \"e\" lines", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if nodes < 0 {
+		return fmt.Errorf("no \"p edge\" line")
+	}
+	return nil
+}
+
+// read the node and edge statements of a DOT graph, e.g. `a -- b -- c
+// [color=red];`, taking edges either way. a statement's attributes, the
+// graph's and the node and edge defaults are all skipped, as are the
+// braces of subgraphs, whose nodes join the graph's
+func (g *graphBuilder) readDOT(r io.Reader) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	tokens, err := dotTokens(string(src))
+	if err != nil {
+		return err
+	}
+
+	keywords := map[string]bool{"strict": true, "graph": true, "digraph": true, "subgraph": true, "node": true, "edge": true}
+	named := map[string]bool{"graph": true, "digraph": true, "subgraph": true}
+	for ndx := 0; ndx < len(tokens); {
+		tok := tokens[ndx]
+		switch {
+		case !tok.id && tok.text == "[":
+			// an attribute list, to its closing bracket
+			for ndx < len(tokens) && tokens[ndx].text != "]" {
+				ndx++
+			}
+			ndx++
+		case !tok.id:
+			// braces, semicolons and stray punctuation
+			ndx++
+		case !tok.quoted && keywords[strings.ToLower(tok.text)]:
+			// the name that may follow graph or subgraph goes with the
+			// keyword, rather than being a node
+			ndx++
+			if named[strings.ToLower(tok.text)] && ndx < len(tokens) && tokens[ndx].id {
+				ndx++
+			}
+		case ndx+1 < len(tokens) && tokens[ndx+1].text == "=":
+			// a graph attribute, e.g. rankdir=LR
+			ndx += 3
+		default:
+			// a node, and the edges chained on from it
+			from := Node(tok.text)
+			g.node(from)
+			ndx = skipPort(tokens, ndx+1)
+			for ndx+1 < len(tokens) && (tokens[ndx].text == "--" || tokens[ndx].text == "->") {
+				if !tokens[ndx+1].id {
+					return fmt.Errorf("line %d: an edge must join two nodes", tokens[ndx].line)
+				}
+				to := Node(tokens[ndx+1].text)
+				if err := g.edge(from, to); err != nil {
+					return fmt.Errorf("line %d: %s", tokens[ndx].line, err)
+				}
+				from = to
+				ndx = skipPort(tokens, ndx+2)
+			}
+		}
+	}
+	return nil
+}
+
+// skip a node's port, e.g. the :ne of `a:ne -- b`
+func skipPort(tokens []dotToken, ndx int) int {
+	for ndx+1 < len(tokens) && tokens[ndx].text == ":" {
+		ndx += 2
+	}
+	return ndx
+}
+
+type dotToken struct {
+	text string
+	line int
+	// an ID, rather than an operator or punctuation
+	id bool
+	// written within double quotes, so never a keyword
+	quoted bool
+}
+
+// split DOT source into IDs, edge operators and punctuation, dropping
+// comments and the quotes of quoted IDs
+func dotTokens(src string) ([]dotToken, error) {
+	var tokens []dotToken
+	line := 1
+	for ndx := 0; ndx < len(src); {
+		c := src[ndx]
+		switch {
+		case c == '\n':
+			line++
+			ndx++
+		case unicode.IsSpace(rune(c)):
+			ndx++
+		case strings.HasPrefix(src[ndx:], "//") || (c == '#' && (ndx == 0 || src[ndx-1] == '\n')):
+			for ndx < len(src) && src[ndx] != '\n' {
+				ndx++
+			}
+		case strings.HasPrefix(src[ndx:], "/*"):
+			end := strings.Index(src[ndx+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[ndx:ndx+2+end], "\n")
+			ndx += end + 4
+		case strings.HasPrefix(src[ndx:], "--") || strings.HasPrefix(src[ndx:], "->"):
+			tokens = append(tokens, dotToken{text: src[ndx : ndx+2], line: line})
+			ndx += 2
+		case c == '"':
+			var sb strings.Builder
+			start := line
+			for ndx++; ndx < len(src) && src[ndx] != '"'; ndx++ {
+				if src[ndx] == '\\' && ndx+1 < len(src) && src[ndx+1] == '"' {
+					ndx++
+				}
+				if src[ndx] == '\n' {
+					line++
+				}
+				sb.WriteByte(src[ndx])
+			}
+			if ndx == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			tokens = append(tokens, dotToken{text: sb.String(), line: start, id: true, quoted: true})
+			ndx++
+		case c == '_' || c == '.' || c == '-' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			start := ndx
+			for ndx < len(src) {
+				c := src[ndx]
+				if !(c == '_' || c == '.' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))) && !(c == '-' && ndx == start) {
+					break
+				}
+				ndx++
+			}
+			tokens = append(tokens, dotToken{text: src[start:ndx], line: line, id: true})
+		default:
+			tokens = append(tokens, dotToken{text: string(c), line: line})
+			ndx++
+		}
+	}
+	return tokens, nil
+}
+
+// model the coloring with a variable per node, ranging over the colors,
+// and the two ends of every edge different. the node with the most edges
+// gets the first color, as any coloring can have its colors renumbered so
+func NewProblem(g Graph, colors int) csp.Problem[Node, Color] {
+	var palette []Color
+	for c := Color(0); c < Color(colors); c++ {
+		palette = append(palette, c)
+	}
+
+	degree := map[Node]int{}
+	for _, e := range g.Edges {
+		degree[e[0]]++
+		degree[e[1]]++
+	}
+	domain := map[Node][]Color{}
+	for _, n := range g.Nodes {
+		domain[n] = palette
+	}
+	if len(g.Nodes) > 0 && colors > 0 {
+		busiest := g.Nodes[0]
+		for _, n := range g.Nodes {
+			if degree[n] > degree[busiest] {
+				busiest = n
+			}
+		}
+		domain[busiest] = palette[:1]
+	}
+
+	problem := csp.New[Node, Color](domain, nil)
+	for _, e := range g.Edges {
+		problem.AddConstraint(csp.NotEqual(e[0], e[1]))
+	}
+	return problem
+}
+
+// try the colors in use so far, and then just one new color: any other
+// new color would only give a renumbering of the same coloring
+func newColorLast(problem csp.Problem[Node, Color]) csp.ValueOrder[Node, Color] {
+	return func(variable Node, assignment map[Node]Color) []Color {
+		used := Color(-1)
+		for _, c := range assignment {
+			if c > used {
+				used = c
+			}
+		}
+
+		var values []Color
+		for _, c := range problem.Domain[variable] {
+			if c <= used+1 {
+				values = append(values, c)
+			}
+		}
+		return values
+	}
+}
+
+// a lower bound on the colors needed: the size of a clique, found
+// greedily by adding the nodes with the most edges first
+func cliqueBound(g Graph) int {
+	adjacent := map[[2]Node]bool{}
+	degree := map[Node]int{}
+	for _, e := range g.Edges {
+		adjacent[e] = true
+		adjacent[[2]Node{e[1], e[0]}] = true
+		degree[e[0]]++
+		degree[e[1]]++
+	}
+	nodes := append([]Node{}, g.Nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return degree[nodes[i]] > degree[nodes[j]] })
+
+	best := 0
+	for _, start := range nodes {
+		clique := []Node{start}
+		for _, n := range nodes {
+			joined := n != start
+			for _, m := range clique {
+				joined = joined && adjacent[[2]Node{n, m}]
+			}
+			if joined {
+				clique = append(clique, n)
+			}
+		}
+		if len(clique) > best {
+			best = len(clique)
+		}
+	}
+	return best
+}
+
+// the number of colors a coloring uses
+func colorsUsed(result map[Node]Color) int {
+	used := map[Color]bool{}
+	for _, c := range result {
+		used[c] = true
+	}
+	return len(used)
+}
+
+// list the nodes of each color, in the order the graph names them
+func drawColoring(g Graph, candidate map[Node]Color) string {
+	byColor := map[Color][]Node{}
+	var uncolored []Node
+	for _, n := range g.Nodes {
+		if c, found := candidate[n]; found {
+			byColor[c] = append(byColor[c], n)
+		} else {
+			uncolored = append(uncolored, n)
+		}
+	}
+	var colors []Color
+	for c := range byColor {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool { return colors[i] < colors[j] })
+
+	var b strings.Builder
+	for _, c := range colors {
+		var names []string
+		for _, n := range byColor[c] {
+			names = append(names, string(n))
+		}
+		fmt.Fprintf(&b, "\x1b[%sm%3d \x1b[0;0m %s\n", Palette[int(c)%len(Palette)], c+1, strings.Join(names, " "))
+	}
+	if len(uncolored) > 0 {
+		var names []string
+		for _, n := range uncolored {
+			names = append(names, string(n))
+		}
+		fmt.Fprintf(&b, "\x1b[0;38m ·· \x1b[0;0m %s\n", strings.Join(names, " "))
+	}
+	return b.String()
+}
+
+// search for a coloring with the given number of colors
+func color(g Graph, colors int, title string) (map[Node]Color, csp.Stats) {
+	problem := NewProblem(g, colors)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = newColorLast(problem)
+	bt.Timeout = Timeout
+
+	if !TUI {
+		return bt.Solve(map[Node]Color{}), bt.Stats()
+	}
+	draw := func(candidate map[Node]Color) string { return drawColoring(g, candidate) }
+	result, err := tui.New(title, draw).Run(bt, map[Node]Color{})
+	if err != nil {
+		panic(err)
+	}
+	if bt.Stats().Canceled {
+		os.Exit(0)
+	}
+	return result, bt.Stats()
+}
+
+// model graph coloring using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Colors < 1 {
+		fmt.Fprintf(os.Stderr, "error: -colors must be at least 1, got %d\n", Colors)
+		os.Exit(2)
+	}
+
+	var in io.ReadCloser
+	var err error
+	name := GraphName
+	switch flag.NArg() {
+	case 0:
+		var path string
+		for _, ext := range []string{".col", ".dot", ".txt"} {
+			if _, err := fs.Stat(graphs, "graphs/"+name+ext); err == nil {
+				path = "graphs/" + name + ext
+				break
+			}
+		}
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "error: no bundled graph %q, try one of: %s\n", name, strings.Join(bundled(), ", "))
+			os.Exit(2)
+		}
+		if Format == "" {
+			Format = formatOf(path)
+		}
+		in, err = graphs.Open(path)
+	case 1:
+		name = flag.Arg(0)
+		if Format == "" {
+			Format = formatOf(name)
+		}
+		in, err = os.Open(name)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	var g Graph
+	if err == nil {
+		g, err = ReadGraph(in, Format)
+		in.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	title := fmt.Sprintf("Graph coloring: %s, %d nodes and %d edges", name, len(g.Nodes), len(g.Edges))
+
+	// color the graph, and then, to minimize, again with one color fewer
+	// than the last coloring used, until there's none
+	var best map[Node]Color
+	var total csp.Stats
+	bound := cliqueBound(g)
+	proven := true
+	for k := Colors; k > 0; {
+		result, stats := color(g, k, title)
+		total.Nodes += stats.Nodes
+		total.Backtracks += stats.Backtracks
+		total.Duration += stats.Duration
+		if result == nil {
+			proven = !stats.TimedOut
+			if best == nil {
+				fmt.Printf("No coloring with %d colors (%d nodes, %d backtracks)\n", k, stats.Nodes, stats.Backtracks)
+				if stats.TimedOut {
+					fmt.Println("The search timed out")
+				}
+				os.Exit(1)
+			}
+			break
+		}
+
+		best = result
+		if !Minimize {
+			break
+		}
+		used := colorsUsed(result)
+		if !DOT {
+			fmt.Printf("Found a coloring with %d colors after %s\n", used, total.Duration)
+		}
+		if used <= bound {
+			// there's a clique of as many nodes, which needs them all
+			break
+		}
+		k = used - 1
+	}
+
+	if DOT {
+		named := map[Node]Color{}
+		for n, c := range best {
+			named[n] = c + 1
+		}
+		if err := NewProblem(g, Colors).ExportDOTSolution(os.Stdout, named); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	fmt.Printf("Solution: %s, %d nodes and %d edges, in %d colors\n", name, len(g.Nodes), len(g.Edges), colorsUsed(best))
+	fmt.Print(drawColoring(g, best))
+	if Minimize {
+		switch {
+		case colorsUsed(best) <= bound:
+			fmt.Printf("The fewest colors possible, as the graph has a clique of %d nodes\n", bound)
+		case proven:
+			fmt.Printf("The fewest colors possible, as there's no coloring with %d\n", colorsUsed(best)-1)
+		default:
+			fmt.Printf("Perhaps not the fewest colors possible, as the search timed out\n")
+		}
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", total.Nodes, total.Backtracks, total.Duration)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Cell is a cell of the grid, by row and column counting from 0
+type Cell struct {
+	Row, Col int
+}
+
+// Number is one of the numbers to place, from 1 to the count of open
+// cells. its variable holds the index of the cell it's placed in
+type Number int
+
+// Puzzle is a hidato: a grid of open cells, some holding their numbers
+// already, and blocked ones
+type Puzzle struct {
+	Rows, Cols int
+	Open       map[Cell]bool
+	Given      map[Number]Cell
+}
+
+var (
+	// the puzzle solved when none is given, a row per line of . for an
+	// open cell, # for a blocked one, or a given number
+	Example = []string{
+		".  33 35 .  .  #  #  #",
+		".  .  24 22 .  #  #  #",
+		".  .  .  21 .  .  #  #",
+		".  26 .  13 40 11 #  #",
+		"27 .  .  .  9  .  1  #",
+		"#  #  .  .  18 .  .  #",
+		"#  #  #  #  .  7  .  .",
+		"#  #  #  #  #  #  5  .",
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: hidato [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a hidato: number the open cells of the grid from 1 on, keeping the\n")
+		fmt.Fprintf(os.Stderr, "numbers given, so that each number touches the next, across, down or at\n")
+		fmt.Fprintf(os.Stderr, "a corner. a file lays out the grid a row per line, with a field per cell\n")
+		fmt.Fprintf(os.Stderr, "separated by spaces: . for an open cell, # for a blocked one, or a number\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// parse a puzzle as described by the usage: every row must have as many
+// cells as the first, and each given number be one of those to place
+func ParsePuzzle(lines []string) (Puzzle, error) {
+	p := Puzzle{Open: map[Cell]bool{}, Given: map[Number]Cell{}}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if p.Rows > 0 && len(fields) != p.Cols {
+			return p, fmt.Errorf("row %d has %d cells, not %d", p.Rows+1, len(fields), p.Cols)
+		}
+		p.Cols = len(fields)
+		for col, field := range fields {
+			cell := Cell{p.Rows, col}
+			if field == "#" {
+				continue
+			}
+			p.Open[cell] = true
+			if field == "." {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 {
+				return p, fmt.Errorf("row %d: invalid cell %q", p.Rows+1, field)
+			}
+			if _, found := p.Given[Number(n)]; found {
+				return p, fmt.Errorf("row %d: %d is given twice", p.Rows+1, n)
+			}
+			p.Given[Number(n)] = cell
+		}
+		p.Rows++
+	}
+	if len(p.Open) == 0 {
+		return p, fmt.Errorf("no open cells")
+	}
+	for n := range p.Given {
+		if int(n) > len(p.Open) {
+			return p, fmt.Errorf("%d is given, but there are only %d open cells", n, len(p.Open))
+		}
+	}
+	return p, nil
+}
+
+// the value a number's variable holds for a cell
+func (p Puzzle) index(c Cell) int {
+	return c.Row*p.Cols + c.Col
+}
+
+func (p Puzzle) cell(index int) Cell {
+	return Cell{index / p.Cols, index % p.Cols}
+}
+
+func touching(a, b Cell) bool {
+	dr, dc := a.Row-b.Row, a.Col-b.Col
+	return a != b && dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1
+}
+
+// how many steps apart two cells are, moving across, down or at a corner
+func steps(a, b Cell) int {
+	dr, dc := a.Row-b.Row, a.Col-b.Col
+	if dr < 0 {
+		dr = -dr
+	}
+	if dc < 0 {
+		dc = -dc
+	}
+	if dr > dc {
+		return dr
+	}
+	return dc
+}
+
+// model the puzzle by where each number goes, rather than which number
+// goes in each cell: a variable per number for the index of its cell, the
+// given ones fixed by their domains. AllDifferent puts every number in a
+// cell of its own, and a Table constraint per number and the next lists
+// the pairs of touching cells they could take, as a successor relation
+// would. a number's domain leaves out the cells too many steps from any
+// given number to reach it in time
+func NewProblem(p Puzzle) csp.Problem[Number, int] {
+	domain := map[Number][]int{}
+	var numbers []Number
+	for n := Number(1); int(n) <= len(p.Open); n++ {
+		numbers = append(numbers, n)
+		if at, found := p.Given[n]; found {
+			domain[n] = []int{p.index(at)}
+			continue
+		}
+		domain[n] = []int{}
+		for index := 0; index < p.Rows*p.Cols; index++ {
+			c := p.cell(index)
+			if !p.Open[c] {
+				continue
+			}
+			reachable := true
+			for g, at := range p.Given {
+				if gap := int(g - n); at == c || steps(at, c) > gap && steps(at, c) > -gap {
+					reachable = false
+				}
+			}
+			if reachable {
+				domain[n] = append(domain[n], index)
+			}
+		}
+	}
+
+	problem := csp.New[Number, int](domain, nil)
+	problem.AddConstraint(csp.AllDifferent(numbers...))
+	for ndx := 0; ndx+1 < len(numbers); ndx++ {
+		n := numbers[ndx]
+		var tuples [][]int
+		for _, a := range domain[n] {
+			for _, b := range domain[n+1] {
+				if touching(p.cell(a), p.cell(b)) {
+					tuples = append(tuples, []int{a, b})
+				}
+			}
+		}
+		problem.AddConstraint(csp.Table([]Number{n, n + 1}, tuples))
+	}
+	return problem
+}
+
+// place the numbers in order, each next to the last, so that every Table
+// is checked as soon as it can be
+func inOrder(p Puzzle) csp.VariableOrder[Number, int] {
+	return func(assignment map[Number]int) Number {
+		for n := Number(1); int(n) <= len(p.Open); n++ {
+			if _, found := assignment[n]; !found {
+				return n
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// draw the grid with the given numbers inverted, the rest plain, and the
+// blocked cells blank
+func drawGrid(p Puzzle, result map[Number]int) string {
+	at := map[Cell]Number{}
+	for n, index := range result {
+		at[p.cell(index)] = n
+	}
+	return render.Grid{
+		Rows: p.Rows,
+		Cols: p.Cols,
+		Cell: func(row, col int) render.Cell {
+			c := Cell{row, col}
+			n := at[c]
+			if !p.Open[c] {
+				return render.Cell{Text: " "}
+			}
+			if given, found := p.Given[n]; found && given == c {
+				return render.Cell{Text: fmt.Sprint(n), Color: render.White}
+			}
+			return render.Cell{Text: fmt.Sprint(n)}
+		},
+	}.String()
+}
+
+// model hidato using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	lines := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		lines = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := ParsePuzzle(lines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = inOrder(p)
+	found := 0
+	bt.Observe(csp.Hooks[Number, int]{
+		OnSolution: func(map[Number]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Number]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+//go:embed instances/*.txt
+var instances embed.FS
+
+// Op is an operation: the Step'th, from 0, of a job's operations
+type Op struct {
+	Job  int
+	Step int
+}
+
+// Task is an operation's need for a machine, for a duration
+type Task struct {
+	Machine  int
+	Duration int
+}
+
+// Instance is a job-shop scheduling problem: jobs of tasks to be done in
+// order, on machines that do one task at a time
+type Instance struct {
+	Name     string
+	Machines int
+	Jobs     [][]Task
+}
+
+// the widest a Gantt chart is drawn, in columns
+const ChartWidth = 72
+
+var (
+	// the bundled instance to schedule, if no file is given
+	InstanceName string
+
+	// give up on improving the schedule after this long
+	Timeout time.Duration
+
+	// the colors the jobs are drawn in, cycled through past the last
+	Palette = []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan, render.White}
+)
+
+func init() {
+	flag.StringVar(&InstanceName, "instance", "ft06", "the bundled instance to schedule: ft06 or la01")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on improving the schedule after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: jobshop [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "schedule the operations of a job-shop problem in the shortest makespan.\n")
+		fmt.Fprintf(os.Stderr, "a file is in the standard format of the OR-Library benchmarks: a line\n")
+		fmt.Fprintf(os.Stderr, "of the numbers of jobs and machines, then a line per job of its\n")
+		fmt.Fprintf(os.Stderr, "operations in order, as machine and duration pairs with machines\n")
+		fmt.Fprintf(os.Stderr, "numbered from 0. lines starting with # are comments\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read an instance in the format described by the usage
+func ReadInstance(r io.Reader) (Instance, error) {
+	var in Instance
+	jobs := -1
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var numbers []int
+		for _, field := range strings.Fields(line) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 {
+				return in, fmt.Errorf("line %d: invalid number %q", lineNo, field)
+			}
+			numbers = append(numbers, n)
+		}
+		if jobs < 0 {
+			if len(numbers) != 2 {
+				return in, fmt.Errorf("line %d: want the numbers of jobs and machines", lineNo)
+			}
+			jobs, in.Machines = numbers[0], numbers[1]
+			continue
+		}
+
+		if len(numbers)%2 != 0 {
+			return in, fmt.Errorf("line %d: want machine and duration pairs", lineNo)
+		}
+		var job []Task
+		for ndx := 0; ndx < len(numbers); ndx += 2 {
+			if numbers[ndx] >= in.Machines {
+				return in, fmt.Errorf("line %d: no machine %d of %d", lineNo, numbers[ndx], in.Machines)
+			}
+			job = append(job, Task{Machine: numbers[ndx], Duration: numbers[ndx+1]})
+		}
+		in.Jobs = append(in.Jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return in, err
+	}
+	if jobs < 0 {
+		return in, fmt.Errorf("no line of the numbers of jobs and machines")
+	}
+	if len(in.Jobs) != jobs {
+		return in, fmt.Errorf("want %d jobs, got %d", jobs, len(in.Jobs))
+	}
+	return in, nil
+}
+
+func (in Instance) task(op Op) Task {
+	return in.Jobs[op.Job][op.Step]
+}
+
+// the time the job needs before op can start, and from op's start on
+func (in Instance) headTail(op Op) (int, int) {
+	head, tail := 0, 0
+	for step, t := range in.Jobs[op.Job] {
+		if step < op.Step {
+			head += t.Duration
+		} else {
+			tail += t.Duration
+		}
+	}
+	return head, tail
+}
+
+// model the schedule with an interval per operation: a variable for its
+// start, with its duration fixed. the operations of a job follow one
+// another, those of a machine are Disjunctive, and every job must finish
+// within the horizon, which bounds the starts' domains
+func NewProblem(in Instance, horizon int) csp.Problem[Op, int] {
+	domain := map[Op][]int{}
+	onMachine := make([][]Op, in.Machines)
+	for job, tasks := range in.Jobs {
+		for step, t := range tasks {
+			op := Op{job, step}
+			head, tail := in.headTail(op)
+			domain[op] = []int{}
+			for start := head; start <= horizon-tail; start++ {
+				domain[op] = append(domain[op], start)
+			}
+			onMachine[t.Machine] = append(onMachine[t.Machine], op)
+		}
+	}
+
+	problem := csp.New[Op, int](domain, nil)
+	for job, tasks := range in.Jobs {
+		for step := 1; step < len(tasks); step++ {
+			prev, next := Op{job, step - 1}, Op{job, step}
+			problem.AddConstraint(csp.Linear([]Op{next, prev}, []int{1, -1}, csp.Ge, tasks[step-1].Duration))
+		}
+	}
+	for _, ops := range onMachine {
+		if len(ops) < 2 {
+			continue
+		}
+		durations := make([]int, len(ops))
+		for ndx, op := range ops {
+			durations[ndx] = in.task(op).Duration
+		}
+		problem.AddConstraint(csp.Disjunctive(ops, durations))
+	}
+	return problem
+}
+
+// a schedule under construction: the intervals taken on each machine, and
+// where each job's operations have got to
+type schedule struct {
+	in       Instance
+	busy     [][][2]int
+	released []int
+	next     []int
+}
+
+func newSchedule(in Instance, assignment map[Op]int) schedule {
+	s := schedule{in: in, busy: make([][][2]int, in.Machines), released: make([]int, len(in.Jobs)), next: make([]int, len(in.Jobs))}
+	for op, start := range assignment {
+		t := in.task(op)
+		s.busy[t.Machine] = append(s.busy[t.Machine], [2]int{start, start + t.Duration})
+	}
+	for job := range in.Jobs {
+		for {
+			op := Op{job, s.next[job]}
+			start, found := assignment[op]
+			if !found {
+				break
+			}
+			s.released[job] = start + in.task(op).Duration
+			s.next[job]++
+		}
+	}
+	return s
+}
+
+// report whether op fits on its machine, started at start
+func (s schedule) fits(op Op, start int) bool {
+	t := s.in.task(op)
+	for _, interval := range s.busy[t.Machine] {
+		if start < interval[1] && interval[0] < start+t.Duration {
+			return false
+		}
+	}
+	return true
+}
+
+// the earliest op can start, after the job's previous operation and in a
+// gap on its machine
+func (s schedule) earliest(op Op) int {
+	t := s.in.task(op)
+	start := s.released[op.Job]
+	intervals := append([][2]int{}, s.busy[t.Machine]...)
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i][0] < intervals[j][0] })
+	for _, interval := range intervals {
+		if start < interval[1] && interval[0] < start+t.Duration {
+			start = interval[1]
+		}
+	}
+	return start
+}
+
+// a lower bound on the makespan of any schedule completing this one, by
+// the one-machine bound: each remaining operation can start no earlier
+// than its job allows, and leaves the rest of its job to do after it, so
+// those of a machine that start from any time on take at least their
+// total duration, then the least of their jobs' remaining work after them
+func (s schedule) bound() int {
+	type remaining struct{ release, duration, after int }
+	onMachine := make([][]remaining, s.in.Machines)
+	for job, tasks := range s.in.Jobs {
+		if s.next[job] == len(tasks) {
+			continue
+		}
+		release := s.earliest(Op{job, s.next[job]})
+		for step := s.next[job]; step < len(tasks); step++ {
+			t := tasks[step]
+			_, tail := s.in.headTail(Op{job, step})
+			onMachine[t.Machine] = append(onMachine[t.Machine], remaining{release, t.Duration, tail - t.Duration})
+			release += t.Duration
+		}
+	}
+
+	best := 0
+	for _, ops := range onMachine {
+		for _, from := range ops {
+			work, after := 0, -1
+			for _, op := range ops {
+				if op.release >= from.release {
+					work += op.duration
+					if after < 0 || op.after < after {
+						after = op.after
+					}
+				}
+			}
+			if from.release+work+after > best {
+				best = from.release + work + after
+			}
+		}
+	}
+	return best
+}
+
+// schedule the operations forward in time: next, the one that can start
+// the earliest, of those next in their jobs. ties go to the one whose job
+// has the most work left, as it's the likeliest to run late
+func earliestStart(in Instance) csp.VariableOrder[Op, int] {
+	return func(assignment map[Op]int) Op {
+		s := newSchedule(in, assignment)
+		var best Op
+		bestStart, bestTail := -1, -1
+		for job, tasks := range in.Jobs {
+			if s.next[job] == len(tasks) {
+				continue
+			}
+			op := Op{job, s.next[job]}
+			start := s.earliest(op)
+			_, tail := in.headTail(op)
+			if bestStart < 0 || start < bestStart || (start == bestStart && tail > bestTail) {
+				best, bestStart, bestTail = op, start, tail
+			}
+		}
+		return best
+	}
+}
+
+// try the starts that fit, from the earliest, leaving out those no
+// semi-active schedule has, where no operation can be shifted earlier: an
+// operation starts as soon as its job allows, or as its machine's previous
+// operation ends. so after the earliest start, the only starts are the
+// ends of operations already on the machine, and those from the earliest
+// completion of one still to be scheduled there. and if no completion of
+// the schedule can make the horizon, there's no point trying any
+func semiActiveStarts(in Instance, problem csp.Problem[Op, int], horizon int) csp.ValueOrder[Op, int] {
+	return func(op Op, assignment map[Op]int) []int {
+		s := newSchedule(in, assignment)
+		if s.bound() > horizon {
+			return nil
+		}
+
+		machine := in.task(op).Machine
+		earliest := s.earliest(op)
+		ends := map[int]bool{}
+		for _, interval := range s.busy[machine] {
+			ends[interval[1]] = true
+		}
+		competitor := -1
+		for job, tasks := range in.Jobs {
+			if job == op.Job {
+				continue
+			}
+			release := s.released[job]
+			for step := s.next[job]; step < len(tasks); step++ {
+				if t := tasks[step]; t.Machine == machine && (competitor < 0 || release+t.Duration < competitor) {
+					competitor = release + t.Duration
+				}
+				release += tasks[step].Duration
+			}
+		}
+
+		var values []int
+		for _, start := range problem.Domain[op] {
+			if start < earliest || !s.fits(op, start) {
+				continue
+			}
+			if start == earliest || ends[start] || (competitor >= 0 && start >= competitor) {
+				values = append(values, start)
+			}
+		}
+		return values
+	}
+}
+
+// the latest any job finishes
+func makespan(in Instance, result map[Op]int) int {
+	end := 0
+	for op, start := range result {
+		if finish := start + in.task(op).Duration; finish > end {
+			end = finish
+		}
+	}
+	return end
+}
+
+// draw a Gantt chart, a line per machine with each operation marked by
+// its job, scaled to fit ChartWidth
+func drawChart(in Instance, result map[Op]int) string {
+	span := makespan(in, result)
+	scale := (span + ChartWidth - 1) / ChartWidth
+	if scale < 1 {
+		scale = 1
+	}
+
+	var b strings.Builder
+	for m := 0; m < in.Machines; m++ {
+		row := make([]int, (span+scale-1)/scale)
+		for ndx := range row {
+			row[ndx] = -1
+		}
+		for op, start := range result {
+			if t := in.task(op); t.Machine == m {
+				for at := start / scale; at < (start+t.Duration+scale-1)/scale; at++ {
+					row[at] = op.Job
+				}
+			}
+		}
+
+		fmt.Fprintf(&b, "M%-2d |", m)
+		for _, job := range row {
+			if job < 0 {
+				b.WriteString(" ")
+				continue
+			}
+			fmt.Fprintf(&b, "\x1b[%sm%s\x1b[0;0m", Palette[job%len(Palette)], strconv.FormatInt(int64(job%36), 36))
+		}
+		b.WriteString("|\n")
+	}
+	columns := (span + scale - 1) / scale
+	fmt.Fprintf(&b, "     0%*d, a column per %d time units\n", columns-1, span, scale)
+	return b.String()
+}
+
+// model job-shop scheduling using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in Instance
+	var err error
+	switch flag.NArg() {
+	case 0:
+		var src []byte
+		if src, err = instances.ReadFile("instances/" + InstanceName + ".txt"); err != nil {
+			fmt.Fprintf(os.Stderr, "error: no bundled instance %q\n", InstanceName)
+			os.Exit(2)
+		}
+		in, err = ReadInstance(strings.NewReader(string(src)))
+		in.Name = InstanceName
+	case 1:
+		var file *os.File
+		if file, err = os.Open(flag.Arg(0)); err == nil {
+			in, err = ReadInstance(file)
+			file.Close()
+		}
+		in.Name = flag.Arg(0)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", in.Name, err)
+		os.Exit(2)
+	}
+
+	// start from a horizon every schedule meets, with the jobs run one
+	// after another, and then, to minimize the makespan, search again
+	// within one less than the last schedule took, until there's none
+	horizon := 0
+	for _, tasks := range in.Jobs {
+		for _, t := range tasks {
+			horizon += t.Duration
+		}
+	}
+	deadline := time.Now().Add(Timeout)
+	var best map[Op]int
+	var total csp.Stats
+	proven := true
+	for {
+		problem := NewProblem(in, horizon)
+		bt := csp.NewBacktracker(problem)
+		bt.SelectVariable = earliestStart(in)
+		bt.OrderValues = semiActiveStarts(in, problem, horizon)
+		bt.Timeout = time.Until(deadline)
+		if bt.Timeout <= 0 {
+			proven = false
+			break
+		}
+
+		result := bt.Solve(map[Op]int{})
+		stats := bt.Stats()
+		total.Nodes += stats.Nodes
+		total.Backtracks += stats.Backtracks
+		total.Duration += stats.Duration
+		if result == nil {
+			proven = !stats.TimedOut
+			break
+		}
+		best = result
+		horizon = makespan(in, result) - 1
+		fmt.Printf("Found a schedule with makespan %d after %s\n", horizon+1, total.Duration)
+	}
+	if best == nil {
+		fmt.Printf("No schedule found (%d nodes, %d backtracks)\n", total.Nodes, total.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %s, %d jobs on %d machines, with makespan %d\n", in.Name, len(in.Jobs), in.Machines, makespan(in, best))
+	fmt.Print(drawChart(in, best))
+	if proven {
+		fmt.Println("The shortest makespan possible, as there's no schedule within one less")
+	} else {
+		fmt.Printf("Perhaps not the shortest makespan possible, as the search timed out after %s\n", Timeout)
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", total.Nodes, total.Backtracks, total.Duration)
+}
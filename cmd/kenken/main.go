@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+type Cell struct {
+	Row int
+	Col int
+}
+
+type Digit int
+
+// Cage is a group of cells whose digits make Target when combined with Op:
+// added, subtracted, multiplied or divided. a single cell just holds Target,
+// and has no Op
+type Cage struct {
+	Label  rune
+	Cells  []Cell
+	Target int
+	Op     string
+}
+
+// the clue a cage shows in its first cell, e.g. "12*"
+func (c Cage) Clue() string {
+	return fmt.Sprint(c.Target) + c.Op
+}
+
+// Puzzle is a KenKen: an empty Size by Size Latin square divided into cages
+type Puzzle struct {
+	Name  string
+	Size  int
+	Cages []Cage
+}
+
+//go:embed puzzles/*.txt
+var bundled embed.FS
+
+var (
+	// the colors that tell neighboring cages apart
+	Palette = []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan}
+
+	// the spellings of each operator that puzzle files accept
+	Operators = map[string]string{
+		"+": "+",
+		"-": "-", "−": "-",
+		"*": "*", "x": "*", "×": "*",
+		"/": "/", "÷": "/",
+	}
+
+	// solve this bundled puzzle, when no file is given
+	Name string
+
+	// animate the search in the terminal
+	TUI bool
+)
+
+func init() {
+	flag.StringVar(&Name, "puzzle", "6x6", "the bundled puzzle to solve: 4x4, 6x6 or 7x7")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: kenken [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a KenKen. a puzzle file maps the grid's cells to cages in a line of\n")
+		fmt.Fprintf(os.Stderr, "labels per row, followed by a line per cage giving its label and clue: a\n")
+		fmt.Fprintf(os.Stderr, "target and one of + - * /, or just the digit of a single cell, such as\n")
+		fmt.Fprintf(os.Stderr, "cmd/kenken/puzzles/6x6.txt\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// read a puzzle in the format of the bundled ones. the length of the first
+// row of labels sets the size of the grid. lines starting with # are comments
+func ReadPuzzle(name string, r io.Reader) (Puzzle, error) {
+	p := Puzzle{Name: name}
+	cages := map[rune]*Cage{}
+	var order []rune
+
+	scanner := bufio.NewScanner(r)
+	row, lineNo := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// the cage map comes first
+		if p.Size == 0 || row < p.Size {
+			labels := []rune(line)
+			if p.Size == 0 {
+				p.Size = len(labels)
+			}
+			if len(labels) != p.Size {
+				return p, fmt.Errorf("line %d: want %d cage labels, got %d", lineNo, p.Size, len(labels))
+			}
+			for col, label := range labels {
+				cage, found := cages[label]
+				if !found {
+					cage = &Cage{Label: label, Target: -1}
+					cages[label] = cage
+					order = append(order, label)
+				}
+				cage.Cells = append(cage.Cells, Cell{row, col})
+			}
+			row++
+			continue
+		}
+
+		// then the clues
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len([]rune(fields[0])) != 1 {
+			return p, fmt.Errorf("line %d: want a cage label and its clue", lineNo)
+		}
+		cage, found := cages[[]rune(fields[0])[0]]
+		if !found {
+			return p, fmt.Errorf("line %d: no cage %s in the grid", lineNo, fields[0])
+		}
+		target, op, err := parseClue(fields[1])
+		if err != nil {
+			return p, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		cage.Target, cage.Op = target, op
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	if p.Size == 0 || row < p.Size {
+		return p, fmt.Errorf("only %d of %d rows of cage labels", row, p.Size)
+	}
+
+	for _, label := range order {
+		cage := cages[label]
+		switch {
+		case cage.Target < 0:
+			return p, fmt.Errorf("no clue for cage %c", label)
+		case cage.Op == "" && len(cage.Cells) > 1:
+			return p, fmt.Errorf("cage %c has %d cells but no operator", label, len(cage.Cells))
+		case (cage.Op == "-" || cage.Op == "/") && len(cage.Cells) != 2:
+			return p, fmt.Errorf("cage %c has %d cells, but %s takes 2", label, len(cage.Cells), cage.Op)
+		}
+		p.Cages = append(p.Cages, *cage)
+	}
+	return p, nil
+}
+
+// split a clue like "12*" into its target and operator
+func parseClue(clue string) (int, string, error) {
+	digits := strings.TrimRightFunc(clue, func(r rune) bool { return r < '0' || r > '9' })
+	op := clue[len(digits):]
+	if op != "" {
+		canonical, found := Operators[op]
+		if !found {
+			return 0, "", fmt.Errorf("unknown operator %q", op)
+		}
+		op = canonical
+	}
+
+	target, err := strconv.Atoi(digits)
+	if err != nil || target < 0 || (target == 0 && op != "-") {
+		return 0, "", fmt.Errorf("invalid clue %q", clue)
+	}
+	return target, op, nil
+}
+
+// model the puzzle with a variable per cell, the single-cell cages fixed
+// by their domains, all the digits of each row and column different, and
+// an arithmetic constraint per other cage. unlike killer sudoku, a cage
+// may repeat a digit, so long as it doesn't repeat in a row or column
+func NewProblem(p Puzzle) csp.Problem[Cell, Digit] {
+	var digits []Digit
+	for d := Digit(1); d <= Digit(p.Size); d++ {
+		digits = append(digits, d)
+	}
+	domain := map[Cell][]Digit{}
+	for row := 0; row < p.Size; row++ {
+		for col := 0; col < p.Size; col++ {
+			domain[Cell{row, col}] = digits
+		}
+	}
+	for _, cage := range p.Cages {
+		if cage.Op == "" {
+			domain[cage.Cells[0]] = []Digit{Digit(cage.Target)}
+		}
+	}
+
+	problem := csp.New[Cell, Digit](domain, nil)
+	for i := 0; i < p.Size; i++ {
+		var row, col []Cell
+		for j := 0; j < p.Size; j++ {
+			row = append(row, Cell{i, j})
+			col = append(col, Cell{j, i})
+		}
+		problem.AddConstraint(csp.AllDifferent(row...))
+		problem.AddConstraint(csp.AllDifferent(col...))
+	}
+
+	for _, cage := range p.Cages {
+		switch cage.Op {
+		case "+":
+			problem.AddConstraint(csp.Sum(cage.Cells, csp.Eq, cage.Target))
+		case "-":
+			problem.AddConstraint(csp.AbsDiff(cage.Cells[0], cage.Cells[1], csp.Eq, cage.Target))
+		case "*":
+			problem.AddConstraint(csp.Product(cage.Cells, cage.Target))
+		case "/":
+			problem.AddConstraint(csp.Quotient(cage.Cells[0], cage.Cells[1], cage.Target))
+		}
+	}
+
+	return problem
+}
+
+// pick a color for each cage so that no two touching cages share one
+func colorCages(p Puzzle) map[Cell]render.Color {
+	owner := map[Cell]int{}
+	for ndx, cage := range p.Cages {
+		for _, c := range cage.Cells {
+			owner[c] = ndx
+		}
+	}
+
+	// largest cages first, since they have the most neighbors
+	order := make([]int, len(p.Cages))
+	for ndx := range order {
+		order[ndx] = ndx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(p.Cages[order[i]].Cells) > len(p.Cages[order[j]].Cells)
+	})
+
+	colors := map[int]int{}
+	for _, ndx := range order {
+		used := map[int]bool{}
+		for _, c := range p.Cages[ndx].Cells {
+			for _, n := range []Cell{{c.Row - 1, c.Col}, {c.Row + 1, c.Col}, {c.Row, c.Col - 1}, {c.Row, c.Col + 1}} {
+				if other, found := owner[n]; found && other != ndx {
+					if color, colored := colors[other]; colored {
+						used[color] = true
+					}
+				}
+			}
+		}
+		for color := range Palette {
+			if !used[color] {
+				colors[ndx] = color
+				break
+			}
+		}
+	}
+
+	out := map[Cell]render.Color{}
+	for c, ndx := range owner {
+		out[c] = Palette[colors[ndx]]
+	}
+	return out
+}
+
+// draw the grid in the colors of its cages, showing the digits assigned
+// so far, or else the clue of each cage in its first cell
+func drawGrid(p Puzzle, colors map[Cell]render.Color, candidate map[Cell]Digit) string {
+	clues := map[Cell]string{}
+	for _, cage := range p.Cages {
+		clues[cage.Cells[0]] = cage.Clue()
+	}
+
+	return render.Grid{
+		Rows: p.Size,
+		Cols: p.Size,
+		Cell: func(row, col int) render.Cell {
+			c := Cell{row, col}
+			cell := render.Cell{Text: ".", Color: colors[c]}
+			if candidate == nil {
+				if clue, found := clues[c]; found {
+					cell.Text = clue
+				}
+			} else if d, found := candidate[c]; found {
+				cell.Text = fmt.Sprint(d)
+			}
+			return cell
+		},
+	}.String()
+}
+
+func solve(p Puzzle) bool {
+	colors := colorCages(p)
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+
+	var result map[Cell]Digit
+	if TUI {
+		draw := func(candidate map[Cell]Digit) string { return drawGrid(p, colors, candidate) }
+		var err error
+		if result, err = tui.New("KenKen: "+p.Name, draw).Run(bt, map[Cell]Digit{}); err != nil {
+			panic(err)
+		}
+	} else {
+		result = bt.Solve(map[Cell]Digit{})
+	}
+
+	fmt.Printf("Puzzle %s, with %d cages:\n", p.Name, len(p.Cages))
+	fmt.Print(drawGrid(p, colors, nil))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, colors, result))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
+
+// model KenKen using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in io.ReadCloser
+	var err error
+	name := flag.Arg(0)
+	switch {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case name == "":
+		name = Name
+		in, err = bundled.Open("puzzles/" + Name + ".txt")
+		if err != nil {
+			err = fmt.Errorf("no bundled puzzle %q", Name)
+		}
+	default:
+		in, err = os.Open(name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	defer in.Close()
+
+	p, err := ReadPuzzle(name, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	if !solve(p) {
+		os.Exit(1)
+	}
+}
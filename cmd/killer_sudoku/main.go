@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+const (
+	Size    = 9
+	BoxSize = 3
+)
+
+type Cell struct {
+	Row int
+	Col int
+}
+
+type Digit int
+
+// Cage is a group of cells whose digits differ and add up to Sum
+type Cage struct {
+	Label rune
+	Cells []Cell
+	Sum   int
+}
+
+// Puzzle is a killer sudoku: an empty grid divided into cages
+type Puzzle struct {
+	Name  string
+	Cages []Cage
+}
+
+//go:embed puzzles/*.txt
+var bundled embed.FS
+
+var (
+	// CSP domains
+	Digits []Digit
+
+	// the colors that tell neighboring cages apart
+	Palette = []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan}
+
+	// solve this bundled puzzle, when no file is given
+	Name string
+
+	// animate the search in the terminal
+	TUI bool
+)
+
+func init() {
+	flag.StringVar(&Name, "puzzle", "easy", "the bundled puzzle to solve: easy, medium or hard")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: killer_sudoku [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a killer sudoku. a puzzle file maps the grid's cells to cages in\n")
+		fmt.Fprintf(os.Stderr, "9 lines of 9 labels, followed by a line per cage giving its label and sum,\n")
+		fmt.Fprintf(os.Stderr, "such as cmd/killer_sudoku/puzzles/easy.txt\n\n")
+		flag.PrintDefaults()
+	}
+
+	for d := Digit(1); d <= Size; d++ {
+		Digits = append(Digits, d)
+	}
+}
+
+// read a puzzle in the format of the bundled ones. lines starting with #
+// are comments
+func ReadPuzzle(name string, r io.Reader) (Puzzle, error) {
+	p := Puzzle{Name: name}
+	cages := map[rune]*Cage{}
+	var order []rune
+
+	scanner := bufio.NewScanner(r)
+	row, lineNo := 0, 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// the cage map comes first
+		if row < Size {
+			labels := []rune(line)
+			if len(labels) != Size {
+				return p, fmt.Errorf("line %d: want %d cage labels, got %d", lineNo, Size, len(labels))
+			}
+			for col, label := range labels {
+				cage, found := cages[label]
+				if !found {
+					cage = &Cage{Label: label, Sum: -1}
+					cages[label] = cage
+					order = append(order, label)
+				}
+				cage.Cells = append(cage.Cells, Cell{row, col})
+			}
+			row++
+			continue
+		}
+
+		// then the sums
+		fields := strings.Fields(line)
+		if len(fields) != 2 || len([]rune(fields[0])) != 1 {
+			return p, fmt.Errorf("line %d: want a cage label and its sum", lineNo)
+		}
+		cage, found := cages[[]rune(fields[0])[0]]
+		if !found {
+			return p, fmt.Errorf("line %d: no cage %s in the grid", lineNo, fields[0])
+		}
+		sum, err := strconv.Atoi(fields[1])
+		if err != nil || sum < 1 {
+			return p, fmt.Errorf("line %d: invalid sum %q", lineNo, fields[1])
+		}
+		cage.Sum = sum
+	}
+	if err := scanner.Err(); err != nil {
+		return p, err
+	}
+	if row < Size {
+		return p, fmt.Errorf("only %d of %d rows of cage labels", row, Size)
+	}
+
+	for _, label := range order {
+		cage := cages[label]
+		if cage.Sum < 0 {
+			return p, fmt.Errorf("no sum for cage %c", label)
+		}
+		p.Cages = append(p.Cages, *cage)
+	}
+	return p, nil
+}
+
+// model the puzzle with a variable per cell, all the digits of each row,
+// column and box different, and the digits of each cage different and
+// adding up to its sum
+func NewProblem(p Puzzle) csp.Problem[Cell, Digit] {
+	domain := map[Cell][]Digit{}
+	for row := 0; row < Size; row++ {
+		for col := 0; col < Size; col++ {
+			domain[Cell{row, col}] = Digits
+		}
+	}
+	// the sum of each cage's reachability constraint, by its identity
+	sums := map[*Cell]int{}
+
+	problem := csp.New(domain, func(constraint csp.Constraint[Cell], candidate map[Cell]Digit) bool {
+		return reachable(sums[&constraint.Variables[0]], constraint.Variables, candidate)
+	})
+	for i := 0; i < Size; i++ {
+		var row, col, box []Cell
+		for j := 0; j < Size; j++ {
+			row = append(row, Cell{i, j})
+			col = append(col, Cell{j, i})
+			box = append(box, Cell{BoxSize*(i/BoxSize) + j/BoxSize, BoxSize*(i%BoxSize) + j%BoxSize})
+		}
+		problem.AddConstraint(csp.AllDifferent(row...))
+		problem.AddConstraint(csp.AllDifferent(col...))
+		problem.AddConstraint(csp.AllDifferent(box...))
+	}
+
+	for _, cage := range p.Cages {
+		problem.AddConstraint(csp.Sum(cage.Cells, csp.Eq, cage.Sum))
+		if len(cage.Cells) > 1 {
+			problem.AddConstraint(csp.AllDifferent(cage.Cells...))
+
+			// the Sum can only be checked once the cage is full; this
+			// rules out partial cages that can no longer reach it
+			partial := csp.Constraint[Cell]{Variables: cage.Cells}
+			sums[&partial.Variables[0]] = cage.Sum
+			problem.AddConstraint(partial)
+		}
+	}
+
+	return problem
+}
+
+// report whether the unassigned cells of a cage can still make up its
+// sum, with digits different from each other and from those assigned
+func reachable(sum int, cells []Cell, candidate map[Cell]Digit) bool {
+	used := map[Digit]bool{}
+	open := 0
+	for _, c := range cells {
+		if d, found := candidate[c]; found {
+			sum -= int(d)
+			used[d] = true
+		} else {
+			open++
+		}
+	}
+
+	// the least and most that open distinct unused digits add up to
+	least, most := 0, 0
+	for d, n := Digit(1), 0; d <= Size && n < open; d++ {
+		if !used[d] {
+			least += int(d)
+			n++
+		}
+	}
+	for d, n := Digit(Size), 0; d >= 1 && n < open; d-- {
+		if !used[d] {
+			most += int(d)
+			n++
+		}
+	}
+	return least <= sum && sum <= most
+}
+
+// pick a color for each cage so that no two touching cages share one
+func colorCages(p Puzzle) map[Cell]render.Color {
+	owner := map[Cell]int{}
+	for ndx, cage := range p.Cages {
+		for _, c := range cage.Cells {
+			owner[c] = ndx
+		}
+	}
+
+	// largest cages first, since they have the most neighbors
+	order := make([]int, len(p.Cages))
+	for ndx := range order {
+		order[ndx] = ndx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(p.Cages[order[i]].Cells) > len(p.Cages[order[j]].Cells)
+	})
+
+	colors := map[int]int{}
+	for _, ndx := range order {
+		used := map[int]bool{}
+		for _, c := range p.Cages[ndx].Cells {
+			for _, n := range []Cell{{c.Row - 1, c.Col}, {c.Row + 1, c.Col}, {c.Row, c.Col - 1}, {c.Row, c.Col + 1}} {
+				if other, found := owner[n]; found && other != ndx {
+					if color, colored := colors[other]; colored {
+						used[color] = true
+					}
+				}
+			}
+		}
+		for color := range Palette {
+			if !used[color] {
+				colors[ndx] = color
+				break
+			}
+		}
+	}
+
+	out := map[Cell]render.Color{}
+	for c, ndx := range owner {
+		out[c] = Palette[colors[ndx]]
+	}
+	return out
+}
+
+// draw the grid in the colors of its cages, showing the digits assigned
+// so far, or else the sum of each cage in its first cell
+func drawGrid(p Puzzle, colors map[Cell]render.Color, candidate map[Cell]Digit) string {
+	sums := map[Cell]int{}
+	for _, cage := range p.Cages {
+		sums[cage.Cells[0]] = cage.Sum
+	}
+
+	return render.Grid{
+		Rows:    Size,
+		Cols:    Size,
+		BoxRows: BoxSize,
+		BoxCols: BoxSize,
+		Cell: func(row, col int) render.Cell {
+			c := Cell{row, col}
+			cell := render.Cell{Text: ".", Color: colors[c]}
+			if candidate == nil {
+				if sum, found := sums[c]; found {
+					cell.Text = fmt.Sprint(sum)
+				}
+			} else if d, found := candidate[c]; found {
+				cell.Text = fmt.Sprint(d)
+			}
+			return cell
+		},
+	}.String()
+}
+
+func solve(p Puzzle) bool {
+	colors := colorCages(p)
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+
+	var result map[Cell]Digit
+	if TUI {
+		draw := func(candidate map[Cell]Digit) string { return drawGrid(p, colors, candidate) }
+		var err error
+		if result, err = tui.New("Killer sudoku: "+p.Name, draw).Run(bt, map[Cell]Digit{}); err != nil {
+			panic(err)
+		}
+	} else {
+		result = bt.Solve(map[Cell]Digit{})
+	}
+
+	fmt.Printf("Puzzle %s, with %d cages:\n", p.Name, len(p.Cages))
+	fmt.Print(drawGrid(p, colors, nil))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, colors, result))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
+
+// model killer sudoku using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var in io.ReadCloser
+	var err error
+	name := flag.Arg(0)
+	switch {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case name == "":
+		name = Name
+		in, err = bundled.Open("puzzles/" + Name + ".txt")
+		if err != nil {
+			err = fmt.Errorf("no bundled puzzle %q", Name)
+		}
+	default:
+		in, err = os.Open(name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+	defer in.Close()
+
+	p, err := ReadPuzzle(name, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", name, err)
+		os.Exit(2)
+	}
+	if !solve(p) {
+		os.Exit(1)
+	}
+}
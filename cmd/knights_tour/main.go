@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+// Square numbers the squares of the board row by row from 0, so that
+// each square's successor variable names the square the knight goes to next
+type Square int
+
+var (
+	// the size of the board
+	Size int
+
+	// the tour need not end a knight's move from where it started
+	Open bool
+
+	// animate the search in the terminal
+	TUI bool
+
+	// the moves a knight can make
+	Moves = [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+)
+
+func init() {
+	flag.IntVar(&Size, "size", 8, "the size of the board")
+	flag.BoolVar(&Open, "open", false, "find an open tour, which need not end a knight's move from its start")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+}
+
+// the squares a knight's move from s
+func jumps(s Square) []Square {
+	row, col := int(s)/Size, int(s)%Size
+	var out []Square
+	for _, m := range Moves {
+		r, c := row+m[0], col+m[1]
+		if r >= 0 && r < Size && c >= 0 && c < Size {
+			out = append(out, Square(r*Size+c))
+		}
+	}
+	return out
+}
+
+// model the tour with a successor variable per square, ranging over the
+// squares a knight's move away, and a single Circuit through them all. an
+// open tour adds the square End, which the tour's last square goes to and
+// which goes on to its first, closing the circuit
+func NewProblem() (csp.Problem[Square, Square], Square) {
+	squares := Square(Size * Size)
+	end := Square(-1)
+	domain := map[Square][]Square{}
+	for s := Square(0); s < squares; s++ {
+		domain[s] = jumps(s)
+	}
+	if moves := domain[0]; !Open && len(moves) == 2 {
+		// the corner the tour starts from has only two moves, one out
+		// and one back in: taking the second as the way back picks one
+		// of each tour and its reverse, and saves the search from
+		// visiting it too soon
+		domain[moves[1]] = []Square{0}
+	}
+	if Open {
+		end = squares
+		for s := Square(0); s < squares; s++ {
+			domain[s] = append(domain[s], end)
+			domain[end] = append(domain[end], s)
+		}
+	}
+
+	var vars []Square
+	for s := Square(0); s < Square(len(domain)); s++ {
+		vars = append(vars, s)
+	}
+	problem := csp.New[Square, Square](domain, nil)
+	problem.AddConstraint(csp.Circuit(vars...))
+	return problem, end
+}
+
+// extend the tour from its last square, where it was left off: the
+// successor of the last square assigned, starting from square 0
+func followTour(end Square) csp.VariableOrder[Square, Square] {
+	return func(assignment map[Square]Square) Square {
+		at := Square(0)
+		if end >= 0 {
+			at = end
+		}
+		for {
+			next, found := assignment[at]
+			if !found {
+				return at
+			}
+			at = next
+		}
+	}
+}
+
+// try the squares with the fewest onward moves first, by Warnsdorff's
+// rule: those are the ones most likely to be stranded later
+func warnsdorff(problem csp.Problem[Square, Square], end Square) csp.ValueOrder[Square, Square] {
+	return func(variable Square, assignment map[Square]Square) []Square {
+		visited := map[Square]bool{}
+		for _, next := range assignment {
+			visited[next] = true
+		}
+		onward := func(s Square) int {
+			if s == end {
+				// the end of an open tour is a last resort
+				return len(Moves) + 1
+			}
+			n := 0
+			for _, next := range jumps(s) {
+				if !visited[next] && next != 0 {
+					n++
+				}
+			}
+			return n
+		}
+
+		// ties go to the square farther from the center, as edge squares
+		// are the harder ones to come back for
+		center := func(s Square) int {
+			dr, dc := 2*(int(s)/Size)-(Size-1), 2*(int(s)%Size)-(Size-1)
+			return dr*dr + dc*dc
+		}
+
+		// a closed tour must keep a way into the square it returns home
+		// from, so it puts off taking the last one until the very end
+		home := Square(-1)
+		for s, values := range problem.Domain {
+			if len(values) == 1 && values[0] == 0 {
+				home = s
+			}
+		}
+		stranding := func(s Square) bool {
+			left := len(problem.Domain) - len(assignment)
+			return home >= 0 && s != home && left > 2 && onward(home) == 1 && contains(jumps(home), s)
+		}
+
+		values := append([]Square{}, problem.Domain[variable]...)
+		sort.SliceStable(values, func(i, j int) bool {
+			if a, b := stranding(values[i]), stranding(values[j]); a != b {
+				return b
+			}
+			if a, b := onward(values[i]), onward(values[j]); a != b {
+				return a < b
+			}
+			return center(values[i]) > center(values[j])
+		})
+		return values
+	}
+}
+
+func contains(squares []Square, s Square) bool {
+	for _, other := range squares {
+		if other == s {
+			return true
+		}
+	}
+	return false
+}
+
+// number the squares in the order the tour visits them, from its start
+func tourOrder(result map[Square]Square, end Square) map[Square]int {
+	at := Square(0)
+	if end >= 0 {
+		at = result[end]
+	}
+
+	out := map[Square]int{}
+	for step := 1; step <= Size*Size; step++ {
+		if _, found := result[at]; !found {
+			break
+		}
+		out[at] = step
+		at = result[at]
+	}
+	return out
+}
+
+// draw the board with each square's step in the tour, the start and end
+// picked out
+func drawBoard(result map[Square]Square, end Square) string {
+	order := tourOrder(result, end)
+	last := len(order)
+	return render.Grid{
+		Rows: Size,
+		Cols: Size,
+		Cell: func(row, col int) render.Cell {
+			step, found := order[Square(row*Size+col)]
+			switch {
+			case !found:
+				return render.Cell{Text: "."}
+			case step == 1 || step == last:
+				return render.Cell{Text: fmt.Sprint(step), Color: render.Cyan}
+			}
+			return render.Cell{Text: fmt.Sprint(step)}
+		},
+	}.String()
+}
+
+// model the knight's tour using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Size < 1 {
+		fmt.Fprintf(os.Stderr, "error: -size must be at least 1, got %d\n", Size)
+		os.Exit(2)
+	}
+
+	problem, end := NewProblem()
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = followTour(end)
+	bt.OrderValues = warnsdorff(problem, end)
+
+	var result map[Square]Square
+	if TUI {
+		draw := func(candidate map[Square]Square) string { return drawBoard(candidate, end) }
+		var err error
+		if result, err = tui.New("Knight's tour", draw).Run(bt, map[Square]Square{}); err != nil {
+			panic(err)
+		}
+		if bt.Stats().Canceled {
+			return
+		}
+	} else {
+		result = bt.Solve(map[Square]Square{})
+	}
+
+	kind := "closed"
+	if Open {
+		kind = "open"
+	}
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No %s tour of a %dx%d board (%d nodes, %d backtracks)\n", kind, Size, Size, stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+	fmt.Printf("Solution: a %s tour of a %dx%d board\n", kind, Size, Size)
+	fmt.Print(drawBoard(result, end))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+type Cell struct {
+	Row int
+	Col int
+}
+
+type Symbol int
+
+// Square is a partial Latin square: Size by Size, with the symbols from 1
+// to Size given in some cells, and 0 in the holes
+type Square struct {
+	Size  int
+	Cells [][]Symbol
+}
+
+var (
+	// the size of the generated square
+	Size int
+
+	// the fraction of the generated square's cells left as holes
+	Holes float64
+
+	// seed the generator and the restarts, or the clock if zero
+	Seed int64
+
+	// how to pick the next cell: first, mrv or degree
+	Heuristic string
+
+	// restart the search with a fresh random value order after this
+	// long, doubling the limit on each restart; zero searches just once
+	Restart time.Duration
+)
+
+func init() {
+	flag.IntVar(&Size, "n", 15, "the size of the generated square")
+	flag.Float64Var(&Holes, "holes", 0.42, "the fraction of the generated square's cells to leave as holes; around 0.42 is hardest")
+	flag.Int64Var(&Seed, "seed", 1, "seed the generator and the restarts, or the clock if 0")
+	flag.StringVar(&Heuristic, "heuristic", "mrv", "how to pick the next cell: first, mrv or degree")
+	flag.DurationVar(&Restart, "restart", 0, "restart with a fresh random value order after this long, doubling each time (0 to search once)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: latin_square [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "complete a partial Latin square, so that each symbol appears once in\n")
+		fmt.Fprintf(os.Stderr, "every row and column. a file has a line per row of symbols from 1 to its\n")
+		fmt.Fprintf(os.Stderr, "size, separated by spaces, with . for the holes; without one, a square is\n")
+		fmt.Fprintf(os.Stderr, "generated by punching holes in a random Latin square, so that the\n")
+		fmt.Fprintf(os.Stderr, "completion is sure to exist\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// generate a random Latin square, by shuffling the rows, columns and
+// symbols of the cyclic one, and punch holes in it at random
+func Generate(size int, holes float64, rng *rand.Rand) Square {
+	rows, cols, symbols := rng.Perm(size), rng.Perm(size), rng.Perm(size)
+	s := Square{Size: size, Cells: make([][]Symbol, size)}
+	for row := range s.Cells {
+		s.Cells[row] = make([]Symbol, size)
+		for col := range s.Cells[row] {
+			if rng.Float64() >= holes {
+				s.Cells[row][col] = Symbol(symbols[(rows[row]+cols[col])%size] + 1)
+			}
+		}
+	}
+	return s
+}
+
+// read a square in the format described by the usage. lines starting
+// with # are comments
+func ReadSquare(r io.Reader) (Square, error) {
+	var s Square
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if s.Size == 0 {
+			s.Size = len(fields)
+		}
+		if len(fields) != s.Size {
+			return s, fmt.Errorf("line %d: want %d cells, got %d", lineNo, s.Size, len(fields))
+		}
+		row := make([]Symbol, s.Size)
+		for col, field := range fields {
+			if field == "." {
+				continue
+			}
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > s.Size {
+				return s, fmt.Errorf("line %d: invalid symbol %q", lineNo, field)
+			}
+			row[col] = Symbol(n)
+		}
+		s.Cells = append(s.Cells, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return s, err
+	}
+	if len(s.Cells) != s.Size {
+		return s, fmt.Errorf("want %d rows, got %d", s.Size, len(s.Cells))
+	}
+	return s, nil
+}
+
+// model the square with a variable per cell, the given ones fixed by their
+// domains, and all the symbols of each row and column different
+func NewProblem(s Square) csp.Problem[Cell, Symbol] {
+	var symbols []Symbol
+	for n := Symbol(1); n <= Symbol(s.Size); n++ {
+		symbols = append(symbols, n)
+	}
+	domain := map[Cell][]Symbol{}
+	for row := 0; row < s.Size; row++ {
+		for col := 0; col < s.Size; col++ {
+			if given := s.Cells[row][col]; given != 0 {
+				domain[Cell{row, col}] = []Symbol{given}
+			} else {
+				domain[Cell{row, col}] = symbols
+			}
+		}
+	}
+
+	problem := csp.New[Cell, Symbol](domain, nil)
+	for i := 0; i < s.Size; i++ {
+		var row, col []Cell
+		for j := 0; j < s.Size; j++ {
+			row = append(row, Cell{i, j})
+			col = append(col, Cell{j, i})
+		}
+		problem.AddConstraint(csp.AllDifferent(row...))
+		problem.AddConstraint(csp.AllDifferent(col...))
+	}
+
+	return problem
+}
+
+// draw the square, with the symbols found by the search picked out from the givens
+func drawSquare(s Square, candidate map[Cell]Symbol) string {
+	return render.Grid{
+		Rows: s.Size,
+		Cols: s.Size,
+		Cell: func(row, col int) render.Cell {
+			if given := s.Cells[row][col]; given != 0 {
+				return render.Cell{Text: fmt.Sprint(given)}
+			}
+			if n, found := candidate[Cell{row, col}]; found {
+				return render.Cell{Text: fmt.Sprint(n), Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// try the values of each cell in a random order, so that each restart
+// explores a different part of the search space
+func shuffled(problem csp.Problem[Cell, Symbol], rng *rand.Rand) csp.ValueOrder[Cell, Symbol] {
+	return func(variable Cell, _ map[Cell]Symbol) []Symbol {
+		values := append([]Symbol{}, problem.Domain[variable]...)
+		rng.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+		return values
+	}
+}
+
+// search for a completion, restarting with a doubled time limit whenever
+// the limit runs out, if Restart is set. the stats add up every attempt
+func solve(problem csp.Problem[Cell, Symbol], rng *rand.Rand) (map[Cell]Symbol, csp.Stats, int) {
+	bt := csp.NewBacktracker(problem)
+	switch Heuristic {
+	case "first":
+	case "mrv":
+		bt.SelectVariable = csp.MinRemainingValues(problem)
+	case "degree":
+		bt.SelectVariable = csp.MaxDegree(problem)
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown heuristic %q\n", Heuristic)
+		os.Exit(2)
+	}
+
+	var total csp.Stats
+	limit := Restart
+	for restarts := 0; ; restarts++ {
+		if Restart > 0 {
+			bt.OrderValues = shuffled(problem, rng)
+			bt.Timeout = limit
+		}
+		result := bt.Solve(map[Cell]Symbol{})
+
+		stats := bt.Stats()
+		total.Nodes += stats.Nodes
+		total.Backtracks += stats.Backtracks
+		total.Rejections += stats.Rejections
+		total.Duration += stats.Duration
+		if result != nil || !stats.TimedOut {
+			total.Solutions = stats.Solutions
+			return result, total, restarts
+		}
+		limit *= 2
+	}
+}
+
+// model quasigroup completion using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Seed == 0 {
+		Seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(Seed))
+
+	var s Square
+	switch flag.NArg() {
+	case 0:
+		if Size < 1 || Holes < 0 || Holes > 1 {
+			fmt.Fprintf(os.Stderr, "error: need -n of at least 1 and -holes between 0 and 1\n")
+			os.Exit(2)
+		}
+		s = Generate(Size, Holes, rng)
+	case 1:
+		in, err := os.Open(flag.Arg(0))
+		if err == nil {
+			s, err = ReadSquare(in)
+			in.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", flag.Arg(0), err)
+			os.Exit(2)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problem := NewProblem(s)
+	fmt.Printf("Partial Latin square of size %d:\n", s.Size)
+	fmt.Print(drawSquare(s, nil))
+	result, stats, restarts := solve(problem, rng)
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawSquare(s, result))
+	fmt.Printf("%d nodes, %d backtracks, %d restarts in %s\n", stats.Nodes, stats.Backtracks, restarts, stats.Duration)
+}
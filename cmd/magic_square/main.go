@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+var (
+	// the size of the square
+	Size int
+
+	// give up on the search after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.IntVar(&Size, "n", 4, "the size of the square")
+	flag.DurationVar(&Timeout, "timeout", time.Minute, "give up on the search after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: magic_square [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "fill an n by n square with the numbers from 1 to n*n so that every row,\n")
+		fmt.Fprintf(os.Stderr, "column and both diagonals add up to the same sum\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// model the square as a matrix of variables with the fluent model
+// builder, slicing it into the rows, columns and diagonals to sum
+func NewModel(n int) (*csp.Model, csp.Matrix) {
+	m := csp.NewModel()
+	square := m.IntVarMatrix("cell", n, n, 1, n*n)
+	m.AllDifferent(square.Flat()...)
+
+	// the numbers add up to n*n*(n*n+1)/2, shared by the n rows
+	magic := csp.Const(n * (n*n + 1) / 2)
+	for ndx := 0; ndx < n; ndx++ {
+		m.Post(csp.SumOf(square.Row(ndx)...).Eq(magic))
+		m.Post(csp.SumOf(square.Col(ndx)...).Eq(magic))
+	}
+	m.Post(csp.SumOf(square.Diagonal()...).Eq(magic))
+	m.Post(csp.SumOf(square.AntiDiagonal()...).Eq(magic))
+
+	// a square turned or reflected is magic too, so keep the least corner
+	// top left, and the top right corner less than the bottom left
+	if n > 1 {
+		corner := square[0][0]
+		for _, other := range []csp.Expr{square[0][n-1], square[n-1][0], square[n-1][n-1]} {
+			m.Post(corner.Lt(other))
+		}
+		m.Post(square[0][n-1].Lt(square[n-1][0]))
+	}
+	return m, square
+}
+
+// model magic squares using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Size < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	m, square := NewModel(Size)
+	problem, err := m.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// the sums only fail once their rows are nearly full, so look ahead
+	// for the values they leave none for, rather than run into them
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = csp.MaintainArcConsistency(problem)
+	bt.Timeout = Timeout
+	result := bt.Solve(map[string]int{})
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No magic square found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		if stats.TimedOut {
+			fmt.Printf("The search timed out after %s\n", Timeout)
+		}
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	width := len(fmt.Sprint(Size * Size))
+	for _, row := range square {
+		var cells []string
+		for _, cell := range row {
+			cells = append(cells, fmt.Sprintf("%*d", width, cell.Value(result)))
+		}
+		fmt.Println(strings.Join(cells, " "))
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
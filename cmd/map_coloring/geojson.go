@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// the properties a feature's name is looked for in, if -name isn't given
+var nameProperties = []string{"name", "NAME", "Name", "admin", "ADMIN", "STATE_NAME", "NAME_1"}
+
+type geoJSON struct {
+	Type       string          `json:"type"`
+	Features   []geoJSON       `json:"features"`
+	Properties map[string]any  `json:"properties"`
+	Geometry   *geoJSON        `json:"geometry"`
+	Geometries []geoJSON       `json:"geometries"`
+	Coords     json.RawMessage `json:"coordinates"`
+}
+
+type point struct{ X, Y float64 }
+
+// segment is one edge of a region's boundary
+type segment struct {
+	A, B   point
+	Region int
+}
+
+// read the regions of a GeoJSON FeatureCollection, a Feature per region,
+// and a border between each pair of regions whose boundaries run along
+// each other for more than tolerance, in the file's units. regions that
+// only meet at a corner don't border, and features of the same name are
+// one region, e.g. a country split over several islands
+func ReadGeoJSON(r io.Reader, nameProperty string, tolerance float64) ([]Province, []csp.Constraint[Province], error) {
+	var doc geoJSON
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	features := doc.Features
+	switch doc.Type {
+	case "FeatureCollection":
+	case "Feature":
+		features = []geoJSON{doc}
+	default:
+		return nil, nil, fmt.Errorf("want a FeatureCollection, got %q", doc.Type)
+	}
+
+	var regions []Province
+	index := map[Province]int{}
+	var segments []segment
+	for ndx, feature := range features {
+		name := featureName(feature, nameProperty)
+		if name == "" {
+			name = Province(fmt.Sprintf("feature %d", ndx+1))
+		}
+		if _, found := index[name]; !found {
+			index[name] = len(regions)
+			regions = append(regions, name)
+		}
+		if feature.Geometry == nil {
+			continue
+		}
+
+		rings, err := geometryRings(*feature.Geometry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %s", name, err)
+		}
+		for _, ring := range rings {
+			for i := 0; i+1 < len(ring); i++ {
+				if ring[i] != ring[i+1] {
+					segments = append(segments, segment{ring[i], ring[i+1], index[name]})
+				}
+			}
+		}
+	}
+
+	var borders []csp.Constraint[Province]
+	for _, pair := range bordering(segments, tolerance) {
+		borders = append(borders, NewBorder(regions[pair[0]], regions[pair[1]]))
+	}
+	return regions, borders, nil
+}
+
+// the name of a feature, from its nameProperty or the first of the usual
+// properties it has
+func featureName(feature geoJSON, nameProperty string) Province {
+	properties := nameProperties
+	if nameProperty != "" {
+		properties = []string{nameProperty}
+	}
+	for _, property := range properties {
+		if value, found := feature.Properties[property]; found && value != nil {
+			return Province(fmt.Sprint(value))
+		}
+	}
+	return ""
+}
+
+// the boundary rings of a Polygon or MultiPolygon, or of those collected
+// in a GeometryCollection; other geometries have none
+func geometryRings(g geoJSON) ([][]point, error) {
+	var rings [][]point
+	switch g.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(g.Coords, &polygon); err != nil {
+			return nil, err
+		}
+		return toRings(polygon)
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coords, &polygons); err != nil {
+			return nil, err
+		}
+		for _, polygon := range polygons {
+			more, err := toRings(polygon)
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, more...)
+		}
+	case "GeometryCollection":
+		for _, member := range g.Geometries {
+			more, err := geometryRings(member)
+			if err != nil {
+				return nil, err
+			}
+			rings = append(rings, more...)
+		}
+	}
+	return rings, nil
+}
+
+func toRings(polygon [][][]float64) ([][]point, error) {
+	var rings [][]point
+	for _, coords := range polygon {
+		var ring []point
+		for _, c := range coords {
+			if len(c) < 2 {
+				return nil, fmt.Errorf("a position needs two coordinates, got %d", len(c))
+			}
+			ring = append(ring, point{c[0], c[1]})
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+// the pairs of regions, by index, with segments that overlap. segments
+// are bucketed in a grid, so that only those in the same cells are
+// compared
+func bordering(segments []segment, tolerance float64) [][2]int {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	// cells a few segments long, on average
+	total := 0.0
+	for _, s := range segments {
+		total += math.Hypot(s.B.X-s.A.X, s.B.Y-s.A.Y)
+	}
+	size := 4 * total / float64(len(segments))
+	if size <= tolerance {
+		size = 2 * tolerance
+	}
+
+	type cell struct{ X, Y int }
+	grid := map[cell][]int{}
+	for ndx, s := range segments {
+		x0, x1 := math.Min(s.A.X, s.B.X)-tolerance, math.Max(s.A.X, s.B.X)+tolerance
+		y0, y1 := math.Min(s.A.Y, s.B.Y)-tolerance, math.Max(s.A.Y, s.B.Y)+tolerance
+		for x := int(math.Floor(x0 / size)); x <= int(math.Floor(x1/size)); x++ {
+			for y := int(math.Floor(y0 / size)); y <= int(math.Floor(y1/size)); y++ {
+				grid[cell{x, y}] = append(grid[cell{x, y}], ndx)
+			}
+		}
+	}
+
+	found := map[[2]int]bool{}
+	for _, members := range grid {
+		for i, a := range members {
+			for _, b := range members[i+1:] {
+				sa, sb := segments[a], segments[b]
+				pair := [2]int{sa.Region, sb.Region}
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if pair[0] == pair[1] || found[pair] {
+					continue
+				}
+				if overlap(sa, sb, tolerance) {
+					found[pair] = true
+				}
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for pair := range found {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// report whether b lies along the line through a, to within tolerance,
+// and the two overlap for more than tolerance along it
+func overlap(a, b segment, tolerance float64) bool {
+	dx, dy := a.B.X-a.A.X, a.B.Y-a.A.Y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return false
+	}
+
+	// the distance of p from the line, and how far along it p falls
+	across := func(p point) float64 { return math.Abs(dx*(p.Y-a.A.Y)-dy*(p.X-a.A.X)) / length }
+	along := func(p point) float64 { return (dx*(p.X-a.A.X) + dy*(p.Y-a.A.Y)) / length }
+	if across(b.A) > tolerance || across(b.B) > tolerance {
+		return false
+	}
+
+	lo, hi := along(b.A), along(b.B)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return math.Min(hi, length)-math.Max(lo, 0) > tolerance
+}
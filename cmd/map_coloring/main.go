@@ -1,8 +1,15 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/report"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
 )
 
 type Province string
@@ -10,13 +17,36 @@ type Color string
 
 var (
 	// CSP variables
-	Canada []Province
+	Provinces []Province
 
 	// CSP domains
 	Colors []Color
 
 	// CSP constraints
 	Constraints []csp.Constraint[Province]
+
+	// print the colored constraint graph in Graphviz DOT format instead,
+	// e.g. `go run ./cmd/map_coloring -dot | neato -Tsvg > canada.svg`
+	DOT bool
+
+	// write an HTML report of the solve to this path
+	Report string
+
+	// animate the search in the terminal
+	TUI bool
+
+	// color the regions of this GeoJSON file instead of Canada's provinces
+	GeoJSON string
+
+	// the feature property holding each region's name
+	NameProperty string
+
+	// how far apart, in the file's units, two boundaries may run and
+	// still be taken as a border
+	Tolerance float64
+
+	// the name of the map, for titles
+	Title = "Canada"
 )
 
 func NewBorder(us, them Province) csp.Constraint[Province] {
@@ -43,7 +73,14 @@ func SatisfiesConstraint(border csp.Constraint[Province], candidate map[Province
 }
 
 func init() {
-	Canada = []Province{
+	flag.BoolVar(&DOT, "dot", false, "print the solved constraint graph in Graphviz DOT format")
+	flag.StringVar(&Report, "report", "", "write an HTML report of the solve to this path")
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.StringVar(&GeoJSON, "geojson", "", "color the regions of this GeoJSON FeatureCollection, bordering where their boundaries run together")
+	flag.StringVar(&NameProperty, "name", "", "the feature property naming each region (default: name, NAME, admin and the like)")
+	flag.Float64Var(&Tolerance, "tolerance", 1e-6, "how far apart two boundaries may run and still be a border, in the file's units")
+
+	Provinces = []Province{
 		"Yukon",
 		"British Columbia",
 		"Northwest Territories",
@@ -105,12 +142,39 @@ func printColor(c Color) string {
 	panic(fmt.Sprintf("Unknown Color %s", c))
 }
 
+// list every province, in its color once assigned one
+func drawMap(candidate map[Province]Color) string {
+	var b strings.Builder
+	for _, p := range Provinces {
+		if c, found := candidate[p]; found {
+			fmt.Fprintf(&b, "%s  \x1b[0;0m %s\n", printColor(c), p)
+		} else {
+			fmt.Fprintf(&b, "\x1b[0;38m··\x1b[0;0m %s\n", p)
+		}
+	}
+	return b.String()
+}
+
 // model the map-coloring problem using CSP framework + Go generics
 func main() {
+	flag.Parse()
+	if GeoJSON != "" {
+		in, err := os.Open(GeoJSON)
+		if err == nil {
+			Provinces, Constraints, err = ReadGeoJSON(in, NameProperty, Tolerance)
+			in.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", GeoJSON, err)
+			os.Exit(2)
+		}
+		Title = strings.TrimSuffix(filepath.Base(GeoJSON), filepath.Ext(GeoJSON))
+	}
+
 	// assemble mapping of variables to a set of possible
 	// values to search for a valid solution
 	domain := map[Province][]Color{}
-	for _, p := range Canada {
+	for _, p := range Provinces {
 		domain[p] = Colors
 	}
 
@@ -123,8 +187,42 @@ func main() {
 	// init empty solution to begin search through problem space
 	candidate := map[Province]Color{}
 
+	bt := csp.NewBacktracker(problem)
+	reporter := report.New("Map coloring: "+Title, problem)
+	bt.Observe(reporter.Hooks())
+
 	// find ONE possible solution, and display it, if it exists
-	if result := problem.Solve(candidate); result != nil {
+	var result map[Province]Color
+	if TUI {
+		var err error
+		if result, err = tui.New("Map coloring: "+Title, drawMap).Run(bt, candidate); err != nil {
+			panic(err)
+		}
+		if bt.Stats().Canceled {
+			return
+		}
+	} else {
+		result = bt.Solve(candidate)
+	}
+	if Report != "" {
+		out, err := os.Create(Report)
+		if err != nil {
+			panic(err)
+		}
+		if err := reporter.Write(out, csp.Solution[Province, Color]{Assignment: result, Stats: bt.Stats(), Strategy: "backtracking"}); err != nil {
+			panic(err)
+		}
+		out.Close()
+	}
+
+	if result != nil {
+		if DOT {
+			if err := problem.ExportDOTSolution(os.Stdout, result); err != nil {
+				panic(err)
+			}
+			return
+		}
+
 		fmt.Println("Solution:")
 		for p, c := range result {
 			fmt.Printf("%s%s\x1b[0;0m\n", printColor(c), p)
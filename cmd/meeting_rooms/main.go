@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Meeting is a meeting to be scheduled: who's in it, for how many
+// slots, and what the room needs to have
+type Meeting struct {
+	Name      string
+	Attendees []string
+	Slots     int
+	Needs     []string
+}
+
+// Room is a meeting room, the people it seats and what it has
+type Room struct {
+	Name     string
+	Seats    int
+	Features []string
+}
+
+// Part is which of a meeting's two decisions a variable holds
+type Part int
+
+const (
+	Start Part = iota
+	InRoom
+)
+
+// Var is the starting slot or the room of a meeting, both counting from 0
+type Var struct {
+	Meeting string
+	Part    Part
+}
+
+// the working day, in half-hour slots from 9:00 to 17:00
+const (
+	DayStart = 9 * 60
+	SlotLen  = 30
+	DaySlots = 16
+)
+
+var (
+	// CSP variables: a start and a room per meeting
+	Meetings = []Meeting{
+		{"Standup", []string{"Ana", "Ben", "Cho", "Dev", "Eve"}, 1, nil},
+		{"Design review", []string{"Ana", "Cho", "Fay"}, 3, []string{"whiteboard"}},
+		{"Customer call", []string{"Ben", "Gus"}, 2, []string{"video"}},
+		{"1:1 Ana/Hal", []string{"Ana", "Hal"}, 1, nil},
+		{"1:1 Ben/Hal", []string{"Ben", "Hal"}, 1, nil},
+		{"Planning", []string{"Ana", "Ben", "Cho", "Dev", "Eve", "Fay", "Gus", "Hal"}, 2, []string{"projector"}},
+		{"Interview", []string{"Dev", "Eve"}, 2, []string{"video"}},
+		{"Budget", []string{"Gus", "Hal", "Fay"}, 2, []string{"projector"}},
+		{"Retro", []string{"Ana", "Ben", "Cho", "Dev", "Eve"}, 2, []string{"whiteboard"}},
+		{"Hiring sync", []string{"Dev", "Hal"}, 1, []string{"video"}},
+	}
+
+	// CSP domains: the slots of the day, and the rooms that fit a meeting
+	Rooms = []Room{
+		{"Boardroom", 10, []string{"projector", "video", "whiteboard"}},
+		{"Studio", 6, []string{"whiteboard"}},
+		{"Booth", 2, []string{"video"}},
+		{"Huddle", 4, []string{"projector", "video"}},
+	}
+
+	// the slots each attendee can't meet in, e.g. lunch
+	Busy = map[string][]int{
+		"Ana": {6, 7},
+		"Gus": {0, 1, 2, 3},
+		"Hal": {12, 13, 14, 15},
+	}
+)
+
+// report whether the room seats everyone and has everything needed
+func (r Room) Fits(m Meeting) bool {
+	if r.Seats < len(m.Attendees) {
+		return false
+	}
+	for _, need := range m.Needs {
+		found := false
+		for _, feature := range r.Features {
+			if feature == need {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// the meetings, by name
+var byName = map[string]Meeting{}
+
+func init() {
+	for _, m := range Meetings {
+		byName[m.Name] = m
+	}
+}
+
+// constraint: two meetings that might share a room, where the Variables
+// are their starts and then their rooms
+func NewRoomClash(a, b string) csp.Constraint[Var] {
+	return csp.Constraint[Var]{
+		Variables: []Var{{a, Start}, {b, Start}, {a, InRoom}, {b, InRoom}},
+	}
+}
+
+// constraint: meetings in the same room don't overlap. until both are
+// placed, it's satisfied for now
+func SatisfiesConstraint(clash csp.Constraint[Var], candidate map[Var]int) bool {
+	var starts, rooms [2]int
+	for ndx := range starts {
+		var found bool
+		if starts[ndx], found = candidate[clash.Variables[ndx]]; !found {
+			return true
+		}
+		if rooms[ndx], found = candidate[clash.Variables[ndx+2]]; !found {
+			return true
+		}
+	}
+	if rooms[0] != rooms[1] {
+		return true
+	}
+
+	a, b := byName[clash.Variables[0].Meeting], byName[clash.Variables[1].Meeting]
+	return starts[0]+a.Slots <= starts[1] || starts[1]+b.Slots <= starts[0]
+}
+
+func clock(slot int) string {
+	minutes := DayStart + slot*SlotLen
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+// tabulate the day with a row per slot and a column per room
+func drawDay(result map[Var]int) string {
+	at := map[[2]int]string{}
+	for _, m := range Meetings {
+		for slot := 0; slot < m.Slots; slot++ {
+			at[[2]int{result[Var{m.Name, Start}] + slot, result[Var{m.Name, InRoom}]}] = m.Name
+		}
+	}
+
+	return render.Grid{
+		Rows: DaySlots + 1,
+		Cols: len(Rooms) + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: Rooms[col-1].Name}
+			case col == 0:
+				return render.Cell{Text: clock(row - 1)}
+			}
+			if name, found := at[[2]int{row - 1, col - 1}]; found {
+				return render.Cell{Text: name, Color: render.Cyan}
+			}
+			return render.Cell{Text: "-"}
+		},
+	}.String()
+}
+
+// model meeting room scheduling using CSP framework + Go generics
+func main() {
+	// assemble mapping of variables to a set of possible
+	// values to search for a valid solution: a meeting starts in any slot
+	// that lets it finish by the end of the day without an attendee being
+	// busy, and is held in any room that fits it, the smallest first so
+	// as to leave the big ones free
+	bySize := make([]int, len(Rooms))
+	for ndx := range bySize {
+		bySize[ndx] = ndx
+	}
+	sort.SliceStable(bySize, func(i, j int) bool { return Rooms[bySize[i]].Seats < Rooms[bySize[j]].Seats })
+
+	domain := map[Var][]int{}
+	for _, m := range Meetings {
+		domain[Var{m.Name, Start}] = []int{}
+		for start := 0; start+m.Slots <= DaySlots; start++ {
+			free := true
+			for _, person := range m.Attendees {
+				for _, busy := range Busy[person] {
+					if busy >= start && busy < start+m.Slots {
+						free = false
+					}
+				}
+			}
+			if free {
+				domain[Var{m.Name, Start}] = append(domain[Var{m.Name, Start}], start)
+			}
+		}
+		for _, ndx := range bySize {
+			if Rooms[ndx].Fits(m) {
+				domain[Var{m.Name, InRoom}] = append(domain[Var{m.Name, InRoom}], ndx)
+			}
+		}
+		if len(domain[Var{m.Name, InRoom}]) == 0 {
+			panic(fmt.Sprintf("No room fits %s", m.Name))
+		}
+	}
+
+	// create CSP framework instance, populate: nobody's in two meetings at
+	// once, and no room hosts two at once
+	problem := csp.New(domain, SatisfiesConstraint)
+	diary := map[string][]Var{}
+	lengths := map[string][]int{}
+	for _, m := range Meetings {
+		for _, person := range m.Attendees {
+			diary[person] = append(diary[person], Var{m.Name, Start})
+			lengths[person] = append(lengths[person], m.Slots)
+		}
+	}
+	for person, starts := range diary {
+		if len(starts) > 1 {
+			problem.AddConstraint(csp.Disjunctive(starts, lengths[person]))
+		}
+	}
+	for i, a := range Meetings {
+		for _, b := range Meetings[i+1:] {
+			for _, room := range domain[Var{a.Name, InRoom}] {
+				if Rooms[room].Fits(b) {
+					problem.AddConstraint(NewRoomClash(a.Name, b.Name))
+					break
+				}
+			}
+		}
+	}
+
+	// find ONE possible solution, and display it, if it exists
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	result := bt.Solve(map[Var]int{})
+	if result == nil {
+		panic("No solution found")
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawDay(result))
+	sorted := append([]Meeting{}, Meetings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return result[Var{sorted[i].Name, Start}] < result[Var{sorted[j].Name, Start}]
+	})
+	for _, m := range sorted {
+		start := result[Var{m.Name, Start}]
+		fmt.Printf("%5s-%5s  %-14s in %-9s with %s\n", clock(start), clock(start+m.Slots), m.Name, Rooms[result[Var{m.Name, InRoom}]].Name, strings.Join(m.Attendees, ", "))
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Cell is a cell of the board, by row and column counting from 0
+type Cell struct {
+	Row, Col int
+}
+
+// Board is a partly revealed board: a row per line, each cell a digit
+// counting the mines around it if revealed, or # if not
+type Board []string
+
+var (
+	// the board analyzed when none is given, a beginner's game a few
+	// clicks in
+	Example = Board{
+		"#########",
+		"######111",
+		"######100",
+		"######100",
+		"####21100",
+		"##2110000",
+		"111000000",
+		"000000000",
+		"000000000",
+	}
+
+	// the number of mines on the board
+	Mines int
+)
+
+func init() {
+	flag.IntVar(&Mines, "mines", 10, "the number of mines on the board")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: minesweeper [flags] [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "find which hidden cells of a minesweeper board are certainly mines,\n")
+		fmt.Fprintf(os.Stderr, "which are certainly safe, and the chance of a mine in each of the rest.\n")
+		fmt.Fprintf(os.Stderr, "a file holds the board a row per line, each cell a digit if revealed\n")
+		fmt.Fprintf(os.Stderr, "or # if not\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the cells around a cell, within the board
+func (b Board) neighbors(c Cell) []Cell {
+	var out []Cell
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			n := Cell{c.Row + dr, c.Col + dc}
+			if (dr != 0 || dc != 0) && n.Row >= 0 && n.Row < len(b) && n.Col >= 0 && n.Col < len(b[n.Row]) {
+				out = append(out, n)
+			}
+		}
+	}
+	return out
+}
+
+func (b Board) hidden(c Cell) bool {
+	return b[c.Row][c.Col] == '#'
+}
+
+// model the board with a boolean variable per hidden cell next to a
+// revealed one, 1 for a mine, and a Sum constraint per revealed cell
+// making its hidden neighbors hold as many mines as it counts. the other
+// hidden cells, with no revealed neighbor, are left out: nothing is known
+// of them but how many mines there are in all
+func NewProblem(b Board) (csp.Problem[Cell, int], []Cell) {
+	domain := map[Cell][]int{}
+	var interior []Cell
+	var sums []csp.Constraint[Cell]
+	for row := range b {
+		for col := range b[row] {
+			c := Cell{row, col}
+			if b.hidden(c) {
+				continue
+			}
+			var around []Cell
+			for _, n := range b.neighbors(c) {
+				if b.hidden(n) {
+					around = append(around, n)
+					domain[n] = []int{0, 1}
+				}
+			}
+			sums = append(sums, csp.Sum(around, csp.Eq, int(b[row][col]-'0')))
+		}
+	}
+	for row := range b {
+		for col := range b[row] {
+			if c := (Cell{row, col}); b.hidden(c) && domain[c] == nil {
+				interior = append(interior, c)
+			}
+		}
+	}
+
+	problem := csp.New[Cell, int](domain, nil)
+	for _, sum := range sums {
+		if len(sum.Variables) > 0 {
+			problem.AddConstraint(sum)
+		}
+	}
+	return problem, interior
+}
+
+// Tally counts the solutions of part of the board by the number of mines
+// in them, in all and with each cell a mine
+type Tally struct {
+	ByMines []*big.Int
+	Mined   map[Cell][]*big.Int
+}
+
+// find every solution of an independent part of the board, and tally them
+func count(sub csp.Problem[Cell, int]) Tally {
+	t := Tally{Mined: map[Cell][]*big.Int{}}
+	grow := func(counts []*big.Int, k int) []*big.Int {
+		for len(counts) <= k {
+			counts = append(counts, new(big.Int))
+		}
+		return counts
+	}
+
+	bt := csp.NewBacktracker(sub)
+	bt.SelectVariable = csp.MinRemainingValues(sub)
+	for _, solution := range bt.SolveAll(map[Cell]int{}) {
+		k := 0
+		for _, mine := range solution {
+			k += mine
+		}
+		t.ByMines = grow(t.ByMines, k)
+		t.ByMines[k].Add(t.ByMines[k], big.NewInt(1))
+		for c, mine := range solution {
+			t.Mined[c] = grow(t.Mined[c], k)
+			if mine == 1 {
+				t.Mined[c][k].Add(t.Mined[c][k], big.NewInt(1))
+			}
+		}
+	}
+	return t
+}
+
+// the number of ways to have k mines across independent parts of the
+// board, for each k, given the numbers for each part
+func convolve(parts ...[]*big.Int) []*big.Int {
+	out := []*big.Int{big.NewInt(1)}
+	for _, part := range parts {
+		next := make([]*big.Int, len(out)+len(part)-1)
+		for ndx := range next {
+			next[ndx] = new(big.Int)
+		}
+		for i, a := range out {
+			for j, b := range part {
+				next[i+j].Add(next[i+j], new(big.Int).Mul(a, b))
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+// the number of boards with the given numbers of ways to lay k mines in
+// the cells next to revealed ones, for each k, and the rest of the mines
+// among the interior cells
+func boards(ways []*big.Int, interior, mines int) *big.Int {
+	total := new(big.Int)
+	for k, n := range ways {
+		if rest := mines - k; rest >= 0 && rest <= interior {
+			total.Add(total, new(big.Int).Mul(n, new(big.Int).Binomial(int64(interior), int64(rest))))
+		}
+	}
+	return total
+}
+
+// Analysis is the number of boards consistent with what's revealed, and
+// in how many of them each hidden cell is a mine. the Interior cells have
+// no revealed neighbor, and so all the same chance of a mine
+type Analysis struct {
+	Boards   *big.Int
+	Mined    map[Cell]*big.Int
+	Interior []Cell
+}
+
+// count the boards consistent with what's revealed and the number of
+// mines: those of each independent part of the cells next to revealed
+// ones are counted by solving it for all its solutions, and then the
+// parts combined and the rest of the mines spread among the interior
+func Analyze(b Board, mines int) Analysis {
+	problem, interior := NewProblem(b)
+	var tallies []Tally
+	for _, sub := range problem.Components() {
+		tallies = append(tallies, count(sub))
+	}
+	var parts [][]*big.Int
+	for _, t := range tallies {
+		parts = append(parts, t.ByMines)
+	}
+
+	a := Analysis{Boards: boards(convolve(parts...), len(interior), mines), Mined: map[Cell]*big.Int{}, Interior: interior}
+	for ndx, t := range tallies {
+		others := convolve(append(append([][]*big.Int{}, parts[:ndx]...), parts[ndx+1:]...)...)
+		for c, mined := range t.Mined {
+			a.Mined[c] = boards(convolve(others, mined), len(interior), mines)
+		}
+	}
+	// with one of the rest of the mines in an interior cell, the others
+	// are spread among the rest of the interior
+	if len(interior) > 0 {
+		mined := boards(convolve(parts...), len(interior)-1, mines-1)
+		for _, c := range interior {
+			a.Mined[c] = mined
+		}
+	}
+	return a
+}
+
+// read a board a row per line, as described by the usage
+func ReadBoard(name string) (Board, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var b Board
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			b = append(b, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for row, line := range b {
+		if len(line) != len(b[0]) {
+			return nil, fmt.Errorf("row %d has %d cells, not %d", row+1, len(line), len(b[0]))
+		}
+		for _, ch := range line {
+			if ch != '#' && (ch < '0' || ch > '8') {
+				return nil, fmt.Errorf("row %d: invalid cell %q", row+1, ch)
+			}
+		}
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("no rows")
+	}
+	return b, nil
+}
+
+// draw the board with the certain mines as * in red, the certainly safe
+// cells as o in green, and the rest of the hidden cells as #
+func drawBoard(b Board, a Analysis) string {
+	return render.Grid{
+		Rows: len(b),
+		Cols: len(b[0]),
+		Cell: func(row, col int) render.Cell {
+			c := Cell{row, col}
+			switch {
+			case !b.hidden(c):
+				return render.Cell{Text: string(b[row][col])}
+			case a.Mined[c].Sign() == 0:
+				return render.Cell{Text: "o", Color: render.Green}
+			case a.Mined[c].Cmp(a.Boards) == 0:
+				return render.Cell{Text: "*", Color: render.Red}
+			}
+			return render.Cell{Text: "#"}
+		},
+	}.String()
+}
+
+// model minesweeper inference using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	b := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		var err error
+		if b, err = ReadBoard(flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", flag.Arg(0), err)
+			os.Exit(2)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a := Analyze(b, Mines)
+	if a.Boards.Sign() == 0 {
+		fmt.Printf("No board with %d mines matches what's revealed\n", Mines)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %s boards with %d mines match what's revealed\n", a.Boards, Mines)
+	fmt.Print(drawBoard(b, a))
+
+	// the chance of a mine in each cell next to a revealed one neither
+	// certainly a mine nor safe, the safest first, and then in the interior
+	chance := func(c Cell) float64 {
+		f, _ := new(big.Rat).SetFrac(a.Mined[c], a.Boards).Float64()
+		return f
+	}
+	unsure := func(c Cell) bool {
+		return a.Mined[c].Sign() != 0 && a.Mined[c].Cmp(a.Boards) != 0
+	}
+	inside := map[Cell]bool{}
+	for _, c := range a.Interior {
+		inside[c] = true
+	}
+	var edge []Cell
+	for c := range a.Mined {
+		if !inside[c] && unsure(c) {
+			edge = append(edge, c)
+		}
+	}
+	sort.Slice(edge, func(i, j int) bool {
+		if ci, cj := chance(edge[i]), chance(edge[j]); ci != cj {
+			return ci < cj
+		}
+		if edge[i].Row != edge[j].Row {
+			return edge[i].Row < edge[j].Row
+		}
+		return edge[i].Col < edge[j].Col
+	})
+	if len(edge) > 0 {
+		fmt.Println("Chance of a mine in each of the other hidden cells next to a revealed one, the safest first:")
+		for _, c := range edge {
+			fmt.Printf("  row %d, col %d: %.1f%%\n", c.Row+1, c.Col+1, 100*chance(c))
+		}
+	}
+	if len(a.Interior) > 0 && unsure(a.Interior[0]) {
+		fmt.Printf("Chance of a mine in each of the %d hidden cells with no revealed neighbor: %.1f%%\n", len(a.Interior), 100*chance(a.Interior[0]))
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Shift is what a nurse works on a day, or Off
+type Shift int
+
+const (
+	Off Shift = iota
+	Early
+	Late
+	Night
+)
+
+// the letter a shift is drawn as, and its color
+var shiftCells = map[Shift]render.Cell{
+	Off:   {Text: "-"},
+	Early: {Text: "E", Color: render.Yellow},
+	Late:  {Text: "L", Color: render.Blue},
+	Night: {Text: "N", Color: render.Magenta},
+}
+
+// Slot is a nurse's shift on a day, both counting from 0
+type Slot struct {
+	Nurse int
+	Day   int
+}
+
+// Nurse is a member of the ward's staff, their contract and their wishes
+type Nurse struct {
+	Name string
+	// the fewest and most shifts a week the contract allows
+	MinShifts, MaxShifts int
+	// the days asked for off
+	DaysOff []int
+	// a shift the nurse would rather not work, or Off for none
+	Avoid Shift
+}
+
+// Preference is a soft constraint of the roster, and what it's for
+type Preference struct {
+	csp.Soft[Slot]
+	Why string
+}
+
+var (
+	// the ward's staff, the first six full-time and the last two part-time
+	Nurses = []Nurse{
+		{Name: "Ada", MinShifts: 4, MaxShifts: 5, DaysOff: []int{3, 4}},
+		{Name: "Bea", MinShifts: 4, MaxShifts: 5, Avoid: Night, DaysOff: []int{5}},
+		{Name: "Cal", MinShifts: 4, MaxShifts: 5, DaysOff: []int{9, 11}},
+		{Name: "Dev", MinShifts: 4, MaxShifts: 5, Avoid: Early, DaysOff: []int{5, 11}},
+		{Name: "Eli", MinShifts: 4, MaxShifts: 5, DaysOff: []int{0, 1, 2}},
+		{Name: "Fay", MinShifts: 4, MaxShifts: 5, Avoid: Night, DaysOff: []int{5, 11}},
+		{Name: "Gus", MinShifts: 2, MaxShifts: 4, DaysOff: []int{5, 7, 8}},
+		{Name: "Hal", MinShifts: 2, MaxShifts: 4, Avoid: Late, DaysOff: []int{11}},
+	}
+
+	// how many nurses each shift needs every day, at least and at most
+	Cover = []struct {
+		Shift     Shift
+		Low, High int
+	}{
+		{Early, 2, 3},
+		{Late, 2, 2},
+		{Night, 1, 1},
+	}
+
+	// the names of the days of the week, the roster starting on a Monday
+	Weekdays = []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"}
+
+	// the penalty for each kind of unmet wish
+	DayOffWeight, WeekendWeight, AvoidWeight = 4, 2, 1
+
+	// the number of weeks to roster
+	Weeks int
+
+	// give up on improving the roster after this long
+	Timeout time.Duration
+
+	// stop improving the roster once its penalty is at most this, or
+	// never if it's negative
+	GoodEnough int
+
+	// the nurses who can't work the days they're rostered after all, as
+	// name:day, counting from 1, to mend the roster for
+	Absent string
+)
+
+const (
+	// the most days in a row a nurse works, and nights in a row
+	MaxRun, MaxNights = 5, 3
+	// the most nights a week a nurse works
+	MaxNightShifts = 2
+)
+
+func init() {
+	flag.IntVar(&Weeks, "weeks", 2, "the number of weeks to roster")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on improving the roster after this long")
+	flag.StringVar(&Absent, "absent", "", "then mend the roster for nurses who can't work, as comma-separated name:day, e.g. Ada:2,Cal:9")
+	flag.IntVar(&GoodEnough, "good-enough", -1, "stop improving the roster once its penalty is at most this (negative to go on)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: nurse_rostering [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "roster a ward's nurses onto early, late and night shifts, covering\n")
+		fmt.Fprintf(os.Stderr, "every shift every day within their contracts and the rules on rest,\n")
+		fmt.Fprintf(os.Stderr, "while meeting as many of their wishes for days off, whole weekends\n")
+		fmt.Fprintf(os.Stderr, "and shifts to avoid as possible. wishes for days past the last week\n")
+		fmt.Fprintf(os.Stderr, "are dropped\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the rules on rest as an automaton over a nurse's shifts: no early or
+// late straight after a night, no early straight after a late, and no
+// more than MaxRun days or MaxNights nights in a row. its states track
+// the last shift worked and the runs of days and nights it ends
+func RestRules() csp.Automaton {
+	type state struct {
+		last        Shift
+		run, nights int
+	}
+	follows := map[Shift][]Shift{
+		Off:   {Off, Early, Late, Night},
+		Early: {Off, Early, Late, Night},
+		Late:  {Off, Late, Night},
+		Night: {Off, Night},
+	}
+
+	ids := map[state]int{{Off, 0, 0}: 0}
+	queue := []state{{Off, 0, 0}}
+	a := csp.Automaton{Start: 0}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		a.Accept = append(a.Accept, ids[from])
+		for _, shift := range follows[from.last] {
+			to := state{last: shift}
+			if shift != Off {
+				to.run = from.run + 1
+			}
+			if shift == Night {
+				to.nights = from.nights + 1
+			}
+			if to.run > MaxRun || to.nights > MaxNights {
+				continue
+			}
+			if _, found := ids[to]; !found {
+				ids[to] = len(ids)
+				queue = append(queue, to)
+			}
+			a.Transitions = append(a.Transitions, csp.Transition{From: ids[from], Value: int(shift), To: ids[to]})
+		}
+	}
+	return a
+}
+
+// model the roster with a variable per nurse and day, holding their
+// shift. each day's shifts are covered, by GlobalCardinality over the
+// day's nurses, and each nurse's shifts keep to their contract, by
+// another over their days, and to the rules on rest, by Regular
+func NewProblem(days int) csp.Problem[Slot, Shift] {
+	domain := map[Slot][]Shift{}
+	for nurse := range Nurses {
+		for day := 0; day < days; day++ {
+			domain[Slot{nurse, day}] = []Shift{Off, Early, Late, Night}
+		}
+	}
+
+	problem := csp.New[Slot, Shift](domain, nil)
+	var values, low, high []int
+	for _, c := range Cover {
+		values, low, high = append(values, int(c.Shift)), append(low, c.Low), append(high, c.High)
+	}
+	for day := 0; day < days; day++ {
+		var onDay []Slot
+		for nurse := range Nurses {
+			onDay = append(onDay, Slot{nurse, day})
+		}
+		problem.AddConstraint(csp.GlobalCardinality(onDay, values, low, high))
+	}
+
+	rules := RestRules()
+	for nurse, n := range Nurses {
+		var row []Slot
+		for day := 0; day < days; day++ {
+			row = append(row, Slot{nurse, day})
+		}
+		problem.AddConstraint(csp.GlobalCardinality(row,
+			[]int{int(Off), int(Night)},
+			[]int{days - n.MaxShifts*Weeks, 0},
+			[]int{days - n.MinShifts*Weeks, MaxNightShifts * Weeks}))
+		problem.AddConstraint(csp.Regular(row, rules))
+	}
+	return problem
+}
+
+// the nurses' wishes as soft constraints: a day off asked for, both days
+// of a weekend worked or both off, and no shifts of the kind they avoid
+func Preferences(days int) []Preference {
+	var out []Preference
+	for nurse, n := range Nurses {
+		for _, day := range n.DaysOff {
+			if day >= days {
+				continue
+			}
+			out = append(out, Preference{
+				Soft: csp.Soft[Slot]{Constraint: csp.Table([]Slot{{nurse, day}}, [][]int{{int(Off)}}), Weight: DayOffWeight},
+				Why:  fmt.Sprintf("%s asked for %s off", n.Name, dayName(day)),
+			})
+		}
+
+		for saturday := 5; saturday+1 < days; saturday += 7 {
+			var split [][]int
+			for _, sat := range []Shift{Off, Early, Late, Night} {
+				for _, sun := range []Shift{Off, Early, Late, Night} {
+					if (sat == Off) == (sun == Off) {
+						split = append(split, []int{int(sat), int(sun)})
+					}
+				}
+			}
+			out = append(out, Preference{
+				Soft: csp.Soft[Slot]{Constraint: csp.Table([]Slot{{nurse, saturday}, {nurse, saturday + 1}}, split), Weight: WeekendWeight},
+				Why:  fmt.Sprintf("%s works half the weekend of %s", n.Name, dayName(saturday)),
+			})
+		}
+
+		if n.Avoid == Off {
+			continue
+		}
+		var others [][]int
+		for _, shift := range []Shift{Off, Early, Late, Night} {
+			if shift != n.Avoid {
+				others = append(others, []int{int(shift)})
+			}
+		}
+		for day := 0; day < days; day++ {
+			out = append(out, Preference{
+				Soft: csp.Soft[Slot]{Constraint: csp.Table([]Slot{{nurse, day}}, others), Weight: AvoidWeight},
+				Why:  fmt.Sprintf("%s works the %s shift they avoid on %s", n.Name, shiftCells[n.Avoid].Text, dayName(day)),
+			})
+		}
+	}
+	return out
+}
+
+// tighten the cost of a partial roster with the days off that can't all
+// be given: a day's cover leaves only so many nurses off, so the wishes
+// for it past that many are bound to go unmet, whether or not the search
+// has got to them yet
+func withCover(soft csp.Cost[Slot, Shift], days int) csp.Cost[Slot, Shift] {
+	spare := len(Nurses)
+	for _, c := range Cover {
+		spare -= c.Low
+	}
+
+	return func(assignment map[Slot]Shift) int {
+		cost := soft(assignment)
+		for day := 0; day < days; day++ {
+			wished, unmet := 0, 0
+			for nurse, n := range Nurses {
+				if !contains(n.DaysOff, day) {
+					continue
+				}
+				wished++
+				if shift, found := assignment[Slot{nurse, day}]; found && shift != Off {
+					unmet++
+				}
+			}
+			// the unmet ones are already counted
+			if extra := wished - spare - unmet; extra > 0 {
+				cost += extra * DayOffWeight
+			}
+		}
+		return cost
+	}
+}
+
+func dayName(day int) string {
+	return fmt.Sprintf("%s %d", Weekdays[day%7], day+1)
+}
+
+// assign the roster a day at a time, so that each day's cover is settled
+// before the next is started. within a day, the nurses who asked for it
+// off go first, to be given it while there's cover to spare, and then
+// the rest from a nurse that moves on each day, to share out the days off
+func dayByDay(days int) csp.VariableOrder[Slot, Shift] {
+	var order [][]Slot
+	for day := 0; day < days; day++ {
+		var wished, rest []Slot
+		for ndx := range Nurses {
+			nurse := (day + ndx) % len(Nurses)
+			if contains(Nurses[nurse].DaysOff, day) {
+				wished = append(wished, Slot{nurse, day})
+			} else {
+				rest = append(rest, Slot{nurse, day})
+			}
+		}
+		order = append(order, append(wished, rest...))
+	}
+
+	return func(assignment map[Slot]Shift) Slot {
+		for _, slots := range order {
+			for _, slot := range slots {
+				if _, found := assignment[slot]; !found {
+					return slot
+				}
+			}
+		}
+		panic("error: no unassigned slot left")
+	}
+}
+
+func contains(days []int, day int) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// draw the roster with a row per nurse and a column per day, and the
+// number of shifts each nurse works
+func drawRoster(result map[Slot]Shift, days int) string {
+	return render.Grid{
+		Rows:    len(Nurses) + 1,
+		Cols:    days + 2,
+		BoxCols: 7,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0 && col == days+1:
+				return render.Cell{Text: "#"}
+			case row == 0:
+				return render.Cell{Text: Weekdays[(col-1)%7]}
+			case col == 0:
+				return render.Cell{Text: Nurses[row-1].Name}
+			case col == days+1:
+				worked := 0
+				for day := 0; day < days; day++ {
+					if result[Slot{row - 1, day}] != Off {
+						worked++
+					}
+				}
+				return render.Cell{Text: fmt.Sprint(worked)}
+			}
+			return shiftCells[result[Slot{row - 1, col - 1}]]
+		},
+	}.String()
+}
+
+// model nurse rostering using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Weeks < 1 {
+		fmt.Fprintf(os.Stderr, "error: can't roster %d weeks\n", Weeks)
+		os.Exit(2)
+	}
+	days := 7 * Weeks
+	absent, err := parseAbsences(Absent, days)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: -absent: %s\n", err)
+		os.Exit(2)
+	}
+
+	// the rules and cover must be met, and of the rosters that meet them,
+	// branch and bound finds the one leaving the least weight of wishes
+	// unmet, trying each nurse's least costly shift first
+	problem := NewProblem(days)
+	prefs := Preferences(days)
+	var soft []csp.Soft[Slot]
+	for _, p := range prefs {
+		soft = append(soft, p.Soft)
+	}
+	cost := withCover(csp.SoftCost(problem, soft), days)
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = dayByDay(days)
+	bt.OrderValues = csp.CheapestValue(problem, cost)
+	bt.Timeout = Timeout
+	// the best roster so far, the search stopping once it's good enough
+	best := csp.NewIncumbent(cost)
+	best.OnImprove = func(_ map[Slot]Shift, penalty int) {
+		fmt.Printf("Found a roster with penalty %d after %d nodes\n", penalty, bt.Stats().Nodes)
+		if penalty <= GoodEnough {
+			bt.Cancel()
+		}
+	}
+	bt.Observe(best.Hooks())
+	result, penalty := bt.Minimize(map[Slot]Shift{}, cost)
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No roster found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		if stats.TimedOut {
+			fmt.Println("The search timed out")
+		}
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d nurses over %d days, with penalty %d\n", len(Nurses), days, penalty)
+	fmt.Print(drawRoster(result, days))
+	var unmet []string
+	for _, p := range prefs {
+		if !problem.SatFn(p.Constraint, result) {
+			unmet = append(unmet, fmt.Sprintf("  %s (%d)", p.Why, p.Weight))
+		}
+	}
+	if len(unmet) > 0 {
+		fmt.Printf("Unmet wishes:\n%s\n", strings.Join(unmet, "\n"))
+	}
+	if stats.Canceled {
+		fmt.Printf("Perhaps not the least penalty possible, but good enough, at most %d\n", GoodEnough)
+	} else if stats.TimedOut {
+		fmt.Printf("Perhaps not the least penalty possible, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The least penalty possible, as no roster does better")
+	}
+	fmt.Printf("%d rosters found, %d nodes, %d backtracks in %s\n", best.Improvements(), stats.Nodes, stats.Backtracks, stats.Duration)
+
+	if len(absent) > 0 && !mend(result, absent, days, cost) {
+		os.Exit(1)
+	}
+}
+
+// parse the absences, as name:day, into the slots the nurses must have off
+func parseAbsences(spec string, days int) ([]Slot, error) {
+	var out []Slot
+	for _, absence := range strings.Split(spec, ",") {
+		if absence == "" {
+			continue
+		}
+		name, day := "", 0
+		if n, err := fmt.Sscanf(strings.Replace(absence, ":", " ", 1), "%s %d", &name, &day); n != 2 || err != nil {
+			return nil, fmt.Errorf("%q is not name:day", absence)
+		}
+		if day < 1 || day > days {
+			return nil, fmt.Errorf("%q: the roster runs from day 1 to %d", absence, days)
+		}
+
+		nurse := -1
+		for ndx, n := range Nurses {
+			if strings.EqualFold(n.Name, name) {
+				nurse = ndx
+			}
+		}
+		if nurse < 0 {
+			return nil, fmt.Errorf("%q: no nurse named %s", absence, name)
+		}
+		out = append(out, Slot{nurse, day - 1})
+	}
+	return out, nil
+}
+
+// mend the roster for the absences, changing as few shifts as it can,
+// and print the shifts changed. reports whether it could be mended
+func mend(roster map[Slot]Shift, absent []Slot, days int, cost csp.Cost[Slot, Shift]) bool {
+	problem := NewProblem(days)
+	for _, slot := range absent {
+		problem.Domain[slot] = []Shift{Off}
+	}
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = dayByDay(days)
+	bt.Timeout = Timeout
+
+	mended, changed := csp.Repair(bt, map[Slot]Shift{}, roster)
+	stats := bt.Stats()
+	if mended == nil {
+		fmt.Printf("No roster covers the absences (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+
+	fmt.Printf("Mended: %d shifts changed, now with penalty %d\n", changed, cost(mended))
+	fmt.Print(drawRoster(mended, days))
+	for nurse, n := range Nurses {
+		for day := 0; day < days; day++ {
+			slot := Slot{nurse, day}
+			if roster[slot] != mended[slot] {
+				fmt.Printf("  %s on %s: %s rather than %s\n", n.Name, dayName(day), shiftCells[mended[slot]].Text, shiftCells[roster[slot]].Text)
+			}
+		}
+	}
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the fewest changes possible, as the search timed out after %s\n", Timeout)
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Rect is a rectangle to pack, by its label and size
+type Rect struct {
+	Name          string
+	Width, Height int
+}
+
+// Axis is which coordinate of a rectangle's corner a variable holds
+type Axis int
+
+const (
+	X Axis = iota
+	Y
+)
+
+// Var is the x or the y of a rectangle's lower left corner in the
+// container, counting from 0
+type Var struct {
+	Rect string
+	Axis Axis
+}
+
+var (
+	// CSP variables: the corner of each rectangle
+	Rects = []Rect{
+		{"A", 4, 11}, {"B", 7, 5}, {"C", 5, 6}, {"D", 6, 5},
+		{"E", 5, 5}, {"F", 6, 4}, {"G", 5, 4}, {"H", 6, 3},
+		{"I", 3, 5}, {"J", 5, 3}, {"K", 4, 3}, {"L", 6, 2},
+	}
+
+	// the size of the container
+	Width, Height int
+
+	// write the packing as an SVG image to this file
+	SVGFile string
+)
+
+func init() {
+	flag.IntVar(&Width, "width", 20, "the width of the container")
+	flag.IntVar(&Height, "height", 14, "the height of the container")
+	flag.StringVar(&SVGFile, "svg", "", "write the packing as an SVG image to this file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: rectangle_packing [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "pack rectangles into a container without any overlapping. the\n")
+		fmt.Fprintf(os.Stderr, "rectangles exactly fill the default container, leaving no gaps\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// model the packing with a variable for each of the x and y of each
+// rectangle's corner, as far along as leaves it inside the container,
+// and a Diffn constraint keeping the rectangles from overlapping
+func NewProblem(rects []Rect, width, height int) csp.Problem[Var, int] {
+	domain := map[Var][]int{}
+	var xs, ys []Var
+	var widths, heights []int
+	for _, r := range rects {
+		for x := 0; x+r.Width <= width; x++ {
+			domain[Var{r.Name, X}] = append(domain[Var{r.Name, X}], x)
+		}
+		for y := 0; y+r.Height <= height; y++ {
+			domain[Var{r.Name, Y}] = append(domain[Var{r.Name, Y}], y)
+		}
+		xs, ys = append(xs, Var{r.Name, X}), append(ys, Var{r.Name, Y})
+		widths, heights = append(widths, r.Width), append(heights, r.Height)
+	}
+
+	problem := csp.New[Var, int](domain, nil)
+	problem.AddConstraint(csp.Diffn(xs, ys, widths, heights))
+	return problem
+}
+
+// place the biggest rectangles first, the hardest to find room for, each
+// its x and then its y
+func biggestFirst(rects []Rect) csp.VariableOrder[Var, int] {
+	ordered := append([]Rect{}, rects...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Width*ordered[i].Height > ordered[j].Width*ordered[j].Height
+	})
+	return func(assignment map[Var]int) Var {
+		for _, r := range ordered {
+			for _, axis := range []Axis{X, Y} {
+				if _, found := assignment[Var{r.Name, axis}]; !found {
+					return Var{r.Name, axis}
+				}
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// which rectangle, if any, covers each cell of the container
+func cover(result map[Var]int, rects []Rect, width, height int) [][]string {
+	cells := make([][]string, height)
+	for y := range cells {
+		cells[y] = make([]string, width)
+	}
+	for _, r := range rects {
+		x0, y0 := result[Var{r.Name, X}], result[Var{r.Name, Y}]
+		for y := y0; y < y0+r.Height; y++ {
+			for x := x0; x < x0+r.Width; x++ {
+				cells[y][x] = r.Name
+			}
+		}
+	}
+	return cells
+}
+
+// draw the container a cell at a time, with y increasing up the page
+func drawPacking(result map[Var]int, rects []Rect, width, height int) string {
+	cells := cover(result, rects, width, height)
+	colors := []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan}
+	index := map[string]int{}
+	for ndx, r := range rects {
+		index[r.Name] = ndx
+	}
+
+	return render.Grid{
+		Rows: height,
+		Cols: width,
+		Cell: func(row, col int) render.Cell {
+			name := cells[height-1-row][col]
+			if name == "" {
+				return render.Cell{Text: "."}
+			}
+			return render.Cell{Text: name, Color: colors[index[name]%len(colors)]}
+		},
+	}.String()
+}
+
+// write the packing as an SVG image, a square of the given size per unit
+// of the container, with y increasing up the page
+func writeSVG(w io.Writer, result map[Var]int, rects []Rect, width, height, unit int) error {
+	fills := []string{"#e41a1c", "#377eb8", "#4daf4a", "#984ea3", "#ff7f00", "#ffff33", "#a65628", "#f781bf"}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width*unit, height*unit, width*unit, height*unit)
+	fmt.Fprintf(&sb, "  <rect width=\"%d\" height=\"%d\" fill=\"white\" stroke=\"black\"/>\n", width*unit, height*unit)
+	for ndx, r := range rects {
+		x, y := result[Var{r.Name, X}]*unit, (height-result[Var{r.Name, Y}]-r.Height)*unit
+		fmt.Fprintf(&sb, "  <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\" fill-opacity=\"0.7\" stroke=\"black\"/>\n",
+			x, y, r.Width*unit, r.Height*unit, fills[ndx%len(fills)])
+		fmt.Fprintf(&sb, "  <text x=\"%d\" y=\"%d\" text-anchor=\"middle\" dominant-baseline=\"middle\" font-family=\"sans-serif\">%s</text>\n",
+			x+r.Width*unit/2, y+r.Height*unit/2, r.Name)
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// model rectangle packing using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	problem := NewProblem(Rects, Width, Height)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = biggestFirst(Rects)
+	result := bt.Solve(map[Var]int{})
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No packing into %dx%d found (%d nodes, %d backtracks)\n", Width, Height, stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d rectangles in %dx%d\n", len(Rects), Width, Height)
+	fmt.Print(drawPacking(result, Rects, Width, Height))
+	if SVGFile != "" {
+		file, err := os.Create(SVGFile)
+		if err == nil {
+			err = writeSVG(file, result, Rects, Width, Height, 20)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s\n", SVGFile)
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
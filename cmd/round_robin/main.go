@@ -0,0 +1,250 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Part is which of a team's two decisions in a round a variable holds
+type Part int
+
+const (
+	// the team's opponent
+	Opponent Part = iota
+	// 1 if the team plays at home, 0 if away
+	Home
+)
+
+// Var is a team's opponent, or whether it's at home, in a round, the
+// teams and rounds both counting from 0
+type Var struct {
+	Team  int
+	Round int
+	Part  Part
+}
+
+var (
+	// the names the teams are drawn with, in order
+	Names = []string{
+		"Ajax", "Boca", "Celtic", "Dynamo", "Espanyol", "Fiorentina", "Galatasaray", "Hajduk",
+		"Inter", "Juventus", "Kaiserslautern", "Lazio", "Marseille", "Napoli", "Olympiacos", "Porto",
+	}
+
+	// the number of teams in the league
+	Teams int
+
+	// the most games in a row a team plays at home, or away
+	MaxRun int
+)
+
+func init() {
+	flag.IntVar(&Teams, "teams", 8, fmt.Sprintf("the number of teams in the league, even and at most %d", len(Names)))
+	flag.IntVar(&MaxRun, "max-run", 2, "the most games in a row a team plays at home, or away")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: round_robin [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "schedule a single round-robin league: every team plays every other\n")
+		fmt.Fprintf(os.Stderr, "once, one game a round, with home and away games shared out evenly\n")
+		fmt.Fprintf(os.Stderr, "and no long runs of either\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the venues a team plays at over the season as an automaton: its states
+// track the venue of the last game and how many games in a row were there
+func Venues(maxRun int) csp.Automaton {
+	// state 0 is the start, and then 1 to maxRun are runs of that many
+	// away games, and maxRun+1 to 2*maxRun of home games
+	a := csp.Automaton{Start: 0}
+	for state := 0; state <= 2*maxRun; state++ {
+		a.Accept = append(a.Accept, state)
+		home, run := state > maxRun, state
+		if home {
+			run -= maxRun
+		}
+		if state == 0 || home || run < maxRun {
+			to := 1
+			if !home {
+				to = run + 1
+			}
+			a.Transitions = append(a.Transitions, csp.Transition{From: state, Value: 0, To: to})
+		}
+		if state == 0 || !home || run < maxRun {
+			to := maxRun + 1
+			if home {
+				to = state + 1
+			}
+			a.Transitions = append(a.Transitions, csp.Transition{From: state, Value: 1, To: to})
+		}
+	}
+	return a
+}
+
+// model the league with a variable per team and round for its opponent,
+// and another for whether it's at home. each team's opponents are
+// AllDifferent, so it plays every other once, and so are each round's,
+// so every team plays in it. a Table per pair of teams and round makes
+// them each other's opponent, one at home and the other away. and each
+// team's venues are balanced, by GlobalCardinality, without long runs,
+// by Regular
+func NewProblem(teams, maxRun int) csp.Problem[Var, int] {
+	rounds := teams - 1
+	domain := map[Var][]int{}
+	for t := 0; t < teams; t++ {
+		for r := 0; r < rounds; r++ {
+			for other := 0; other < teams; other++ {
+				if other != t {
+					domain[Var{t, r, Opponent}] = append(domain[Var{t, r, Opponent}], other)
+				}
+			}
+			domain[Var{t, r, Home}] = []int{0, 1}
+		}
+	}
+	// the rounds are interchangeable, so fix the first team's opponents
+	for r := 0; r < rounds; r++ {
+		domain[Var{0, r, Opponent}] = []int{r + 1}
+	}
+
+	problem := csp.New[Var, int](domain, nil)
+	for t := 0; t < teams; t++ {
+		var opponents, venues []Var
+		for r := 0; r < rounds; r++ {
+			opponents = append(opponents, Var{t, r, Opponent})
+			venues = append(venues, Var{t, r, Home})
+		}
+		problem.AddConstraint(csp.AllDifferent(opponents...))
+		problem.AddConstraint(csp.GlobalCardinality(venues, []int{1}, []int{rounds / 2}, []int{(rounds + 1) / 2}))
+		problem.AddConstraint(csp.Regular(venues, Venues(maxRun)))
+	}
+
+	for r := 0; r < rounds; r++ {
+		var round []Var
+		for t := 0; t < teams; t++ {
+			round = append(round, Var{t, r, Opponent})
+		}
+		problem.AddConstraint(csp.AllDifferent(round...))
+
+		for t := 0; t < teams; t++ {
+			for s := t + 1; s < teams; s++ {
+				problem.AddConstraint(csp.Table(
+					[]Var{{t, r, Opponent}, {s, r, Opponent}, {t, r, Home}, {s, r, Home}},
+					fixture(teams, t, s)))
+			}
+		}
+	}
+	return problem
+}
+
+// the allowed combinations of the opponents of teams t and s in a round,
+// and whether each is at home: if either plays the other, so does the
+// other, and exactly one of them is at home
+func fixture(teams, t, s int) [][]int {
+	var tuples [][]int
+	for a := 0; a < teams; a++ {
+		for b := 0; b < teams; b++ {
+			if a == t || b == s || (a == s) != (b == t) {
+				continue
+			}
+			for home := 0; home < 4; home++ {
+				tHome, sHome := home/2, home%2
+				if a != s || tHome != sHome {
+					tuples = append(tuples, []int{a, b, tHome, sHome})
+				}
+			}
+		}
+	}
+	return tuples
+}
+
+// assign the season a round at a time, each team's opponent and then
+// whether it's at home
+func roundByRound(teams int) csp.VariableOrder[Var, int] {
+	return func(assignment map[Var]int) Var {
+		for r := 0; r < teams-1; r++ {
+			for t := 0; t < teams; t++ {
+				for _, part := range []Part{Opponent, Home} {
+					if _, found := assignment[Var{t, r, part}]; !found {
+						return Var{t, r, part}
+					}
+				}
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// the number of breaks in the season: a team playing two games in a row
+// at home, or two away
+func breaks(result map[Var]int, teams int) int {
+	n := 0
+	for t := 0; t < teams; t++ {
+		for r := 1; r < teams-1; r++ {
+			if result[Var{t, r, Home}] == result[Var{t, r - 1, Home}] {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// tabulate each team's season with a row per team and a column per
+// round, showing the opponent, in green at home and in blue away
+func drawSeason(result map[Var]int, teams int) string {
+	return render.Grid{
+		Rows: teams + 1,
+		Cols: teams,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprint("R", col)}
+			case col == 0:
+				return render.Cell{Text: Names[row-1]}
+			}
+			v := Var{row - 1, col - 1, Opponent}
+			text := Names[result[v]][:3]
+			if result[Var{v.Team, v.Round, Home}] == 1 {
+				return render.Cell{Text: strings.ToUpper(text), Color: render.Green}
+			}
+			return render.Cell{Text: strings.ToLower(text), Color: render.Blue}
+		},
+	}.String()
+}
+
+// model round-robin tournament scheduling using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	if Teams < 2 || Teams%2 != 0 || Teams > len(Names) || MaxRun < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problem := NewProblem(Teams, MaxRun)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = roundByRound(Teams)
+	result := bt.Solve(map[Var]int{})
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No schedule found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d teams over %d rounds, with %d breaks\n", Teams, Teams-1, breaks(result, Teams))
+	for r := 0; r < Teams-1; r++ {
+		var games []string
+		for t := 0; t < Teams; t++ {
+			if result[Var{t, r, Home}] == 1 {
+				games = append(games, fmt.Sprintf("%s v %s", Names[t], Names[result[Var{t, r, Opponent}]]))
+			}
+		}
+		fmt.Printf("Round %d: %s\n", r+1, strings.Join(games, ", "))
+	}
+	fmt.Print(drawSeason(result, Teams))
+	fmt.Println("Home games in capitals")
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
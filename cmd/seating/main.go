@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Relation is where two guests sit, relative to each other
+type Relation int
+
+const (
+	// side by side at the same table
+	NextTo Relation = iota
+	// anywhere but side by side
+	NotNextTo
+	// at the same table
+	SameTable
+)
+
+// Rule is where two guests sit relative to each other: a must if its
+// Weight is 0, or else a wish, costing its Weight if unmet
+type Rule struct {
+	A, B     string
+	Relation Relation
+	Weight   int
+}
+
+// what a rule asks for, in words
+func (r Rule) String() string {
+	switch r.Relation {
+	case NextTo:
+		return fmt.Sprintf("%s next to %s", r.A, r.B)
+	case NotNextTo:
+		return fmt.Sprintf("%s not next to %s", r.A, r.B)
+	}
+	return fmt.Sprintf("%s at the same table as %s", r.A, r.B)
+}
+
+var (
+	// CSP variables: the seat each guest takes, the first the host
+	Guests = []string{
+		"Ana", "Ben", "Cleo", "Dev", "Eve", "Finn",
+		"Gus", "Hana", "Ira", "Jo", "Kim", "Lou",
+	}
+
+	// CSP constraints: the couples sit together, and the guests who don't
+	// get along apart
+	Musts = []Rule{
+		{"Ana", "Ben", NextTo, 0},
+		{"Cleo", "Dev", NextTo, 0},
+		{"Eve", "Finn", NextTo, 0},
+		{"Gus", "Hana", NextTo, 0},
+		{"Dev", "Gus", NotNextTo, 0},
+		{"Ira", "Jo", NotNextTo, 0},
+	}
+
+	// soft constraints: the host's guesses at who would enjoy whose company
+	Wishes = []Rule{
+		{"Ira", "Kim", NextTo, 3},
+		{"Cleo", "Eve", NextTo, 2},
+		{"Lou", "Ben", NextTo, 2},
+		{"Jo", "Lou", NextTo, 2},
+		{"Kim", "Finn", SameTable, 2},
+		{"Hana", "Ira", SameTable, 1},
+		{"Ana", "Gus", SameTable, 1},
+		{"Jo", "Dev", NextTo, 1},
+		{"Lou", "Cleo", NotNextTo, 1},
+		{"Ana", "Kim", NextTo, 1},
+	}
+
+	// the number of round tables, and of seats at each
+	Tables, Seats int
+
+	// give up on improving the seating after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.IntVar(&Tables, "tables", 2, "the number of round tables")
+	flag.IntVar(&Seats, "seats", 6, "the number of seats at each table")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on improving the seating after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: seating [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "seat the guests of a dinner at round tables, keeping couples together\n")
+		fmt.Fprintf(os.Stderr, "and feuding guests apart, and meeting as many of the host's wishes for\n")
+		fmt.Fprintf(os.Stderr, "who sits near whom as can be, the weightiest first\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// whether two seats, numbered from 0 around each table in turn, are as
+// the relation says
+func holds(relation Relation, a, b, seats int) bool {
+	sameTable := a/seats == b/seats
+	gap := (a - b + seats) % seats
+	nextTo := sameTable && seats > 1 && (gap == 1 || gap == seats-1)
+	switch relation {
+	case NextTo:
+		return nextTo
+	case NotNextTo:
+		return !nextTo
+	}
+	return sameTable
+}
+
+// a Table constraint listing the pairs of seats that meet the rule
+func (r Rule) Constraint(tables, seats int) csp.Constraint[string] {
+	var tuples [][]int
+	for a := 0; a < tables*seats; a++ {
+		for b := 0; b < tables*seats; b++ {
+			if a != b && holds(r.Relation, a, b, seats) {
+				tuples = append(tuples, []int{a, b})
+			}
+		}
+	}
+	return csp.Table([]string{r.A, r.B}, tuples)
+}
+
+// model the seating with a variable per guest for their seat, numbered
+// around each table in turn, AllDifferent giving each guest a seat of
+// their own and a Table constraint per must listing the pairs of seats
+// that meet it. the host takes the first seat, as turning the host's
+// table, or swapping it with another, doesn't change who sits by whom
+func NewProblem(tables, seats int) (csp.Problem[string, int], error) {
+	if len(Guests) > tables*seats {
+		return csp.Problem[string, int]{}, fmt.Errorf("%d guests but only %d seats", len(Guests), tables*seats)
+	}
+	domain := map[string][]int{}
+	for ndx, guest := range Guests {
+		if ndx == 0 {
+			domain[guest] = []int{0}
+			continue
+		}
+		for seat := 0; seat < tables*seats; seat++ {
+			domain[guest] = append(domain[guest], seat)
+		}
+	}
+
+	problem := csp.New[string, int](domain, nil)
+	problem.AddConstraint(csp.AllDifferent(Guests...))
+	for _, must := range Musts {
+		problem.AddConstraint(must.Constraint(tables, seats))
+	}
+	return problem, nil
+}
+
+// the wishes as soft constraints, each a Table of the pairs of seats that
+// meet it
+func SoftWishes(tables, seats int) []csp.Soft[string] {
+	var out []csp.Soft[string]
+	for _, wish := range Wishes {
+		out = append(out, csp.Soft[string]{Constraint: wish.Constraint(tables, seats), Weight: wish.Weight})
+	}
+	return out
+}
+
+// draw the tables a row each, with the guests in their seats around it
+func drawTables(result map[string]int, tables, seats int) string {
+	at := map[int]string{}
+	for guest, seat := range result {
+		at[seat] = guest
+	}
+	return render.Grid{
+		Rows: tables,
+		Cols: seats + 1,
+		Cell: func(row, col int) render.Cell {
+			if col == 0 {
+				return render.Cell{Text: fmt.Sprint("table ", row+1)}
+			}
+			if guest, found := at[row*seats+col-1]; found {
+				return render.Cell{Text: guest}
+			}
+			return render.Cell{Text: "-"}
+		},
+	}.String()
+}
+
+// model dinner seating using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	problem, err := NewProblem(Tables, Seats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// the musts must be met, and of the seatings that meet them, branch
+	// and bound finds the one leaving the least weight of wishes unmet
+	cost := csp.SoftCost(problem, SoftWishes(Tables, Seats))
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	bt.OrderValues = csp.CheapestValue(problem, cost)
+	bt.Timeout = Timeout
+	bt.Observe(csp.Hooks[string, int]{
+		OnSolution: func(solution map[string]int) {
+			fmt.Printf("Found a seating with penalty %d after %d nodes\n", cost(solution), bt.Stats().Nodes)
+		},
+	})
+	result, penalty := bt.Minimize(map[string]int{}, cost)
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No seating found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d guests at %d tables, with penalty %d\n", len(Guests), Tables, penalty)
+	fmt.Print(drawTables(result, Tables, Seats))
+	var unmet []string
+	for _, wish := range Wishes {
+		if !problem.SatFn(wish.Constraint(Tables, Seats), result) {
+			unmet = append(unmet, fmt.Sprintf("  %s (%d)", wish, wish.Weight))
+		}
+	}
+	if len(unmet) > 0 {
+		fmt.Printf("Unmet wishes:\n%s\n", strings.Join(unmet, "\n"))
+	}
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the least penalty possible, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The least penalty possible, as no seating does better")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
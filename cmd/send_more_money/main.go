@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// the letters of the puzzle, each standing for a different digit
+var Letters = []string{"S", "E", "N", "D", "M", "O", "R", "Y"}
+
+// the number the digits of the word's letters spell, most significant first
+func word(letters map[string]csp.Expr, spelling string) csp.Expr {
+	n := csp.Const(0)
+	for _, letter := range spelling {
+		n = n.Times(10).Plus(letters[string(letter)])
+	}
+	return n
+}
+
+// model the cryptarithm SEND + MORE = MONEY with the fluent model builder
+func main() {
+	m := csp.NewModel()
+	letters := map[string]csp.Expr{}
+	var all []csp.Expr
+	for _, letter := range Letters {
+		letters[letter] = m.IntVar(letter, 0, 9)
+		all = append(all, letters[letter])
+	}
+	m.AllDifferent(all...)
+	// the leading digits can't be 0
+	m.Post(letters["S"].Gt(csp.Const(0)), letters["M"].Gt(csp.Const(0)))
+	m.Post(word(letters, "SEND").Plus(word(letters, "MORE")).Eq(word(letters, "MONEY")))
+
+	problem, err := m.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	result := bt.Solve(map[string]int{})
+	if result == nil {
+		fmt.Println("No solution found")
+		os.Exit(1)
+	}
+
+	spell := func(spelling string) string {
+		out := ""
+		for _, letter := range spelling {
+			out += fmt.Sprint(result[string(letter)])
+		}
+		return out
+	}
+	fmt.Println("Solution:")
+	fmt.Printf("   %s\n + %s\n = %s\n", spell("SEND"), spell("MORE"), spell("MONEY"))
+	stats := bt.Stats()
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
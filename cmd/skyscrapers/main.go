@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Cell is a cell of the grid, by row and column counting from 0
+type Cell struct {
+	Row, Col int
+}
+
+// Puzzle is a skyscrapers grid framed by its clues, a row per line: the
+// first and last lines hold the clues above and below each column, and
+// the first and last characters of the lines between hold those left and
+// right of each row, a digit or . for none. the cells inside are a digit
+// for a given height, or . for none
+type Puzzle []string
+
+var (
+	// the puzzle solved when none is given
+	Example = Puzzle{
+		"..6..2..",
+		"4.......",
+		"3.......",
+		"........",
+		".......4",
+		"........",
+		"........",
+		".4.3..3.",
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: skyscrapers [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a skyscrapers puzzle: fill an N by N grid with buildings of\n")
+		fmt.Fprintf(os.Stderr, "heights 1 to N, each height once in every row and column, so that as\n")
+		fmt.Fprintf(os.Stderr, "many buildings are seen from each clue as it says, a taller one hiding\n")
+		fmt.Fprintf(os.Stderr, "those behind it. a file lays out the grid framed by its clues a row per\n")
+		fmt.Fprintf(os.Stderr, "line, with a digit or . for each clue and cell, and . in the corners\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// the size of the grid inside the clues
+func (p Puzzle) size() int {
+	return len(p) - 2
+}
+
+// constraint: seen from outside the grid, the buildings in a line hide
+// those shorter than them behind, leaving as many in sight as the clue,
+// where the Variables are the line's cells from the nearest to the clue
+// and Args holds the clue
+func NewVisible(line []Cell, clue int) csp.Constraint[Cell] {
+	return csp.Constraint[Cell]{Variables: line, Args: []int{clue}}
+}
+
+// constraint: the buildings in sight from the clue so far, up to the first
+// cell with no height yet, are no more than the clue, and with those past
+// it each taller than the last, could still be as many. once the tallest
+// is in sight, none past it can be seen, and the count is final
+func SatisfiesConstraint(visible csp.Constraint[Cell], candidate map[Cell]int) bool {
+	clue, n := visible.Args[0], len(visible.Variables)
+	seen, tallest := 0, 0
+	for ndx, cell := range visible.Variables {
+		height, found := candidate[cell]
+		if !found {
+			more := n - ndx
+			if n-tallest < more {
+				more = n - tallest
+			}
+			return seen+more >= clue
+		}
+		if height > tallest {
+			seen, tallest = seen+1, height
+		}
+		if seen > clue {
+			return false
+		}
+		if tallest == n {
+			break
+		}
+	}
+	return seen == clue
+}
+
+// model the puzzle with a variable per cell for the height of its
+// building, AllDifferent constraints making a Latin square of the rows
+// and columns, and a user constraint per clue counting the buildings in
+// sight along its line
+func NewProblem(p Puzzle) csp.Problem[Cell, int] {
+	n := p.size()
+	var heights []int
+	for h := 1; h <= n; h++ {
+		heights = append(heights, h)
+	}
+	domain := map[Cell][]int{}
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			if ch := p[r+1][c+1]; ch != '.' {
+				domain[Cell{r, c}] = []int{int(ch - '0')}
+			} else {
+				domain[Cell{r, c}] = heights
+			}
+		}
+	}
+
+	problem := csp.New(domain, SatisfiesConstraint)
+	for i := 0; i < n; i++ {
+		// each row and column, seen from either end
+		var row, col, back, up []Cell
+		for j := 0; j < n; j++ {
+			row, col = append(row, Cell{i, j}), append(col, Cell{j, i})
+			back, up = append(back, Cell{i, n - 1 - j}), append(up, Cell{n - 1 - j, i})
+		}
+		problem.AddConstraint(csp.AllDifferent(row...))
+		problem.AddConstraint(csp.AllDifferent(col...))
+
+		for _, view := range []struct {
+			clue byte
+			line []Cell
+		}{
+			{p[i+1][0], row}, {p[i+1][n+1], back}, {p[0][i+1], col}, {p[n+1][i+1], up},
+		} {
+			if view.clue != '.' {
+				problem.AddConstraint(NewVisible(view.line, int(view.clue-'0')))
+			}
+		}
+	}
+	return problem
+}
+
+// read a puzzle a row per line, as described by the usage
+func ReadPuzzle(name string) (Puzzle, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var p Puzzle
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p = append(p, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, p.check()
+}
+
+// report whether the puzzle is a square grid inside a frame of clues, each
+// clue and height from 1 to the size of the grid
+func (p Puzzle) check() error {
+	n := p.size()
+	if n < 1 || n > 9 {
+		return fmt.Errorf("%d lines frame a %d by %d grid, not one of 1 to 9", len(p), n, n)
+	}
+	for row, line := range p {
+		if len(line) != n+2 {
+			return fmt.Errorf("row %d has %d characters, not %d", row+1, len(line), n+2)
+		}
+		for col, ch := range line {
+			corner := (row == 0 || row == n+1) && (col == 0 || col == n+1)
+			if ch != '.' && (corner || ch < '1' || ch > rune('0'+n)) {
+				return fmt.Errorf("row %d: invalid clue or height %q", row+1, ch)
+			}
+		}
+	}
+	return nil
+}
+
+// draw the grid framed by its clues in cyan, with the given heights in
+// white
+func drawGrid(p Puzzle, result map[Cell]int) string {
+	n := p.size()
+	return render.Grid{
+		Rows: n + 2,
+		Cols: n + 2,
+		Cell: func(row, col int) render.Cell {
+			ch := p[row][col]
+			switch {
+			case row == 0 || row == n+1 || col == 0 || col == n+1:
+				if ch == '.' {
+					return render.Cell{Text: " "}
+				}
+				return render.Cell{Text: string(ch), Color: render.Cyan}
+			case ch != '.':
+				return render.Cell{Text: string(ch), Color: render.White}
+			}
+			return render.Cell{Text: fmt.Sprint(result[Cell{row - 1, col - 1}])}
+		},
+	}.String()
+}
+
+// model skyscrapers using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	p := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		var err error
+		if p, err = ReadPuzzle(flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", flag.Arg(0), err)
+			os.Exit(2)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	found := 0
+	bt.Observe(csp.Hooks[Cell, int]{
+		OnSolution: func(map[Cell]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Cell]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Edge is a side of a cell of the grid, by the corner it starts from, the
+// corners counting from 0 at the top left: it runs right from the corner,
+// or down if Down is set
+type Edge struct {
+	Row, Col int
+	Down     bool
+}
+
+// Puzzle is a slitherlink: a grid of cells, some with a clue counting how
+// many of their sides the loop runs along, or -1 for none
+type Puzzle [][]int
+
+var (
+	// the puzzle solved when none is given
+	Example = []string{
+		".0.1..1",
+		"...1..1",
+		"..20..2",
+		".......",
+		"0..0...",
+		"1...233",
+		".32....",
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: slitherlink [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a slitherlink: draw a single closed loop along the sides of the\n")
+		fmt.Fprintf(os.Stderr, "cells, running along as many sides of each clued cell as its clue\n")
+		fmt.Fprintf(os.Stderr, "says. a file lays out the grid a row per line, with a digit or . per\n")
+		fmt.Fprintf(os.Stderr, "cell\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// parse a puzzle a row per line, each cell a digit from 0 to 3 or . for
+// none. every row must be as long as the first
+func ParsePuzzle(lines []string) (Puzzle, error) {
+	var p Puzzle
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(p) > 0 && len(line) != len(p[0]) {
+			return nil, fmt.Errorf("row %d has %d cells, not %d", len(p)+1, len(line), len(p[0]))
+		}
+		row := make([]int, len(line))
+		for col, ch := range line {
+			switch {
+			case ch == '.':
+				row[col] = -1
+			case ch >= '0' && ch <= '3':
+				row[col] = int(ch - '0')
+			default:
+				return nil, fmt.Errorf("row %d: invalid cell %q", len(p)+1, ch)
+			}
+		}
+		p = append(p, row)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("no rows")
+	}
+	return p, nil
+}
+
+// the edges of the grid, along each row of corners and then down from it
+func (p Puzzle) edges() []Edge {
+	rows, cols := len(p), len(p[0])
+	var out []Edge
+	for r := 0; r <= rows; r++ {
+		for c := 0; c < cols; c++ {
+			out = append(out, Edge{r, c, false})
+		}
+		for c := 0; r < rows && c <= cols; c++ {
+			out = append(out, Edge{r, c, true})
+		}
+	}
+	return out
+}
+
+// the edges meeting at a corner
+func (p Puzzle) at(r, c int) []Edge {
+	rows, cols := len(p), len(p[0])
+	var out []Edge
+	if c > 0 {
+		out = append(out, Edge{r, c - 1, false})
+	}
+	if c < cols {
+		out = append(out, Edge{r, c, false})
+	}
+	if r > 0 {
+		out = append(out, Edge{r - 1, c, true})
+	}
+	if r < rows {
+		out = append(out, Edge{r, c, true})
+	}
+	return out
+}
+
+// the two corners an edge joins
+func ends(e Edge) [2][2]int {
+	if e.Down {
+		return [2][2]int{{e.Row, e.Col}, {e.Row + 1, e.Col}}
+	}
+	return [2][2]int{{e.Row, e.Col}, {e.Row, e.Col + 1}}
+}
+
+// constraint: the edges the loop runs along form a single closed loop,
+// where the Variables are all the edges of the grid
+func NewSingleLoop(edges []Edge) csp.Constraint[Edge] {
+	return csp.Constraint[Edge]{Variables: edges}
+}
+
+// constraint: once the edges drawn close a loop, no edge outside it may
+// be drawn, and once every edge is decided, one must be. the Table
+// constraints at the corners keep every corner to two drawn edges or
+// none, so that the drawn edges can only form closed loops or paths that
+// are yet to close
+func SatisfiesConstraint(loop csp.Constraint[Edge], candidate map[Edge]int) bool {
+	neighbors := map[[2]int][][2]int{}
+	drawn, decided := 0, 0
+	for _, e := range loop.Variables {
+		value, found := candidate[e]
+		if !found {
+			continue
+		}
+		decided++
+		if value == 1 {
+			drawn++
+			corners := ends(e)
+			neighbors[corners[0]] = append(neighbors[corners[0]], corners[1])
+			neighbors[corners[1]] = append(neighbors[corners[1]], corners[0])
+		}
+	}
+	if decided == len(loop.Variables) && drawn == 0 {
+		return false
+	}
+
+	// walk each set of joined corners, and if it's closed, with two drawn
+	// edges at every corner, it must hold every drawn edge
+	seen := map[[2]int]bool{}
+	for start := range neighbors {
+		if seen[start] {
+			continue
+		}
+		closed, edges := true, 0
+		stack := [][2]int{start}
+		seen[start] = true
+		for len(stack) > 0 {
+			corner := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(neighbors[corner]) != 2 {
+				closed = false
+			}
+			edges += len(neighbors[corner])
+			for _, next := range neighbors[corner] {
+				if !seen[next] {
+					seen[next] = true
+					stack = append(stack, next)
+				}
+			}
+		}
+		if closed && edges/2 != drawn {
+			return false
+		}
+	}
+	return true
+}
+
+// model the puzzle with a boolean variable per edge, 1 if the loop runs
+// along it. a Sum constraint per clue counts the edges around its cell, a
+// Table constraint per corner allows the loop to pass through it or not,
+// with two of its edges drawn or none, and a single user constraint joins
+// the drawn edges into one loop
+func NewProblem(p Puzzle) csp.Problem[Edge, int] {
+	edges := p.edges()
+	domain := map[Edge][]int{}
+	for _, e := range edges {
+		domain[e] = []int{0, 1}
+	}
+
+	problem := csp.New(domain, SatisfiesConstraint)
+	for r, row := range p {
+		for c, clue := range row {
+			if clue >= 0 {
+				sides := []Edge{{r, c, false}, {r + 1, c, false}, {r, c, true}, {r, c + 1, true}}
+				problem.AddConstraint(csp.Sum(sides, csp.Eq, clue))
+			}
+		}
+	}
+	for r := 0; r <= len(p); r++ {
+		for c := 0; c <= len(p[0]); c++ {
+			meeting := p.at(r, c)
+			var tuples [][]int
+			for bits := 0; bits < 1<<len(meeting); bits++ {
+				tuple := make([]int, len(meeting))
+				drawn := 0
+				for ndx := range tuple {
+					tuple[ndx] = bits >> ndx & 1
+					drawn += tuple[ndx]
+				}
+				if drawn == 0 || drawn == 2 {
+					tuples = append(tuples, tuple)
+				}
+			}
+			problem.AddConstraint(csp.Table(meeting, tuples))
+		}
+	}
+	problem.AddConstraint(NewSingleLoop(edges))
+	return problem
+}
+
+// decide the edges a row of corners at a time, along it and then down
+// from it, so that each corner's Table is checked as soon as it can be
+func rowByRow(p Puzzle) csp.VariableOrder[Edge, int] {
+	edges := p.edges()
+	return func(assignment map[Edge]int) Edge {
+		for _, e := range edges {
+			if _, found := assignment[e]; !found {
+				return e
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// draw the grid in text, with corners as +, the loop as - and |, and the
+// clues in their cells
+func drawLoop(p Puzzle, result map[Edge]int) string {
+	var sb strings.Builder
+	for r := 0; r <= len(p); r++ {
+		for c := 0; c < len(p[0]); c++ {
+			sb.WriteString("+")
+			if result[Edge{r, c, false}] == 1 {
+				sb.WriteString("---")
+			} else {
+				sb.WriteString("   ")
+			}
+		}
+		sb.WriteString("+\n")
+		if r == len(p) {
+			break
+		}
+		for c := 0; c <= len(p[0]); c++ {
+			if result[Edge{r, c, true}] == 1 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(" ")
+			}
+			if c < len(p[0]) {
+				if clue := p[r][c]; clue >= 0 {
+					fmt.Fprintf(&sb, " %d ", clue)
+				} else {
+					sb.WriteString("   ")
+				}
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// model slitherlink using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	lines := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		lines = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := ParsePuzzle(lines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = rowByRow(p)
+	found := 0
+	bt.Observe(csp.Hooks[Edge, int]{
+		OnSolution: func(map[Edge]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Edge]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawLoop(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Var is the group a golfer plays in in a week, the golfers, weeks and
+// groups all counting from 0
+type Var struct {
+	Golfer int
+	Week   int
+}
+
+var (
+	// the number of groups that play each week
+	Groups int
+
+	// the number of golfers in each group
+	Size int
+
+	// the number of weeks to schedule
+	Weeks int
+
+	// whether to break the problem's symmetries
+	Symmetry bool
+
+	// how long to search before giving up, or 0 for no limit
+	Timeout time.Duration
+)
+
+func init() {
+	flag.IntVar(&Groups, "groups", 5, "the number of groups that play each week")
+	flag.IntVar(&Size, "size", 3, "the number of golfers in each group")
+	flag.IntVar(&Weeks, "weeks", 5, "the number of weeks to schedule")
+	flag.BoolVar(&Symmetry, "symmetry", true, "break the symmetries of golfers, groups and weeks; try without to see the search slow")
+	flag.DurationVar(&Timeout, "timeout", 30*time.Second, "how long to search before giving up, or 0 for no limit")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: social_golfer [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "schedule a golf club's weeks so that its golfers play in groups of\n")
+		fmt.Fprintf(os.Stderr, "the same size each week, and no two play in the same group twice.\n")
+		fmt.Fprintf(os.Stderr, "the defaults are the first five days of Kirkman's schoolgirl problem\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// constraint: golfers a and b play in the same group at most once, where
+// the Variables are a's groups week by week and then b's
+func NewMeetOnce(a, b, weeks int) csp.Constraint[Var] {
+	var variables []Var
+	for _, golfer := range []int{a, b} {
+		for w := 0; w < weeks; w++ {
+			variables = append(variables, Var{golfer, w})
+		}
+	}
+	return csp.Constraint[Var]{Variables: variables}
+}
+
+// constraint: no two golfers share a group in more than one of the
+// weeks placing both of them so far
+func SatisfiesConstraint(meet csp.Constraint[Var], candidate map[Var]int) bool {
+	weeks := len(meet.Variables) / 2
+	met := 0
+	for w := 0; w < weeks; w++ {
+		a, found := candidate[meet.Variables[w]]
+		if !found {
+			continue
+		}
+		b, found := candidate[meet.Variables[weeks+w]]
+		if found && a == b {
+			met++
+		}
+	}
+	return met <= 1
+}
+
+// model the schedule with a variable per golfer and week for the group
+// they play in. a GlobalCardinality constraint per week fills every group,
+// and a constraint per pair of golfers keeps them from meeting twice.
+//
+// any solution can have its golfers renumbered, its groups renumbered in
+// any week and its weeks reordered to give another, so there are vastly
+// many that are the same schedule, and a search that doesn't know so
+// spends almost all of its time proving that partial schedules equivalent
+// to ones it's already ruled out are no good either. with symmetry set,
+// the first week is fixed with the golfers in order, the first group of it
+// split in order over the groups of each later week, and the later weeks
+// ordered by the group of the first golfer of the second group
+func NewProblem(groups, size, weeks int, symmetry bool) csp.Problem[Var, int] {
+	golfers := groups * size
+	domain := map[Var][]int{}
+	for g := 0; g < golfers; g++ {
+		for w := 0; w < weeks; w++ {
+			for group := 0; group < groups; group++ {
+				domain[Var{g, w}] = append(domain[Var{g, w}], group)
+			}
+		}
+	}
+	if symmetry {
+		for g := 0; g < golfers; g++ {
+			domain[Var{g, 0}] = []int{g / size}
+		}
+		for g := 0; g < size && g < groups; g++ {
+			for w := 1; w < weeks; w++ {
+				domain[Var{g, w}] = []int{g}
+			}
+		}
+	}
+
+	problem := csp.New(domain, SatisfiesConstraint)
+	values, full := make([]int, groups), make([]int, groups)
+	for group := range values {
+		values[group], full[group] = group, size
+	}
+	for w := 0; w < weeks; w++ {
+		var week []Var
+		for g := 0; g < golfers; g++ {
+			week = append(week, Var{g, w})
+		}
+		problem.AddConstraint(csp.GlobalCardinality(week, values, full, full))
+	}
+	for a := 0; a < golfers; a++ {
+		for b := a + 1; b < golfers; b++ {
+			problem.AddConstraint(NewMeetOnce(a, b, weeks))
+		}
+	}
+	if symmetry && size < golfers {
+		for w := 1; w+1 < weeks; w++ {
+			problem.AddConstraint(csp.Linear([]Var{{size, w}, {size, w + 1}}, []int{1, -1}, csp.Le, 0))
+		}
+	}
+	return problem
+}
+
+// assign the weeks in turn, each golfer in turn
+func weekByWeek(golfers, weeks int) csp.VariableOrder[Var, int] {
+	return func(assignment map[Var]int) Var {
+		for w := 0; w < weeks; w++ {
+			for g := 0; g < golfers; g++ {
+				if _, found := assignment[Var{g, w}]; !found {
+					return Var{g, w}
+				}
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// the golfers are lettered, A to Z and then a to z
+func name(golfer int) string {
+	if golfer < 26 {
+		return string(rune('A' + golfer))
+	}
+	return string(rune('a' + golfer - 26))
+}
+
+// model the social golfer problem using CSP framework + Go generics
+func main() {
+	flag.Parse()
+	golfers := Groups * Size
+	if Groups < 1 || Size < 1 || Weeks < 1 || golfers > 52 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	problem := NewProblem(Groups, Size, Weeks, Symmetry)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = weekByWeek(golfers, Weeks)
+	bt.Timeout = Timeout
+	result := bt.Solve(map[Var]int{})
+	stats := bt.Stats()
+	if result == nil {
+		reason := "none exists"
+		if stats.TimedOut {
+			reason = "timed out"
+		}
+		fmt.Printf("No schedule found, %s (%d nodes, %d backtracks in %s)\n", reason, stats.Nodes, stats.Backtracks, stats.Duration)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d golfers in %d groups of %d for %d weeks\n", golfers, Groups, Size, Weeks)
+	for w := 0; w < Weeks; w++ {
+		week := make([][]string, Groups)
+		for g := 0; g < golfers; g++ {
+			group := result[Var{g, w}]
+			week[group] = append(week[group], name(g))
+		}
+		sort.Slice(week, func(i, j int) bool { return week[i][0] < week[j][0] })
+		var groups []string
+		for _, group := range week {
+			groups = append(groups, strings.Join(group, ""))
+		}
+		fmt.Printf("Week %d: %s\n", w+1, strings.Join(groups, " "))
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
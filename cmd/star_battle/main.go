@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Cell is a cell of the grid, by row and column counting from 0
+type Cell struct {
+	Row, Col int
+}
+
+// Puzzle is a star battle: a square grid divided into regions, each cell
+// labeled with its region's letter, and the number of stars to place in
+// every row, column and region
+type Puzzle struct {
+	Stars   int
+	Regions []string
+}
+
+var (
+	// the puzzle solved when none is given, its first line holding the
+	// number of stars and the rest the regions
+	Example = []string{
+		"1",
+		"CCCCCAAAA",
+		"CCAAAAABA",
+		"FCCAFABBE",
+		"FFFFFADEE",
+		"FGFFAAFFE",
+		"FGFFFFFEE",
+		"GGFFFEFFE",
+		"GIFHEEEEE",
+		"GIIIIEEEE",
+	}
+
+	// the colors that tell the regions apart
+	Palette = []render.Color{render.Red, render.Green, render.Yellow, render.Blue, render.Magenta, render.Cyan}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: star_battle [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a star battle: place stars in a square grid divided into regions,\n")
+		fmt.Fprintf(os.Stderr, "as many in every row, column and region, so that no two stars touch,\n")
+		fmt.Fprintf(os.Stderr, "not even at a corner. a file holds the number of stars on its first\n")
+		fmt.Fprintf(os.Stderr, "line, and then the grid a row per line, each cell a letter naming its\n")
+		fmt.Fprintf(os.Stderr, "region\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// parse a puzzle as described by the usage: the grid must be square
+func ParsePuzzle(lines []string) (Puzzle, error) {
+	var p Puzzle
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if p.Stars == 0 {
+			stars, err := strconv.Atoi(line)
+			if err != nil || stars < 1 {
+				return p, fmt.Errorf("line 1: invalid number of stars %q", line)
+			}
+			p.Stars = stars
+			continue
+		}
+		p.Regions = append(p.Regions, line)
+	}
+	if len(p.Regions) == 0 {
+		return p, fmt.Errorf("no rows")
+	}
+	for row, line := range p.Regions {
+		if len(line) != len(p.Regions) {
+			return p, fmt.Errorf("row %d has %d cells, but a grid of %d rows must be square", row+1, len(line), len(p.Regions))
+		}
+	}
+	return p, nil
+}
+
+// the cells of each region, by its letter
+func (p Puzzle) regions() map[byte][]Cell {
+	out := map[byte][]Cell{}
+	for r, line := range p.Regions {
+		for c := range line {
+			out[line[c]] = append(out[line[c]], Cell{r, c})
+		}
+	}
+	return out
+}
+
+// model the puzzle with a boolean variable per cell for whether it holds
+// a star. a Count constraint per row, column and region places its stars,
+// and an Among constraint per 2x2 block allows one star in it at most:
+// any two touching cells share a block
+func NewProblem(p Puzzle) csp.Problem[Cell, int] {
+	n := len(p.Regions)
+	domain := map[Cell][]int{}
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			domain[Cell{r, c}] = []int{0, 1}
+		}
+	}
+
+	problem := csp.New[Cell, int](domain, nil)
+	for i := 0; i < n; i++ {
+		var row, col []Cell
+		for j := 0; j < n; j++ {
+			row, col = append(row, Cell{i, j}), append(col, Cell{j, i})
+		}
+		problem.AddConstraint(csp.Count(row, 1, p.Stars))
+		problem.AddConstraint(csp.Count(col, 1, p.Stars))
+	}
+	for _, cells := range p.regions() {
+		problem.AddConstraint(csp.Count(cells, 1, p.Stars))
+	}
+	for r := 0; r+1 < n; r++ {
+		for c := 0; c+1 < n; c++ {
+			block := []Cell{{r, c}, {r, c + 1}, {r + 1, c}, {r + 1, c + 1}}
+			problem.AddConstraint(csp.Among(block, []int{1}, 0, 1))
+		}
+	}
+	return problem
+}
+
+// decide the smallest regions first, whose stars have the fewest places
+// to go, a cell at a time
+func smallestRegionFirst(p Puzzle) csp.VariableOrder[Cell, int] {
+	var regions [][]Cell
+	for _, cells := range p.regions() {
+		regions = append(regions, cells)
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		if len(regions[i]) != len(regions[j]) {
+			return len(regions[i]) < len(regions[j])
+		}
+		return p.Regions[regions[i][0].Row][regions[i][0].Col] < p.Regions[regions[j][0].Row][regions[j][0].Col]
+	})
+	return func(assignment map[Cell]int) Cell {
+		for _, cells := range regions {
+			for _, cell := range cells {
+				if _, found := assignment[cell]; !found {
+					return cell
+				}
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// draw the grid with each region in a color, no two touching regions the
+// same where the palette allows, and the stars as * among the . of the
+// other cells
+func drawGrid(p Puzzle, result map[Cell]int) string {
+	colors := map[byte]int{}
+	var labels []byte
+	for label := range p.regions() {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i] < labels[j] })
+	for _, label := range labels {
+		used := map[int]bool{}
+		for r, line := range p.Regions {
+			for c := range line {
+				if line[c] != label {
+					continue
+				}
+				for _, n := range []Cell{{r - 1, c}, {r + 1, c}, {r, c - 1}, {r, c + 1}} {
+					if n.Row >= 0 && n.Row < len(p.Regions) && n.Col >= 0 && n.Col < len(line) {
+						if other := p.Regions[n.Row][n.Col]; other != label {
+							if color, found := colors[other]; found {
+								used[color] = true
+							}
+						}
+					}
+				}
+			}
+		}
+		colors[label] = 0
+		for color := range Palette {
+			if !used[color] {
+				colors[label] = color
+				break
+			}
+		}
+	}
+
+	return render.Grid{
+		Rows: len(p.Regions),
+		Cols: len(p.Regions),
+		Cell: func(row, col int) render.Cell {
+			cell := render.Cell{Text: ".", Color: Palette[colors[p.Regions[row][col]]]}
+			if result[Cell{row, col}] == 1 {
+				cell.Text = "*"
+			}
+			return cell
+		},
+	}.String()
+}
+
+// model star battle using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	lines := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		lines = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := ParsePuzzle(lines)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = smallestRegionFirst(p)
+	found := 0
+	bt.Observe(csp.Hooks[Cell, int]{
+		OnSolution: func(map[Cell]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Cell]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: %d stars in every row, column and region\n", p.Stars)
+	fmt.Print(drawGrid(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
+)
+
+const (
+	Size    = 9
+	BoxSize = 3
+)
+
+type Cell struct {
+	Row int
+	Col int
+}
+
+type Digit int
+
+// Puzzle holds the given digits of a sudoku, with 0 for the blanks
+type Puzzle [Size][Size]Digit
+
+var (
+	// the puzzle solved when none is given: "an easy one", from Wikipedia
+	Example = "53..7....6..195....98....6.8...6...34..8.3..17...2...6.6....28....419..5....8..79"
+
+	// CSP domains
+	Digits []Digit
+
+	// animate the search in the terminal
+	TUI bool
+)
+
+func init() {
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: sudoku [flags] [PUZZLE | FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a sudoku given as a line of 81 digits, with . or 0 for the blanks,\n")
+		fmt.Fprintf(os.Stderr, "or a file of such lines, or a .sdk file laying out the grid in 9 lines\n\n")
+		flag.PrintDefaults()
+	}
+
+	for d := Digit(1); d <= Size; d++ {
+		Digits = append(Digits, d)
+	}
+}
+
+// parse the cells of a puzzle, ignoring the spacing and rules that
+// files use to lay out the grid
+func ParsePuzzle(text string) (Puzzle, error) {
+	var p Puzzle
+	n := 0
+	for _, c := range text {
+		var d Digit
+		switch {
+		case c >= '1' && c <= '9':
+			d = Digit(c - '0')
+		case c == '.' || c == '0' || c == '_':
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '|' || c == '-' || c == '+':
+			continue
+		default:
+			return p, fmt.Errorf("unexpected %q in puzzle", c)
+		}
+
+		if n == Size*Size {
+			return p, fmt.Errorf("more than %d cells in puzzle", Size*Size)
+		}
+		p[n/Size][n%Size] = d
+		n++
+	}
+	if n < Size*Size {
+		return p, fmt.Errorf("only %d of %d cells in puzzle", n, Size*Size)
+	}
+	return p, nil
+}
+
+// read the puzzles of a file: either one per line, or a single puzzle
+// laid out over several lines. lines starting with # are comments, as
+// are the [Puzzle]-style headers of some .sdk files
+func ReadPuzzles(path string) ([]Puzzle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	oneLine := len(lines) > 0
+	for _, line := range lines {
+		oneLine = oneLine && len(line) == Size*Size
+	}
+	if !oneLine {
+		lines = []string{strings.Join(lines, "\n")}
+	}
+
+	var out []Puzzle
+	for ndx, line := range lines {
+		p, err := ParsePuzzle(line)
+		if err != nil {
+			if oneLine {
+				return nil, fmt.Errorf("%s: line %d: %w", path, ndx+1, err)
+			}
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// model the puzzle with a variable per cell, the givens fixed by their
+// domains, and all the digits of each row, column and box different
+func NewProblem(p Puzzle) csp.Problem[Cell, Digit] {
+	domain := map[Cell][]Digit{}
+	for row := 0; row < Size; row++ {
+		for col := 0; col < Size; col++ {
+			if d := p[row][col]; d != 0 {
+				domain[Cell{row, col}] = []Digit{d}
+			} else {
+				domain[Cell{row, col}] = Digits
+			}
+		}
+	}
+
+	problem := csp.New[Cell, Digit](domain, nil)
+	for i := 0; i < Size; i++ {
+		for j := 0; j < Size; j++ {
+			cell := Cell{i, j}
+			problem.Labels[cell] = csp.Label{Name: fmt.Sprintf("r%dc%d", i+1, j+1)}
+			problem.Group(fmt.Sprintf("row%d", i+1), cell)
+			problem.Group(fmt.Sprintf("col%d", j+1), cell)
+			problem.Group(fmt.Sprintf("box%d", BoxSize*(i/BoxSize)+j/BoxSize+1), cell)
+		}
+	}
+	// every row, column and box holds each digit once
+	problem.ConstrainGroups("*", csp.AllDifferent[Cell])
+
+	return problem
+}
+
+// draw the grid, with the digits found by the search picked out from the givens
+func drawGrid(p Puzzle, candidate map[Cell]Digit) string {
+	return render.Grid{
+		Rows:    Size,
+		Cols:    Size,
+		BoxRows: BoxSize,
+		BoxCols: BoxSize,
+		Cell: func(row, col int) render.Cell {
+			if d := p[row][col]; d != 0 {
+				return render.Cell{Text: fmt.Sprint(d)}
+			}
+			if d, found := candidate[Cell{row, col}]; found {
+				return render.Cell{Text: fmt.Sprint(d), Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+func solve(p Puzzle) bool {
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	// filling the most constrained cell first is what makes sudoku tractable
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+
+	var result map[Cell]Digit
+	if TUI {
+		draw := func(candidate map[Cell]Digit) string { return drawGrid(p, candidate) }
+		var err error
+		if result, err = tui.New("Sudoku", draw).Run(bt, map[Cell]Digit{}); err != nil {
+			panic(err)
+		}
+	} else {
+		result = bt.Solve(map[Cell]Digit{})
+	}
+
+	fmt.Println("Puzzle:")
+	fmt.Print(drawGrid(p, nil))
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		return false
+	}
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, result))
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+	return true
+}
+
+// model sudoku using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	var puzzles []Puzzle
+	switch arg := flag.Arg(0); {
+	case flag.NArg() > 1:
+		flag.Usage()
+		os.Exit(2)
+	case arg == "":
+		arg = Example
+		fallthrough
+	case len(arg) == Size*Size:
+		p, err := ParsePuzzle(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		puzzles = append(puzzles, p)
+	default:
+		var err error
+		if puzzles, err = ReadPuzzles(arg); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	unsolved := 0
+	for ndx, p := range puzzles {
+		if ndx > 0 {
+			fmt.Println()
+		}
+		if !solve(p) {
+			unsolved++
+		}
+	}
+	if unsolved > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Var is a cell of the grid, by row and column counting from 0: either a
+// tree's cell, whose variable holds the cell of the tent it's matched
+// with, or a cell next to a tree, whose variable is 1 if it holds a tent
+type Var struct {
+	Row, Col int
+	Tree     bool
+}
+
+// Puzzle is a tents and trees grid: the number of tents in each row and
+// column, and the cells, T for a tree and . for none
+type Puzzle struct {
+	RowCounts, ColCounts []int
+	Cells                []string
+}
+
+var (
+	// the puzzle solved when none is given, its first line holding the
+	// column counts and each of the others a row count and then the row
+	Example = []string{
+		".3123201314",
+		"3.......T..",
+		"0.T.T.....T",
+		"2T.....T...",
+		"2.......T.T",
+		"2...TT.....",
+		"2T.....T...",
+		"3..TT......",
+		"1T........T",
+		"1..........",
+		"4.T...TT.T.",
+	}
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tents [FILE]\n\n")
+		fmt.Fprintf(os.Stderr, "solve a tents and trees puzzle: pitch a tent next to each tree, across\n")
+		fmt.Fprintf(os.Stderr, "or down from it, each tree with its own tent, so that no two tents\n")
+		fmt.Fprintf(os.Stderr, "touch, not even at a corner, and each row and column has as many tents\n")
+		fmt.Fprintf(os.Stderr, "as its count. a file holds a line of . and the column counts, and then\n")
+		fmt.Fprintf(os.Stderr, "a line per row of its count and its cells, T for a tree or . for none\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// parse a puzzle as described by the usage: the counts are digits, and
+// every line must be as long as the first
+func ParsePuzzle(lines []string) (Puzzle, error) {
+	var p Puzzle
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if len(p.ColCounts) > 0 && len(line) != len(p.ColCounts)+1 {
+			return p, fmt.Errorf("line %d has %d characters, not %d", len(p.Cells)+2, len(line), len(p.ColCounts)+1)
+		}
+		if len(p.ColCounts) == 0 {
+			for _, ch := range line[1:] {
+				if ch < '0' || ch > '9' {
+					return p, fmt.Errorf("line 1: invalid column count %q", ch)
+				}
+				p.ColCounts = append(p.ColCounts, int(ch-'0'))
+			}
+			continue
+		}
+		if ch := line[0]; ch < '0' || ch > '9' {
+			return p, fmt.Errorf("line %d: invalid row count %q", len(p.Cells)+2, ch)
+		}
+		for _, ch := range line[1:] {
+			if ch != '.' && ch != 'T' {
+				return p, fmt.Errorf("line %d: invalid cell %q", len(p.Cells)+2, ch)
+			}
+		}
+		p.RowCounts = append(p.RowCounts, int(line[0]-'0'))
+		p.Cells = append(p.Cells, line[1:])
+	}
+	if len(p.Cells) == 0 || len(p.ColCounts) == 0 {
+		return p, fmt.Errorf("no rows")
+	}
+	return p, nil
+}
+
+func (p Puzzle) tree(r, c int) bool {
+	return r >= 0 && r < len(p.Cells) && c >= 0 && c < len(p.ColCounts) && p.Cells[r][c] == 'T'
+}
+
+// the number a tree's variable holds for a cell
+func (p Puzzle) index(r, c int) int {
+	return r*len(p.ColCounts) + c
+}
+
+// the cells a tent could be pitched in: those across or down from a tree,
+// that aren't trees themselves
+func (p Puzzle) sites() map[Var][]Var {
+	out := map[Var][]Var{}
+	for r := range p.Cells {
+		for c := range p.Cells[r] {
+			if !p.tree(r, c) {
+				continue
+			}
+			tree := Var{r, c, true}
+			for _, n := range [][2]int{{r - 1, c}, {r + 1, c}, {r, c - 1}, {r, c + 1}} {
+				if n[0] >= 0 && n[0] < len(p.Cells) && n[1] >= 0 && n[1] < len(p.ColCounts) && !p.tree(n[0], n[1]) {
+					out[tree] = append(out[tree], Var{n[0], n[1], false})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// model the puzzle with two kinds of variable: one per tree for the cell
+// of its tent, and a boolean one per cell next to a tree for whether it
+// holds a tent. a Table constraint per tree and cell next to it channels
+// between them, pitching a tent in the cell the tree is matched with, and
+// AllDifferent over the trees gives each its own tent. Count constraints
+// keep to the tents in each row and column, and as they add up to the
+// trees, every tent is matched with a tree. a Sum constraint per pair of
+// touching cells keeps their tents apart
+func NewProblem(p Puzzle) csp.Problem[Var, int] {
+	sites := p.sites()
+	domain := map[Var][]int{}
+	var trees []Var
+	for r := range p.Cells {
+		for c := range p.Cells[r] {
+			if !p.tree(r, c) {
+				continue
+			}
+			tree := Var{r, c, true}
+			trees = append(trees, tree)
+			domain[tree] = []int{}
+			for _, site := range sites[tree] {
+				domain[tree] = append(domain[tree], p.index(site.Row, site.Col))
+				domain[site] = []int{0, 1}
+			}
+		}
+	}
+
+	problem := csp.New[Var, int](domain, nil)
+	problem.AddConstraint(csp.AllDifferent(trees...))
+	for _, tree := range trees {
+		for _, site := range sites[tree] {
+			var tuples [][]int
+			for _, at := range domain[tree] {
+				if at == p.index(site.Row, site.Col) {
+					tuples = append(tuples, []int{at, 1})
+				} else {
+					tuples = append(tuples, []int{at, 0}, []int{at, 1})
+				}
+			}
+			problem.AddConstraint(csp.Table([]Var{tree, site}, tuples))
+		}
+	}
+
+	rows, cols := make([][]Var, len(p.RowCounts)), make([][]Var, len(p.ColCounts))
+	for v := range domain {
+		if !v.Tree {
+			rows[v.Row], cols[v.Col] = append(rows[v.Row], v), append(cols[v.Col], v)
+		}
+	}
+	for r, row := range rows {
+		if len(row) > 0 {
+			problem.AddConstraint(csp.Count(row, 1, p.RowCounts[r]))
+		}
+	}
+	for c, col := range cols {
+		if len(col) > 0 {
+			problem.AddConstraint(csp.Count(col, 1, p.ColCounts[c]))
+		}
+	}
+
+	for v := range domain {
+		if v.Tree {
+			continue
+		}
+		// each pair once, from its top or left cell
+		for _, n := range []Var{{v.Row, v.Col + 1, false}, {v.Row + 1, v.Col - 1, false}, {v.Row + 1, v.Col, false}, {v.Row + 1, v.Col + 1, false}} {
+			if _, found := domain[n]; found {
+				problem.AddConstraint(csp.Sum([]Var{v, n}, csp.Le, 1))
+			}
+		}
+	}
+	return problem
+}
+
+// report whether the tents add up the same by row and by column, and to
+// the number of trees, as every solution's must, and whether every row
+// and column with tents has a cell next to a tree
+func (p Puzzle) check() error {
+	trees, rows, cols := 0, 0, 0
+	for r := range p.Cells {
+		rows += p.RowCounts[r]
+		for c := range p.Cells[r] {
+			if p.tree(r, c) {
+				trees++
+			}
+		}
+	}
+	for _, count := range p.ColCounts {
+		cols += count
+	}
+	if rows != trees || cols != trees {
+		return fmt.Errorf("%d trees, but the rows count %d tents and the columns %d", trees, rows, cols)
+	}
+
+	// a row or column counting tents must have somewhere to pitch them
+	sited := map[[2]int]bool{}
+	for _, cells := range p.sites() {
+		for _, site := range cells {
+			sited[[2]int{site.Row, 0}], sited[[2]int{site.Col, 1}] = true, true
+		}
+	}
+	for r, count := range p.RowCounts {
+		if count > 0 && !sited[[2]int{r, 0}] {
+			return fmt.Errorf("row %d counts %d tents, but no cell in it is next to a tree", r+1, count)
+		}
+	}
+	for c, count := range p.ColCounts {
+		if count > 0 && !sited[[2]int{c, 1}] {
+			return fmt.Errorf("column %d counts %d tents, but no cell in it is next to a tree", c+1, count)
+		}
+	}
+	return nil
+}
+
+// draw the grid with the counts in cyan above and left of it, the trees
+// as T in green and the tents as A in yellow
+func drawGrid(p Puzzle, result map[Var]int) string {
+	return render.Grid{
+		Rows: len(p.RowCounts) + 1,
+		Cols: len(p.ColCounts) + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{Text: " "}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprint(p.ColCounts[col-1]), Color: render.Cyan}
+			case col == 0:
+				return render.Cell{Text: fmt.Sprint(p.RowCounts[row-1]), Color: render.Cyan}
+			case p.tree(row-1, col-1):
+				return render.Cell{Text: "T", Color: render.Green}
+			case result[Var{row - 1, col - 1, false}] == 1:
+				return render.Cell{Text: "A", Color: render.Yellow}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// model tents and trees using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	lines := Example
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		file, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+		lines = nil
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		file.Close()
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	p, err := ParsePuzzle(lines)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(2)
+	}
+
+	// look for a second solution too, to tell whether the puzzle is sound
+	problem := NewProblem(p)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	found := 0
+	bt.Observe(csp.Hooks[Var, int]{
+		OnSolution: func(map[Var]int) {
+			if found++; found == 2 {
+				bt.Cancel()
+			}
+		},
+	})
+	solutions := bt.SolveAll(map[Var]int{})
+	stats := bt.Stats()
+	if len(solutions) == 0 {
+		fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawGrid(p, solutions[0]))
+	if len(solutions) > 1 {
+		fmt.Println("The puzzle has more than one solution")
+	} else {
+		fmt.Println("The only solution")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
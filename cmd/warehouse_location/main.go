@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Warehouse is a site a warehouse could be opened at, and the most stores
+// it could supply
+type Warehouse struct {
+	Name     string
+	Capacity int
+}
+
+// Var is either whether a warehouse is open, 1 if it is and 0 if not, or
+// the warehouse a store is supplied from, counting from 0. the stores
+// count from 0, so a warehouse's variable has Store -1
+type Var struct {
+	Open  string
+	Store int
+}
+
+// the variable for whether the warehouse is open
+func opened(w string) Var { return Var{Open: w, Store: -1} }
+
+// the variable for the warehouse supplying the store
+func store(s int) Var { return Var{Store: s} }
+
+var (
+	// CSP variables: whether each warehouse opens
+	Warehouses = []Warehouse{
+		{"Bonn", 1}, {"Bordeaux", 4}, {"London", 2}, {"Paris", 1}, {"Rome", 3},
+	}
+
+	// CSP variables: the warehouse each store is supplied from, and the
+	// cost of supplying it from each. this is the example of CSPLib
+	// problem 34, whose cheapest plan costs 383
+	SupplyCost = [][]int{
+		{20, 24, 11, 25, 30},
+		{28, 27, 82, 83, 74},
+		{74, 97, 71, 96, 70},
+		{2, 55, 73, 69, 61},
+		{46, 96, 59, 83, 4},
+		{42, 22, 29, 67, 59},
+		{1, 5, 73, 59, 56},
+		{10, 73, 13, 43, 96},
+		{93, 35, 63, 85, 46},
+		{47, 65, 55, 71, 95},
+	}
+
+	// the cost of opening any of the warehouses
+	FixedCost int
+
+	// give up on proving the cheapest plan after this long
+	Timeout time.Duration
+)
+
+func init() {
+	flag.IntVar(&FixedCost, "fixed-cost", 30, "the cost of opening any of the warehouses")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "give up on proving the cheapest plan after this long")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: warehouse_location [flags]\n\n")
+		fmt.Fprintf(os.Stderr, "choose which warehouses to open, and which supplies each store, for the\n")
+		fmt.Fprintf(os.Stderr, "least cost of opening them and supplying the stores from them\n\n")
+		flag.PrintDefaults()
+	}
+}
+
+// model the plan with a boolean variable per warehouse for whether it's
+// open, and an integer one per store for the warehouse supplying it. a
+// Table constraint per store and warehouse only lets a store be supplied
+// from a warehouse that's open, and a GlobalCardinality constraint keeps
+// each warehouse within the stores it can supply
+func NewProblem(warehouses []Warehouse, stores int) csp.Problem[Var, int] {
+	domain := map[Var][]int{}
+	var sites, capacities, none []int
+	for w, site := range warehouses {
+		domain[opened(site.Name)] = []int{0, 1}
+		sites = append(sites, w)
+		capacities = append(capacities, site.Capacity)
+		none = append(none, 0)
+	}
+	var supplied []Var
+	for s := 0; s < stores; s++ {
+		domain[store(s)] = sites
+		supplied = append(supplied, store(s))
+	}
+
+	problem := csp.New[Var, int](domain, nil)
+	problem.AddConstraint(csp.GlobalCardinality(supplied, sites, none, capacities))
+	for s := 0; s < stores; s++ {
+		for w, site := range warehouses {
+			var tuples [][]int
+			for _, from := range sites {
+				tuples = append(tuples, []int{from, 1})
+				if from != w {
+					tuples = append(tuples, []int{from, 0})
+				}
+			}
+			problem.AddConstraint(csp.Table([]Var{store(s), opened(site.Name)}, tuples))
+		}
+	}
+	return problem
+}
+
+// the cost of the plan, or for one partly made, a lower bound on it: the
+// warehouses open so far or supplying a store, the stores supplied so far,
+// and for each of the others the cheapest warehouse not closed
+func planCost(warehouses []Warehouse, supply [][]int, fixed int) csp.Cost[Var, int] {
+	return func(assignment map[Var]int) int {
+		open := map[int]bool{}
+		closed := map[int]bool{}
+		for w, site := range warehouses {
+			if value, found := assignment[opened(site.Name)]; found {
+				open[w], closed[w] = value == 1, value == 0
+			}
+		}
+		cost := 0
+		for s, costs := range supply {
+			if w, found := assignment[store(s)]; found {
+				open[w] = true
+				cost += costs[w]
+				continue
+			}
+			cheapest := -1
+			for w, c := range costs {
+				if !closed[w] && (cheapest < 0 || c < cheapest) {
+					cheapest = c
+				}
+			}
+			cost += cheapest
+		}
+		for _, isOpen := range open {
+			if isOpen {
+				cost += fixed
+			}
+		}
+		return cost
+	}
+}
+
+// supply the stores first, those with the most to lose by not getting
+// their cheapest warehouse first, and then open the warehouses they need
+func storesFirst(warehouses []Warehouse, supply [][]int) csp.VariableOrder[Var, int] {
+	var order []Var
+	regret := func(costs []int) int {
+		best, next := -1, -1
+		for _, c := range costs {
+			switch {
+			case best < 0 || c < best:
+				best, next = c, best
+			case next < 0 || c < next:
+				next = c
+			}
+		}
+		return next - best
+	}
+	for s := range supply {
+		order = append(order, store(s))
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return regret(supply[order[i].Store]) > regret(supply[order[j].Store])
+	})
+	for _, site := range warehouses {
+		order = append(order, opened(site.Name))
+	}
+
+	return func(assignment map[Var]int) Var {
+		for _, v := range order {
+			if _, found := assignment[v]; !found {
+				return v
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// tabulate the plan with a row per store and a column per warehouse,
+// showing the cost of supplying the store from the warehouse it's
+// supplied from, and a footer of the warehouses opened
+func drawPlan(result map[Var]int, warehouses []Warehouse, supply [][]int) string {
+	return render.Grid{
+		Rows: len(supply) + 2,
+		Cols: len(warehouses) + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: warehouses[col-1].Name}
+			case row == len(supply)+1 && col == 0:
+				return render.Cell{Text: "open"}
+			case row == len(supply)+1:
+				if result[opened(warehouses[col-1].Name)] == 1 {
+					return render.Cell{Text: "yes", Color: render.Green}
+				}
+				return render.Cell{Text: "-"}
+			case col == 0:
+				return render.Cell{Text: fmt.Sprint("store ", row)}
+			}
+			if result[store(row-1)] == col-1 {
+				return render.Cell{Text: fmt.Sprint(supply[row-1][col-1]), Color: render.Cyan}
+			}
+			return render.Cell{Text: "."}
+		},
+	}.String()
+}
+
+// model warehouse location using CSP framework + Go generics
+func main() {
+	flag.Parse()
+
+	problem := NewProblem(Warehouses, len(SupplyCost))
+	cost := planCost(Warehouses, SupplyCost, FixedCost)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = storesFirst(Warehouses, SupplyCost)
+	bt.OrderValues = csp.CheapestValue(problem, cost)
+	bt.Timeout = Timeout
+	bt.Observe(csp.Hooks[Var, int]{
+		OnSolution: func(solution map[Var]int) {
+			fmt.Printf("Found a plan costing %d after %d nodes\n", cost(solution), bt.Stats().Nodes)
+		},
+	})
+	result, total := bt.Minimize(map[Var]int{}, cost)
+	stats := bt.Stats()
+	if result == nil {
+		fmt.Printf("No plan found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Solution: supplying %d stores costs %d\n", len(SupplyCost), total)
+	fmt.Print(drawPlan(result, Warehouses, SupplyCost))
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the cheapest plan, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The cheapest plan possible, as no plan costs less")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
+}
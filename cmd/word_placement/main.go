@@ -1,15 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"math/rand"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+	"github.com/elireisman/generic-csp-go/pkg/tui"
 )
 
-const GridSize = 16
-
 type Word string
 
 type Point struct {
@@ -21,19 +26,6 @@ type Placement struct {
 	Points []Point
 }
 
-type Letter struct {
-	Rune  rune
-	Color LetterColor
-}
-
-type LetterColor string
-
-var (
-	None   LetterColor = "\x1b[0;0m"
-	Green  LetterColor = "\x1b[1;42m"
-	Yellow LetterColor = "\x1b[1;41m"
-)
-
 var (
 	// CSP variables
 	Words []Word
@@ -42,12 +34,42 @@ var (
 	Orientations []Point
 	Placements   map[Word][]Placement
 
-	// CSP constraints
-	Constraints []csp.Constraint[Word]
+	// animate the search in the terminal
+	TUI bool
+
+	// require each word to share a cell with another, as the words of a
+	// themed word search cross
+	Overlap bool
+
+	// the number of cells along each side of the square grid
+	GridSize int
+
+	// read the words to place from this file, rather than the default ones
+	WordsFile string
+
+	// seed the shuffled placements and the filler letters, or 0 for the
+	// current time
+	Seed int64
+
+	// write the puzzle and its answer key as images to print
+	PrintFile string
+
+	// place as many of the words as fit, rather than all of them or none
+	Maximize bool
+
+	// give up on placing more words after this long
+	Timeout time.Duration
 )
 
 func init() {
-	rand.Seed(time.Now().Unix())
+	flag.BoolVar(&TUI, "tui", false, "animate the search in the terminal")
+	flag.BoolVar(&Overlap, "overlap", false, "require each word to share a letter's cell with another word")
+	flag.IntVar(&GridSize, "size", 16, "the number of cells along each side of the square grid")
+	flag.StringVar(&WordsFile, "words-file", "", "read the words to place from this file, one per line, rather than the default ones")
+	flag.StringVar(&PrintFile, "print", "", "write the puzzle to this .svg or .png file to print, and its answer key alongside, named with -key")
+	flag.BoolVar(&Maximize, "maximize", false, "place as many of the words as will fit, rather than all of them")
+	flag.DurationVar(&Timeout, "timeout", 10*time.Second, "with -maximize, give up on placing more words after this long")
+	flag.Int64Var(&Seed, "seed", 0, "seed the shuffled placements and the filler letters, or 0 for the current time")
 
 	Words = []Word{
 		"ANNA",
@@ -85,27 +107,170 @@ func init() {
 		// Diagonal Right Up
 		Point{Row: -1, Col: 1},
 	}
+}
 
-	// create a domain of candidate placements and
-	// a constraint per word we need to place on
-	// the board
+// create a domain of candidate placements per word
+// we need to place on the board, once the flags
+// have set its size. when maximizing, a word may
+// also be left off the board, by the empty
+// placement last in its domain
+func setup() {
 	Placements = map[Word][]Placement{}
-	Constraints = []csp.Constraint[Word]{}
 	for _, word := range Words {
 		Placements[word] = generatePlacements(word)
-		Constraints = append(Constraints, NewWord(word))
+		if Maximize {
+			Placements[word] = append(Placements[word], Placement{})
+		}
 	}
 }
 
+// create CSP framework instance placing the given words, populate
+// with a constraint per word, and one per word to overlap the others
+func newProblem(words []Word) csp.Problem[Word, Placement] {
+	domain := map[Word][]Placement{}
+	for _, word := range words {
+		domain[word] = Placements[word]
+	}
+	problem := csp.New(domain, SatisfiesConstraint)
+	for _, word := range words {
+		problem.AddConstraint(NewWord(word))
+	}
+	if Overlap && len(words) > 1 {
+		for _, word := range words {
+			problem.AddConstraint(NewOverlap(word, words))
+		}
+	}
+	return problem
+}
+
+// place the words in the order they're listed, rather than map order, so
+// that a -seed repeats the same search
+func inOrder(words []Word) csp.VariableOrder[Word, Placement] {
+	return func(assignment map[Word]Placement) Word {
+		for _, word := range words {
+			if _, found := assignment[word]; !found {
+				return word
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// solve for the given words alone, in order
+func newBacktracker(words []Word) *csp.Backtracker[Word, Placement] {
+	bt := csp.NewBacktracker(newProblem(words))
+	bt.SelectVariable = inOrder(words)
+	if Overlap {
+		bt.OrderValues = crossingFirst
+	}
+	return bt
+}
+
+// find the word that makes the instance infeasible: the first whose
+// addition leaves the words listed before it no room on the grid
+func infeasibleWord(words []Word) (Word, bool) {
+	for n := 1; n <= len(words); n++ {
+		if newBacktracker(words[:n]).Solve(map[Word]Placement{}) == nil {
+			return words[n-1], true
+		}
+	}
+	return "", false
+}
+
+// read the words to place, one per line, skipping blank lines and those
+// starting with #. words are upper-cased, and must be letters only
+func readWords(name string) ([]Word, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []Word
+	seen := map[Word]bool{}
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		word := Word(strings.ToUpper(line))
+		for _, letter := range word {
+			if letter < 'A' || letter > 'Z' {
+				return nil, fmt.Errorf("line %d: %q is not a word of letters A to Z", lineNo, line)
+			}
+		}
+		if seen[word] {
+			return nil, fmt.Errorf("line %d: %s is listed twice", lineNo, word)
+		}
+		seen[word] = true
+		out = append(out, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no words")
+	}
+	return out, nil
+}
+
+// report the first word too long to fit the grid at all, in any
+// orientation
+func checkFit(words []Word, size int) error {
+	for _, word := range words {
+		if len(word) > size {
+			return fmt.Errorf("%s has %d letters, too many for a grid of %d by %d", word, len(word), size, size)
+		}
+	}
+	return nil
+}
+
+// soft constraint: the word is placed on the board, rather than left off
+// it. its Args tell it apart from the word's constraint above
+func NewPlaced(word Word) csp.Constraint[Word] {
+	return csp.Constraint[Word]{
+		Variables: []Word{word},
+		Args:      []int{1},
+	}
+}
+
+// whether the placement puts its word on the board
+func (p Placement) placed() bool {
+	return len(p.Points) > 0
+}
+
 func NewWord(word Word) csp.Constraint[Word] {
 	return csp.Constraint[Word]{
 		Variables: []Word{word},
 	}
 }
 
+// constraint: the first of the words shares a cell with at least one of
+// the others, where the Variables are that word and then all the others
+func NewOverlap(word Word, words []Word) csp.Constraint[Word] {
+	variables := []Word{word}
+	for _, other := range words {
+		if other != word {
+			variables = append(variables, other)
+		}
+	}
+	return csp.Constraint[Word]{
+		Variables: variables,
+	}
+}
+
 // check each existing placement in the candidate assingments for conflicts
 // with the new (proposed) placement named in the constraint
 func SatisfiesConstraint(wordConstraint csp.Constraint[Word], candidate map[Word]Placement) bool {
+	if len(wordConstraint.Variables) > 1 {
+		return overlaps(wordConstraint.Variables[0], wordConstraint.Variables[1:], candidate)
+	}
+	if len(wordConstraint.Args) > 0 {
+		placement, found := candidate[wordConstraint.Variables[0]]
+		return !found || placement.placed()
+	}
+
 	nextWord := wordConstraint.Variables[0]
 	nextPlacement := candidate[nextWord]
 
@@ -130,6 +295,62 @@ func SatisfiesConstraint(wordConstraint csp.Constraint[Word], candidate map[Word
 	return true
 }
 
+// check that the word shares a cell with one of the others placed so far,
+// or could yet with one still to be placed
+func overlaps(word Word, others []Word, candidate map[Word]Placement) bool {
+	placement, found := candidate[word]
+	if !found || !placement.placed() {
+		return true
+	}
+
+	pending := false
+	for _, other := range others {
+		otherPlacement, found := candidate[other]
+		if !found {
+			pending = true
+			continue
+		}
+		if !otherPlacement.placed() {
+			continue
+		}
+		if crosses(placement, otherPlacement) {
+			return true
+		}
+	}
+
+	return pending
+}
+
+// whether two placements share a cell
+func crosses(a, b Placement) bool {
+	for _, point := range a.Points {
+		for _, other := range b.Points {
+			if point == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// try the placements crossing the words placed so far first, so that
+// the words overlap as they're placed rather than only by chance once the
+// last of them is
+func crossingFirst(word Word, candidate map[Word]Placement) []Placement {
+	var crossing, rest []Placement
+next:
+	for _, placement := range Placements[word] {
+		for other, otherPlacement := range candidate {
+			if other != word && crosses(placement, otherPlacement) {
+				crossing = append(crossing, placement)
+				continue next
+			}
+		}
+		rest = append(rest, placement)
+	}
+	return append(crossing, rest...)
+}
+
 func generatePlacements(word Word) []Placement {
 	out := []Placement{}
 
@@ -176,59 +397,193 @@ func generatePlacement(start Point, word Word, diff Point) []Point {
 	return out
 }
 
-func renderGrid[V Word, D Placement](candidate map[Word]Placement) {
-	// init puzzle board
-	puzzle := [GridSize][]Letter{}
+// lay out the letters of the grid, those of the placed words and the
+// rest taken from fill
+func fillGrid(candidate map[Word]Placement, fill func() rune) [][]rune {
+	letters := make([][]rune, GridSize)
 	for row := 0; row < GridSize; row++ {
-		puzzle[row] = make([]Letter, GridSize, GridSize)
+		letters[row] = make([]rune, GridSize)
 		for col := 0; col < GridSize; col++ {
-			puzzle[row][col] = Letter{
-				Rune:  'A' + rune(rand.Intn(26)),
-				Color: Green,
-			}
+			letters[row][col] = fill()
 		}
 	}
-
-	// apply resolved placements to puzzle
 	for word, placement := range candidate {
-		for ndx := 0; ndx < len(word); ndx++ {
-			row := placement.Points[ndx].Row
-			col := placement.Points[ndx].Col
-			letter := word[ndx]
-			puzzle[row][col] = Letter{
-				Rune:  rune(letter),
-				Color: Yellow,
-			}
+		for ndx, point := range placement.Points {
+			letters[point.Row][point.Col] = rune(word[ndx])
+		}
+	}
+	return letters
+}
+
+// draw the grid of letters, those of the placed words in red
+func drawGrid(candidate map[Word]Placement, letters [][]rune) string {
+	placed := map[Point]bool{}
+	for _, placement := range candidate {
+		for _, point := range placement.Points {
+			placed[point] = true
 		}
 	}
 
 	// render the puzzle with all placements
-	for row := 0; row < GridSize; row++ {
-		for col := 0; col < GridSize; col++ {
-			letter := puzzle[row][col]
-			fmt.Printf("%s%c%s ", letter.Color, letter.Rune, None)
+	return render.Grid{
+		Rows: GridSize,
+		Cols: GridSize,
+		Cell: func(row, col int) render.Cell {
+			cell := render.Cell{Text: string(letters[row][col]), Color: render.Green.Bold()}
+			if placed[Point{Row: row, Col: col}] {
+				cell.Color = render.Red.Bold()
+			}
+			return cell
+		},
+	}.String()
+}
+
+// draw the solution, with random filler letters, and print it if asked
+func printSolution(result map[Word]Placement) {
+	letters := fillGrid(result, func() rune { return 'A' + rune(rand.Intn(26)) })
+	fmt.Print(drawGrid(result, letters))
+	if PrintFile != "" {
+		if err := (sheet{letters, result}).print(PrintFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote %s and its answer key %s\n", PrintFile, keyName(PrintFile))
+	}
+}
+
+// place as many of the words as fit: a soft constraint per word to place
+// it, each of weight 1, and branch and bound finds the placement leaving
+// the fewest words off the board. the placements are tried before leaving
+// a word off, so the first solutions found place words greedily
+func maximize() {
+	problem := newProblem(Words)
+	var soft []csp.Soft[Word]
+	for _, word := range Words {
+		soft = append(soft, csp.Soft[Word]{Constraint: NewPlaced(word), Weight: 1})
+	}
+	cost := csp.SoftCost(problem, soft)
+
+	// the shortest words first, as each takes the least room from the rest
+	shortest := append([]Word{}, Words...)
+	sort.SliceStable(shortest, func(i, j int) bool { return len(shortest[i]) < len(shortest[j]) })
+
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = inOrder(shortest)
+	bt.OrderValues = csp.CheapestValue(problem, cost)
+	if Overlap {
+		// crossing placements first, but still all before leaving the word off
+		bt.OrderValues = crossingFirst
+	}
+	bt.Timeout = Timeout
+	bt.Observe(csp.Hooks[Word, Placement]{
+		OnSolution: func(solution map[Word]Placement) {
+			fmt.Printf("Found a placement of %d words after %d nodes\n", len(Words)-cost(solution), bt.Stats().Nodes)
+		},
+	})
+	// the words too long for the grid are left off from the start, so that
+	// the bound counts them
+	candidate := map[Word]Placement{}
+	for _, word := range Words {
+		if len(Placements[word]) == 1 {
+			candidate[word] = Placement{}
+		}
+	}
+	result, unplaced := bt.Minimize(candidate, cost)
+	stats := bt.Stats()
+
+	placed := map[Word]Placement{}
+	var left []string
+	for _, word := range Words {
+		if result[word].placed() {
+			placed[word] = result[word]
+		} else {
+			left = append(left, string(word))
 		}
-		fmt.Println()
 	}
+	fmt.Printf("Solution: %d of %d words on a grid of %d by %d, with -seed %d\n", len(Words)-unplaced, len(Words), GridSize, GridSize, Seed)
+	printSolution(placed)
+	if len(left) > 0 {
+		fmt.Printf("Left off: %s\n", strings.Join(left, ", "))
+	}
+	if stats.TimedOut {
+		fmt.Printf("Perhaps not the most words possible, as the search timed out after %s\n", Timeout)
+	} else {
+		fmt.Println("The most words possible, as no placement fits more")
+	}
+	fmt.Printf("%d nodes, %d backtracks in %s\n", stats.Nodes, stats.Backtracks, stats.Duration)
 }
 
 // model puzzle the word placement problem using CSP framework + Go generics
 func main() {
-	// create CSP framework instance, populate
-	problem := csp.New(Placements, SatisfiesConstraint)
-	for _, wordToPlace := range Constraints {
-		problem.AddConstraint(wordToPlace)
+	flag.Parse()
+
+	if WordsFile != "" {
+		words, err := readWords(WordsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", WordsFile, err)
+			os.Exit(2)
+		}
+		Words = words
+	}
+	if GridSize < 1 {
+		fmt.Fprintf(os.Stderr, "error: invalid -size %d\n", GridSize)
+		os.Exit(2)
+	}
+	if PrintFile != "" {
+		if err := checkPrintFile(PrintFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(2)
+		}
+	}
+	if Maximize && TUI {
+		fmt.Fprintf(os.Stderr, "error: -tui can't be combined with -maximize\n")
+		os.Exit(2)
+	}
+	if err := checkFit(Words, GridSize); err != nil && !Maximize {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+	if Seed == 0 {
+		Seed = time.Now().UnixNano()
+	}
+	rand.Seed(Seed)
+	setup()
+	if Maximize {
+		maximize()
+		return
 	}
 
 	// init empty solution to begin search through problem space
 	candidate := map[Word]Placement{}
 
 	// find ONE possible solution, and display it, if it exists
-	if result := problem.Solve(candidate); result != nil {
-		fmt.Println("Solution:")
-		renderGrid(result)
+	var result map[Word]Placement
+	bt := newBacktracker(Words)
+	if TUI {
+		// random filler would flicker as the board is redrawn
+		draw := func(candidate map[Word]Placement) string {
+			return drawGrid(candidate, fillGrid(candidate, func() rune { return '.' }))
+		}
+		var err error
+		if result, err = tui.New("Word placement", draw).Run(bt, candidate); err != nil {
+			panic(err)
+		}
+		if bt.Stats().Canceled {
+			return
+		}
+	} else {
+		result = bt.Solve(candidate)
+	}
+	if result != nil {
+		fmt.Printf("Solution: %d words on a grid of %d by %d, with -seed %d\n", len(Words), GridSize, GridSize, Seed)
+		printSolution(result)
 		return
 	}
 
-	panic("No solution found")
+	stats := bt.Stats()
+	fmt.Printf("No solution found (%d nodes, %d backtracks)\n", stats.Nodes, stats.Backtracks)
+	if word, found := infeasibleWord(Words); found {
+		fmt.Printf("The words listed before %s fit, but leave no room for it\n", word)
+	}
+	os.Exit(1)
 }
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// the size of a grid cell in the printed images, in pixels
+	cellSize = 40
+
+	// the space around the grid, and between it and the word list
+	margin = 40
+
+	// the words listed per column under the grid
+	wordsPerColumn = 6
+)
+
+// the colors the answer key rings the words in, as #rrggbb
+var keyColors = []string{"#e41a1c", "#377eb8", "#4daf4a", "#984ea3", "#ff7f00", "#a65628", "#f781bf", "#999999"}
+
+// a printable word search: the grid's letters, hidden words and filler
+// alike, and where each word was placed
+type sheet struct {
+	letters [][]rune
+	result  map[Word]Placement
+}
+
+// the words to find, in alphabetical order, as listed under the grid
+func (s sheet) words() []Word {
+	var out []Word
+	for word := range s.result {
+		out = append(out, word)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// the width of a column of the word list, long enough for the longest word
+func (s sheet) columnWidth() int {
+	longest := 0
+	for word := range s.result {
+		if len(word) > longest {
+			longest = len(word)
+		}
+	}
+	return (longest + 2) * cellSize / 2
+}
+
+// the size of the whole page, in pixels
+func (s sheet) bounds() (int, int) {
+	words := len(s.result)
+	columns := (words + wordsPerColumn - 1) / wordsPerColumn
+	width := len(s.letters) * cellSize
+	if listed := columns * s.columnWidth(); listed > width {
+		width = listed
+	}
+	rows := words
+	if rows > wordsPerColumn {
+		rows = wordsPerColumn
+	}
+	return width + 2*margin, len(s.letters)*cellSize + rows*cellSize/2 + 3*margin
+}
+
+// the top left corner of the nth word in the list
+func (s sheet) listed(n int) (int, int) {
+	return margin + n/wordsPerColumn*s.columnWidth(), len(s.letters)*cellSize + 2*margin + n%wordsPerColumn*cellSize/2
+}
+
+// the center of a cell of the grid
+func center(p Point) (int, int) {
+	return margin + p.Col*cellSize + cellSize/2, margin + p.Row*cellSize + cellSize/2
+}
+
+// write the puzzle as an SVG image, ringing each word if it's the key
+func (s sheet) writeSVG(w io.Writer, key bool) error {
+	width, height := s.bounds()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", width, height, width, height)
+	fmt.Fprintf(&sb, "  <rect width=\"%d\" height=\"%d\" fill=\"white\"/>\n", width, height)
+	fmt.Fprintf(&sb, "  <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"black\"/>\n",
+		margin, margin, len(s.letters)*cellSize, len(s.letters)*cellSize)
+	if key {
+		for ndx, word := range s.words() {
+			points := s.result[word].Points
+			x1, y1 := center(points[0])
+			x2, y2 := center(points[len(points)-1])
+			fmt.Fprintf(&sb, "  <line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%s\" stroke-opacity=\"0.4\" stroke-width=\"%d\" stroke-linecap=\"round\"/>\n",
+				x1, y1, x2, y2, keyColors[ndx%len(keyColors)], cellSize*3/4)
+		}
+	}
+	for row, letters := range s.letters {
+		for col, letter := range letters {
+			x, y := center(Point{Row: row, Col: col})
+			fmt.Fprintf(&sb, "  <text x=\"%d\" y=\"%d\" text-anchor=\"middle\" dominant-baseline=\"central\" font-family=\"monospace\" font-size=\"%d\">%c</text>\n",
+				x, y, cellSize/2, letter)
+		}
+	}
+	for ndx, word := range s.words() {
+		x, y := s.listed(ndx)
+		fmt.Fprintf(&sb, "  <text x=\"%d\" y=\"%d\" dominant-baseline=\"hanging\" font-family=\"monospace\" font-size=\"%d\">%s</text>\n",
+			x, y, cellSize*3/8, word)
+	}
+	sb.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// write the puzzle as a PNG image, ringing each word if it's the key. the
+// letters are drawn from a built-in bitmap font, scaled up
+func (s sheet) writePNG(w io.Writer, key bool) error {
+	width, height := s.bounds()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	black := color.RGBA{A: 0xff}
+	side := len(s.letters) * cellSize
+	for d := 0; d <= side; d++ {
+		img.Set(margin+d, margin, black)
+		img.Set(margin+d, margin+side, black)
+		img.Set(margin, margin+d, black)
+		img.Set(margin+side, margin+d, black)
+	}
+	if key {
+		for ndx, word := range s.words() {
+			points := s.result[word].Points
+			x1, y1 := center(points[0])
+			x2, y2 := center(points[len(points)-1])
+			ring(img, x1, y1, x2, y2, cellSize*3/8, parseHex(keyColors[ndx%len(keyColors)]))
+		}
+	}
+	for row, letters := range s.letters {
+		for col, letter := range letters {
+			x, y := center(Point{Row: row, Col: col})
+			drawGlyph(img, letter, x-5*3/2, y-7*3/2, 3, black)
+		}
+	}
+	for ndx, word := range s.words() {
+		x, y := s.listed(ndx)
+		for i, letter := range word {
+			drawGlyph(img, letter, x+i*6*2, y, 2, black)
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// blend a color over every pixel within radius of the segment between two
+// points, as a highlighter pen would
+func ring(img *image.RGBA, x1, y1, x2, y2, radius int, c color.RGBA) {
+	minX, maxX, minY, maxY := x1, x2, y1, y2
+	if minX > maxX {
+		minX, maxX = maxX, minX
+	}
+	if minY > maxY {
+		minY, maxY = maxY, minY
+	}
+	dx, dy := float64(x2-x1), float64(y2-y1)
+	length := dx*dx + dy*dy
+	for y := minY - radius; y <= maxY+radius; y++ {
+		for x := minX - radius; x <= maxX+radius; x++ {
+			// the distance from the nearest point of the segment
+			t := 0.0
+			if length > 0 {
+				t = (float64(x-x1)*dx + float64(y-y1)*dy) / length
+			}
+			if t < 0 {
+				t = 0
+			} else if t > 1 {
+				t = 1
+			}
+			ex, ey := float64(x1)+t*dx-float64(x), float64(y1)+t*dy-float64(y)
+			if ex*ex+ey*ey > float64(radius*radius) {
+				continue
+			}
+			at := img.RGBAAt(x, y)
+			img.SetRGBA(x, y, color.RGBA{
+				R: uint8((int(at.R)*3 + int(c.R)*2) / 5),
+				G: uint8((int(at.G)*3 + int(c.G)*2) / 5),
+				B: uint8((int(at.B)*3 + int(c.B)*2) / 5),
+				A: 0xff,
+			})
+		}
+	}
+}
+
+func parseHex(hex string) color.RGBA {
+	var r, g, b uint8
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	return color.RGBA{R: r, G: g, B: b, A: 0xff}
+}
+
+// the capital letters of a 5x7 bitmap font, a row of # and . per line
+var glyphs = map[rune][7]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G': {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I': {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q': {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S': {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+}
+
+// draw a letter with its top left corner at x, y, each dot of the font a
+// square of scale pixels
+func drawGlyph(img *image.RGBA, letter rune, x, y, scale int, c color.RGBA) {
+	for row, line := range glyphs[letter] {
+		for col, dot := range line {
+			if dot != '#' {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetRGBA(x+col*scale+dx, y+row*scale+dy, c)
+				}
+			}
+		}
+	}
+}
+
+// the name of the answer key written alongside the puzzle, e.g.
+// puzzle-key.svg for puzzle.svg
+func keyName(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-key" + ext
+}
+
+// check that the named file is one the puzzle can be printed to, before
+// the search for it
+func checkPrintFile(name string) error {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".svg", ".png":
+		return nil
+	}
+	return fmt.Errorf("%s: not a .svg or .png file", name)
+}
+
+// write the puzzle to the named file, an SVG or PNG image by its
+// extension, and its answer key alongside
+func (s sheet) print(name string) error {
+	if err := checkPrintFile(name); err != nil {
+		return err
+	}
+	write := s.writeSVG
+	if strings.ToLower(filepath.Ext(name)) == ".png" {
+		write = s.writePNG
+	}
+
+	for _, page := range []struct {
+		name string
+		key  bool
+	}{{name, false}, {keyName(name), true}} {
+		file, err := os.Create(page.name)
+		if err == nil {
+			err = write(file, page.key)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/render"
+)
+
+// Attribute is one of the things that belongs to a house: its color,
+// its owner's nationality, drink, smoke or pet
+type Attribute string
+
+// House numbers the houses from 1 to 5, left to right
+type House int
+
+var (
+	// CSP variables, by category: each category's attributes go to
+	// different houses
+	Categories = []struct {
+		Name       string
+		Attributes []Attribute
+	}{
+		{"Color", []Attribute{"Red", "Green", "Ivory", "Yellow", "Blue"}},
+		{"Nationality", []Attribute{"Englishman", "Spaniard", "Ukrainian", "Norwegian", "Japanese"}},
+		{"Drink", []Attribute{"Coffee", "Tea", "Milk", "Orange juice", "Water"}},
+		{"Smoke", []Attribute{"Old Gold", "Kools", "Chesterfields", "Lucky Strike", "Parliaments"}},
+		{"Pet", []Attribute{"Dog", "Snails", "Fox", "Horse", "Zebra"}},
+	}
+
+	// CSP domains
+	Houses = []House{1, 2, 3, 4, 5}
+
+	// CSP constraints: the clues, as Life International published them in 1962
+	Constraints = []csp.Constraint[Attribute]{
+		// 2. the Englishman lives in the red house
+		csp.Equal[Attribute]("Englishman", "Red"),
+		// 3. the Spaniard owns the dog
+		csp.Equal[Attribute]("Spaniard", "Dog"),
+		// 4. coffee is drunk in the green house
+		csp.Equal[Attribute]("Coffee", "Green"),
+		// 5. the Ukrainian drinks tea
+		csp.Equal[Attribute]("Ukrainian", "Tea"),
+		// 6. the green house is immediately to the right of the ivory house
+		csp.Linear([]Attribute{"Green", "Ivory"}, []int{1, -1}, csp.Eq, 1),
+		// 7. the Old Gold smoker owns snails
+		csp.Equal[Attribute]("Old Gold", "Snails"),
+		// 8. Kools are smoked in the yellow house
+		csp.Equal[Attribute]("Kools", "Yellow"),
+		// 11. the man who smokes Chesterfields lives in the house next to the man with the fox
+		csp.AbsDiff[Attribute]("Chesterfields", "Fox", csp.Eq, 1),
+		// 12. Kools are smoked in the house next to the house where the horse is kept
+		csp.AbsDiff[Attribute]("Kools", "Horse", csp.Eq, 1),
+		// 13. the Lucky Strike smoker drinks orange juice
+		csp.Equal[Attribute]("Lucky Strike", "Orange juice"),
+		// 14. the Japanese smokes Parliaments
+		csp.Equal[Attribute]("Japanese", "Parliaments"),
+		// 15. the Norwegian lives next to the blue house
+		csp.AbsDiff[Attribute]("Norwegian", "Blue", csp.Eq, 1),
+	}
+
+	// the clues that give a house outright, which the domains encode
+	Fixed = map[Attribute]House{
+		// 9. milk is drunk in the middle house
+		"Milk": 3,
+		// 10. the Norwegian lives in the first house
+		"Norwegian": 1,
+	}
+)
+
+// tabulate the solution with a row per category and a column per house
+func drawTable(result map[Attribute]House) string {
+	at := map[string]map[House]Attribute{}
+	for _, category := range Categories {
+		at[category.Name] = map[House]Attribute{}
+		for _, a := range category.Attributes {
+			at[category.Name][result[a]] = a
+		}
+	}
+
+	return render.Grid{
+		Rows: len(Categories) + 1,
+		Cols: len(Houses) + 1,
+		Cell: func(row, col int) render.Cell {
+			switch {
+			case row == 0 && col == 0:
+				return render.Cell{}
+			case row == 0:
+				return render.Cell{Text: fmt.Sprint("House ", col)}
+			case col == 0:
+				return render.Cell{Text: Categories[row-1].Name}
+			}
+			a := at[Categories[row-1].Name][House(col)]
+			if a == "Water" || a == "Zebra" {
+				return render.Cell{Text: string(a), Color: render.Cyan}
+			}
+			return render.Cell{Text: string(a)}
+		},
+	}.String()
+}
+
+// model the zebra puzzle using CSP framework + Go generics
+func main() {
+	// assemble mapping of variables to a set of possible
+	// values to search for a valid solution
+	domain := map[Attribute][]House{}
+	for _, category := range Categories {
+		for _, a := range category.Attributes {
+			if house, found := Fixed[a]; found {
+				domain[a] = []House{house}
+			} else {
+				domain[a] = Houses
+			}
+		}
+	}
+
+	// create CSP framework instance, populate
+	problem := csp.New[Attribute, House](domain, nil)
+	for _, category := range Categories {
+		// 1. there are five houses, one of each kind
+		problem.AddConstraint(csp.AllDifferent(category.Attributes...))
+	}
+	for _, clue := range Constraints {
+		problem.AddConstraint(clue)
+	}
+
+	// find ONE possible solution, and display it, if it exists
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	result := bt.Solve(map[Attribute]House{})
+	if result == nil {
+		panic("No solution found")
+	}
+
+	fmt.Println("Solution:")
+	fmt.Print(drawTable(result))
+	for _, question := range []struct {
+		Attribute Attribute
+		Verb      string
+	}{{"Water", "drinks"}, {"Zebra", "owns the"}} {
+		for _, owner := range Categories[1].Attributes {
+			if result[owner] == result[question.Attribute] {
+				fmt.Printf("The %s %s %s\n", owner, question.Verb, strings.ToLower(string(question.Attribute)))
+			}
+		}
+	}
+}
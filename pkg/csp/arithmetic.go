@@ -0,0 +1,128 @@
+package csp
+
+import "fmt"
+
+// require the absolute difference of a and b to compare to the constant
+// as the operator specifies, e.g. |a - b| != 1
+func AbsDiff[V comparable](a, b V, operator Operator, constant int) Constraint[V] {
+	// strict inequalities are rewritten in terms of <= and >=
+	var relation Relation
+	switch operator {
+	case Eq:
+		relation = RelationAbsDiffEq
+	case Ne:
+		relation = RelationAbsDiffNe
+	case Le:
+		relation = RelationAbsDiffLe
+	case Lt:
+		relation, constant = RelationAbsDiffLe, constant-1
+	case Ge:
+		relation = RelationAbsDiffGe
+	case Gt:
+		relation, constant = RelationAbsDiffGe, constant+1
+	default:
+		panic(fmt.Sprintf("error: unknown operator %q", operator))
+	}
+
+	return Constraint[V]{
+		Variables: []V{a, b},
+		Relation:  relation,
+		Args:      []int{constant},
+	}
+}
+
+// require the product of the variables to equal the constant
+func Product[V comparable](variables []V, constant int) Constraint[V] {
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationProduct,
+		Args:      []int{constant},
+	}
+}
+
+// require a divided by b, or b divided by a, to equal the constant with
+// no remainder, e.g. 6 and 2 for a constant of 3
+func Quotient[V comparable](a, b V, constant int) Constraint[V] {
+	return Constraint[V]{
+		Variables: []V{a, b},
+		Relation:  RelationQuotient,
+		Args:      []int{constant},
+	}
+}
+
+// compare the difference once both variables are assigned
+func checkAbsDiff(compare func(diff, constant int) bool) relationFn {
+	return func(args []int, _ int, value func(ndx int) (int, bool)) bool {
+		a, assignedA := value(0)
+		b, assignedB := value(1)
+		if !assignedA || !assignedB {
+			return true
+		}
+
+		diff := a - b
+		if diff < 0 {
+			diff = -diff
+		}
+		return compare(diff, args[0])
+	}
+}
+
+// compare the product once every variable is assigned. before then, the
+// assigned values must still divide the constant, since the product of
+// the rest is an integer too
+func checkProduct(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	product, complete := 1, true
+	for ndx := 0; ndx < arity; ndx++ {
+		if v, assigned := value(ndx); assigned {
+			product *= v
+		} else {
+			complete = false
+		}
+	}
+
+	constant := args[0]
+	switch {
+	case complete:
+		return product == constant
+	case constant == 0:
+		return true
+	case product == 0:
+		return false
+	}
+	return constant%product == 0
+}
+
+func checkQuotient(args []int, _ int, value func(ndx int) (int, bool)) bool {
+	a, assignedA := value(0)
+	b, assignedB := value(1)
+	if !assignedA || !assignedB {
+		return true
+	}
+
+	c := args[0]
+	return a == b*c || b == a*c
+}
+
+func validAbsDiff(args []int, arity int) error {
+	if arity != 2 || len(args) != 1 {
+		return fmt.Errorf("absolute difference relates 2 variables to 1 argument, got %d and %d", arity, len(args))
+	}
+	return nil
+}
+
+func validProduct(args []int, arity int) error {
+	if arity == 0 || len(args) != 1 {
+		return fmt.Errorf("product relates its variables to 1 argument, got %d", len(args))
+	}
+	return nil
+}
+
+func validQuotient(args []int, arity int) error {
+	if arity != 2 || len(args) != 1 {
+		return fmt.Errorf("quotient relates 2 variables to 1 argument, got %d and %d", arity, len(args))
+	}
+	if args[0] == 0 {
+		return fmt.Errorf("quotient can't be 0")
+	}
+	return nil
+}
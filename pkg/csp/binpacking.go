@@ -0,0 +1,59 @@
+package csp
+
+import "fmt"
+
+// require the items packed into each bin to fit it: each variable is the
+// bin its item goes in, and the sum of the sizes of the items in any one
+// bin can't exceed the capacity, e.g. parcels loaded onto vans
+func BinPacking[V comparable](items []V, sizes []int, capacity int) Constraint[V] {
+	if len(items) != len(sizes) {
+		panic(fmt.Sprintf("error: bin packing constraint over %d items given %d sizes", len(items), len(sizes)))
+	}
+
+	return Constraint[V]{
+		Variables: items,
+		Relation:  RelationBinPacking,
+		Args:      append([]int{capacity}, sizes...),
+	}
+}
+
+// report whether every bin's load so far is within the capacity. the
+// loads are kept by bin in a buffer on the stack, for the usual handful
+// of bins, rather than a map, which would be made at every check
+func checkBinPacking(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	capacity := args[0]
+	var bufBins, bufLoads [32]int
+	bins, loads := bufBins[:0], bufLoads[:0]
+	for ndx := 0; ndx < arity; ndx++ {
+		bin, assigned := value(ndx)
+		if !assigned {
+			continue
+		}
+		at := 0
+		for at < len(bins) && bins[at] != bin {
+			at++
+		}
+		if at == len(bins) {
+			bins, loads = append(bins, bin), append(loads, 0)
+		}
+		if loads[at] += args[1+ndx]; loads[at] > capacity {
+			return false
+		}
+	}
+	return true
+}
+
+func validBinPacking(args []int, arity int) error {
+	if len(args) != 1+arity {
+		return fmt.Errorf("bin packing takes a capacity and a size per item, got %d arguments for %d items", len(args), arity)
+	}
+	if args[0] < 0 {
+		return fmt.Errorf("bin packing capacity can't be negative, got %d", args[0])
+	}
+	for ndx, size := range args[1:] {
+		if size < 0 {
+			return fmt.Errorf("bin packing item %d has negative size %d", ndx, size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package csp
+
+import "fmt"
+
+// start an empty Model, to build up in code rather than write out as a
+// document, and then Build into a Problem:
+//
+//	m := csp.NewModel()
+//	x, y, z := m.IntVar("x", 1, 9), m.IntVar("y", 1, 9), m.IntVar("z", 1, 9)
+//	m.AllDifferent(x, y, z)
+//	m.Post(x.Plus(y).Eq(z))
+//	problem, err := m.Build()
+//
+// a mistake along the way, such as declaring a variable twice, is kept
+// and returned by Build, so that the model reads as a list of statements
+// rather than one error check after another
+func NewModel() *Model {
+	return &Model{}
+}
+
+// Expr is an integer expression over a Model's variables: a sum of them,
+// each times a coefficient, plus a constant, and of any products and
+// absolute values of other expressions, from Mul and Abs. declaring a
+// variable with IntVar gives the expression of the variable alone
+type Expr struct {
+	names        []string
+	coefficients []int
+	constant     int
+	terms        []term
+}
+
+// the expression of a constant
+func Const(n int) Expr {
+	return Expr{constant: n}
+}
+
+// declare a variable taking the values from min to max, inclusive, and
+// return its expression
+func (m *Model) IntVar(name string, min, max int) Expr {
+	m.declare(ModelVariable{Name: name, Min: &min, Max: &max})
+	return variableExpr(name)
+}
+
+// declare a variable taking the given values, and return its expression
+func (m *Model) IntVarIn(name string, values ...int) Expr {
+	m.declare(ModelVariable{Name: name, Domain: append([]int{}, values...)})
+	return variableExpr(name)
+}
+
+// the expression of a variable already declared, such as one of a Model
+// read from a file, to Post more about it
+func (m *Model) Var(name string) Expr {
+	if _, err := m.domainOf(name); err != nil {
+		m.fail(err)
+	}
+	return variableExpr(name)
+}
+
+// the expression of a variable alone
+func variableExpr(name string) Expr {
+	return Expr{names: []string{name}, coefficients: []int{1}}
+}
+
+func (m *Model) declare(mv ModelVariable) {
+	for _, declared := range m.Variables {
+		if declared.Name == mv.Name {
+			m.fail(fmt.Errorf("variable %q declared twice", mv.Name))
+			return
+		}
+	}
+	m.Variables = append(m.Variables, mv)
+}
+
+// keep the first mistake made building the model, for Build to return
+func (m *Model) fail(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// add conditions to the model, e.g. comparisons of expressions, each as
+// the constraints whose propagators fit it, rather than leaving it to the
+// model to pick them. see compile for which fit what
+func (m *Model) Post(conds ...Cond) {
+	for _, c := range conds {
+		constraints, err := m.compile(c)
+		if err != nil {
+			m.fail(fmt.Errorf("%s: %w", c, err))
+			continue
+		}
+		m.Constraints = append(m.Constraints, constraints...)
+	}
+}
+
+// require the expressions to take different values. each must be a
+// variable, or a variable plus a constant, its offset, as for the
+// diagonals of N-Queens, e.g. q.Plus(csp.Const(row))
+func (m *Model) AllDifferent(exprs ...Expr) {
+	mc := ModelConstraint{Type: "alldifferent"}
+	offsets := false
+	for _, e := range exprs {
+		if len(e.names) != 1 || e.coefficients[0] != 1 || len(e.terms) > 0 {
+			m.fail(fmt.Errorf("alldifferent: %s isn't a variable plus a constant", e))
+			return
+		}
+		mc.Variables = append(mc.Variables, e.names[0])
+		mc.Offsets = append(mc.Offsets, e.constant)
+		offsets = offsets || e.constant != 0
+	}
+	if !offsets {
+		mc.Offsets = nil
+	}
+	m.Constraints = append(m.Constraints, mc)
+}
+
+// the expression plus another
+func (e Expr) Plus(other Expr) Expr {
+	out := Expr{
+		names:        append([]string{}, e.names...),
+		coefficients: append([]int{}, e.coefficients...),
+		constant:     e.constant + other.constant,
+		terms:        append(append([]term{}, e.terms...), other.terms...),
+	}
+	for ndx, name := range other.names {
+		out.add(name, other.coefficients[ndx])
+	}
+	return out
+}
+
+// the expression less another
+func (e Expr) Minus(other Expr) Expr {
+	return e.Plus(other.Times(-1))
+}
+
+// the expression times a constant
+func (e Expr) Times(k int) Expr {
+	out := Expr{constant: e.constant * k}
+	for ndx, name := range e.names {
+		out.add(name, e.coefficients[ndx]*k)
+	}
+	for _, t := range e.terms {
+		if k != 0 {
+			t.coefficient *= k
+			out.terms = append(out.terms, t)
+		}
+	}
+	return out
+}
+
+// add a term, merging it with any of the same variable, and dropping
+// the variable if they cancel out
+func (e *Expr) add(name string, coefficient int) {
+	for ndx := range e.names {
+		if e.names[ndx] != name {
+			continue
+		}
+		e.coefficients[ndx] += coefficient
+		if e.coefficients[ndx] == 0 {
+			e.names = append(e.names[:ndx], e.names[ndx+1:]...)
+			e.coefficients = append(e.coefficients[:ndx], e.coefficients[ndx+1:]...)
+		}
+		return
+	}
+	if coefficient != 0 {
+		e.names = append(e.names, name)
+		e.coefficients = append(e.coefficients, coefficient)
+	}
+}
+
+// require the expression to equal another
+func (e Expr) Eq(other Expr) Cond { return e.compare(Eq, other) }
+
+// require the expression to differ from another
+func (e Expr) Ne(other Expr) Cond { return e.compare(Ne, other) }
+
+// require the expression to be less than another
+func (e Expr) Lt(other Expr) Cond { return e.compare(Lt, other) }
+
+// require the expression to be at most another
+func (e Expr) Le(other Expr) Cond { return e.compare(Le, other) }
+
+// require the expression to be greater than another
+func (e Expr) Gt(other Expr) Cond { return e.compare(Gt, other) }
+
+// require the expression to be at least another
+func (e Expr) Ge(other Expr) Cond { return e.compare(Ge, other) }
+
+// the condition comparing the expressions, as their difference compared
+// to 0
+func (e Expr) compare(operator Operator, other Expr) Cond {
+	return Cond{op: "cmp", operator: operator, diff: e.Minus(other)}
+}
+
+// the expression as it would be written, e.g. 2*x - y*z + 3
+func (e Expr) String() string {
+	out := ""
+	write := func(k int, text string) {
+		switch {
+		case out != "" && k < 0:
+			out += " - "
+			k = -k
+		case out != "":
+			out += " + "
+		case k < 0:
+			out += "-"
+			k = -k
+		}
+		if k != 1 {
+			out += fmt.Sprintf("%d*", k)
+		}
+		out += text
+	}
+	for ndx, name := range e.names {
+		write(e.coefficients[ndx], name)
+	}
+	for _, t := range e.terms {
+		write(t.coefficient, t.String())
+	}
+	switch {
+	case out == "":
+		return fmt.Sprint(e.constant)
+	case e.constant < 0:
+		return fmt.Sprintf("%s - %d", out, -e.constant)
+	case e.constant > 0:
+		return fmt.Sprintf("%s + %d", out, e.constant)
+	}
+	return out
+}
+
+// declare n variables taking the values from min to max, named for their
+// positions, e.g. x[0] to x[n-1], and return their expressions
+func (m *Model) IntVarArray(name string, n, min, max int) []Expr {
+	out := make([]Expr, n)
+	for ndx := range out {
+		out[ndx] = m.IntVar(fmt.Sprintf("%s[%d]", name, ndx), min, max)
+	}
+	return out
+}
+
+// Matrix is a grid of a Model's variables, by row and then column, as
+// grid puzzles and timetables are laid out
+type Matrix [][]Expr
+
+// declare a rows by cols grid of variables taking the values from min to
+// max, named for their cells, e.g. cell[0][0] to cell[8][8], and return
+// their expressions
+func (m *Model) IntVarMatrix(name string, rows, cols, min, max int) Matrix {
+	out := make(Matrix, rows)
+	for row := range out {
+		out[row] = make([]Expr, cols)
+		for col := range out[row] {
+			out[row][col] = m.IntVar(fmt.Sprintf("%s[%d][%d]", name, row, col), min, max)
+		}
+	}
+	return out
+}
+
+// the expressions of a row, left to right
+func (mx Matrix) Row(row int) []Expr {
+	return append([]Expr{}, mx[row]...)
+}
+
+// the expressions of a column, top to bottom
+func (mx Matrix) Col(col int) []Expr {
+	out := make([]Expr, len(mx))
+	for row := range mx {
+		out[row] = mx[row][col]
+	}
+	return out
+}
+
+// the expressions of the main diagonal, from the top left corner down
+// and right, as far as the shorter side
+func (mx Matrix) Diagonal() []Expr {
+	var out []Expr
+	for ndx := 0; ndx < len(mx) && ndx < len(mx[ndx]); ndx++ {
+		out = append(out, mx[ndx][ndx])
+	}
+	return out
+}
+
+// the expressions of the anti-diagonal, from the top right corner down
+// and left, as far as the shorter side
+func (mx Matrix) AntiDiagonal() []Expr {
+	var out []Expr
+	for ndx := 0; ndx < len(mx); ndx++ {
+		col := len(mx[ndx]) - 1 - ndx
+		if col < 0 {
+			break
+		}
+		out = append(out, mx[ndx][col])
+	}
+	return out
+}
+
+// the expressions of the block of height by width cells with its top left
+// corner at row and col, row by row, e.g. a box of a sudoku
+func (mx Matrix) Block(row, col, height, width int) []Expr {
+	var out []Expr
+	for r := row; r < row+height; r++ {
+		out = append(out, mx[r][col:col+width]...)
+	}
+	return out
+}
+
+// the expressions of every cell, row by row
+func (mx Matrix) Flat() []Expr {
+	var out []Expr
+	for _, row := range mx {
+		out = append(out, row...)
+	}
+	return out
+}
+
+// the expression of the sum of the expressions
+func SumOf(exprs ...Expr) Expr {
+	out := Const(0)
+	for _, e := range exprs {
+		out = out.Plus(e)
+	}
+	return out
+}
+
+// the value of the expression under a solution of the Model's Problem
+func (e Expr) Value(solution map[string]int) int {
+	n := e.constant
+	for ndx, name := range e.names {
+		n += e.coefficients[ndx] * solution[name]
+	}
+	for _, t := range e.terms {
+		n += t.coefficient * t.value(solution)
+	}
+	return n
+}
@@ -0,0 +1,69 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the lexicographically least solution extending the assignment, so that
+// the result is canonical, the same from run to run and version to
+// version: the first of the variables takes the least value any solution
+// gives it, the second the least of those solutions give it, and so on.
+// the variables are ordered as given, and any left out after them in
+// order of their printed form, as Lexicographic orders them; values are
+// ordered by less, or if it's nil, as integers if D is an integer type
+// and else by their printed form. the search takes the variables in that
+// order, whatever SelectVariable would pick, and tries each one's values
+// least first, so OrderValues, if set, may only rule out values, such as
+// MaintainArcConsistency does, that lead to no solution
+func (b *Backtracker[V, D]) SolveCanonical(assignment map[V]D, variables []V, less func(a, b D) bool) map[V]D {
+	if less == nil {
+		less = defaultLess[D]()
+	}
+
+	listed := map[V]bool{}
+	ordered := append([]V{}, variables...)
+	for _, v := range variables {
+		listed[v] = true
+	}
+	var rest []V
+	for v := range b.Problem.Domain {
+		if !listed[v] {
+			rest = append(rest, v)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return fmt.Sprintf("%+v", rest[i]) < fmt.Sprintf("%+v", rest[j])
+	})
+	ordered = append(ordered, rest...)
+
+	selectVariable, order := b.SelectVariable, b.OrderValues
+	defer func() { b.SelectVariable, b.OrderValues = selectVariable, order }()
+	b.SelectVariable = func(assignment map[V]D) V {
+		for _, v := range ordered {
+			if _, found := assignment[v]; !found {
+				return v
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+	b.OrderValues = func(variable V, assignment map[V]D) []D {
+		values := b.Problem.Domain[variable]
+		if order != nil {
+			values = order(variable, assignment)
+		}
+		out := append([]D{}, values...)
+		sort.SliceStable(out, func(i, j int) bool { return less(out[i], out[j]) })
+		return out
+	}
+
+	return b.Solve(assignment)
+}
+
+// the order of values SolveCanonical uses by default
+func defaultLess[D any]() func(a, b D) bool {
+	if isIntegerKind(kindOf[D]()) {
+		return func(a, b D) bool { return asInt(a) < asInt(b) }
+	}
+	return func(a, b D) bool { return fmt.Sprintf("%+v", a) < fmt.Sprintf("%+v", b) }
+}
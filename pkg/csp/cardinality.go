@@ -0,0 +1,79 @@
+package csp
+
+import "fmt"
+
+// require each of the given values to be taken by between low and high of
+// the variables, inclusive, e.g. a day's rota needing two or three nurses
+// on the night shift. values not listed may be taken by any number
+func GlobalCardinality[V comparable](variables []V, values, low, high []int) Constraint[V] {
+	if len(values) != len(low) || len(values) != len(high) {
+		panic(fmt.Sprintf("error: gcc constraint given %d values, %d lower and %d upper bounds", len(values), len(low), len(high)))
+	}
+
+	args := make([]int, 0, 3*len(values))
+	for ndx := range values {
+		args = append(args, values[ndx], low[ndx], high[ndx])
+	}
+
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationGlobalCardinality,
+		Args:      args,
+	}
+}
+
+// require the value to be taken by exactly count of the variables
+func Count[V comparable](variables []V, value, count int) Constraint[V] {
+	return GlobalCardinality(variables, []int{value}, []int{count}, []int{count})
+}
+
+// report whether the values assigned so far leave every listed value
+// within its bounds: taken no more than its upper bound, and by few enough
+// less than its lower bound that the unassigned variables can make it up
+func checkGlobalCardinality(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	unassigned := 0
+	for ndx := 0; ndx < arity; ndx++ {
+		if _, assigned := value(ndx); !assigned {
+			unassigned++
+		}
+	}
+
+	// the unassigned variables can only make up so many shortfalls. each
+	// listed value is counted by a pass of its own, so as not to make a
+	// map of the counts at every check
+	short := 0
+	for start := 0; start < len(args); start += 3 {
+		n := 0
+		for ndx := 0; ndx < arity; ndx++ {
+			if v, assigned := value(ndx); assigned && v == args[start] {
+				n++
+			}
+		}
+		low, high := args[start+1], args[start+2]
+		if n > high {
+			return false
+		}
+		if n < low {
+			short += low - n
+		}
+	}
+	return short <= unassigned
+}
+
+func validGlobalCardinality(args []int, arity int) error {
+	if len(args)%3 != 0 {
+		return fmt.Errorf("gcc takes a value, lower and upper bound per value, got %d arguments", len(args))
+	}
+	seen := map[int]bool{}
+	for start := 0; start < len(args); start += 3 {
+		v, low, high := args[start], args[start+1], args[start+2]
+		if seen[v] {
+			return fmt.Errorf("gcc lists the value %d twice", v)
+		}
+		seen[v] = true
+		if low < 0 || high < low {
+			return fmt.Errorf("gcc bounds for the value %d are out of order: %d to %d", v, low, high)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package csp
+
+import "fmt"
+
+// require the variables to form a single cycle through all of them, where
+// each variable's value is the position of its successor in variables,
+// counting from 0. e.g. with 3 variables, values of 1, 2 and 0 visit them
+// in order; 1, 0 and 2 are two shorter cycles, and fail
+func Circuit[V comparable](variables ...V) Constraint[V] {
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationCircuit,
+	}
+}
+
+// report whether the successors assigned so far can still be completed to
+// a single cycle: each names a position in range, no two name the same
+// one, and following them never closes a cycle before visiting them all
+func checkCircuit(_ []int, arity int, value func(ndx int) (int, bool)) bool {
+	next := make([]int, arity)
+	seen := make([]bool, arity)
+	for ndx := 0; ndx < arity; ndx++ {
+		next[ndx] = -1
+		if v, assigned := value(ndx); assigned {
+			if v < 0 || v >= arity || seen[v] || (v == ndx && arity > 1) {
+				return false
+			}
+			seen[v] = true
+			next[ndx] = v
+		}
+	}
+
+	for start := 0; start < arity; start++ {
+		length, at := 0, start
+		for at >= 0 && length < arity {
+			at = next[at]
+			length++
+			if at == start && length < arity {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validCircuit(args []int, arity int) error {
+	if len(args) != 0 {
+		return fmt.Errorf("circuit takes no arguments, got %d", len(args))
+	}
+	return nil
+}
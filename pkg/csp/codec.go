@@ -0,0 +1,217 @@
+package csp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the canonical text serialization of a Problem[int, int] holds one
+// declaration per line, in a fixed order: a header, each variable with its
+// domain, then each constraint as a built-in relation with its arguments.
+//
+//	csp 1
+//	var 0 : 1 2 3
+//	var 1 : 1 2 3
+//	rel table 0 1 : 1 2 1 3 2 3
+//
+// equal problems always serialize to identical bytes, which makes the
+// format suitable for fuzzing corpora, caching, and diffing
+const codecHeader = "csp 1"
+
+// reformulate the problem over plain integers: variables are numbered in
+// order of their printed form, each domain value is replaced by its index
+// within the domain, and every constraint becomes a table of the index
+// tuples satisfying it. the numbering is returned alongside, so solutions
+// can be mapped back. constraints must only inspect their own Variables
+func (p Problem[V, D]) Extensional() (Problem[int, int], []V) {
+	var variables []V
+	for v := range p.Domain {
+		variables = append(variables, v)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return fmt.Sprintf("%+v", variables[i]) < fmt.Sprintf("%+v", variables[j])
+	})
+
+	number := map[V]int{}
+	domain := map[int][]int{}
+	for ndx, v := range variables {
+		number[v] = ndx
+		indices := make([]int, len(p.Domain[v]))
+		for i := range indices {
+			indices[i] = i
+		}
+		domain[ndx] = indices
+	}
+
+	out := New(domain, nil)
+	for _, constraint := range p.allConstraints() {
+		scope := distinct(constraint.Variables)
+		numbered := make([]int, len(scope))
+		for ndx, v := range scope {
+			numbered[ndx] = number[v]
+		}
+
+		var tuples [][]int
+		for _, tuple := range p.satisfyingTuples(constraint, scope) {
+			tuples = append(tuples, tuple)
+		}
+		out.AddConstraint(Table(numbered, tuples))
+	}
+
+	return out, variables
+}
+
+// write the canonical serialization of the problem. constraints that
+// aren't built-in relations are written as tables of their satisfying
+// tuples, so they must only inspect their own Variables
+func Encode(w io.Writer, p Problem[int, int]) error {
+	var variables []int
+	for v := range p.Domain {
+		variables = append(variables, v)
+	}
+	sort.Ints(variables)
+
+	lines := []string{codecHeader}
+	for _, v := range variables {
+		lines = append(lines, fmt.Sprintf("var %d :%s", v, join(p.Domain[v])))
+	}
+
+	var constraints []string
+	for _, constraint := range p.exportConstraints() {
+		args := constraint.Args
+		if constraint.Relation == RelationTable {
+			args = sortTuples(args, len(constraint.Variables))
+		}
+		constraints = append(constraints, fmt.Sprintf("rel %s%s :%s",
+			constraint.Relation, join(constraint.Variables), join(args)))
+	}
+	sort.Strings(constraints)
+	lines = append(lines, constraints...)
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// order the tuples of a table so that equal tables serialize identically
+func sortTuples(args []int, arity int) []int {
+	tuples := tuples(args, arity)
+	sort.Slice(tuples, func(i, j int) bool {
+		for ndx := range tuples[i] {
+			if tuples[i][ndx] != tuples[j][ndx] {
+				return tuples[i][ndx] < tuples[j][ndx]
+			}
+		}
+		return false
+	})
+
+	out := make([]int, 0, len(args))
+	for _, tuple := range tuples {
+		out = append(out, tuple...)
+	}
+	return out
+}
+
+func join(values []int) string {
+	var sb strings.Builder
+	for _, v := range values {
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.Itoa(v))
+	}
+
+	return sb.String()
+}
+
+// read a problem written by Encode. malformed input of any kind is
+// reported as an error rather than a panic, so the decoder can be
+// fed arbitrary bytes by a fuzzer
+func Decode(r io.Reader) (Problem[int, int], error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != codecHeader {
+		if err := scanner.Err(); err != nil {
+			return Problem[int, int]{}, err
+		}
+		return Problem[int, int]{}, fmt.Errorf("missing %q header", codecHeader)
+	}
+
+	domain := map[int][]int{}
+	var constraints []Constraint[int]
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		head, tail, found := strings.Cut(text, ":")
+		if !found {
+			return Problem[int, int]{}, fmt.Errorf("line %d: missing ':'", line)
+		}
+		fields := strings.Fields(head)
+		args, err := parseInts(strings.Fields(tail))
+		if err != nil {
+			return Problem[int, int]{}, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		switch {
+		case len(fields) == 2 && fields[0] == "var":
+			v, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return Problem[int, int]{}, fmt.Errorf("line %d: %s", line, err)
+			}
+			if _, dup := domain[v]; dup {
+				return Problem[int, int]{}, fmt.Errorf("line %d: variable %d redeclared", line, v)
+			}
+			domain[v] = args
+
+		case len(fields) >= 2 && fields[0] == "rel":
+			variables, err := parseInts(fields[2:])
+			if err != nil {
+				return Problem[int, int]{}, fmt.Errorf("line %d: %s", line, err)
+			}
+			constraint := Constraint[int]{Variables: variables, Relation: Relation(fields[1]), Args: args}
+			if err := validateRelation[int](constraint.Relation, len(variables), args); err != nil {
+				return Problem[int, int]{}, fmt.Errorf("line %d: %s", line, err)
+			}
+			constraints = append(constraints, constraint)
+
+		default:
+			return Problem[int, int]{}, fmt.Errorf("line %d: unrecognized declaration %q", line, head)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Problem[int, int]{}, err
+	}
+
+	p := New(domain, nil)
+	for _, constraint := range constraints {
+		if len(constraint.Variables) == 0 {
+			return Problem[int, int]{}, fmt.Errorf("constraint %q has no variables", constraint.Relation)
+		}
+		for _, v := range constraint.Variables {
+			if _, found := domain[v]; !found {
+				return Problem[int, int]{}, fmt.Errorf("constraint %q references undeclared variable %d", constraint.Relation, v)
+			}
+		}
+		p.AddConstraint(constraint)
+	}
+
+	return p, nil
+}
+
+func parseInts(fields []string) ([]int, error) {
+	out := make([]int, len(fields))
+	for ndx, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		out[ndx] = n
+	}
+
+	return out, nil
+}
@@ -0,0 +1,60 @@
+package csp
+
+// split the Problem into independent subproblems, one per connected
+// component of the constraint graph. no constraint spans two subproblems,
+// so each can be solved on its own and the solutions merged
+func (p Problem[V, D]) Components() []Problem[V, D] {
+	var out []Problem[V, D]
+	for _, component := range p.components() {
+		sub := Problem[V, D]{
+			Domain:      make(map[V][]D, len(component)),
+			Constraints: map[V][]Constraint[V]{},
+			SatFn:       p.SatFn,
+			Labels:      p.Labels,
+			Groups:      p.Groups,
+			cache:       &problemCache[V]{},
+		}
+		for _, v := range component {
+			sub.Domain[v] = p.Domain[v]
+			if constraints, found := p.Constraints[v]; found {
+				sub.Constraints[v] = constraints
+			}
+		}
+		out = append(out, sub)
+	}
+
+	return out
+}
+
+// solve each independent subproblem separately and merge the results, so
+// that unrelated clusters of variables add their search spaces together
+// rather than multiplying them. constraints must only inspect their own
+// Variables for this to be sound. variables already present in assignment
+// keep their values; the completed assignment is returned, or nil if any
+// subproblem has no solution, and the one passed in is left as it was
+func (p Problem[V, D]) SolveComponents(assignment map[V]D) map[V]D {
+	var solved []map[V]D
+	for _, sub := range p.Components() {
+		// seed each subproblem with only its own pre-assigned variables
+		partial := map[V]D{}
+		for v := range sub.Domain {
+			if value, found := assignment[v]; found {
+				partial[v] = value
+			}
+		}
+
+		result := sub.Solve(partial)
+		if result == nil {
+			return nil
+		}
+		solved = append(solved, result)
+	}
+
+	out := dup(assignment)
+	for _, result := range solved {
+		for v, value := range result {
+			out[v] = value
+		}
+	}
+	return out
+}
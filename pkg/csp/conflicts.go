@@ -0,0 +1,128 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConflictStats observes a Backtracker, counting how many candidate values
+// each constraint rejected and how often the search backtracked over each
+// variable. constraints that fail often are candidates for reformulation,
+// and variables that are backtracked over often are candidates for being
+// assigned earlier
+type ConflictStats[V comparable, D any] struct {
+	// Labels, if set, name the variables and constraints in the Report,
+	// e.g. the Problem's
+	Labels Labels[V]
+
+	// by the constraint's Key, shared by every copy of a constraint stored
+	// in the Problem
+	constraints map[ConstraintKey[V]]*ConstraintConflicts[V]
+	variables   map[V]*VariableConflicts[V]
+}
+
+// ConstraintConflicts counts the candidate values a constraint rejected
+type ConstraintConflicts[V comparable] struct {
+	Constraint Constraint[V]
+	Failures   int
+}
+
+// VariableConflicts counts the failures suffered by a single variable
+type VariableConflicts[V comparable] struct {
+	Variable V
+	// Backtracks is how many times every value of the variable failed
+	Backtracks int
+	// Rejections is how many of its candidate values constraints ruled out
+	Rejections int
+}
+
+// ConflictReport ranks constraints and variables, worst offenders first
+type ConflictReport[V comparable] struct {
+	Constraints []ConstraintConflicts[V]
+	Variables   []VariableConflicts[V]
+	// names the variables and constraints in String
+	Labels Labels[V]
+}
+
+// construct an empty ConflictStats
+func NewConflictStats[V comparable, D any]() *ConflictStats[V, D] {
+	return &ConflictStats[V, D]{
+		constraints: map[ConstraintKey[V]]*ConstraintConflicts[V]{},
+		variables:   map[V]*VariableConflicts[V]{},
+	}
+}
+
+// obtain the Hooks that feed this ConflictStats, for use with Backtracker.Observe
+func (cs *ConflictStats[V, D]) Hooks() Hooks[V, D] {
+	return Hooks[V, D]{
+		OnReject: func(variable V, value D, constraint Constraint[V]) {
+			key := constraint.Key()
+			if _, found := cs.constraints[key]; !found {
+				cs.constraints[key] = &ConstraintConflicts[V]{Constraint: constraint}
+			}
+			cs.constraints[key].Failures++
+			cs.variable(variable).Rejections++
+		},
+		OnBacktrack: func(variable V, depth int) {
+			cs.variable(variable).Backtracks++
+		},
+	}
+}
+
+func (cs *ConflictStats[V, D]) variable(v V) *VariableConflicts[V] {
+	if _, found := cs.variables[v]; !found {
+		cs.variables[v] = &VariableConflicts[V]{Variable: v}
+	}
+
+	return cs.variables[v]
+}
+
+// rank the constraints and variables observed so far
+func (cs *ConflictStats[V, D]) Report() ConflictReport[V] {
+	report := ConflictReport[V]{Labels: cs.Labels}
+	for _, conflicts := range cs.constraints {
+		report.Constraints = append(report.Constraints, *conflicts)
+	}
+	for _, conflicts := range cs.variables {
+		report.Variables = append(report.Variables, *conflicts)
+	}
+
+	// break ties by name so that reports are stable across runs
+	sort.Slice(report.Constraints, func(i, j int) bool {
+		a, b := report.Constraints[i], report.Constraints[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return cs.Labels.Constraint(a.Constraint) < cs.Labels.Constraint(b.Constraint)
+	})
+	sort.Slice(report.Variables, func(i, j int) bool {
+		a, b := report.Variables[i], report.Variables[j]
+		if a.Backtracks != b.Backtracks {
+			return a.Backtracks > b.Backtracks
+		}
+		if a.Rejections != b.Rejections {
+			return a.Rejections > b.Rejections
+		}
+		return cs.Labels.Name(a.Variable) < cs.Labels.Name(b.Variable)
+	})
+
+	return report
+}
+
+// render the report as a pair of ranked tables
+func (r ConflictReport[V]) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("Constraints by failures caused:\n")
+	for _, c := range r.Constraints {
+		fmt.Fprintf(&sb, "  %8d  %s\n", c.Failures, r.Labels.Constraint(c.Constraint))
+	}
+
+	sb.WriteString("Variables by backtracks (rejected values):\n")
+	for _, v := range r.Variables {
+		fmt.Fprintf(&sb, "  %8d  (%d)  %s\n", v.Backtracks, v.Rejections, r.Labels.Name(v.Variable))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package csp
+
+// try only the values after which arc consistency holds, maintaining it
+// through the search as MAC does: having assigned the value, each
+// unassigned variable keeps the values consistent with the assignment,
+// and then, pair by pair of the unassigned variables sharing a
+// constraint, those with a supporting value of the other, until no more
+// are lost. a value is ruled out if any variable is left with none.
+//
+// it checks far more than plain backtracking does at each node, and so
+// pays off on problems whose dead ends lie a few assignments deep. like
+// the other heuristics, constraints must only inspect their own
+// Variables, and only fail a partial assignment once no extension of it
+// can satisfy them
+func MaintainArcConsistency[V comparable, D any](p Problem[V, D]) ValueOrder[V, D] {
+	return func(variable V, assignment map[V]D) []D {
+		var out []D
+		for _, value := range p.Domain[variable] {
+			if !p.consistentWith(variable, value, assignment) {
+				continue
+			}
+			assignment[variable] = value
+			if p.arcConsistent(assignment) {
+				out = append(out, value)
+			}
+			delete(assignment, variable)
+		}
+		return out
+	}
+}
+
+// an arc from one variable to another through a constraint they share:
+// each value of from needs a supporting value of to
+type arc[V comparable] struct {
+	from, to   V
+	constraint Constraint[V]
+}
+
+// establish arc consistency among the unassigned variables, by AC-3,
+// reporting whether every one of them keeps a value. the assignment is
+// left as it was
+func (p Problem[V, D]) arcConsistent(assignment map[V]D) bool {
+	_, ok := p.propagate(assignment)
+	return ok
+}
+
+// establish arc consistency as arcConsistent does, returning the values
+// each unassigned variable keeps, or false once one is left with none
+func (p Problem[V, D]) propagate(assignment map[V]D) (map[V][]D, bool) {
+	live := map[V][]D{}
+	for v, values := range p.Domain {
+		if _, found := assignment[v]; found {
+			continue
+		}
+		for _, value := range values {
+			if p.consistentWith(v, value, assignment) {
+				live[v] = append(live[v], value)
+			}
+		}
+		if len(live[v]) == 0 {
+			return nil, false
+		}
+	}
+
+	// the arcs into each variable, to revisit those whose support it lost
+	var arcs []arc[V]
+	into := map[V][]int{}
+	for _, constraint := range p.allConstraints() {
+		for _, from := range constraint.Variables {
+			for _, to := range constraint.Variables {
+				_, fromLive := live[from]
+				_, toLive := live[to]
+				if from == to || !fromLive || !toLive {
+					continue
+				}
+				into[to] = append(into[to], len(arcs))
+				arcs = append(arcs, arc[V]{from, to, constraint})
+			}
+		}
+	}
+
+	queue := make([]int, len(arcs))
+	queued := make([]bool, len(arcs))
+	for ndx := range arcs {
+		queue[ndx], queued[ndx] = ndx, true
+	}
+	for len(queue) > 0 {
+		a := arcs[queue[0]]
+		queued[queue[0]] = false
+		queue = queue[1:]
+
+		if !p.revise(a, live, assignment) {
+			continue
+		}
+		if len(live[a.from]) == 0 {
+			return nil, false
+		}
+		for _, next := range into[a.from] {
+			if !queued[next] {
+				queue, queued[next] = append(queue, next), true
+			}
+		}
+	}
+	return live, true
+}
+
+// drop the values of the arc's from variable that no live value of its
+// to variable supports, reporting whether any were
+func (p Problem[V, D]) revise(a arc[V], live map[V][]D, assignment map[V]D) bool {
+	defer delete(assignment, a.from)
+	defer delete(assignment, a.to)
+
+	var kept []D
+	for _, value := range live[a.from] {
+		assignment[a.from] = value
+		for _, other := range live[a.to] {
+			assignment[a.to] = other
+			if p.SatFn(a.constraint, assignment) {
+				kept = append(kept, value)
+				break
+			}
+		}
+		delete(assignment, a.to)
+	}
+	if len(kept) == len(live[a.from]) {
+		return false
+	}
+	live[a.from] = kept
+	return true
+}
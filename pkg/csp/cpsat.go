@@ -0,0 +1,104 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// write the problem as an OR-Tools CP-SAT model, in the text format of the
+// operations_research.sat.CpModelProto message, so heavy instances can be
+// handed to OR-Tools (e.g. via its solve binary's --input flag, or
+// text_format.Parse in Python). built-in relations map onto all_diff, linear
+// and table constraints; user-defined constraints are written as tables of
+// the value tuples satisfying them. domain values must be integers
+func (p Problem[V, D]) ExportCPSAT(w io.Writer) error {
+	en := p.exportNames()
+
+	lines := []string{
+		"# proto-file: ortools/sat/cp_model.proto",
+		"# proto-message: operations_research.sat.CpModelProto",
+	}
+	index := map[V]int{}
+	for ndx, v := range en.ordered {
+		values, err := p.exportDomain(v)
+		if err != nil {
+			return err
+		}
+		index[v] = ndx
+		lines = append(lines, fmt.Sprintf("variables { name: %q domain: [%s] }", en.names[v], joinInts(intervals(values), ", ")))
+	}
+
+	var constraints []string
+	for _, constraint := range p.exportConstraints() {
+		// the arithmetic relations are stated by their tuples
+		switch constraint.Relation {
+		case RelationAbsDiffEq, RelationAbsDiffNe, RelationAbsDiffLe, RelationAbsDiffGe, RelationProduct, RelationQuotient:
+			constraint = p.tabulate(constraint)
+		}
+
+		vars := make([]int, len(constraint.Variables))
+		for ndx, v := range constraint.Variables {
+			vars[ndx] = index[v]
+		}
+
+		switch constraint.Relation {
+		case RelationTable:
+			constraints = append(constraints, fmt.Sprintf("constraints { table { vars: [%s] values: [%s] } }",
+				joinInts(vars, ", "), joinInts(constraint.Args, ", ")))
+
+		case RelationAllDifferent:
+			var exprs []string
+			for ndx, v := range vars {
+				expr := fmt.Sprintf("vars: %d coeffs: 1", v)
+				if len(constraint.Args) > 0 && constraint.Args[ndx] != 0 {
+					expr += fmt.Sprintf(" offset: %d", constraint.Args[ndx])
+				}
+				exprs = append(exprs, "exprs { "+expr+" }")
+			}
+			constraints = append(constraints, fmt.Sprintf("constraints { all_diff { %s } }", strings.Join(exprs, " ")))
+
+		case RelationLinearEq, RelationLinearNe, RelationLinearLe:
+			c := constraint.Args[len(vars)]
+			domain := []int{c, c}
+			switch constraint.Relation {
+			case RelationLinearNe:
+				domain = []int{math.MinInt, c - 1, c + 1, math.MaxInt}
+			case RelationLinearLe:
+				domain = []int{math.MinInt, c}
+			}
+			constraints = append(constraints, fmt.Sprintf("constraints { linear { vars: [%s] coeffs: [%s] domain: [%s] } }",
+				joinInts(vars, ", "), joinInts(constraint.Args[:len(vars)], ", "), joinInts(domain, ", ")))
+
+		default:
+			return fmt.Errorf("no CP-SAT translation for relation %q", constraint.Relation)
+		}
+	}
+	sort.Strings(constraints)
+
+	_, err := io.WriteString(w, strings.Join(append(lines, constraints...), "\n")+"\n")
+	return err
+}
+
+// convert domain values into the sorted, disjoint, inclusive
+// intervals CP-SAT uses to describe domains, flattened
+func intervals(values []int) []int {
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+
+	var out []int
+	for ndx, v := range sorted {
+		switch {
+		case ndx == 0:
+			out = append(out, v, v)
+		case v == out[len(out)-1] || v == out[len(out)-1]+1:
+			out[len(out)-1] = v
+		default:
+			out = append(out, v, v)
+		}
+	}
+
+	return out
+}
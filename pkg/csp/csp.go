@@ -1,11 +1,60 @@
 package csp
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
 
 // Constraint models a single constraint to be satisfied
 // while attempting to find a valid solution for a Problem
 type Constraint[V comparable] struct {
 	Variables []V
+	// Relation names a built-in relation the package evaluates itself, in
+	// place of the Problem's SatFn. user-defined constraints leave it empty
+	Relation Relation
+	// Args holds the integer arguments of a built-in Relation, or any a
+	// user-defined constraint's SatFn needs
+	Args []int
+	// Label, if set, names and describes the constraint for people
+	Label *Label
+
+	// numbers the constraint as AddConstraint adds it, from 1
+	seq uint64
+}
+
+// the number of constraints added by AddConstraint, to number the next
+var constraintSeq uint64
+
+// ConstraintKey identifies a constraint of a Problem, as a map key: the
+// copies of a constraint stored for each of its variables share a key,
+// while distinct constraints differ, even over the same Variables slice
+// with the same relation, args and label, by the number AddConstraint
+// gave each
+type ConstraintKey[V comparable] struct {
+	seq uint64
+	// for a constraint never added to a Problem, what it's made of
+	scope    *V
+	arity    int
+	relation Relation
+	args     string
+	label    *Label
+}
+
+// the key identifying the constraint
+func (c Constraint[V]) Key() ConstraintKey[V] {
+	if c.seq != 0 {
+		return ConstraintKey[V]{seq: c.seq}
+	}
+	key := ConstraintKey[V]{arity: len(c.Variables), relation: c.Relation, label: c.Label}
+	if len(c.Variables) > 0 {
+		key.scope = &c.Variables[0]
+	}
+	if len(c.Args) > 0 {
+		key.args = fmt.Sprint(c.Args)
+	}
+	return key
 }
 
 // checks if the given Constraint is satisfied by the current candidate solution
@@ -17,85 +66,140 @@ type Problem[V comparable, D any] struct {
 	Domain      map[V][]D
 	Constraints map[V][]Constraint[V]
 	SatFn       Satisfied[V, D]
+	// Labels name and describe the variables for people, e.g. in errors
+	// and exports
+	Labels Labels[V]
+	// Groups are named lists of the variables, to constrain together
+	Groups map[string][]V
+
+	// what's derived from the constraints, shared by the Problem's copies
+	cache *problemCache[V]
+}
+
+// problemCache holds the structure of a Problem's constraints, built the
+// first time it's needed and cleared by AddConstraint, so that heuristics
+// and propagation don't walk all the constraints over and over
+type problemCache[V comparable] struct {
+	mu          sync.Mutex
+	neighbors   map[V][]V
+	constraints []Constraint[V]
 }
 
-// construct a Problem instance
+// construct a Problem instance. constraints using a built-in Relation are
+// evaluated by the package; satFn checks all the others, and may be nil if
+// there are none
 func New[V comparable, D any](domain map[V][]D, satFn Satisfied[V, D]) Problem[V, D] {
 	return Problem[V, D]{
 		Domain:      domain,
 		Constraints: map[V][]Constraint[V]{},
-		SatFn:       satFn,
+		SatFn:       withRelations(satFn),
+		Labels:      Labels[V]{},
+		Groups:      map[string][]V{},
+		cache:       &problemCache[V]{},
 	}
 }
 
 // apply another Constraint to filter candidate solutions
 func (p Problem[V, D]) AddConstraint(constraint Constraint[V]) {
+	if constraint.Relation != "" {
+		if err := validateRelation[D](constraint.Relation, len(constraint.Variables), constraint.Args); err != nil {
+			panic(fmt.Sprintf("error: %s: %s", p.Labels.Constraint(constraint), err))
+		}
+	}
+
+	constraint.seq = atomic.AddUint64(&constraintSeq, 1)
 	for _, constraintVar := range constraint.Variables {
 		// ensure each constraint var is part of the problem space
-		found := false
-		for acceptableVar := range p.Domain {
-			if acceptableVar == constraintVar {
-				found = true
-				break
-			}
-		}
-		if !found {
-			panic(fmt.Sprintf("error: constraint variable %+v not found in Problem", constraintVar))
+		if _, found := p.Domain[constraintVar]; !found {
+			panic(fmt.Sprintf("error: constraint variable %s not found in Problem", p.Labels.Name(constraintVar)))
 		}
 
 		// store valid constraint
 		p.Constraints[constraintVar] = append(p.Constraints[constraintVar], constraint)
 	}
+
+	if p.cache != nil {
+		p.cache.mu.Lock()
+		p.cache.neighbors, p.cache.constraints = nil, nil
+		p.cache.mu.Unlock()
+	}
+}
+
+// enumerate each distinct constraint applied to the problem exactly once,
+// rather than once per variable it constrains. the result is shared, and
+// must not be modified
+func (p Problem[V, D]) allConstraints() []Constraint[V] {
+	if p.cache == nil {
+		return p.listConstraints()
+	}
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+	if p.cache.constraints == nil {
+		p.cache.constraints = p.listConstraints()
+	}
+	return p.cache.constraints
+}
+
+func (p Problem[V, D]) listConstraints() []Constraint[V] {
+	var out []Constraint[V]
+	for v, constraints := range p.Constraints {
+		for _, constraint := range constraints {
+			if constraint.Variables[0] == v {
+				out = append(out, constraint)
+			}
+		}
+	}
+
+	return out
 }
 
 // backtracking recursive search through the domain of problem
 // variables and all their possible values. the first valid
 // solution obtained in this brute-force effort is returned
 func (p Problem[V, D]) Solve(assignment map[V]D) map[V]D {
-	// base case: all variables are assigned, a solution has been found
-	if len(assignment) == len(p.Domain) {
-		return assignment
-	}
+	return NewBacktracker(p).Solve(assignment)
+}
 
-	// enumerate all currently-unassigned variables
-	var unassigned []V
-	for acceptableVar := range p.Domain {
-		if _, found := assignment[acceptableVar]; !found {
-			unassigned = append(unassigned, acceptableVar)
+// enumerate every solution extending the given assignment
+func (p Problem[V, D]) SolveAll(assignment map[V]D) []map[V]D {
+	return NewBacktracker(p).SolveAll(assignment)
+}
+
+// confirm that assignment is a complete solution to the problem: every
+// variable holds one of its domain values, and every constraint is
+// satisfied. the first discrepancy found is reported as an error
+func (p Problem[V, D]) Verify(assignment map[V]D) error {
+	for v := range assignment {
+		if _, found := p.Domain[v]; !found {
+			return fmt.Errorf("variable %s not found in Problem", p.Labels.Name(v))
 		}
 	}
 
-	// test the current solution, augmented by the next
-	// unassigned variable and a candidate value, against
-	// all the constraints
-	nextVar := unassigned[0]
-	for _, candidateValue := range p.Domain[nextVar] {
-		assignment[nextVar] = candidateValue
-		if p.consistent(nextVar, assignment) {
-			result := p.Solve(assignment)
-			if result != nil {
-				return result
+	for v, values := range p.Domain {
+		value, found := assignment[v]
+		if !found {
+			return fmt.Errorf("variable %s is unassigned", p.Labels.Name(v))
+		}
+
+		inDomain := false
+		for _, candidate := range values {
+			if reflect.DeepEqual(candidate, value) {
+				inDomain = true
+				break
 			}
-		} else {
-			// the candidate value isn't a component of a
-			// valid solution; ditch it and keep trying
-			delete(assignment, nextVar)
+		}
+		if !inDomain {
+			return fmt.Errorf("variable %s holds %+v, which is outside its domain", p.Labels.Name(v), value)
 		}
 	}
 
-	return nil
-}
-
-// determine if this variable and assignment satisfy the
-// constraints applied to the problem space for that variable
-func (p Problem[V, D]) consistent(variable V, assignment map[V]D) bool {
-	for _, constraint := range p.Constraints[variable] {
+	for _, constraint := range p.allConstraints() {
 		if !p.SatFn(constraint, assignment) {
-			return false
+			return fmt.Errorf("%s is not satisfied", p.Labels.Constraint(constraint))
 		}
 	}
 
-	return true
+	return nil
 }
 
 // utility: copy the current candidate solution into a new map
@@ -0,0 +1,41 @@
+package csp
+
+// split the search below the assignment into at least n cubes, as
+// embarrassingly parallel search does: each cube extends the assignment
+// by a few more variables, consistently, and together they cover every
+// solution extending it, so that each can be searched on its own, by as
+// many processes or machines, with Solve(cube). the tree is expanded a
+// level at a time, each cube by its variable with the fewest values left,
+// so there may be many more than n, or fewer if the tree is that small,
+// and none if no extension is consistent. constraints must only inspect
+// their own Variables
+func (p Problem[V, D]) Cubes(assignment map[V]D, n int) []map[V]D {
+	next := MinRemainingValues(p)
+	cubes := []map[V]D{dup(assignment)}
+	for len(cubes) < n {
+		var expanded []map[V]D
+		grew := false
+		for _, cube := range cubes {
+			if len(cube) == len(p.Domain) {
+				expanded = append(expanded, cube)
+				continue
+			}
+
+			variable := next(cube)
+			for _, value := range p.Domain[variable] {
+				if p.consistentWith(variable, value, cube) {
+					extended := dup(cube)
+					extended[variable] = value
+					expanded = append(expanded, extended)
+				}
+			}
+			grew = true
+		}
+		cubes = expanded
+		if !grew {
+			break
+		}
+	}
+
+	return cubes
+}
@@ -0,0 +1,183 @@
+package csp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// denseSearch is the Backtracker's fast path for a problem whose
+// constraints are all built-in relations: its variables are numbered 0
+// to n-1, and the assignment kept in slices indexed by them rather than
+// a map, converted back only at the API boundary, for each solution. the
+// relations are checked against the slices directly, so that the hot
+// path of assigning, checking and unassigning makes no map operations
+type denseSearch[V comparable, D any] struct {
+	b    *Backtracker[V, D]
+	vars []V
+	// each variable's domain, and the int each value is checked as
+	domains [][]D
+	ints    [][]int
+	// the constraints of each variable, ready to check
+	constraints [][]*denseConstraint
+	// the variables left to assign, in the order they're assigned
+	order []int
+
+	// the assignment: the index into its domain each variable holds, the
+	// value as an int, and whether it's assigned at all
+	chosen []int
+	values []int
+	set    []bool
+}
+
+// a built-in constraint over variable numbers, with a lookup of their
+// values into the search's slices made once, rather than at every check
+type denseConstraint struct {
+	check relationFn
+	args  []int
+	arity int
+	value func(ndx int) (int, bool)
+}
+
+// the dense form of the Backtracker's Problem, extending the assignment,
+// or false if it needs the map-based search: some constraint is checked
+// by the user's SatFn, or the search is tuned or observed node by node
+// through funcs that take the assignment as a map. hooks that only see
+// the solutions, which are made into maps anyway, are no obstacle
+func (b *Backtracker[V, D]) dense(assignment map[V]D) (*denseSearch[V, D], bool) {
+	if b.SelectVariable != nil || b.OrderValues != nil || b.prune != nil {
+		return nil, false
+	}
+	for _, h := range b.hooks {
+		if h.OnAssign != nil || h.OnReject != nil || h.OnBacktrack != nil {
+			return nil, false
+		}
+	}
+	if !isIntegerKind(kindOf[D]()) {
+		return nil, false
+	}
+	for _, constraints := range b.Problem.Constraints {
+		for _, constraint := range constraints {
+			if constraint.Relation == "" {
+				return nil, false
+			}
+		}
+	}
+	for v := range assignment {
+		if _, found := b.Problem.Domain[v]; !found {
+			return nil, false
+		}
+	}
+
+	d := &denseSearch[V, D]{b: b}
+	index := map[V]int{}
+	for v, domain := range b.Problem.Domain {
+		index[v] = len(d.vars)
+		d.vars = append(d.vars, v)
+		d.domains = append(d.domains, domain)
+		ints := make([]int, len(domain))
+		for ndx, value := range domain {
+			ints[ndx] = asInt(value)
+		}
+		d.ints = append(d.ints, ints)
+	}
+	n := len(d.vars)
+	d.chosen, d.values, d.set = make([]int, n), make([]int, n), make([]bool, n)
+
+	// each constraint is shared by the lists of all its variables
+	d.constraints = make([][]*denseConstraint, n)
+	for _, constraint := range b.Problem.allConstraints() {
+		vars := make([]int, len(constraint.Variables))
+		for ndx, v := range constraint.Variables {
+			vars[ndx] = index[v]
+		}
+		dc := &denseConstraint{
+			check: relations[constraint.Relation].check,
+			args:  constraint.Args,
+			arity: len(vars),
+			value: func(ndx int) (int, bool) {
+				v := vars[ndx]
+				return d.values[v], d.set[v]
+			},
+		}
+		seen := map[int]bool{}
+		for _, v := range vars {
+			if !seen[v] {
+				seen[v] = true
+				d.constraints[v] = append(d.constraints[v], dc)
+			}
+		}
+	}
+
+	for v, value := range assignment {
+		ndx := index[v]
+		d.values[ndx], d.set[ndx] = asInt(value), true
+	}
+	for ndx := range d.vars {
+		if !d.set[ndx] {
+			d.order = append(d.order, ndx)
+		}
+	}
+	return d, true
+}
+
+// as Backtracker.search, over the slices: each solution is written into
+// the assignment for found, and the assignment is left as it was given
+// unless found ends the search
+func (d *denseSearch[V, D]) search(assignment map[V]D, depth int, found func(map[V]D) bool) bool {
+	b := d.b
+	// checked before recording a solution, as Backtracker.search does
+	if b.stats.Nodes%1024 == 0 && !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		b.stats.TimedOut = true
+	}
+	if atomic.LoadInt32(&b.canceled) == 1 {
+		b.stats.Canceled = true
+	}
+	if b.stats.TimedOut || b.stats.Canceled {
+		return true
+	}
+
+	if depth == len(d.order) {
+		b.stats.Solutions++
+		for _, v := range d.order {
+			assignment[d.vars[v]] = d.domains[v][d.chosen[v]]
+		}
+		for _, h := range b.hooks {
+			if h.OnSolution != nil {
+				h.OnSolution(assignment)
+			}
+		}
+		if found(assignment) {
+			return true
+		}
+		for _, v := range d.order {
+			delete(assignment, d.vars[v])
+		}
+		return false
+	}
+
+	v := d.order[depth]
+	d.set[v] = true
+	for ndx, value := range d.ints[v] {
+		d.chosen[v], d.values[v] = ndx, value
+		b.stats.Nodes++
+
+		if d.consistent(v) && d.search(assignment, depth+1, found) {
+			return true
+		}
+	}
+
+	d.set[v] = false
+	b.stats.Backtracks++
+	return false
+}
+
+// determine whether the variable's constraints hold with its value
+func (d *denseSearch[V, D]) consistent(v int) bool {
+	for _, dc := range d.constraints[v] {
+		if !dc.check(dc.args, dc.arity, dc.value) {
+			d.b.stats.Rejections++
+			return false
+		}
+	}
+	return true
+}
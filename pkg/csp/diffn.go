@@ -0,0 +1,61 @@
+package csp
+
+import "fmt"
+
+// require the rectangles with their lower left corners at the values of
+// the xs and ys, each as wide and high as given, to never overlap, e.g.
+// pieces cut from a sheet: for any two, one is wholly to the left of or
+// below the other. rectangles of zero area overlap nothing
+func Diffn[V comparable](xs, ys []V, widths, heights []int) Constraint[V] {
+	if len(xs) != len(ys) || len(xs) != len(widths) || len(xs) != len(heights) {
+		panic(fmt.Sprintf("error: diffn constraint given %d xs, %d ys, %d widths and %d heights", len(xs), len(ys), len(widths), len(heights)))
+	}
+
+	args := append(append([]int{}, widths...), heights...)
+	return Constraint[V]{
+		Variables: append(append([]V{}, xs...), ys...),
+		Relation:  RelationDiffn,
+		Args:      args,
+	}
+}
+
+// report whether no two of the rectangles placed so far overlap
+func checkDiffn(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	type rect struct{ x0, y0, x1, y1 int }
+	n := arity / 2
+	var placed []rect
+	for ndx := 0; ndx < n; ndx++ {
+		x, assigned := value(ndx)
+		if !assigned {
+			continue
+		}
+		y, assigned := value(n + ndx)
+		if !assigned || args[ndx] == 0 || args[n+ndx] == 0 {
+			continue
+		}
+
+		r := rect{x, y, x + args[ndx], y + args[n+ndx]}
+		for _, other := range placed {
+			if r.x0 < other.x1 && other.x0 < r.x1 && r.y0 < other.y1 && other.y0 < r.y1 {
+				return false
+			}
+		}
+		placed = append(placed, r)
+	}
+	return true
+}
+
+func validDiffn(args []int, arity int) error {
+	if arity%2 != 0 {
+		return fmt.Errorf("diffn needs an x and a y per rectangle, got %d variables", arity)
+	}
+	if len(args) != arity {
+		return fmt.Errorf("diffn takes a width and a height per rectangle, got %d arguments for %d rectangles", len(args), arity/2)
+	}
+	for ndx, size := range args {
+		if size < 0 {
+			return fmt.Errorf("diffn rectangle %d has negative size %d", ndx%(arity/2), size)
+		}
+	}
+	return nil
+}
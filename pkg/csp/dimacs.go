@@ -0,0 +1,110 @@
+package csp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// read a SAT instance in DIMACS CNF format as a Problem over boolean
+// variables 1..n, each with the domain {0, 1}. each clause becomes a
+// linear constraint requiring at least one of its literals to hold:
+// the clause (x1 | -x2 | x3) is x1 + (1 - x2) + x3 >= 1
+func LoadDIMACS(r io.Reader) (Problem[int, int], error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	vars, clauses := -1, -1
+	var parsed [][]int
+	var clause []int
+lines:
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case text == "" || strings.HasPrefix(text, "c"):
+			continue
+		case strings.HasPrefix(text, "%"):
+			// SATLIB instances mark the end of the clauses this way
+			break lines
+		case strings.HasPrefix(text, "p"):
+			fields := strings.Fields(text)
+			if vars >= 0 || len(fields) != 4 || fields[1] != "cnf" {
+				return Problem[int, int]{}, fmt.Errorf("line %d: malformed problem line %q", line, text)
+			}
+			var err error
+			if vars, err = strconv.Atoi(fields[2]); err != nil || vars < 0 {
+				return Problem[int, int]{}, fmt.Errorf("line %d: bad variable count %q", line, fields[2])
+			}
+			if clauses, err = strconv.Atoi(fields[3]); err != nil || clauses < 0 {
+				return Problem[int, int]{}, fmt.Errorf("line %d: bad clause count %q", line, fields[3])
+			}
+			continue
+		}
+		if vars < 0 {
+			return Problem[int, int]{}, fmt.Errorf("line %d: clause before problem line", line)
+		}
+
+		// clauses are terminated by 0, and may span lines
+		for _, field := range strings.Fields(text) {
+			literal, err := strconv.Atoi(field)
+			if err != nil {
+				return Problem[int, int]{}, fmt.Errorf("line %d: bad literal %q", line, field)
+			}
+			if literal == 0 {
+				if len(clause) == 0 {
+					return Problem[int, int]{}, fmt.Errorf("line %d: empty clause makes the instance trivially unsatisfiable", line)
+				}
+				parsed = append(parsed, clause)
+				clause = nil
+				continue
+			}
+			if literal > vars || -literal > vars {
+				return Problem[int, int]{}, fmt.Errorf("line %d: literal %d exceeds the %d declared variables", line, literal, vars)
+			}
+			clause = append(clause, literal)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Problem[int, int]{}, err
+	}
+	if vars < 0 {
+		return Problem[int, int]{}, fmt.Errorf("missing problem line")
+	}
+	if len(clause) > 0 {
+		parsed = append(parsed, clause)
+	}
+	if len(parsed) != clauses {
+		return Problem[int, int]{}, fmt.Errorf("problem line declares %d clauses, found %d", clauses, len(parsed))
+	}
+
+	domain := map[int][]int{}
+	for v := 1; v <= vars; v++ {
+		domain[v] = []int{0, 1}
+	}
+	p := New(domain, nil)
+	for _, literals := range parsed {
+		p.AddConstraint(NewClause(literals))
+	}
+
+	return p, nil
+}
+
+// build the constraint requiring at least one of the DIMACS literals to
+// hold: a positive literal v requires variable v to be 1, and a negative
+// literal -v requires variable v to be 0
+func NewClause(literals []int) Constraint[int] {
+	vars := make([]int, len(literals))
+	coefficients := make([]int, len(literals))
+	negated := 0
+	for ndx, literal := range literals {
+		vars[ndx], coefficients[ndx] = literal, 1
+		if literal < 0 {
+			vars[ndx], coefficients[ndx] = -literal, -1
+			negated++
+		}
+	}
+
+	return Linear(vars, coefficients, Ge, 1-negated)
+}
@@ -0,0 +1,51 @@
+package csp
+
+import "fmt"
+
+// require the tasks that start at the values of the variables, each
+// lasting its duration, to never overlap, as if they all need the same
+// machine: for any two tasks, one finishes before the other starts.
+// tasks of zero duration overlap nothing
+func Disjunctive[V comparable](starts []V, durations []int) Constraint[V] {
+	if len(starts) != len(durations) {
+		panic(fmt.Sprintf("error: %d durations for %d tasks", len(durations), len(starts)))
+	}
+	return Constraint[V]{
+		Variables: starts,
+		Relation:  RelationDisjunctive,
+		Args:      append([]int{}, durations...),
+	}
+}
+
+// report whether no two of the tasks started so far overlap
+func checkDisjunctive(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	type task struct{ start, end int }
+	var started []task
+	for ndx := 0; ndx < arity; ndx++ {
+		start, assigned := value(ndx)
+		if !assigned || args[ndx] == 0 {
+			continue
+		}
+
+		t := task{start, start + args[ndx]}
+		for _, other := range started {
+			if t.start < other.end && other.start < t.end {
+				return false
+			}
+		}
+		started = append(started, t)
+	}
+	return true
+}
+
+func validDisjunctive(args []int, arity int) error {
+	if len(args) != arity {
+		return fmt.Errorf("disjunctive takes a duration per task, got %d for %d tasks", len(args), arity)
+	}
+	for _, d := range args {
+		if d < 0 {
+			return fmt.Errorf("disjunctive durations can't be negative, got %d", d)
+		}
+	}
+	return nil
+}
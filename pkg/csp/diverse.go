@@ -0,0 +1,66 @@
+package csp
+
+// up to k solutions extending the assignment, each as different from the
+// others as the search can make it: after the first, each is found by
+// branch and bound to maximize the least Hamming distance, the number of
+// variables assigned differently, between it and those before, and so
+// must differ from each of them in at least one variable. fewer are
+// returned if the problem has fewer solutions. the Backtracker's Timeout
+// bounds each search, which returns the most different solution found
+// by then, and Stats sums the work of them all. values are compared with
+// ==, so D must be comparable
+func Diverse[V comparable, D comparable](b *Backtracker[V, D], assignment map[V]D, k int) []map[V]D {
+	if k < 1 {
+		return nil
+	}
+	first := b.Solve(assignment)
+	total := b.stats
+	if first == nil {
+		return nil
+	}
+	out := []map[V]D{first}
+
+	for len(out) < k && !total.TimedOut && !total.Canceled {
+		// the negated least distance, at most that of any extension, as
+		// those only differ from out in the variables still unassigned
+		cost := func(candidate map[V]D) int {
+			unassigned := len(b.Problem.Domain) - len(candidate)
+			least := -1
+			for _, solution := range out {
+				distance := unassigned
+				for v, value := range candidate {
+					if solution[v] != value {
+						distance++
+					}
+				}
+				if least < 0 || distance < least {
+					least = distance
+				}
+			}
+			return -least
+		}
+
+		next, _ := b.MinimizeBelow(assignment, cost, 0)
+		total = sumStats(total, b.stats)
+		if next == nil {
+			break
+		}
+		out = append(out, dup(next))
+	}
+
+	b.stats = total
+	return out
+}
+
+// the work of two searches, one after the other
+func sumStats(a, b Stats) Stats {
+	return Stats{
+		Nodes:      a.Nodes + b.Nodes,
+		Backtracks: a.Backtracks + b.Backtracks,
+		Rejections: a.Rejections + b.Rejections,
+		Solutions:  a.Solutions + b.Solutions,
+		Duration:   a.Duration + b.Duration,
+		TimedOut:   a.TimedOut || b.TimedOut,
+		Canceled:   a.Canceled || b.Canceled,
+	}
+}
@@ -0,0 +1,108 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// write the constraint graph in Graphviz DOT format: each variable is a
+// node, each binary constraint an edge between its variables, and each
+// constraint over more variables a small hub node joined to all of them.
+// render it with e.g. `dot -Tsvg` or `neato -Tsvg`
+func (p Problem[V, D]) ExportDOT(w io.Writer) error {
+	return p.ExportDOTSolution(w, nil)
+}
+
+// write the constraint graph as ExportDOT does, with each variable the
+// solution assigns labeled with its value and filled with a color chosen
+// by that value, so that e.g. a map coloring is drawn in its colors.
+// values beyond the palette's 12 colors reuse them
+func (p Problem[V, D]) ExportDOTSolution(w io.Writer, solution map[V]D) error {
+	en := p.exportNames()
+
+	// number the distinct values by their printed form, to pick colors
+	palette := map[string]int{}
+	var printed []string
+	for _, v := range en.ordered {
+		if value, found := solution[v]; found {
+			s := fmt.Sprintf("%+v", value)
+			if _, seen := palette[s]; !seen {
+				palette[s] = 0
+				printed = append(printed, s)
+			}
+		}
+	}
+	sort.Strings(printed)
+	for ndx, s := range printed {
+		palette[s] = ndx%12 + 1
+	}
+
+	var sb strings.Builder
+	sb.WriteString("graph csp {\n")
+	sb.WriteString("  node [shape=ellipse, colorscheme=set312];\n")
+	for _, v := range en.ordered {
+		label := p.Labels.Name(v)
+		if value, found := solution[v]; found {
+			s := fmt.Sprintf("%+v", value)
+			fmt.Fprintf(&sb, "  %s [label=%s, style=filled, fillcolor=%d];\n",
+				en.names[v], strconv.Quote(label+" = "+s), palette[s])
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s [label=%s];\n", en.names[v], strconv.Quote(label))
+	}
+
+	type edge struct {
+		names []string
+		label string
+	}
+	var edges []edge
+	for _, constraint := range p.allConstraints() {
+		scope := distinct(constraint.Variables)
+		if len(scope) < 2 {
+			// a unary constraint only restricts its variable's domain
+			continue
+		}
+
+		e := edge{label: string(constraint.Relation)}
+		if constraint.Label != nil && constraint.Label.Name != "" {
+			e.label = constraint.Label.Name
+		}
+		for _, v := range scope {
+			e.names = append(e.names, en.names[v])
+		}
+		sort.Strings(e.names)
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := strings.Join(edges[i].names, " "), strings.Join(edges[j].names, " ")
+		if a != b {
+			return a < b
+		}
+		return edges[i].label < edges[j].label
+	})
+
+	for ndx, e := range edges {
+		attrs := ""
+		if e.label != "" {
+			attrs = fmt.Sprintf(" [label=%s]", strconv.Quote(e.label))
+		}
+		if len(e.names) == 2 {
+			fmt.Fprintf(&sb, "  %s -- %s%s;\n", e.names[0], e.names[1], attrs)
+			continue
+		}
+
+		// a hyperedge: join each of its variables to a hub node
+		hub := fmt.Sprintf("c%d", ndx+1)
+		fmt.Fprintf(&sb, "  %s [shape=box, width=0.2, height=0.2, label=%s];\n", hub, strconv.Quote(e.label))
+		for _, name := range e.names {
+			fmt.Fprintf(&sb, "  %s -- %s;\n", hub, name)
+		}
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,130 @@
+package csp
+
+// Tuple picks one value for each variable in a constraint's scope,
+// stored as indices into the original Problem's domains
+type Tuple []int
+
+// DualEncoding is the binary reformulation of a Problem: every constraint
+// becomes a dual variable whose domain holds the tuples satisfying it, and
+// dual variables sharing an original variable are linked by a binary
+// constraint requiring the tuples to agree on it. algorithms that only
+// understand binary constraints can then be applied to arbitrary models
+type DualEncoding[V comparable, D any] struct {
+	// Problem is the binary problem over dual variables 0..len(Scopes)-1
+	Problem Problem[int, Tuple]
+	// Scopes lists the original variables covered by each dual variable
+	Scopes [][]V
+
+	original Problem[V, D]
+}
+
+// build the dual encoding of the problem. variables not covered by any
+// constraint get a dual variable of their own, so that every variable is
+// represented. constraints must only inspect their own Variables, and
+// enumerating their satisfying tuples is exponential in arity
+func (p Problem[V, D]) Dual() DualEncoding[V, D] {
+	de := DualEncoding[V, D]{
+		original: p,
+	}
+	domain := map[int][]Tuple{}
+
+	covered := map[V]bool{}
+	for _, constraint := range p.allConstraints() {
+		scope := distinct(constraint.Variables)
+		for _, v := range scope {
+			covered[v] = true
+		}
+
+		domain[len(de.Scopes)] = p.satisfyingTuples(constraint, scope)
+		de.Scopes = append(de.Scopes, scope)
+	}
+	for v, values := range p.Domain {
+		if covered[v] {
+			continue
+		}
+
+		var tuples []Tuple
+		for ndx := range values {
+			tuples = append(tuples, Tuple{ndx})
+		}
+		domain[len(de.Scopes)] = tuples
+		de.Scopes = append(de.Scopes, []V{v})
+	}
+
+	// positions at which each linked pair of dual variables must agree
+	shared := map[[2]int][][2]int{}
+	for i := range de.Scopes {
+		for j := i + 1; j < len(de.Scopes); j++ {
+			for pi, vi := range de.Scopes[i] {
+				for pj, vj := range de.Scopes[j] {
+					if vi == vj {
+						shared[[2]int{i, j}] = append(shared[[2]int{i, j}], [2]int{pi, pj})
+					}
+				}
+			}
+		}
+	}
+
+	de.Problem = New(domain, func(link Constraint[int], candidate map[int]Tuple) bool {
+		left, foundLeft := candidate[link.Variables[0]]
+		right, foundRight := candidate[link.Variables[1]]
+		if !foundLeft || !foundRight {
+			return true
+		}
+
+		for _, positions := range shared[[2]int{link.Variables[0], link.Variables[1]}] {
+			if left[positions[0]] != right[positions[1]] {
+				return false
+			}
+		}
+		return true
+	})
+	for pair := range shared {
+		de.Problem.AddConstraint(Constraint[int]{
+			Variables: []int{pair[0], pair[1]},
+		})
+	}
+
+	return de
+}
+
+// enumerate every combination of values for scope that satisfies the constraint
+func (p Problem[V, D]) satisfyingTuples(constraint Constraint[V], scope []V) []Tuple {
+	var out []Tuple
+	candidate := make(map[V]D, len(scope))
+	current := make(Tuple, len(scope))
+
+	var extend func(ndx int)
+	extend = func(ndx int) {
+		if ndx == len(scope) {
+			out = append(out, append(Tuple{}, current...))
+			return
+		}
+
+		v := scope[ndx]
+		for i, value := range p.Domain[v] {
+			candidate[v] = value
+			current[ndx] = i
+			if p.SatFn(constraint, candidate) {
+				extend(ndx + 1)
+			}
+		}
+		delete(candidate, v)
+	}
+	extend(0)
+
+	return out
+}
+
+// translate a solution of the dual problem back into
+// an assignment of the original problem's variables
+func (de DualEncoding[V, D]) Decode(dual map[int]Tuple) map[V]D {
+	out := map[V]D{}
+	for ndx, tuple := range dual {
+		for pos, v := range de.Scopes[ndx] {
+			out[v] = de.original.Domain[v][tuple[pos]]
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,106 @@
+package csp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rejection records a single value ruled out by a constraint
+type Rejection[V comparable, D any] struct {
+	Value      D
+	Constraint Constraint[V]
+}
+
+// DeadEnd records a point in the search where every value of a variable
+// was ruled out directly by constraints against the assignment so far
+type DeadEnd[V comparable, D any] struct {
+	Variable   V
+	Depth      int
+	Rejections []Rejection[V, D]
+	// Count is how many times the search reached a dead end on this variable
+	Count int
+
+	// names the variables in String
+	labels Labels[V]
+}
+
+// explain the dead end in terms of the constraints that removed each value
+func (de DeadEnd[V, D]) String() string {
+	if len(de.Rejections) == 0 {
+		return fmt.Sprintf("variable %s has no values in its domain", de.labels.Name(de.Variable))
+	}
+
+	var reasons []string
+	for _, rejection := range de.Rejections {
+		reasons = append(reasons, fmt.Sprintf("%+v by %s", rejection.Value, de.labels.Constraint(rejection.Constraint)))
+	}
+	return fmt.Sprintf("variable %s has no remaining value because constraints removed them: %s",
+		de.labels.Name(de.Variable), strings.Join(reasons, "; "))
+}
+
+// Explainer observes a Backtracker, collecting the most recent DeadEnd
+// reached on each variable. values that were consistent but failed deeper
+// in the search don't produce a DeadEnd, since their failure is explained
+// by the dead ends of other variables further down the tree
+type Explainer[V comparable, D any] struct {
+	// Labels, if set, name the variables and constraints in the
+	// explanations, e.g. the Problem's
+	Labels Labels[V]
+
+	deadEnds map[V]DeadEnd[V, D]
+	// per depth: values tried and rejected for the variable being assigned there
+	tried    map[int]int
+	rejected map[int][]Rejection[V, D]
+	depth    map[V]int
+}
+
+// construct an empty Explainer
+func NewExplainer[V comparable, D any]() *Explainer[V, D] {
+	return &Explainer[V, D]{
+		deadEnds: map[V]DeadEnd[V, D]{},
+		tried:    map[int]int{},
+		rejected: map[int][]Rejection[V, D]{},
+		depth:    map[V]int{},
+	}
+}
+
+// obtain the Hooks that feed this Explainer, for use with Backtracker.Observe
+func (e *Explainer[V, D]) Hooks() Hooks[V, D] {
+	return Hooks[V, D]{
+		OnAssign: func(variable V, value D, depth int) {
+			e.tried[depth]++
+			e.depth[variable] = depth
+		},
+		OnReject: func(variable V, value D, constraint Constraint[V]) {
+			depth := e.depth[variable]
+			e.rejected[depth] = append(e.rejected[depth], Rejection[V, D]{
+				Value:      value,
+				Constraint: constraint,
+			})
+		},
+		OnBacktrack: func(variable V, depth int) {
+			if len(e.rejected[depth]) == e.tried[depth] {
+				e.deadEnds[variable] = DeadEnd[V, D]{
+					Variable:   variable,
+					Depth:      depth,
+					Rejections: e.rejected[depth],
+					Count:      e.deadEnds[variable].Count + 1,
+					labels:     e.Labels,
+				}
+			}
+			delete(e.tried, depth)
+			delete(e.rejected, depth)
+		},
+	}
+}
+
+// the most recent DeadEnd reached on each variable
+func (e *Explainer[V, D]) DeadEnds() map[V]DeadEnd[V, D] {
+	return e.deadEnds
+}
+
+// the DeadEnd for a single variable, if the search ever reached one
+func (e *Explainer[V, D]) Explain(variable V) (DeadEnd[V, D], bool) {
+	deadEnd, found := e.deadEnds[variable]
+	return deadEnd, found
+}
@@ -0,0 +1,99 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the problem's variables in a deterministic order, keyed by
+// names that are valid identifiers in other modeling languages
+type exportNames[V comparable] struct {
+	ordered []V
+	names   map[V]string
+}
+
+// name each variable after its Label's Name or else its printed form,
+// with characters outside [A-Za-z0-9_] replaced, prefixed so that it
+// never starts with a digit, and suffixed where needed to keep the names
+// unique
+func (p Problem[V, D]) exportNames() exportNames[V] {
+	en := exportNames[V]{names: map[V]string{}}
+	for v := range p.Domain {
+		en.ordered = append(en.ordered, v)
+	}
+	sort.Slice(en.ordered, func(i, j int) bool {
+		return fmt.Sprintf("%+v", en.ordered[i]) < fmt.Sprintf("%+v", en.ordered[j])
+	})
+
+	taken := map[string]bool{}
+	for _, v := range en.ordered {
+		name := "v_" + strings.Map(func(r rune) rune {
+			if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+			return '_'
+		}, p.Labels.Name(v))
+
+		unique := name
+		for n := 2; taken[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", name, n)
+		}
+		taken[unique] = true
+		en.names[v] = unique
+	}
+
+	return en
+}
+
+// the integer values of a variable's domain, for export
+func (p Problem[V, D]) exportDomain(v V) ([]int, error) {
+	if kind := kindOf[D](); !isIntegerKind(kind) {
+		return nil, fmt.Errorf("export requires integer domain values, not %s", kind)
+	}
+
+	values := make([]int, len(p.Domain[v]))
+	for ndx, value := range p.Domain[v] {
+		values[ndx] = asInt(value)
+	}
+	return values, nil
+}
+
+// the constraints of the problem restated in terms of built-in relations:
+// user-defined constraints become tables of the value tuples satisfying
+// them, so they must only inspect their own Variables
+func (p Problem[V, D]) exportConstraints() []Constraint[V] {
+	var out []Constraint[V]
+	for _, constraint := range p.allConstraints() {
+		if constraint.Relation == "" {
+			constraint = p.tabulate(constraint)
+		}
+		out = append(out, constraint)
+	}
+
+	return out
+}
+
+// restate a constraint as a table of the value tuples satisfying it,
+// for formats with no direct translation of it
+func (p Problem[V, D]) tabulate(constraint Constraint[V]) Constraint[V] {
+	scope := distinct(constraint.Variables)
+	var values []int
+	for _, tuple := range p.satisfyingTuples(constraint, scope) {
+		for ndx, i := range tuple {
+			values = append(values, asInt(p.Domain[scope[ndx]][i]))
+		}
+	}
+
+	return Constraint[V]{Variables: scope, Relation: RelationTable, Args: values}
+}
+
+// group a table's arguments into one tuple per row
+func tuples(args []int, arity int) [][]int {
+	var out [][]int
+	for start := 0; start+arity <= len(args); start += arity {
+		out = append(out, args[start:start+arity])
+	}
+
+	return out
+}
@@ -0,0 +1,440 @@
+package csp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// the most combinations of values a condition may range over to be posted
+// as a table, when no propagator of its own fits it
+const maxCondTuples = 1 << 16
+
+// a nonlinear term of an Expr, times its coefficient
+type term struct {
+	coefficient int
+	// "*" for the product of the args, or "abs" for the absolute value of
+	// its one arg
+	op   string
+	args []Expr
+}
+
+// Cond is a condition over a Model's variables, for Post: a comparison of
+// two expressions, e.g. x.Plus(y).Lt(z), or a combination of other
+// conditions with And, Or, Not and Implies
+type Cond struct {
+	// "cmp", "and", "or" or "not"
+	op string
+	// for a comparison, the expression on the left less the one on the
+	// right, compared to 0 by the operator
+	operator Operator
+	diff     Expr
+	args     []Cond
+}
+
+// the expression times another. times a constant, it's the same as Times,
+// and stays linear
+func (e Expr) Mul(other Expr) Expr {
+	if k, ok := other.constantOnly(); ok {
+		return e.Times(k)
+	}
+	if k, ok := e.constantOnly(); ok {
+		return other.Times(k)
+	}
+	// products of products are flattened, so x.Mul(y).Mul(z) is x*y*z
+	var args []Expr
+	for _, arg := range []Expr{e, other} {
+		if arg.isTerm("*") {
+			args = append(args, arg.terms[0].args...)
+		} else {
+			args = append(args, arg)
+		}
+	}
+	return Expr{terms: []term{{coefficient: 1, op: "*", args: args}}}
+}
+
+// the absolute value of the expression
+func (e Expr) Abs() Expr {
+	if k, ok := e.constantOnly(); ok {
+		if k < 0 {
+			k = -k
+		}
+		return Const(k)
+	}
+	return Expr{terms: []term{{coefficient: 1, op: "abs", args: []Expr{e}}}}
+}
+
+// the value of the expression if it's a constant
+func (e Expr) constantOnly() (int, bool) {
+	return e.constant, len(e.names) == 0 && len(e.terms) == 0
+}
+
+// whether the expression is a single variable, and which
+func (e Expr) variable() (string, bool) {
+	if len(e.names) == 1 && e.coefficients[0] == 1 && e.constant == 0 && len(e.terms) == 0 {
+		return e.names[0], true
+	}
+	return "", false
+}
+
+// whether the expression is nothing but one nonlinear term of the op,
+// with a coefficient of 1
+func (e Expr) isTerm(op string) bool {
+	return len(e.names) == 0 && e.constant == 0 && len(e.terms) == 1 &&
+		e.terms[0].coefficient == 1 && e.terms[0].op == op
+}
+
+// the term as it would be written, e.g. x*(y + 1) or |x - y|
+func (t term) String() string {
+	if t.op == "abs" {
+		return "|" + t.args[0].String() + "|"
+	}
+	factors := make([]string, len(t.args))
+	for ndx, arg := range t.args {
+		factors[ndx] = arg.String()
+		if _, ok := arg.variable(); !ok && !arg.isTerm("abs") {
+			factors[ndx] = "(" + factors[ndx] + ")"
+		}
+	}
+	return strings.Join(factors, "*")
+}
+
+// the value of the term, before its coefficient, under a solution
+func (t term) value(solution map[string]int) int {
+	if t.op == "abs" {
+		n := t.args[0].Value(solution)
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	n := 1
+	for _, arg := range t.args {
+		n *= arg.Value(solution)
+	}
+	return n
+}
+
+// append the variables of the expression not already in the list
+func (e Expr) variables(out []string) []string {
+	for _, name := range e.names {
+		out = appendMissing(out, name)
+	}
+	for _, t := range e.terms {
+		for _, arg := range t.args {
+			out = arg.variables(out)
+		}
+	}
+	return out
+}
+
+func appendMissing(names []string, name string) []string {
+	for _, n := range names {
+		if n == name {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// require all the conditions to hold
+func And(conds ...Cond) Cond {
+	return Cond{op: "and", args: conds}
+}
+
+// require at least one of the conditions to hold
+func Or(conds ...Cond) Cond {
+	return Cond{op: "or", args: conds}
+}
+
+// require the condition not to hold
+func Not(cond Cond) Cond {
+	return Cond{op: "not", args: []Cond{cond}}
+}
+
+// require the consequence to hold wherever the condition does
+func Implies(cond, consequence Cond) Cond {
+	return Or(Not(cond), consequence)
+}
+
+// whether the condition holds under a solution of the Model's Problem
+func (c Cond) Holds(solution map[string]int) bool {
+	switch c.op {
+	case "cmp":
+		return c.operator.holds(c.diff.Value(solution), 0)
+	case "and":
+		for _, arg := range c.args {
+			if !arg.Holds(solution) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, arg := range c.args {
+			if arg.Holds(solution) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return !c.args[0].Holds(solution)
+	}
+	return false
+}
+
+// the condition that holds wherever this one doesn't, with the Not pushed
+// down to the comparisons, whose operators are negated
+func (c Cond) negate() Cond {
+	switch c.op {
+	case "cmp":
+		c.operator = c.operator.negated()
+		return c
+	case "not":
+		return c.args[0]
+	}
+	args := make([]Cond, len(c.args))
+	for ndx, arg := range c.args {
+		args[ndx] = arg.negate()
+	}
+	if c.op == "and" {
+		return Or(args...)
+	}
+	return And(args...)
+}
+
+// the variables the condition ranges over, in the order they appear
+func (c Cond) variables(out []string) []string {
+	if c.op == "cmp" {
+		return c.diff.variables(out)
+	}
+	for _, arg := range c.args {
+		out = arg.variables(out)
+	}
+	return out
+}
+
+// the condition as it would be written, e.g. x + y < z or (x == 1) or (y == 1)
+func (c Cond) String() string {
+	switch c.op {
+	case "cmp":
+		lhs := c.diff
+		lhs.constant = 0
+		return fmt.Sprintf("%s %s %d", lhs, c.operator, -c.diff.constant)
+	case "not":
+		return "not (" + c.args[0].String() + ")"
+	case "and", "or":
+		parts := make([]string, len(c.args))
+		for ndx, arg := range c.args {
+			parts[ndx] = "(" + arg.String() + ")"
+		}
+		return strings.Join(parts, " "+c.op+" ")
+	}
+	return "empty condition"
+}
+
+// post the condition as the constraints whose propagators fit it best:
+//
+//   - a linear comparison, as a linear constraint, propagating bounds
+//   - a conjunction, as the constraints of each of its conditions
+//   - a negation, as its condition with the operators negated, so that
+//     not (x < y) is x >= y
+//   - |x - y| compared to a constant, as an absdiff constraint
+//   - a product of distinct variables equal to a constant, as a product
+//     constraint
+//
+// and anything else, such as a disjunction or a comparison of products,
+// as a table of the combinations of values of its variables that satisfy
+// it, which is refused past maxCondTuples of them
+func (m *Model) compile(c Cond) ([]ModelConstraint, error) {
+	switch c.op {
+	case "and":
+		var out []ModelConstraint
+		for _, arg := range c.args {
+			constraints, err := m.compile(arg)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, constraints...)
+		}
+		return out, nil
+	case "not":
+		return m.compile(c.args[0].negate())
+	case "cmp":
+		if mc, ok := c.propagator(); ok {
+			return []ModelConstraint{mc}, nil
+		}
+	case "or":
+	default:
+		return nil, fmt.Errorf("empty condition")
+	}
+	return m.table(c)
+}
+
+// the constraint of a propagator that fits the comparison, if one does
+func (c Cond) propagator() (ModelConstraint, bool) {
+	diff := c.diff
+	if len(diff.terms) == 0 {
+		return ModelConstraint{
+			Type:         "linear",
+			Variables:    diff.names,
+			Coefficients: diff.coefficients,
+			Operator:     c.operator,
+			Constant:     -diff.constant,
+		}, len(diff.names) > 0
+	}
+	if len(diff.names) > 0 || len(diff.terms) > 1 {
+		return ModelConstraint{}, false
+	}
+	t := diff.terms[0]
+	if t.coefficient != 1 && t.coefficient != -1 {
+		return ModelConstraint{}, false
+	}
+
+	switch t.op {
+	case "abs":
+		// k*|a - b| + constant compared to 0
+		arg := t.args[0]
+		if len(arg.names) != 2 || len(arg.terms) > 0 || arg.constant != 0 ||
+			arg.coefficients[0]*arg.coefficients[1] != -1 {
+			return ModelConstraint{}, false
+		}
+		operator, constant := c.operator, -diff.constant
+		if t.coefficient < 0 {
+			operator, constant = operator.flipped(), diff.constant
+		}
+		return ModelConstraint{Type: "absdiff", Variables: arg.names, Operator: operator, Constant: constant}, true
+
+	case "*":
+		// k*(a*b*...) + constant == 0
+		if c.operator != Eq {
+			return ModelConstraint{}, false
+		}
+		var names []string
+		for _, arg := range t.args {
+			name, ok := arg.variable()
+			if !ok || len(appendMissing(names, name)) == len(names) {
+				return ModelConstraint{}, false
+			}
+			names = append(names, name)
+		}
+		return ModelConstraint{Type: "product", Variables: names, Constant: -diff.constant * t.coefficient}, true
+	}
+	return ModelConstraint{}, false
+}
+
+// the table of the combinations of values of the condition's variables
+// that satisfy it, named for the condition
+func (m *Model) table(c Cond) ([]ModelConstraint, error) {
+	vars := c.variables(nil)
+	if len(vars) == 0 {
+		if c.Holds(nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("never holds")
+	}
+
+	domains := make([][]int, len(vars))
+	size := 1
+	for ndx, name := range vars {
+		values, err := m.domainOf(name)
+		if err != nil {
+			return nil, err
+		}
+		domains[ndx] = values
+		if size *= len(values); size > maxCondTuples {
+			return nil, fmt.Errorf("ranges over more than %d combinations of values; split it into simpler conditions", maxCondTuples)
+		}
+	}
+
+	mc := ModelConstraint{Type: "table", Name: c.String(), Variables: vars, Tuples: [][]int{}}
+	if size == 0 {
+		return []ModelConstraint{mc}, nil
+	}
+	// count through the combinations, the last variable fastest
+	positions := make([]int, len(vars))
+	solution := make(map[string]int, len(vars))
+	for {
+		tuple := make([]int, len(vars))
+		for ndx, name := range vars {
+			tuple[ndx] = domains[ndx][positions[ndx]]
+			solution[name] = tuple[ndx]
+		}
+		if c.Holds(solution) {
+			mc.Tuples = append(mc.Tuples, tuple)
+		}
+
+		ndx := len(vars) - 1
+		for ; ndx >= 0; ndx-- {
+			if positions[ndx]++; positions[ndx] < len(domains[ndx]) {
+				break
+			}
+			positions[ndx] = 0
+		}
+		if ndx < 0 {
+			return []ModelConstraint{mc}, nil
+		}
+	}
+}
+
+// the values of a declared variable
+func (m *Model) domainOf(name string) ([]int, error) {
+	for _, mv := range m.Variables {
+		if mv.Name == name {
+			return mv.values()
+		}
+	}
+	return nil, fmt.Errorf("unknown variable %q", name)
+}
+
+// whether a compares to b as the operator specifies
+func (op Operator) holds(a, b int) bool {
+	switch op {
+	case Eq:
+		return a == b
+	case Ne:
+		return a != b
+	case Lt:
+		return a < b
+	case Le:
+		return a <= b
+	case Gt:
+		return a > b
+	case Ge:
+		return a >= b
+	}
+	return false
+}
+
+// the operator that holds wherever this one doesn't
+func (op Operator) negated() Operator {
+	switch op {
+	case Eq:
+		return Ne
+	case Ne:
+		return Eq
+	case Lt:
+		return Ge
+	case Le:
+		return Gt
+	case Gt:
+		return Le
+	case Ge:
+		return Lt
+	}
+	return op
+}
+
+// the operator comparing the sides the other way around, so that a < b
+// is b > a
+func (op Operator) flipped() Operator {
+	switch op {
+	case Lt:
+		return Gt
+	case Le:
+		return Ge
+	case Gt:
+		return Lt
+	case Ge:
+		return Le
+	}
+	return op
+}
@@ -0,0 +1,66 @@
+package csp
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// the gob wire form of a Problem. SatFn can't be serialized, so
+// constraints are carried as built-in relations only
+type problemGob[V comparable, D any] struct {
+	Domain      map[V][]D
+	Constraints []Constraint[V]
+}
+
+// encode the problem with encoding/gob, so that models can be cached or
+// shipped between processes. user-defined constraints are encoded as
+// tables of their satisfying tuples, so they must only inspect their own
+// Variables; that requires integer domain values, as the built-in
+// relations do. V and D must themselves be encodable by gob
+func (p Problem[V, D]) GobEncode() ([]byte, error) {
+	out := problemGob[V, D]{Domain: p.Domain}
+	if isIntegerKind(kindOf[D]()) {
+		out.Constraints = p.exportConstraints()
+	} else if constraints := p.allConstraints(); len(constraints) > 0 {
+		return nil, fmt.Errorf("constraint over %+v can only be encoded with integer domain values, not %s",
+			constraints[0].Variables, kindOf[D]())
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode a problem encoded by GobEncode. the decoded problem evaluates
+// every constraint as a built-in relation, so needs no SatFn
+func (p *Problem[V, D]) GobDecode(data []byte) error {
+	var in problemGob[V, D]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&in); err != nil {
+		return err
+	}
+	if in.Domain == nil {
+		in.Domain = map[V][]D{}
+	}
+
+	out := New(in.Domain, nil)
+	for _, constraint := range in.Constraints {
+		if constraint.Relation == "" || len(constraint.Variables) == 0 {
+			return fmt.Errorf("constraint over %+v is not a built-in relation", constraint.Variables)
+		}
+		if err := validateRelation[D](constraint.Relation, len(constraint.Variables), constraint.Args); err != nil {
+			return fmt.Errorf("constraint over %+v: %s", constraint.Variables, err)
+		}
+		for _, v := range constraint.Variables {
+			if _, found := out.Domain[v]; !found {
+				return fmt.Errorf("constraint variable %+v not found in Problem", v)
+			}
+		}
+		out.AddConstraint(constraint)
+	}
+
+	*p = out
+	return nil
+}
@@ -0,0 +1,151 @@
+package csp
+
+// Graph describes the structure of a Problem: the variables are nodes, and
+// an edge joins every pair of variables that share a constraint
+type Graph[V comparable] struct {
+	Variables []V
+	// Neighbors lists the variables sharing a constraint with each variable
+	Neighbors map[V][]V
+	// Constraints holds each constraint once, as a hyperedge over its variables
+	Constraints []Constraint[V]
+	Edges       int
+	Degree      DegreeStats
+	// Density is the fraction of all possible edges that are present
+	Density float64
+	// Components holds the variables of each connected component
+	Components [][]V
+	// TreewidthEstimate is the width of a greedy tree decomposition,
+	// an upper bound on the true treewidth of the graph
+	TreewidthEstimate int
+}
+
+// DegreeStats summarizes how many neighbors the variables of a Graph have
+type DegreeStats struct {
+	Min  int
+	Max  int
+	Mean float64
+}
+
+// analyze the structure of the constraint graph, e.g. to pick a solving
+// strategy: many components favor SolveComponents, and a low treewidth
+// estimate favors SolveTree
+func (p Problem[V, D]) Graph() Graph[V] {
+	adj := p.neighbors()
+	g := Graph[V]{
+		Neighbors:         make(map[V][]V, len(adj)),
+		Constraints:       p.allConstraints(),
+		Components:        p.components(),
+		TreewidthEstimate: p.TreeDecomposition().Width(),
+	}
+
+	degrees := 0
+	for v, neighbors := range adj {
+		g.Variables = append(g.Variables, v)
+		g.Neighbors[v] = append([]V{}, neighbors...)
+
+		degree := len(neighbors)
+		degrees += degree
+		if len(g.Variables) == 1 || degree < g.Degree.Min {
+			g.Degree.Min = degree
+		}
+		if degree > g.Degree.Max {
+			g.Degree.Max = degree
+		}
+	}
+
+	g.Edges = degrees / 2
+	if n := len(g.Variables); n > 0 {
+		g.Degree.Mean = float64(degrees) / float64(n)
+		if n > 1 {
+			g.Density = float64(g.Edges) / float64(n*(n-1)/2)
+		}
+	}
+
+	return g
+}
+
+// the variables sharing a constraint with the given one, each once: its
+// neighbors in the primal constraint graph. the list is shared, and must
+// not be modified
+func (p Problem[V, D]) Neighbors(v V) []V {
+	return p.neighbors()[v]
+}
+
+// the neighbors of every variable, built once and cached until the next
+// AddConstraint. the lists are shared, and must not be modified
+func (p Problem[V, D]) neighbors() map[V][]V {
+	if p.cache == nil {
+		return p.listNeighbors()
+	}
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+	if p.cache.neighbors == nil {
+		p.cache.neighbors = p.listNeighbors()
+	}
+	return p.cache.neighbors
+}
+
+// build the primal constraint graph: an edge joins every pair of
+// distinct variables that appear together in at least one constraint
+func (p Problem[V, D]) listNeighbors() map[V][]V {
+	seen := make(map[V]map[V]bool, len(p.Domain))
+	out := make(map[V][]V, len(p.Domain))
+	for v := range p.Domain {
+		seen[v] = map[V]bool{}
+		out[v] = nil
+	}
+
+	for _, constraint := range p.listConstraints() {
+		for _, a := range constraint.Variables {
+			for _, b := range constraint.Variables {
+				if a != b && !seen[a][b] {
+					seen[a][b] = true
+					out[a] = append(out[a], b)
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// the primal constraint graph as a set of neighbors per variable, a
+// fresh copy for the caller to modify
+func (p Problem[V, D]) adjacency() map[V]map[V]struct{} {
+	neighbors := p.neighbors()
+	adj := make(map[V]map[V]struct{}, len(neighbors))
+	for v, list := range neighbors {
+		adj[v] = make(map[V]struct{}, len(list))
+		for _, n := range list {
+			adj[v][n] = struct{}{}
+		}
+	}
+	return adj
+}
+
+// split the variables into the connected components of the constraint graph
+func (p Problem[V, D]) components() [][]V {
+	adj := p.neighbors()
+	seen := make(map[V]bool, len(adj))
+
+	var out [][]V
+	for start := range adj {
+		if seen[start] {
+			continue
+		}
+
+		seen[start] = true
+		component := []V{start}
+		for next := 0; next < len(component); next++ {
+			for _, neighbor := range adj[component[next]] {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					component = append(component, neighbor)
+				}
+			}
+		}
+		out = append(out, component)
+	}
+
+	return out
+}
@@ -0,0 +1,64 @@
+package csp
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// add the variables to the named group, in order, after any already in
+// it, and tag each with the name, so that the group can be picked out of
+// its Labels too. groups of rows, columns, shifts or teams can then be
+// constrained by name, one at a time or all at once with ConstrainGroups
+func (p Problem[V, D]) Group(name string, vars ...V) {
+	for _, v := range vars {
+		if _, found := p.Domain[v]; !found {
+			panic(fmt.Sprintf("error: group %s: variable %s not found in Problem", name, p.Labels.Name(v)))
+		}
+		p.Groups[name] = append(p.Groups[name], v)
+
+		// the tags may be shared with other labels, so are copied on write
+		label := p.Labels[v]
+		label.Tags = append(label.Tags[:len(label.Tags):len(label.Tags)], name)
+		p.Labels[v] = label
+	}
+}
+
+// the variables whose Labels satisfy the predicate, ordered by name, a
+// group defined by what's known of them rather than listed by hand, e.g.
+// those tagged "night", or with a Meta "ward" of "B". variables without a
+// Label are tested with the zero Label
+func (p Problem[V, D]) Select(predicate func(v V, label Label) bool) []V {
+	var out []V
+	for v := range p.Domain {
+		if predicate(v, p.Labels[v]) {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return p.Labels.Name(out[i]) < p.Labels.Name(out[j]) })
+	return out
+}
+
+// apply a constraint over the variables of each group whose name matches
+// the pattern, as path.Match matches it, e.g. "row*" or "*", in order of
+// the groups' names, reporting how many were constrained. constraint is
+// given the group's variables, and may be a constraint's constructor
+// itself, e.g. csp.AllDifferent[Cell], or a func wrapping one
+func (p Problem[V, D]) ConstrainGroups(pattern string, constraint func(vars ...V) Constraint[V]) int {
+	var names []string
+	for name := range p.Groups {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			panic(fmt.Sprintf("error: group pattern %q: %s", pattern, err))
+		}
+		if matched {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p.AddConstraint(constraint(p.Groups[name]...))
+	}
+	return len(names)
+}
@@ -0,0 +1,141 @@
+package csp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// Hardness estimates how difficult a Problem will be to solve, before
+// attempting it. the estimates follow the constrainedness measure of Gent
+// et al.: kappa near 1 marks the phase transition where random instances
+// are hardest, well below 1 the problem is loosely constrained and likely
+// to have many solutions, and well above 1 it is likely infeasible
+type Hardness struct {
+	Variables   int
+	Constraints int
+	DomainSize  DomainStats
+	// Log2SearchSpace is log2 of the number of complete assignments, or
+	// -Inf if a variable has no values, and so the problem no solution
+	Log2SearchSpace float64
+	// MeanTightness is the average fraction of scope assignments the constraints reject
+	MeanTightness float64
+	MaxTightness  float64
+	// Log2ExpectedSolutions is log2 of the expected number of solutions,
+	// were the constraints independent of one another
+	Log2ExpectedSolutions float64
+	Kappa                 float64
+}
+
+// DomainStats summarizes the domain sizes of a Problem's variables
+type DomainStats struct {
+	Min  int
+	Max  int
+	Mean float64
+}
+
+// estimate the difficulty of the problem. each constraint's tightness is
+// estimated by checking it against the given number of random assignments
+// of its own Variables, so constraints must only inspect their own Variables
+// for the estimate to be meaningful
+func (p Problem[V, D]) EstimateHardness(samples int, rng *rand.Rand) Hardness {
+	if samples < 1 {
+		samples = 0
+	}
+	h := Hardness{
+		Variables: len(p.Domain),
+	}
+
+	// seeded from the first domain, so that an empty one, which leaves
+	// the problem unsatisfiable, keeps the Min at 0
+	total, first := 0, true
+	for _, values := range p.Domain {
+		size := len(values)
+		total += size
+		if first || size < h.DomainSize.Min {
+			h.DomainSize.Min = size
+		}
+		first = false
+		if size > h.DomainSize.Max {
+			h.DomainSize.Max = size
+		}
+		// the log2 of 0, -Inf, stays so whatever else is added to it
+		h.Log2SearchSpace += math.Log2(float64(size))
+	}
+	if h.Variables > 0 {
+		h.DomainSize.Mean = float64(total) / float64(h.Variables)
+	}
+
+	// the fraction of assignments surviving every constraint, in log2
+	log2Surviving := 0.0
+	for _, constraint := range p.allConstraints() {
+		h.Constraints++
+		tightness := p.sampleTightness(constraint, samples, rng)
+		h.MeanTightness += tightness
+		if tightness > h.MaxTightness {
+			h.MaxTightness = tightness
+		}
+
+		// a constraint that rejected every sample is treated as
+		// only nearly impossible, to keep the logarithm finite
+		log2Surviving += math.Log2(math.Max(1-tightness, 1/float64(samples+1)))
+	}
+	if h.Constraints > 0 {
+		h.MeanTightness /= float64(h.Constraints)
+	}
+
+	h.Log2ExpectedSolutions = h.Log2SearchSpace + log2Surviving
+	if h.Log2SearchSpace > 0 {
+		h.Kappa = -log2Surviving / h.Log2SearchSpace
+	}
+
+	return h
+}
+
+// estimate the fraction of assignments to the constraint's variables it rejects
+func (p Problem[V, D]) sampleTightness(constraint Constraint[V], samples int, rng *rand.Rand) float64 {
+	if samples <= 0 {
+		return 0
+	}
+
+	rejected := 0
+	candidate := make(map[V]D, len(constraint.Variables))
+	for i := 0; i < samples; i++ {
+		for _, v := range constraint.Variables {
+			values := p.Domain[v]
+			if len(values) == 0 {
+				return 1
+			}
+			candidate[v] = values[rng.Intn(len(values))]
+		}
+		if !p.SatFn(constraint, candidate) {
+			rejected++
+		}
+	}
+
+	return float64(rejected) / float64(samples)
+}
+
+// a rough reading of the constrainedness estimate
+func (h Hardness) Verdict() string {
+	switch {
+	case math.IsInf(h.Log2SearchSpace, -1):
+		return "infeasible: a variable has no values"
+	case h.Kappa < 0.5:
+		return "loosely constrained: likely easy, with many solutions"
+	case h.Kappa < 0.8:
+		return "moderately constrained"
+	case h.Kappa <= 1.2:
+		return "near the phase transition: likely hard"
+	default:
+		return "overconstrained: likely infeasible"
+	}
+}
+
+// summarize the estimate on a single line
+func (h Hardness) String() string {
+	return fmt.Sprintf("%d vars, %d constraints, domains %d-%d (mean %.1f), search space 2^%.1f, "+
+		"tightness %.2f (max %.2f), expected solutions 2^%.1f, kappa %.2f: %s",
+		h.Variables, h.Constraints, h.DomainSize.Min, h.DomainSize.Max, h.DomainSize.Mean,
+		h.Log2SearchSpace, h.MeanTightness, h.MaxTightness, h.Log2ExpectedSolutions, h.Kappa, h.Verdict())
+}
@@ -0,0 +1,150 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// VariableOrder picks which unassigned variable a Backtracker assigns
+// next, given the assignment so far
+type VariableOrder[V comparable, D any] func(assignment map[V]D) V
+
+// ValueOrder orders the values a Backtracker tries for a variable,
+// given the assignment so far
+type ValueOrder[V comparable, D any] func(variable V, assignment map[V]D) []D
+
+// assign variables in order of their printed form, so that searches are
+// reproducible from run to run, unlike the default map order
+func Lexicographic[V comparable, D any](p Problem[V, D]) VariableOrder[V, D] {
+	var ordered []V
+	for v := range p.Domain {
+		ordered = append(ordered, v)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return fmt.Sprintf("%+v", ordered[i]) < fmt.Sprintf("%+v", ordered[j])
+	})
+
+	return func(assignment map[V]D) V {
+		for _, v := range ordered {
+			if _, found := assignment[v]; !found {
+				return v
+			}
+		}
+		panic("error: no unassigned variable left")
+	}
+}
+
+// assign the variable with the fewest values left consistent with the
+// assignment so far, failing early where the search is bound to fail.
+// ties go to the variable with the most unassigned neighbors. constraints
+// must only inspect their own Variables
+func MinRemainingValues[V comparable, D any](p Problem[V, D]) VariableOrder[V, D] {
+	adj := p.neighbors()
+	return func(assignment map[V]D) V {
+		var best V
+		bestRemaining, bestDegree := -1, -1
+		for v := range p.Domain {
+			if _, found := assignment[v]; found {
+				continue
+			}
+
+			remaining := 0
+			for _, value := range p.Domain[v] {
+				if p.consistentWith(v, value, assignment) {
+					remaining++
+				}
+			}
+			if bestRemaining >= 0 && remaining > bestRemaining {
+				continue
+			}
+			degree := unassigned(adj[v], assignment)
+			if remaining < bestRemaining || bestRemaining < 0 || degree > bestDegree {
+				best, bestRemaining, bestDegree = v, remaining, degree
+			}
+		}
+		return best
+	}
+}
+
+// assign the variable sharing constraints with the most unassigned
+// variables, to constrain the rest of the search as much as possible
+func MaxDegree[V comparable, D any](p Problem[V, D]) VariableOrder[V, D] {
+	adj := p.neighbors()
+	return func(assignment map[V]D) V {
+		var best V
+		bestDegree := -1
+		for v := range p.Domain {
+			if _, found := assignment[v]; found {
+				continue
+			}
+			if degree := unassigned(adj[v], assignment); degree > bestDegree {
+				best, bestDegree = v, degree
+			}
+		}
+		return best
+	}
+}
+
+// try first the values that rule out the fewest values of the unassigned
+// neighboring variables, leaving the rest of the search the most room.
+// constraints must only inspect their own Variables
+func LeastConstrainingValue[V comparable, D any](p Problem[V, D]) ValueOrder[V, D] {
+	adj := p.neighbors()
+	return func(variable V, assignment map[V]D) []D {
+		values := p.Domain[variable]
+		ruledOut := make([]int, len(values))
+		for ndx, value := range values {
+			assignment[variable] = value
+			for _, neighbor := range adj[variable] {
+				if _, found := assignment[neighbor]; found {
+					continue
+				}
+				for _, candidate := range p.Domain[neighbor] {
+					if !p.consistentWith(neighbor, candidate, assignment) {
+						ruledOut[ndx]++
+					}
+				}
+			}
+			delete(assignment, variable)
+		}
+
+		order := make([]int, len(values))
+		for ndx := range order {
+			order[ndx] = ndx
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return ruledOut[order[i]] < ruledOut[order[j]]
+		})
+
+		out := make([]D, len(values))
+		for ndx, i := range order {
+			out[ndx] = values[i]
+		}
+		return out
+	}
+}
+
+// count the variables of the list without a value in the assignment
+func unassigned[V comparable, D any](list []V, assignment map[V]D) int {
+	n := 0
+	for _, v := range list {
+		if _, found := assignment[v]; !found {
+			n++
+		}
+	}
+	return n
+}
+
+// determine whether assigning value to the unassigned variable would
+// satisfy its constraints, leaving the assignment as it was
+func (p Problem[V, D]) consistentWith(variable V, value D, assignment map[V]D) bool {
+	assignment[variable] = value
+	defer delete(assignment, variable)
+
+	for _, constraint := range p.Constraints[variable] {
+		if !p.SatFn(constraint, assignment) {
+			return false
+		}
+	}
+	return true
+}
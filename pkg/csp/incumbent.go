@@ -0,0 +1,65 @@
+package csp
+
+import "sync"
+
+// Incumbent tracks the best solution an optimizing search has found so
+// far, while it goes on, so that a caller can take the best at any time,
+// from any goroutine, and stop the search once it's good enough. register
+// its Hooks with the Backtracker running Minimize, or the LNS, whose
+// OnSolution hooks see each improving solution
+type Incumbent[V comparable, D any] struct {
+	// OnImprove, if set, is called with each solution cheaper than the
+	// best before it, and its cost, from the search's goroutine as the
+	// solution is found. the solution is a copy the callee may keep
+	OnImprove func(solution map[V]D, cost int)
+
+	cost         Cost[V, D]
+	mu           sync.Mutex
+	best         map[V]D
+	bestCost     int
+	improvements int
+}
+
+// construct an Incumbent of the solutions of a search minimizing cost
+func NewIncumbent[V comparable, D any](cost Cost[V, D]) *Incumbent[V, D] {
+	return &Incumbent[V, D]{cost: cost}
+}
+
+// the Hooks keeping the Incumbent up to date with the search
+func (i *Incumbent[V, D]) Hooks() Hooks[V, D] {
+	return Hooks[V, D]{
+		OnSolution: func(solution map[V]D) {
+			cost := i.cost(solution)
+			i.mu.Lock()
+			if i.best != nil && cost >= i.bestCost {
+				i.mu.Unlock()
+				return
+			}
+			i.best, i.bestCost = dup(solution), cost
+			i.improvements++
+			i.mu.Unlock()
+
+			if i.OnImprove != nil {
+				i.OnImprove(dup(solution), cost)
+			}
+		},
+	}
+}
+
+// a copy of the best solution so far and its cost, or false if there's
+// none yet
+func (i *Incumbent[V, D]) Best() (map[V]D, int, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.best == nil {
+		return nil, 0, false
+	}
+	return dup(i.best), i.bestCost, true
+}
+
+// the number of times the best solution has improved
+func (i *Incumbent[V, D]) Improvements() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.improvements
+}
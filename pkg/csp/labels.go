@@ -0,0 +1,66 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Label describes a variable or a constraint for the people reading
+// about it: errors, conflict reports, traces and exports show its Name in
+// place of the printed form of the variable, which for a composite one is
+// hard to read, e.g. {Row:3 Col:4}. Tags group related variables, to pick
+// out with Labels.Tagged, and Meta carries anything else worth knowing
+type Label struct {
+	Name string
+	Tags []string
+	Meta map[string]string
+}
+
+// Labels are the Labels of a Problem's variables. an unlabeled variable
+// goes by its printed form
+type Labels[V comparable] map[V]Label
+
+// the name to show for the variable
+func (l Labels[V]) Name(v V) string {
+	if label, found := l[v]; found && label.Name != "" {
+		return label.Name
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// the names to show for the variables, as a list
+func (l Labels[V]) Names(vars []V) string {
+	names := make([]string, len(vars))
+	for ndx, v := range vars {
+		names[ndx] = l.Name(v)
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+// the name to show for the constraint: its Label's Name if it has one,
+// or else what it requires of the names of its variables
+func (l Labels[V]) Constraint(constraint Constraint[V]) string {
+	if constraint.Label != nil && constraint.Label.Name != "" {
+		return constraint.Label.Name
+	}
+	if constraint.Relation != "" {
+		return fmt.Sprintf("%s over %s", constraint.Relation, l.Names(constraint.Variables))
+	}
+	return "constraint over " + l.Names(constraint.Variables)
+}
+
+// the variables tagged with the tag, by name
+func (l Labels[V]) Tagged(tag string) []V {
+	var out []V
+	for v, label := range l {
+		for _, t := range label.Tags {
+			if t == tag {
+				out = append(out, v)
+				break
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return l.Name(out[i]) < l.Name(out[j]) })
+	return out
+}
@@ -0,0 +1,235 @@
+package csp
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LNS is a large neighborhood search engine, for optimization problems
+// too big for Minimize to search exhaustively: from a first solution, it
+// repeatedly frees a part of the best solution so far, keeps the
+// rest as it is, and searches the freed variables by branch and bound for
+// a cheaper completion, which becomes the best. like MinConflicts, it
+// can't prove its best optimal, but it makes the big moves, of many
+// variables at once, that a local search of one at a time can't
+type LNS[V comparable, D any] struct {
+	Problem Problem[V, D]
+	// Relax is the fraction of the variables freed at each step
+	Relax float64
+	// Neighborhood picks which variables each step frees; if nil, it's
+	// RandomNeighborhood
+	Neighborhood Neighborhood[V, D]
+	// StepTimeout bounds the search of each step, or zero for no limit
+	StepTimeout time.Duration
+	// MaxSteps bounds the number of steps, or zero for no limit
+	MaxSteps int
+	// Timeout bounds how long Minimize may run, or zero for no limit
+	Timeout time.Duration
+	// SelectVariable and OrderValues tune the search of each step, as they
+	// do a Backtracker's
+	SelectVariable VariableOrder[V, D]
+	OrderValues    ValueOrder[V, D]
+	// Rand is passed on to the Neighborhood; if nil, one seeded from the
+	// clock is used
+	Rand *rand.Rand
+
+	hooks []Hooks[V, D]
+	stats Stats
+	// the search of the step under way, for Cancel to stop
+	mu       sync.Mutex
+	step     *Backtracker[V, D]
+	canceled int32
+	// recycles the partial assignments of the steps, and the bests each
+	// improves on
+	pool assignmentPool[V, D]
+}
+
+// construct an LNS engine for the given Problem, freeing a fifth of the
+// variables at each step, for up to a second
+func NewLNS[V comparable, D any](p Problem[V, D]) *LNS[V, D] {
+	return &LNS[V, D]{
+		Problem:     p,
+		Relax:       0.2,
+		StepTimeout: time.Second,
+	}
+}
+
+// register another set of Hooks to be notified as the search of each
+// step runs. OnSolution is called with the first solution, and then with
+// each that improves on the best
+func (l *LNS[V, D]) Observe(hooks Hooks[V, D]) {
+	l.hooks = append(l.hooks, hooks)
+}
+
+// stop the running search as soon as possible, from any goroutine: it
+// returns the best solution so far, and Stats reports the cancellation.
+// a canceled LNS stays canceled, so later searches return at once
+func (l *LNS[V, D]) Cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	atomic.StoreInt32(&l.canceled, 1)
+	if l.step != nil {
+		l.step.Cancel()
+	}
+}
+
+// the work done by the most recent call to Minimize, summed over its
+// steps. Solutions counts the first solution and each improvement on it
+func (l *LNS[V, D]) Stats() Stats {
+	return l.stats
+}
+
+// search for a solution extending the given assignment, as a Solver: the
+// first solution of Minimize, which stops at once when every solution
+// costs the same
+func (l *LNS[V, D]) Solve(assignment map[V]D) map[V]D {
+	solution, _ := l.Minimize(assignment, func(map[V]D) int { return 0 })
+	return solution
+}
+
+// the solution Solve finds, if any, as a Solver. a local search can't
+// enumerate solutions
+func (l *LNS[V, D]) SolveAll(assignment map[V]D) []map[V]D {
+	if solution := l.Solve(assignment); solution != nil {
+		return []map[V]D{solution}
+	}
+	return nil
+}
+
+// Minimize, as a Solver, though its best isn't proven optimal
+func (l *LNS[V, D]) SolveOptimal(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	return l.Minimize(assignment, cost)
+}
+
+// search for a solution extending the given assignment, and then for ever
+// cheaper ones, until MaxSteps, the Timeout or a Cancel: with neither of
+// the first two, it runs until canceled, or until the best costs no more
+// than the given assignment, which no solution extending it can beat. the
+// variables of the given assignment are never freed. the result is the
+// best solution found and its cost, or nil if no first solution was
+// found. if the Timeout expires first, Stats reports the timeout
+func (l *LNS[V, D]) Minimize(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	start := time.Now()
+	l.stats = Stats{}
+	defer func() { l.stats.Duration = time.Since(start) }()
+
+	var deadline time.Time
+	if l.Timeout > 0 {
+		deadline = start.Add(l.Timeout)
+	}
+	rng := l.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	// in a fixed order, so that a seeded Rand frees the same ones each run
+	var free []V
+	for v := range l.Problem.Domain {
+		if _, fixed := assignment[v]; !fixed {
+			free = append(free, v)
+		}
+	}
+	sort.Slice(free, func(i, j int) bool {
+		return fmt.Sprintf("%+v", free[i]) < fmt.Sprintf("%+v", free[j])
+	})
+	freeable := make(map[V]bool, len(free))
+	for _, v := range free {
+		freeable[v] = true
+	}
+	neighborhood := l.Neighborhood
+	if neighborhood == nil {
+		neighborhood = RandomNeighborhood[V, D]()
+	}
+	relax := int(l.Relax*float64(len(free)) + 0.5)
+	if relax < 1 {
+		relax = 1
+	}
+	if relax > len(free) {
+		relax = len(free)
+	}
+
+	var best map[V]D
+	bestCost := 0
+	first := l.searcher(deadline, 0)
+	if first == nil {
+		return nil, 0
+	}
+	best = first.Solve(dup(assignment))
+	l.tally(first)
+	if best == nil {
+		l.stats.TimedOut = first.Stats().TimedOut
+		return nil, 0
+	}
+	bestCost = cost(best)
+
+	floor := cost(assignment)
+	for step := 0; bestCost > floor && len(free) > 0 && (l.MaxSteps == 0 || step < l.MaxSteps); step++ {
+		b := l.searcher(deadline, l.StepTimeout)
+		if b == nil {
+			break
+		}
+
+		partial := l.pool.dup(best)
+		for _, v := range neighborhood(best, free, relax, rng) {
+			if freeable[v] {
+				delete(partial, v)
+			}
+		}
+		best, bestCost = b.minimize(partial, cost, best, bestCost)
+		l.pool.put(partial)
+		l.tally(b)
+	}
+
+	return best, bestCost
+}
+
+// a Backtracker for the next step, its Timeout the step's limit or the
+// time left before the deadline, whichever is sooner, or nil if the time
+// is up or the search canceled
+func (l *LNS[V, D]) searcher(deadline time.Time, limit time.Duration) *Backtracker[V, D] {
+	b := NewBacktracker(l.Problem)
+	b.SelectVariable = l.SelectVariable
+	b.OrderValues = l.OrderValues
+	b.hooks = l.hooks
+	b.pool = &l.pool
+	b.Timeout = limit
+	if !deadline.IsZero() {
+		left := time.Until(deadline)
+		if left <= 0 {
+			l.stats.TimedOut = true
+			return nil
+		}
+		if b.Timeout == 0 || left < b.Timeout {
+			b.Timeout = left
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if atomic.LoadInt32(&l.canceled) == 1 {
+		l.stats.Canceled = true
+		return nil
+	}
+	l.step = b
+	return b
+}
+
+// add up the work of a step's search, once it's done
+func (l *LNS[V, D]) tally(b *Backtracker[V, D]) {
+	l.mu.Lock()
+	l.step = nil
+	l.mu.Unlock()
+
+	stats := b.Stats()
+	l.stats.Nodes += stats.Nodes
+	l.stats.Backtracks += stats.Backtracks
+	l.stats.Rejections += stats.Rejections
+	l.stats.Solutions += stats.Solutions
+	if stats.Canceled {
+		l.stats.Canceled = true
+	}
+}
@@ -0,0 +1,272 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Objective is a linear function of the variables for a MILP exporter to
+// optimize. a nil Objective asks only for a feasible solution
+type Objective[V comparable] struct {
+	Coefficients map[V]int
+	Maximize     bool
+}
+
+// write the problem in CPLEX LP format for MILP solvers such as CBC, HiGHS,
+// or Gurobi. only linear relations can be exported; see linearize
+func (p Problem[V, D]) ExportLP(w io.Writer, objective *Objective[V]) error {
+	lm, err := p.linearize(objective)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	if lm.maximize {
+		sb.WriteString("Maximize\n")
+	} else {
+		sb.WriteString("Minimize\n")
+	}
+	fmt.Fprintf(&sb, " obj: %s\n", lm.lpTerms(lm.objective))
+
+	sb.WriteString("Subject To\n")
+	for _, row := range lm.rows {
+		sense := map[byte]string{'L': "<=", 'E': "=", 'G': ">="}[row.sense]
+		fmt.Fprintf(&sb, " %s: %s %s %d\n", row.name, lm.lpTerms(row.terms), sense, row.rhs)
+	}
+
+	sb.WriteString("Bounds\n")
+	for col, name := range lm.columns {
+		fmt.Fprintf(&sb, " %d <= %s <= %d\n", lm.lower[col], name, lm.upper[col])
+	}
+
+	sb.WriteString("General\n")
+	fmt.Fprintf(&sb, " %s\n", strings.Join(lm.columns, " "))
+	sb.WriteString("End\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// write the problem in free-format MPS for MILP solvers. only linear
+// relations can be exported; see linearize
+func (p Problem[V, D]) ExportMPS(w io.Writer, objective *Objective[V]) error {
+	lm, err := p.linearize(objective)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("NAME csp\n")
+	if lm.maximize {
+		sb.WriteString("OBJSENSE\n    MAX\n")
+	}
+
+	sb.WriteString("ROWS\n N obj\n")
+	for _, row := range lm.rows {
+		fmt.Fprintf(&sb, " %c %s\n", row.sense, row.name)
+	}
+
+	// MPS is column-major: gather each column's entries across rows
+	entries := make([][]string, len(lm.columns))
+	for _, t := range lm.objective {
+		entries[t.col] = append(entries[t.col], fmt.Sprintf("obj %d", t.coeff))
+	}
+	for _, row := range lm.rows {
+		for _, t := range row.terms {
+			entries[t.col] = append(entries[t.col], fmt.Sprintf("%s %d", row.name, t.coeff))
+		}
+	}
+
+	sb.WriteString("COLUMNS\n")
+	sb.WriteString("    MARKER 'MARKER' 'INTORG'\n")
+	for col, name := range lm.columns {
+		for _, entry := range entries[col] {
+			fmt.Fprintf(&sb, "    %s %s\n", name, entry)
+		}
+		if len(entries[col]) == 0 {
+			// columns must appear here to exist at all
+			fmt.Fprintf(&sb, "    %s obj 0\n", name)
+		}
+	}
+	sb.WriteString("    MARKER 'MARKER' 'INTEND'\n")
+
+	sb.WriteString("RHS\n")
+	for _, row := range lm.rows {
+		if row.rhs != 0 {
+			fmt.Fprintf(&sb, "    RHS %s %d\n", row.name, row.rhs)
+		}
+	}
+
+	sb.WriteString("BOUNDS\n")
+	for col, name := range lm.columns {
+		fmt.Fprintf(&sb, " LO BND %s %d\n", name, lm.lower[col])
+		fmt.Fprintf(&sb, " UP BND %s %d\n", name, lm.upper[col])
+	}
+	sb.WriteString("ENDATA\n")
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// a mixed integer linear program: integer columns with bounds,
+// and rows comparing a weighted sum of columns to a constant
+type linearModel struct {
+	columns      []string
+	lower, upper []int
+	rows         []linearRow
+	objective    []linearTerm
+	maximize     bool
+}
+
+type linearRow struct {
+	name  string
+	terms []linearTerm
+	// 'L' for <=, 'E' for ==, 'G' for >=
+	sense byte
+	rhs   int
+}
+
+type linearTerm struct {
+	col   int
+	coeff int
+}
+
+// restate the problem as a MILP. each variable becomes an integer column
+// bounded by its domain; domains with gaps are pinned to their values by an
+// auxiliary one-hot set of binary columns. linear == and <= relations become
+// rows directly, and != is split into < or > by an auxiliary binary column
+// with big-M rows. any other constraint makes the model non-linear
+func (p Problem[V, D]) linearize(objective *Objective[V]) (linearModel, error) {
+	en := p.exportNames()
+	lm := linearModel{}
+
+	column := func(name string, lower, upper int) int {
+		lm.columns = append(lm.columns, name)
+		lm.lower = append(lm.lower, lower)
+		lm.upper = append(lm.upper, upper)
+		return len(lm.columns) - 1
+	}
+	row := func(terms []linearTerm, sense byte, rhs int) {
+		lm.rows = append(lm.rows, linearRow{
+			name:  fmt.Sprintf("c%d", len(lm.rows)+1),
+			terms: terms,
+			sense: sense,
+			rhs:   rhs,
+		})
+	}
+
+	index := map[V]int{}
+	for _, v := range en.ordered {
+		values, err := p.exportDomain(v)
+		if err != nil {
+			return lm, err
+		}
+		if len(values) == 0 {
+			return lm, fmt.Errorf("variable %+v has an empty domain", v)
+		}
+
+		sorted := append([]int{}, values...)
+		sort.Ints(sorted)
+		col := column(en.names[v], sorted[0], sorted[len(sorted)-1])
+		index[v] = col
+
+		if sorted[len(sorted)-1]-sorted[0]+1 != len(distinct(sorted)) {
+			// v - sum(value * b_value) == 0, with exactly one b_value set
+			pin := []linearTerm{{col: col, coeff: 1}}
+			var onehot []linearTerm
+			for _, value := range distinct(sorted) {
+				b := column(en.names[v]+"_is_"+lpValue(value), 0, 1)
+				pin = append(pin, linearTerm{col: b, coeff: -value})
+				onehot = append(onehot, linearTerm{col: b, coeff: 1})
+			}
+			row(pin, 'E', 0)
+			row(onehot, 'E', 1)
+		}
+	}
+
+	for _, constraint := range p.allConstraints() {
+		arity := len(constraint.Variables)
+		var terms []linearTerm
+		lower, upper := 0, 0
+		switch constraint.Relation {
+		case RelationLinearEq, RelationLinearNe, RelationLinearLe:
+			for ndx, v := range constraint.Variables {
+				coeff := constraint.Args[ndx]
+				col := index[v]
+				terms = append(terms, linearTerm{col: col, coeff: coeff})
+				if coeff > 0 {
+					lower, upper = lower+coeff*lm.lower[col], upper+coeff*lm.upper[col]
+				} else {
+					lower, upper = lower+coeff*lm.upper[col], upper+coeff*lm.lower[col]
+				}
+			}
+		default:
+			return lm, fmt.Errorf("constraint over %+v is not linear, and can't be exported as a MILP", constraint.Variables)
+		}
+
+		c := constraint.Args[arity]
+		switch constraint.Relation {
+		case RelationLinearEq:
+			row(terms, 'E', c)
+		case RelationLinearLe:
+			row(terms, 'L', c)
+		case RelationLinearNe:
+			// with b set the sum must be at least c+1, and otherwise at most c-1
+			m1, m2 := upper-(c-1), (c+1)-lower
+			if m1 <= 0 || m2 <= 0 {
+				// the sum's bounds already keep it away from c
+				continue
+			}
+			b := column(fmt.Sprintf("ne%d", len(lm.rows)+1), 0, 1)
+			row(append(append([]linearTerm{}, terms...), linearTerm{col: b, coeff: -m1}), 'L', c-1)
+			row(append(append([]linearTerm{}, terms...), linearTerm{col: b, coeff: -m2}), 'G', lower)
+		}
+	}
+
+	if objective != nil {
+		lm.maximize = objective.Maximize
+		for _, v := range en.ordered {
+			if coeff := objective.Coefficients[v]; coeff != 0 {
+				lm.objective = append(lm.objective, linearTerm{col: index[v], coeff: coeff})
+			}
+		}
+	}
+
+	return lm, nil
+}
+
+// render terms as an LP expression, e.g. "2 x - 1 y"
+func (lm linearModel) lpTerms(terms []linearTerm) string {
+	if len(terms) == 0 && len(lm.columns) > 0 {
+		return fmt.Sprintf("0 %s", lm.columns[0])
+	}
+
+	var sb strings.Builder
+	for ndx, t := range terms {
+		coeff := t.coeff
+		switch {
+		case ndx == 0 && coeff < 0:
+			sb.WriteString("- ")
+			coeff = -coeff
+		case ndx > 0 && coeff < 0:
+			sb.WriteString(" - ")
+			coeff = -coeff
+		case ndx > 0:
+			sb.WriteString(" + ")
+		}
+		fmt.Fprintf(&sb, "%d %s", coeff, lm.columns[t.col])
+	}
+
+	return sb.String()
+}
+
+// a value as part of a column name, which in LP format can't hold a '-',
+// so that -3 is m3
+func lpValue(value int) string {
+	if value < 0 {
+		return fmt.Sprintf("m%d", -value)
+	}
+	return fmt.Sprint(value)
+}
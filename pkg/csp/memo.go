@@ -0,0 +1,113 @@
+package csp
+
+// the pair of variables a user-defined binary constraint is over
+type binaryKey[V comparable] struct {
+	a, b V
+}
+
+// the pairs of values that satisfy a binary constraint, a bit per pair of
+// positions in the two variables' domains. its Args tell it apart from
+// any other constraint over the same pair, as the SatFn would
+type compatibility struct {
+	args []int
+	cols int
+	bits []uint64
+}
+
+// whether the constraint is the one memoized
+func (c compatibility) matches(args []int) bool {
+	if len(args) != len(c.args) {
+		return false
+	}
+	for ndx := range args {
+		if args[ndx] != c.args[ndx] {
+			return false
+		}
+	}
+	return true
+}
+
+// whether the pair of positions satisfies the constraint
+func (c compatibility) allows(i, j int) bool {
+	n := i*c.cols + j
+	return c.bits[n/64]&(1<<(n%64)) != 0
+}
+
+// a copy of the Problem whose SatFn looks up, rather than recomputes, each
+// user-defined constraint over two variables once both are assigned:
+// which pairs of their values satisfy it is computed up front, into a bit
+// matrix per constraint, which the search only reads, so the copy can be
+// searched from several goroutines. a lookup costs a few map operations,
+// and computing the matrices a check per pair of values, so this pays off
+// where the SatFn is costly and the search checks the same pairs many
+// times over. it assumes the constraint's result depends only on the two
+// values, as its SatFn sees them, with domain values telling themselves
+// apart by ==. partial assignments and the other constraints are checked
+// by the SatFn as before, as are any constraints added to the copy later
+func MemoizeBinary[V comparable, D comparable](p Problem[V, D]) Problem[V, D] {
+	// each variable's domain values by their position
+	positions := map[V]map[D]int{}
+	for v, values := range p.Domain {
+		positions[v] = make(map[D]int, len(values))
+		for ndx, value := range values {
+			if _, found := positions[v][value]; !found {
+				positions[v][value] = ndx
+			}
+		}
+	}
+
+	satFn := p.SatFn
+	memo := map[binaryKey[V]]compatibility{}
+	for _, constraint := range p.allConstraints() {
+		if constraint.Relation != "" || len(constraint.Variables) != 2 {
+			continue
+		}
+		a, b := constraint.Variables[0], constraint.Variables[1]
+		key := binaryKey[V]{a, b}
+		if _, found := memo[key]; found || a == b {
+			continue
+		}
+
+		rows, cols := p.Domain[a], p.Domain[b]
+		c := compatibility{
+			args: constraint.Args,
+			cols: len(cols),
+			bits: make([]uint64, (len(rows)*len(cols)+63)/64),
+		}
+		pair := make(map[V]D, 2)
+		for i, x := range rows {
+			for j, y := range cols {
+				pair[a], pair[b] = x, y
+				if satFn(constraint, pair) {
+					n := i*len(cols) + j
+					c.bits[n/64] |= 1 << (n % 64)
+				}
+			}
+		}
+		memo[key] = c
+	}
+
+	out := p
+	out.SatFn = func(constraint Constraint[V], candidate map[V]D) bool {
+		if constraint.Relation != "" || len(constraint.Variables) != 2 {
+			return satFn(constraint, candidate)
+		}
+		a, b := constraint.Variables[0], constraint.Variables[1]
+		x, foundA := candidate[a]
+		y, foundB := candidate[b]
+		if !foundA || !foundB {
+			return satFn(constraint, candidate)
+		}
+		c, found := memo[binaryKey[V]{a, b}]
+		if !found || !c.matches(constraint.Args) {
+			return satFn(constraint, candidate)
+		}
+		i, foundX := positions[a][x]
+		j, foundY := positions[b][y]
+		if !foundX || !foundY {
+			return satFn(constraint, candidate)
+		}
+		return c.allows(i, j)
+	}
+	return out
+}
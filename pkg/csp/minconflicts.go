@@ -0,0 +1,263 @@
+package csp
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// MinConflicts is a local search engine: rather than extending a partial
+// assignment depth-first, it starts from a complete one and repairs it,
+// moving a variable in conflict to the value that violates the fewest
+// constraints until none are violated. it can't prove that a problem has
+// no solution, but it reaches solutions to large, loosely constrained
+// problems like N-Queens that backtracking takes far too long over
+type MinConflicts[V comparable, D any] struct {
+	Problem Problem[V, D]
+	// MaxSteps bounds the number of repairs, or zero for no limit
+	MaxSteps int
+	// Timeout bounds how long Solve may run, or zero for no limit
+	Timeout time.Duration
+	// Noise is the probability of moving the variable picked for repair to
+	// a random value instead, which escapes the local minima where every
+	// variable in conflict is already at its least conflicted value
+	Noise float64
+	// Breakout, if set, weighs each constraint's conflicts, from 1, and
+	// adds 1 to the weight of every constraint the variable picked for
+	// repair violates whenever none of its values lowers its weighted
+	// conflicts: a local minimum, which the weights then lift it out of,
+	// as the breakout method does, where Noise would leave it to chance
+	Breakout bool
+	// Rand picks the variable to repair and breaks ties between values;
+	// if nil, one seeded from the clock is used
+	Rand *rand.Rand
+
+	stats    Stats
+	canceled int32
+}
+
+// the state of one constraint during a local search. an AllDifferent
+// counts how many of its variables take each value, so that the conflicts
+// of a move are found without scanning the other variables
+type localConstraint[V comparable, D any] struct {
+	constraint Constraint[V]
+	// by variable, its position in constraint.Variables
+	position map[V]int
+	// for an AllDifferent, the number of variables at each value, after
+	// adding their offsets
+	counts map[int]int
+	// what each of its conflicts counts for, raised by Breakout
+	weight int
+}
+
+// construct a MinConflicts engine for the given Problem, with a little
+// Noise
+func NewMinConflicts[V comparable, D any](p Problem[V, D]) *MinConflicts[V, D] {
+	return &MinConflicts[V, D]{
+		Problem: p,
+		Noise:   0.02,
+	}
+}
+
+// stop the running search as soon as possible, from any goroutine. a
+// canceled MinConflicts stays canceled, so later searches return at once
+func (m *MinConflicts[V, D]) Cancel() {
+	atomic.StoreInt32(&m.canceled, 1)
+}
+
+// the work done by the most recent call to Solve. Nodes counts the values
+// assigned, first to complete the assignment and then to repair it
+func (m *MinConflicts[V, D]) Stats() Stats {
+	return m.stats
+}
+
+// search for a solution, starting from the given assignment with its
+// unassigned variables filled in greedily. the result is nil if MaxSteps
+// repairs, the Timeout or a Cancel came first. the assignment passed in
+// is left as it was
+func (m *MinConflicts[V, D]) Solve(assignment map[V]D) map[V]D {
+	start := time.Now()
+	m.stats = Stats{}
+	defer func() { m.stats.Duration = time.Since(start) }()
+
+	var deadline time.Time
+	if m.Timeout > 0 {
+		deadline = start.Add(m.Timeout)
+	}
+	rng := m.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	// the state of each constraint, shared by all the variables it constrains
+	constraints := map[V][]*localConstraint[V, D]{}
+	for _, constraint := range m.Problem.allConstraints() {
+		lc := &localConstraint[V, D]{constraint: constraint, position: map[V]int{}, weight: 1}
+		if constraint.Relation == RelationAllDifferent {
+			lc.counts = map[int]int{}
+		}
+		for ndx, v := range constraint.Variables {
+			lc.position[v] = ndx
+			constraints[v] = append(constraints[v], lc)
+		}
+	}
+	var vars []V
+	for v := range m.Problem.Domain {
+		vars = append(vars, v)
+	}
+
+	current := map[V]D{}
+	for v, value := range assignment {
+		m.assign(constraints[v], v, value, current)
+	}
+	for _, v := range vars {
+		if _, found := current[v]; !found {
+			m.assign(constraints[v], v, m.leastConflicted(constraints[v], v, current, rng), current)
+		}
+	}
+
+	for step := 0; m.MaxSteps == 0 || step < m.MaxSteps; step++ {
+		if step%1024 == 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			m.stats.TimedOut = true
+		}
+		if atomic.LoadInt32(&m.canceled) == 1 {
+			m.stats.Canceled = true
+		}
+		if m.stats.TimedOut || m.stats.Canceled {
+			return nil
+		}
+
+		var conflicted []V
+		for _, v := range vars {
+			if m.conflicts(constraints[v], v, current[v], current) > 0 {
+				conflicted = append(conflicted, v)
+			}
+		}
+		if len(conflicted) == 0 {
+			m.stats.Solutions++
+			return current
+		}
+
+		v := conflicted[rng.Intn(len(conflicted))]
+		if domain := m.Problem.Domain[v]; m.Noise > 0 && rng.Float64() < m.Noise {
+			m.assign(constraints[v], v, domain[rng.Intn(len(domain))], current)
+			continue
+		}
+		best := m.leastConflicted(constraints[v], v, current, rng)
+		if m.Breakout && m.conflicts(constraints[v], v, best, current) >= m.conflicts(constraints[v], v, current[v], current) {
+			m.breakout(constraints[v], v, current)
+		}
+		m.assign(constraints[v], v, best, current)
+	}
+	return nil
+}
+
+// the solution Solve finds, if any, as a Solver. a local search can't
+// enumerate solutions
+func (m *MinConflicts[V, D]) SolveAll(assignment map[V]D) []map[V]D {
+	if solution := m.Solve(assignment); solution != nil {
+		return []map[V]D{solution}
+	}
+	return nil
+}
+
+// the solution Solve finds, if any, and its cost, as a Solver. min-conflicts
+// only repairs violations, and makes no attempt to lower the cost; LNS
+// does, from a first solution
+func (m *MinConflicts[V, D]) SolveOptimal(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	solution := m.Solve(assignment)
+	if solution == nil {
+		return nil, 0
+	}
+	return solution, cost(solution)
+}
+
+// raise the weight of each of v's constraints it violates
+func (m *MinConflicts[V, D]) breakout(constraints []*localConstraint[V, D], v V, current map[V]D) {
+	for _, lc := range constraints {
+		if lc.counts != nil {
+			if lc.counts[lc.key(v, current[v])] > 1 {
+				lc.weight++
+			}
+		} else if !m.Problem.SatFn(lc.constraint, current) {
+			lc.weight++
+		}
+	}
+}
+
+// pick the value of v that violates the fewest of its constraints, given
+// the rest of the assignment, breaking ties at random
+func (m *MinConflicts[V, D]) leastConflicted(constraints []*localConstraint[V, D], v V, current map[V]D, rng *rand.Rand) D {
+	var best D
+	bestConflicts, ties := -1, 0
+	for _, value := range m.Problem.Domain[v] {
+		n := m.conflicts(constraints, v, value, current)
+		switch {
+		case bestConflicts < 0 || n < bestConflicts:
+			best, bestConflicts, ties = value, n, 1
+		case n == bestConflicts:
+			// keep each of the tied values with equal probability
+			ties++
+			if rng.Intn(ties) == 0 {
+				best = value
+			}
+		}
+	}
+	return best
+}
+
+// count the constraints v would violate at value, given the rest of the
+// assignment, each by its weight. an AllDifferent counts once per other
+// variable v would share a value with, so that moves that remove some of
+// its clashes, but not all, still count as progress
+func (m *MinConflicts[V, D]) conflicts(constraints []*localConstraint[V, D], v V, value D, current map[V]D) int {
+	previous, assigned := current[v]
+	n := 0
+	for _, lc := range constraints {
+		if lc.counts != nil {
+			clashes := lc.counts[lc.key(v, value)]
+			if assigned && lc.key(v, previous) == lc.key(v, value) {
+				clashes--
+			}
+			n += lc.weight * clashes
+			continue
+		}
+
+		current[v] = value
+		if !m.Problem.SatFn(lc.constraint, current) {
+			n += lc.weight
+		}
+	}
+
+	if assigned {
+		current[v] = previous
+	} else {
+		delete(current, v)
+	}
+	return n
+}
+
+// move v to value, updating the counts of its AllDifferent constraints
+func (m *MinConflicts[V, D]) assign(constraints []*localConstraint[V, D], v V, value D, current map[V]D) {
+	previous, assigned := current[v]
+	for _, lc := range constraints {
+		if lc.counts == nil {
+			continue
+		}
+		if assigned {
+			lc.counts[lc.key(v, previous)]--
+		}
+		lc.counts[lc.key(v, value)]++
+	}
+	current[v] = value
+	m.stats.Nodes++
+}
+
+// the value an AllDifferent compares for v at value, after its offset
+func (lc *localConstraint[V, D]) key(v V, value D) int {
+	n := asInt(value)
+	if len(lc.constraint.Args) > 0 {
+		n += lc.constraint.Args[lc.position[v]]
+	}
+	return n
+}
@@ -0,0 +1,188 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// write an equivalent MiniZinc model of the problem, so that results can be
+// cross-checked against solvers like Gecode or Chuffed. built-in relations
+// are translated directly, and user-defined constraints are written as
+// tables of the value tuples satisfying them. domain values must be integers
+func (p Problem[V, D]) ExportMiniZinc(w io.Writer) error {
+	en := p.exportNames()
+
+	var lines []string
+	var includes []string
+	for _, v := range en.ordered {
+		values, err := p.exportDomain(v)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, fmt.Sprintf("var {%s}: %s;", joinInts(values, ", "), en.names[v]))
+	}
+
+	var constraints []string
+	usesTable, usesAllDifferent, usesCircuit, usesDisjunctive, usesCardinality, usesBinPacking, usesDiffn := false, false, false, false, false, false, false
+	for _, constraint := range p.exportConstraints() {
+		vars := make([]string, len(constraint.Variables))
+		for ndx, v := range constraint.Variables {
+			vars[ndx] = en.names[v]
+		}
+
+		switch constraint.Relation {
+		case RelationTable:
+			usesTable = true
+			var rows []string
+			for _, tuple := range tuples(constraint.Args, len(vars)) {
+				rows = append(rows, joinInts(tuple, ", "))
+			}
+			table := "[| |]"
+			if len(rows) > 0 {
+				table = "[| " + strings.Join(rows, " | ") + " |]"
+			}
+			constraints = append(constraints, fmt.Sprintf("constraint table([%s], %s);", strings.Join(vars, ", "), table))
+
+		case RelationAllDifferent:
+			usesAllDifferent = true
+			constraints = append(constraints, fmt.Sprintf("constraint alldifferent([%s]);", strings.Join(offsetTerms(vars, constraint.Args), ", ")))
+
+		case RelationLinearEq, RelationLinearNe, RelationLinearLe:
+			op := map[Relation]string{RelationLinearEq: "=", RelationLinearNe: "!=", RelationLinearLe: "<="}[constraint.Relation]
+			constraints = append(constraints, fmt.Sprintf("constraint %s %s %d;",
+				linearTerms(vars, constraint.Args), op, constraint.Args[len(vars)]))
+
+		case RelationAbsDiffEq, RelationAbsDiffNe, RelationAbsDiffLe, RelationAbsDiffGe:
+			op := map[Relation]string{RelationAbsDiffEq: "=", RelationAbsDiffNe: "!=", RelationAbsDiffLe: "<=", RelationAbsDiffGe: ">="}[constraint.Relation]
+			constraints = append(constraints, fmt.Sprintf("constraint abs(%s - %s) %s %d;", vars[0], vars[1], op, constraint.Args[0]))
+
+		case RelationProduct:
+			constraints = append(constraints, fmt.Sprintf("constraint %s = %d;", strings.Join(vars, " * "), constraint.Args[0]))
+
+		case RelationQuotient:
+			c := constraint.Args[0]
+			constraints = append(constraints, fmt.Sprintf("constraint %s = %d*%s \\/ %s = %d*%s;", vars[0], c, vars[1], vars[1], c, vars[0]))
+
+		case RelationCircuit:
+			// MiniZinc numbers the successors from 1
+			usesCircuit = true
+			offsets := make([]int, len(vars))
+			for ndx := range offsets {
+				offsets[ndx] = 1
+			}
+			constraints = append(constraints, fmt.Sprintf("constraint circuit([%s]);", strings.Join(offsetTerms(vars, offsets), ", ")))
+
+		case RelationDisjunctive:
+			usesDisjunctive = true
+			constraints = append(constraints, fmt.Sprintf("constraint disjunctive([%s], [%s]);", strings.Join(vars, ", "), joinInts(constraint.Args, ", ")))
+
+		case RelationGlobalCardinality:
+			usesCardinality = true
+			var values, low, high []int
+			for start := 0; start < len(constraint.Args); start += 3 {
+				values = append(values, constraint.Args[start])
+				low = append(low, constraint.Args[start+1])
+				high = append(high, constraint.Args[start+2])
+			}
+			constraints = append(constraints, fmt.Sprintf("constraint global_cardinality_low_up([%s], [%s], [%s], [%s]);",
+				strings.Join(vars, ", "), joinInts(values, ", "), joinInts(low, ", "), joinInts(high, ", ")))
+
+		case RelationAmong:
+			constraints = append(constraints, fmt.Sprintf("constraint sum(x in [%s])(x in {%s}) in %d..%d;",
+				strings.Join(vars, ", "), joinInts(constraint.Args[2:], ", "), constraint.Args[0], constraint.Args[1]))
+
+		case RelationSequence:
+			q := constraint.Args[0]
+			constraints = append(constraints, fmt.Sprintf("constraint let { array[int] of var int: x = [%s] } in forall(i in 1..%d)(sum(j in i..i+%d)(x[j] in {%s}) in %d..%d);",
+				strings.Join(vars, ", "), len(vars)-q+1, q-1, joinInts(constraint.Args[3:], ", "), constraint.Args[1], constraint.Args[2]))
+
+		case RelationBinPacking:
+			usesBinPacking = true
+			constraints = append(constraints, fmt.Sprintf("constraint bin_packing(%d, [%s], [%s]);",
+				constraint.Args[0], strings.Join(vars, ", "), joinInts(constraint.Args[1:], ", ")))
+
+		case RelationDiffn:
+			usesDiffn = true
+			n := len(vars) / 2
+			constraints = append(constraints, fmt.Sprintf("constraint diffn([%s], [%s], [%s], [%s]);",
+				strings.Join(vars[:n], ", "), strings.Join(vars[n:], ", "), joinInts(constraint.Args[:n], ", "), joinInts(constraint.Args[n:], ", ")))
+
+		default:
+			return fmt.Errorf("no MiniZinc translation for relation %q", constraint.Relation)
+		}
+	}
+	sort.Strings(constraints)
+
+	if usesAllDifferent {
+		includes = append(includes, `include "alldifferent.mzn";`)
+	}
+	if usesCircuit {
+		includes = append(includes, `include "circuit.mzn";`)
+	}
+	if usesBinPacking {
+		includes = append(includes, `include "bin_packing.mzn";`)
+	}
+	if usesCardinality {
+		includes = append(includes, `include "global_cardinality_low_up.mzn";`)
+	}
+	if usesDiffn {
+		includes = append(includes, `include "diffn.mzn";`)
+	}
+	if usesDisjunctive {
+		includes = append(includes, `include "disjunctive.mzn";`)
+	}
+	if usesTable {
+		includes = append(includes, `include "table.mzn";`)
+	}
+
+	out := append(includes, lines...)
+	out = append(out, constraints...)
+	out = append(out, "solve satisfy;")
+
+	_, err := io.WriteString(w, strings.Join(out, "\n")+"\n")
+	return err
+}
+
+// render the left-hand side of a linear constraint, e.g. "2*x + -1*y"
+func linearTerms(vars []string, coefficients []int) string {
+	terms := make([]string, len(vars))
+	for ndx, v := range vars {
+		terms[ndx] = fmt.Sprintf("%d*%s", coefficients[ndx], v)
+	}
+	if len(terms) == 0 {
+		return "0"
+	}
+
+	return strings.Join(terms, " + ")
+}
+
+// shift each variable by its offset, if the constraint has any
+func offsetTerms(vars []string, offsets []int) []string {
+	if len(offsets) == 0 {
+		return vars
+	}
+
+	out := make([]string, len(vars))
+	for ndx, v := range vars {
+		switch o := offsets[ndx]; {
+		case o > 0:
+			out[ndx] = fmt.Sprintf("%s + %d", v, o)
+		case o < 0:
+			out[ndx] = fmt.Sprintf("%s - %d", v, -o)
+		default:
+			out[ndx] = v
+		}
+	}
+	return out
+}
+
+func joinInts(values []int, sep string) string {
+	out := make([]string, len(values))
+	for ndx, v := range values {
+		out[ndx] = fmt.Sprint(v)
+	}
+
+	return strings.Join(out, sep)
+}
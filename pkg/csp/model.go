@@ -0,0 +1,304 @@
+package csp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// the most values a variable's min and max may span, checked before they
+// are listed, so that a few bytes of model can't ask for gigabytes
+const maxDomainSize = 1 << 20
+
+// Model is a declarative description of a problem over named integer
+// variables, built from the package's built-in relations. it can be
+// written by hand or by other tools, as JSON or YAML, and loaded
+// without recompiling
+//
+//	{
+//	  "variables": [
+//	    {"name": "x", "domain": [1, 2, 3]},
+//	    {"name": "y", "min": 1, "max": 9}
+//	  ],
+//	  "constraints": [
+//	    {"type": "alldifferent", "variables": ["x", "y"]},
+//	    {"type": "linear", "variables": ["x", "y"], "coefficients": [2, 1], "operator": "<=", "constant": 10}
+//	  ]
+//	}
+//
+// or built up in code, starting from NewModel
+type Model struct {
+	Variables   []ModelVariable   `json:"variables" yaml:"variables"`
+	Constraints []ModelConstraint `json:"constraints" yaml:"constraints"`
+
+	// the first mistake made building the model with NewModel's methods
+	err error
+}
+
+// ModelVariable declares a variable, with either an explicit list
+// of domain values or an inclusive range from Min to Max
+type ModelVariable struct {
+	Name   string `json:"name" yaml:"name"`
+	Domain []int  `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Min    *int   `json:"min,omitempty" yaml:"min,omitempty"`
+	Max    *int   `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// ModelConstraint declares a constraint. Type selects the relation:
+//
+//	alldifferent            Variables, and optionally an offset per variable
+//	equal, notequal, less   exactly two Variables
+//	sum                     Variables, Operator, Constant
+//	linear                  Variables, Coefficients, Operator, Constant
+//	table                   Variables, Tuples
+//	absdiff                 exactly two Variables, Operator, Constant
+//	product                 Variables, Constant
+//	quotient                exactly two Variables, Constant
+//	circuit                 Variables, each the 0-based position of its successor
+//	disjunctive             Variables, each a task's start, and Durations
+//	gcc                     Variables, and Values each taken Low to High times
+//	regular                 Variables, and the Start state, Accept states and
+//	                        Transitions, each [from, value, to], of an automaton
+//	among                   Variables, AtLeast to AtMost of them taking one of Values
+//	sequence                Variables, every Window of them in a row as for among
+//	binpacking              Variables, each an item's bin, Sizes, and Constant,
+//	                        the bins' capacity
+//	diffn                   Variables, the xs of rectangles' corners and then
+//	                        the ys, and Sizes, the widths and then the heights
+//
+// any of them may be given a Name, which errors and reports show for it
+type ModelConstraint struct {
+	Type         string   `json:"type" yaml:"type"`
+	Name         string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Variables    []string `json:"variables" yaml:"variables"`
+	Coefficients []int    `json:"coefficients,omitempty" yaml:"coefficients,omitempty"`
+	Operator     Operator `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Constant     int      `json:"constant,omitempty" yaml:"constant,omitempty"`
+	Tuples       [][]int  `json:"tuples,omitempty" yaml:"tuples,omitempty"`
+	Offsets      []int    `json:"offsets,omitempty" yaml:"offsets,omitempty"`
+	Durations    []int    `json:"durations,omitempty" yaml:"durations,omitempty"`
+	Values       []int    `json:"values,omitempty" yaml:"values,omitempty"`
+	Low          []int    `json:"low,omitempty" yaml:"low,omitempty"`
+	High         []int    `json:"high,omitempty" yaml:"high,omitempty"`
+	Start        int      `json:"start,omitempty" yaml:"start,omitempty"`
+	Accept       []int    `json:"accept,omitempty" yaml:"accept,omitempty"`
+	Transitions  [][3]int `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+	AtLeast      int      `json:"at_least,omitempty" yaml:"at_least,omitempty"`
+	AtMost       int      `json:"at_most,omitempty" yaml:"at_most,omitempty"`
+	Window       int      `json:"window,omitempty" yaml:"window,omitempty"`
+	Sizes        []int    `json:"sizes,omitempty" yaml:"sizes,omitempty"`
+}
+
+// read a Model from a JSON document and build the Problem it describes
+func LoadJSON(r io.Reader) (Problem[string, int], error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var m Model
+	if err := decoder.Decode(&m); err != nil {
+		return Problem[string, int]{}, fmt.Errorf("invalid model: %w", err)
+	}
+
+	return m.Build()
+}
+
+// validate the model and build the Problem it describes
+func (m Model) Build() (Problem[string, int], error) {
+	if m.err != nil {
+		return Problem[string, int]{}, m.err
+	}
+	domain := map[string][]int{}
+	for ndx, mv := range m.Variables {
+		if mv.Name == "" {
+			return Problem[string, int]{}, fmt.Errorf("variable %d has no name", ndx)
+		}
+		if _, dup := domain[mv.Name]; dup {
+			return Problem[string, int]{}, fmt.Errorf("variable %q declared twice", mv.Name)
+		}
+
+		values, err := mv.values()
+		if err != nil {
+			return Problem[string, int]{}, fmt.Errorf("variable %q: %w", mv.Name, err)
+		}
+		domain[mv.Name] = values
+	}
+
+	var constraints []Constraint[string]
+	for ndx, mc := range m.Constraints {
+		for _, v := range mc.Variables {
+			if _, found := domain[v]; !found {
+				return Problem[string, int]{}, fmt.Errorf("constraint %d (%s): unknown variable %q", ndx, mc.Type, v)
+			}
+		}
+
+		constraint, err := mc.build()
+		if err != nil {
+			return Problem[string, int]{}, fmt.Errorf("constraint %d (%s): %w", ndx, mc.Type, err)
+		}
+		if mc.Name != "" {
+			constraint.Label = &Label{Name: mc.Name}
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	p := New(domain, nil)
+	for _, constraint := range constraints {
+		p.AddConstraint(constraint)
+	}
+	return p, nil
+}
+
+func (mv ModelVariable) values() ([]int, error) {
+	switch {
+	case mv.Domain != nil && (mv.Min != nil || mv.Max != nil):
+		return nil, fmt.Errorf("give either a domain or a min and max, not both")
+	case mv.Domain != nil:
+		return mv.Domain, nil
+	case mv.Min == nil || mv.Max == nil:
+		return nil, fmt.Errorf("missing domain")
+	case *mv.Max < *mv.Min:
+		return nil, fmt.Errorf("max %d is below min %d", *mv.Max, *mv.Min)
+	// the difference wraps around below 0 past the range of an int
+	case *mv.Max-*mv.Min < 0 || *mv.Max-*mv.Min >= maxDomainSize:
+		return nil, fmt.Errorf("min %d to max %d spans more than %d values", *mv.Min, *mv.Max, maxDomainSize)
+	}
+
+	var out []int
+	for v := *mv.Min; v <= *mv.Max; v++ {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (mc ModelConstraint) build() (Constraint[string], error) {
+	if len(mc.Variables) == 0 {
+		return Constraint[string]{}, fmt.Errorf("no variables")
+	}
+
+	var out Constraint[string]
+	switch mc.Type {
+	case "alldifferent":
+		switch {
+		case mc.Offsets == nil:
+			out = AllDifferent(mc.Variables...)
+		case len(mc.Offsets) != len(mc.Variables):
+			return out, fmt.Errorf("%d offsets for %d variables", len(mc.Offsets), len(mc.Variables))
+		default:
+			out = AllDifferentOffset(mc.Variables, mc.Offsets)
+		}
+
+	case "equal", "notequal", "less":
+		if len(mc.Variables) != 2 {
+			return out, fmt.Errorf("needs exactly 2 variables, got %d", len(mc.Variables))
+		}
+		a, b := mc.Variables[0], mc.Variables[1]
+		switch mc.Type {
+		case "equal":
+			out = Equal(a, b)
+		case "notequal":
+			out = NotEqual(a, b)
+		default:
+			out = LessThan(a, b)
+		}
+
+	case "sum", "linear":
+		if !mc.Operator.valid() {
+			return out, fmt.Errorf("unknown operator %q", mc.Operator)
+		}
+		if mc.Type == "sum" {
+			out = Sum(mc.Variables, mc.Operator, mc.Constant)
+		} else {
+			if len(mc.Coefficients) != len(mc.Variables) {
+				return out, fmt.Errorf("%d coefficients for %d variables", len(mc.Coefficients), len(mc.Variables))
+			}
+			out = Linear(mc.Variables, mc.Coefficients, mc.Operator, mc.Constant)
+		}
+
+	case "absdiff", "quotient":
+		if len(mc.Variables) != 2 {
+			return out, fmt.Errorf("needs exactly 2 variables, got %d", len(mc.Variables))
+		}
+		a, b := mc.Variables[0], mc.Variables[1]
+		if mc.Type == "quotient" {
+			if mc.Constant == 0 {
+				return out, fmt.Errorf("quotient can't be 0")
+			}
+			out = Quotient(a, b, mc.Constant)
+		} else {
+			if !mc.Operator.valid() {
+				return out, fmt.Errorf("unknown operator %q", mc.Operator)
+			}
+			out = AbsDiff(a, b, mc.Operator, mc.Constant)
+		}
+
+	case "product":
+		out = Product(mc.Variables, mc.Constant)
+
+	case "circuit":
+		out = Circuit(mc.Variables...)
+
+	case "disjunctive":
+		if len(mc.Durations) != len(mc.Variables) {
+			return out, fmt.Errorf("%d durations for %d tasks", len(mc.Durations), len(mc.Variables))
+		}
+		out = Disjunctive(mc.Variables, mc.Durations)
+
+	case "gcc":
+		if len(mc.Low) != len(mc.Values) || len(mc.High) != len(mc.Values) {
+			return out, fmt.Errorf("%d values with %d lower and %d upper bounds", len(mc.Values), len(mc.Low), len(mc.High))
+		}
+		out = GlobalCardinality(mc.Variables, mc.Values, mc.Low, mc.High)
+
+	case "regular":
+		a := Automaton{Start: mc.Start, Accept: mc.Accept}
+		for _, t := range mc.Transitions {
+			a.Transitions = append(a.Transitions, Transition{From: t[0], Value: t[1], To: t[2]})
+		}
+		out = Regular(mc.Variables, a)
+
+	case "among":
+		out = Among(mc.Variables, mc.Values, mc.AtLeast, mc.AtMost)
+
+	case "sequence":
+		out = Sequence(mc.Variables, mc.Values, mc.Window, mc.AtLeast, mc.AtMost)
+
+	case "binpacking":
+		if len(mc.Sizes) != len(mc.Variables) {
+			return out, fmt.Errorf("%d sizes for %d items", len(mc.Sizes), len(mc.Variables))
+		}
+		out = BinPacking(mc.Variables, mc.Sizes, mc.Constant)
+
+	case "diffn":
+		if len(mc.Variables)%2 != 0 || len(mc.Sizes) != len(mc.Variables) {
+			return out, fmt.Errorf("%d variables and %d sizes for rectangles", len(mc.Variables), len(mc.Sizes))
+		}
+		n := len(mc.Variables) / 2
+		out = Diffn(mc.Variables[:n], mc.Variables[n:], mc.Sizes[:n], mc.Sizes[n:])
+
+	case "table":
+		for _, tuple := range mc.Tuples {
+			if len(tuple) != len(mc.Variables) {
+				return out, fmt.Errorf("tuple %v doesn't have a value for each of %d variables", tuple, len(mc.Variables))
+			}
+		}
+		out = Table(mc.Variables, mc.Tuples)
+
+	default:
+		return out, fmt.Errorf("unknown constraint type")
+	}
+
+	// catch what AddConstraint would otherwise panic over, such as bounds
+	// out of order, so that a bad document is an error
+	if err := validateRelation[int](out.Relation, len(out.Variables), out.Args); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+func (op Operator) valid() bool {
+	switch op {
+	case Eq, Ne, Lt, Le, Gt, Ge:
+		return true
+	}
+	return false
+}
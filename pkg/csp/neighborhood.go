@@ -0,0 +1,90 @@
+package csp
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Neighborhood picks the variables a step of an LNS frees, given the best
+// solution so far: about n of free, the variables not fixed by the
+// assignment Minimize started from, listed in the same order every step.
+// any others returned are kept as they are. rng is the LNS's Rand, so
+// that a seeded LNS frees the same variables each run
+type Neighborhood[V comparable, D any] func(best map[V]D, free []V, n int, rng *rand.Rand) []V
+
+// free n variables picked uniformly at random, unrelated to each other
+// or to the solution, the neighborhood an LNS uses by default
+func RandomNeighborhood[V comparable, D any]() Neighborhood[V, D] {
+	return func(_ map[V]D, free []V, n int, rng *rand.Rand) []V {
+		out := make([]V, 0, n)
+		for _, ndx := range rng.Perm(len(free))[:n] {
+			out = append(out, free[ndx])
+		}
+		return out
+	}
+}
+
+// free a random variable and then the variables sharing constraints with
+// it, and with those, breadth first, in random order within each layer,
+// until n are free. variables that constrain each other are freed
+// together, so the step can change them together, where a random subset
+// mostly frees variables whose neighbors hold them where they are. if
+// a region is exhausted first, another random variable starts the next
+func RelatedNeighborhood[V comparable, D any](p Problem[V, D]) Neighborhood[V, D] {
+	adj := p.neighbors()
+	return func(_ map[V]D, free []V, n int, rng *rand.Rand) []V {
+		freeable := make(map[V]bool, len(free))
+		for _, v := range free {
+			freeable[v] = true
+		}
+		freed := make(map[V]bool, n)
+		var out []V
+		for _, start := range rng.Perm(len(free)) {
+			if len(out) >= n {
+				break
+			}
+			if freed[free[start]] {
+				continue
+			}
+			queue := []V{free[start]}
+			freed[free[start]] = true
+			for len(queue) > 0 && len(out) < n {
+				v := queue[0]
+				queue = queue[1:]
+				out = append(out, v)
+
+				next := append([]V(nil), adj[v]...)
+				rng.Shuffle(len(next), func(i, j int) { next[i], next[j] = next[j], next[i] })
+				for _, neighbor := range next {
+					if freeable[neighbor] && !freed[neighbor] {
+						freed[neighbor] = true
+						queue = append(queue, neighbor)
+					}
+				}
+			}
+		}
+		return out
+	}
+}
+
+// free the n variables whose times, in the best solution, come one after
+// another from a random point on, for scheduling problems: at gives the
+// time of each, e.g. the start of its task, or for a variable that isn't
+// a time itself, the time of what it belongs to. the tasks of a window of
+// the schedule are rearranged among themselves, with the rest of the
+// schedule held around them
+func TimeWindowNeighborhood[V comparable, D any](at func(variable V, solution map[V]D) int) Neighborhood[V, D] {
+	return func(best map[V]D, free []V, n int, rng *rand.Rand) []V {
+		times := make(map[V]int, len(free))
+		ordered := append([]V(nil), free...)
+		for _, v := range ordered {
+			times[v] = at(v, best)
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return times[ordered[i]] < times[ordered[j]]
+		})
+
+		start := rng.Intn(len(ordered) - n + 1)
+		return ordered[start : start+n]
+	}
+}
@@ -0,0 +1,173 @@
+package csp
+
+import (
+	"sort"
+	"time"
+)
+
+// Soft is a constraint that a solution may violate, at a cost of its
+// Weight, e.g. a nurse's request for a day off
+type Soft[V comparable] struct {
+	Constraint Constraint[V]
+	Weight     int
+}
+
+// Cost is an objective for Minimize. given a partial assignment, it must
+// return a lower bound on the cost of every solution extending it, and
+// given a complete one, that solution's cost
+type Cost[V comparable, D any] func(assignment map[V]D) int
+
+// the total Weight of the soft constraints the assignment violates. as a
+// built-in relation only fails once no extension of the assignment can
+// satisfy it, this bounds the cost of every solution extending it; the
+// Problem's SatFn must do the same for any user-defined soft constraints
+func SoftCost[V comparable, D any](p Problem[V, D], soft []Soft[V]) Cost[V, D] {
+	return func(assignment map[V]D) int {
+		cost := 0
+		for _, s := range soft {
+			if !p.SatFn(s.Constraint, assignment) {
+				cost += s.Weight
+			}
+		}
+		return cost
+	}
+}
+
+// try the values that add the least to the cost first, so that the first
+// solutions Minimize finds are good ones, and its bound tightens early.
+// ties keep their domain order
+func CheapestValue[V comparable, D any](p Problem[V, D], cost Cost[V, D]) ValueOrder[V, D] {
+	return func(variable V, assignment map[V]D) []D {
+		_, assigned := assignment[variable]
+		previous := assignment[variable]
+		values := append([]D{}, p.Domain[variable]...)
+		costs := make([]int, len(values))
+		for ndx, value := range values {
+			assignment[variable] = value
+			costs[ndx] = cost(assignment)
+		}
+		if assigned {
+			assignment[variable] = previous
+		} else {
+			delete(assignment, variable)
+		}
+
+		order := make([]int, len(values))
+		for ndx := range order {
+			order[ndx] = ndx
+		}
+		sort.SliceStable(order, func(i, j int) bool { return costs[order[i]] < costs[order[j]] })
+		out := make([]D, len(values))
+		for ndx, from := range order {
+			out[ndx] = values[from]
+		}
+		return out
+	}
+}
+
+// search for the solution extending the given assignment of least cost,
+// by branch and bound: each solution found becomes the bound, and the
+// search goes on only through partial assignments that cost less. the
+// result is the best solution and its cost, or nil if there is none; if
+// the Timeout expires first, it's the best found so far, and Stats
+// reports the timeout. Stats counts each improving solution, which the
+// OnSolution hooks are also called with
+func (b *Backtracker[V, D]) Minimize(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	return b.minimize(assignment, cost, nil, 0)
+}
+
+// Minimize, as a Solver
+func (b *Backtracker[V, D]) SolveOptimal(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	return b.Minimize(assignment, cost)
+}
+
+// as Minimize, but only searching for solutions that cost less than
+// bound, such as the best another search has found already, and returning
+// nil if there are none
+func (b *Backtracker[V, D]) MinimizeBelow(assignment map[V]D, cost Cost[V, D], bound int) (map[V]D, int) {
+	var best map[V]D
+	b.prune = func(assignment map[V]D) bool {
+		return cost(assignment) >= bound
+	}
+	defer func() { b.prune = nil }()
+
+	pool := b.assignments()
+	b.run(assignment, func(solution map[V]D) bool {
+		if best != nil {
+			pool.put(best)
+		}
+		best, bound = pool.dup(solution), cost(solution)
+		return false
+	})
+
+	return best, bound
+}
+
+// as Minimize, but rather than carry on through the tree from each
+// solution, start the search over from the top, with its cost posted as a
+// bound every partial assignment must beat: only solutions strictly
+// better than the incumbent are searched for, until none is left. each
+// restart goes back on the choices that led to the last solution, which
+// branch and bound would only revisit once it had exhausted the subtree
+// below them, so where the good solutions lie far apart, and the value
+// ordering heads for them, this often converges much faster. the
+// Timeout bounds the whole loop, and Stats sums the work of every
+// restart, counting each improving solution
+func (b *Backtracker[V, D]) MinimizeByRestarts(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	var best map[V]D
+	bestCost := 0
+	b.prune = func(assignment map[V]D) bool {
+		return best != nil && cost(assignment) >= bestCost
+	}
+	timeout := b.Timeout
+	defer func() { b.prune, b.Timeout = nil, timeout }()
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	var total Stats
+	for {
+		if !deadline.IsZero() {
+			if b.Timeout = time.Until(deadline); b.Timeout <= 0 {
+				total.TimedOut = true
+				break
+			}
+		}
+
+		var found map[V]D
+		b.run(assignment, func(solution map[V]D) bool {
+			found = dup(solution)
+			return true
+		})
+		total = sumStats(total, b.stats)
+		if found == nil {
+			break
+		}
+		best, bestCost = found, cost(found)
+	}
+
+	b.stats = total
+	return best, bestCost
+}
+
+// as Minimize, but if best is given, only searching for solutions that
+// cost less than bestCost, and returning best if there are none. a best
+// improved on is recycled, so the caller must not keep it
+func (b *Backtracker[V, D]) minimize(assignment map[V]D, cost Cost[V, D], best map[V]D, bestCost int) (map[V]D, int) {
+	b.prune = func(assignment map[V]D) bool {
+		return best != nil && cost(assignment) >= bestCost
+	}
+	defer func() { b.prune = nil }()
+
+	pool := b.assignments()
+	b.run(assignment, func(solution map[V]D) bool {
+		if best != nil {
+			pool.put(best)
+		}
+		best, bestCost = pool.dup(solution), cost(solution)
+		return false
+	})
+
+	return best, bestCost
+}
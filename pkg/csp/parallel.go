@@ -0,0 +1,57 @@
+package csp
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// a constraint the assignment violates, checking the list across
+// up to workers goroutines, each a contiguous share of it; the rest give
+// up as soon as any one finds a violation. it reports false if none is
+// violated. the SatFn is called concurrently, with an assignment none of
+// them writes to
+func (p Problem[V, D]) violatedConcurrently(constraints []Constraint[V], assignment map[V]D, workers int) (Constraint[V], bool) {
+	if workers > len(constraints) {
+		workers = len(constraints)
+	}
+	share := (len(constraints) + workers - 1) / workers
+
+	// the index of the violated constraint, or -1 while none is
+	violated := int64(-1)
+	var wg sync.WaitGroup
+	for start := 0; start < len(constraints); start += share {
+		end := start + share
+		if end > len(constraints) {
+			end = len(constraints)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for ndx := start; ndx < end && atomic.LoadInt64(&violated) < 0; ndx++ {
+				if !p.SatFn(constraints[ndx], assignment) {
+					atomic.CompareAndSwapInt64(&violated, -1, int64(ndx))
+					return
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	if violated < 0 {
+		return Constraint[V]{}, false
+	}
+	return constraints[violated], true
+}
+
+// the number of goroutines to check a variable's constraints across, or
+// 1 to check them in turn
+func (b *Backtracker[V, D]) workers(constraints int) int {
+	if b.ParallelThreshold <= 0 || constraints < b.ParallelThreshold {
+		return 1
+	}
+	if b.Workers > 0 {
+		return b.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,36 @@
+package csp
+
+import "sync"
+
+// assignmentPool recycles the assignment maps a search copies and then
+// drops, such as each best solution a Minimize improves on, so that a
+// long solve allocates them once rather than at every copy. a map put
+// back must not be used again by anything else
+type assignmentPool[V comparable, D any] struct {
+	pool sync.Pool
+}
+
+// a map from the pool, empty, or a new one if the pool has none
+func (p *assignmentPool[V, D]) get() map[V]D {
+	if m, ok := p.pool.Get().(map[V]D); ok {
+		return m
+	}
+	return map[V]D{}
+}
+
+// return a map to the pool once nothing refers to it, emptied
+func (p *assignmentPool[V, D]) put(m map[V]D) {
+	for v := range m {
+		delete(m, v)
+	}
+	p.pool.Put(m)
+}
+
+// a copy of the assignment, in a map from the pool
+func (p *assignmentPool[V, D]) dup(assignment map[V]D) map[V]D {
+	out := p.get()
+	for k, v := range assignment {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,250 @@
+package csp
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Probing is what probing the root of a search learned about a Problem
+type Probing[V comparable, D any] struct {
+	// a copy of the Problem without the Pruned values, for the search to
+	// start from
+	Problem Problem[V, D]
+	// by variable, the values ruled out: those after which some variable
+	// was left with no value, once arc consistency was established
+	Pruned map[V][]D
+	// the variables left with a single value, and the value
+	Fixed map[V]D
+	// by variable, the fraction of the search space, as the product of
+	// the unassigned variables' domain sizes, that assigning it rules out,
+	// averaged over the values it keeps. see HighestImpact
+	Impact map[V]float64
+	// false if probing proved that no solution exists, leaving a variable
+	// with no value at all
+	Feasible bool
+	// the number of values probed, over every round
+	Probes   int
+	Duration time.Duration
+}
+
+// probe the root of the search before it starts: assign each value of
+// each unassigned variable in turn, tentatively, establish arc
+// consistency, and rule out the value if any variable is left without
+// one, as singleton arc consistency does. having lost values, the others
+// are probed again, until a round rules out no more. what's ruled out is
+// ruled out of every solution extending the assignment, so the search can
+// start from the smaller Problem, without ever making the choices that
+// probing saw fail at once.
+//
+// each probe establishes arc consistency, so probing costs as much as
+// a few levels of MaintainArcConsistency's search, and pays off where
+// many values fail early, or where the Impact it measures along the way
+// guides the search. like arc consistency, constraints must only inspect
+// their own Variables, and only fail a partial assignment once no
+// extension of it can satisfy them. the assignment is left as it was
+func (p Problem[V, D]) Probe(assignment map[V]D) (out Probing[V, D]) {
+	start := time.Now()
+	out = newProbing(p)
+	q := out.Problem
+	defer func() { out.Duration = time.Since(start) }()
+
+	var vars []V
+	for v := range p.Domain {
+		if _, found := assignment[v]; !found {
+			vars = append(vars, v)
+		}
+	}
+	scratch := dup(assignment)
+
+	for pruned := true; pruned; {
+		pruned = false
+		root, ok := q.propagate(scratch)
+		if !ok {
+			return out
+		}
+
+		for _, v := range vars {
+			var kept []D
+			impact := 0.0
+			for _, value := range q.Domain[v] {
+				out.Probes++
+				var after map[V][]D
+				ok := q.consistentWith(v, value, scratch)
+				if ok {
+					scratch[v] = value
+					after, ok = q.propagate(scratch)
+					delete(scratch, v)
+				}
+				if !ok {
+					out.Pruned[v] = append(out.Pruned[v], value)
+					pruned = true
+					continue
+				}
+				kept = append(kept, value)
+				impact += reduction(root, after)
+			}
+
+			q.Domain[v] = kept
+			if len(kept) == 0 {
+				return out
+			}
+			out.Impact[v] = impact / float64(len(kept))
+		}
+	}
+
+	out.settle(vars)
+	return out
+}
+
+// a Probing of a copy of the Problem, whose domains can be narrowed
+// without touching the original's
+func newProbing[V comparable, D any](p Problem[V, D]) Probing[V, D] {
+	q := p
+	q.Domain = make(map[V][]D, len(p.Domain))
+	for v, values := range p.Domain {
+		q.Domain[v] = values
+	}
+	return Probing[V, D]{
+		Problem: q,
+		Pruned:  map[V][]D{},
+		Fixed:   map[V]D{},
+		Impact:  map[V]float64{},
+	}
+}
+
+// record the variables probed down to a single value, having found that
+// none was left with no value
+func (pr *Probing[V, D]) settle(vars []V) {
+	for _, v := range vars {
+		if values := pr.Problem.Domain[v]; len(values) == 1 {
+			pr.Fixed[v] = values[0]
+		}
+	}
+	pr.Feasible = true
+}
+
+// the fraction of the search space, from the root's live domains to those
+// left after an assignment, that the assignment ruled out. the product of
+// the domain sizes is summed as logarithms, so as not to overflow
+func reduction[V comparable, D any](root, after map[V][]D) float64 {
+	log := 0.0
+	for v, values := range after {
+		log += math.Log(float64(len(values))) - math.Log(float64(len(root[v])))
+	}
+	return 1 - math.Exp(log)
+}
+
+// assign the unassigned variable with the highest Impact probing
+// measured, as impact-based search does, to make the choices that shrink
+// the rest of the search the most first. ties go to the variable with the
+// fewest values. the impacts are those of the root, and aren't updated as
+// the search goes deeper, so it's best paired with a search of the
+// Probing's Problem
+func HighestImpact[V comparable, D any](probing Probing[V, D]) VariableOrder[V, D] {
+	domain := probing.Problem.Domain
+	return func(assignment map[V]D) V {
+		var best V
+		bestImpact, bestSize := -1.0, 0
+		for v, values := range domain {
+			if _, found := assignment[v]; found {
+				continue
+			}
+			impact := probing.Impact[v]
+			if impact > bestImpact || impact == bestImpact && len(values) < bestSize {
+				best, bestImpact, bestSize = v, impact, len(values)
+			}
+		}
+		return best
+	}
+}
+
+// shave the bounds of the integer variables: tentatively assign each
+// unassigned variable its least value, establish arc consistency, and
+// rule the value out if any variable is left without one, and so on up
+// until a value holds, then likewise down from its greatest. having lost
+// values, the other bounds are shaved again, until a round shaves no
+// more. only the bounds are tried, so it costs a couple of propagations
+// per variable where Probe tries every value, and pays off on arithmetic
+// constraints, sums and differences, whose bounds propagate furthest. the
+// result is reported as a Probing, without Impact. domains of other than
+// an integer kind aren't shaved at all.
+//
+// like Probe, what's ruled out holds for any search extending the
+// assignment, so a search that restarts can start each time from the
+// shaved Problem, or shave it again with more of its variables assigned
+func (p Problem[V, D]) Shave(assignment map[V]D) (out Probing[V, D]) {
+	start := time.Now()
+	out = newProbing(p)
+	q := out.Problem
+	defer func() { out.Duration = time.Since(start) }()
+	if !isIntegerKind(kindOf[D]()) {
+		out.Feasible = true
+		return out
+	}
+
+	var vars []V
+	for v := range p.Domain {
+		if _, found := assignment[v]; !found {
+			vars = append(vars, v)
+		}
+	}
+	scratch := dup(assignment)
+
+	// whether the value survives assigning it and propagating
+	holds := func(v V, value D) bool {
+		out.Probes++
+		if !q.consistentWith(v, value, scratch) {
+			return false
+		}
+		scratch[v] = value
+		defer delete(scratch, v)
+		_, ok := q.propagate(scratch)
+		return ok
+	}
+
+	for shaved := true; shaved; {
+		shaved = false
+		for _, v := range vars {
+			values := sortedByInt(q.Domain[v])
+			lo, hi := 0, len(values)-1
+			for lo <= hi && !holds(v, values[lo]) {
+				lo++
+			}
+			for hi > lo && !holds(v, values[hi]) {
+				hi--
+			}
+			if lo == 0 && hi == len(values)-1 {
+				continue
+			}
+
+			shaved = true
+			out.Pruned[v] = append(out.Pruned[v], values[:lo]...)
+			if lo > hi {
+				q.Domain[v] = nil
+				return out
+			}
+			out.Pruned[v] = append(out.Pruned[v], values[hi+1:]...)
+			least, greatest := asInt(values[lo]), asInt(values[hi])
+			var kept []D
+			for _, value := range q.Domain[v] {
+				if n := asInt(value); n >= least && n <= greatest {
+					kept = append(kept, value)
+				}
+			}
+			q.Domain[v] = kept
+		}
+	}
+
+	out.settle(vars)
+	return out
+}
+
+// a copy of the integer values, from least to greatest
+func sortedByInt[D any](values []D) []D {
+	out := append([]D(nil), values...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return asInt(out[i]) < asInt(out[j])
+	})
+	return out
+}
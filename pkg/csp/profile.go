@@ -0,0 +1,87 @@
+package csp
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"runtime/pprof"
+)
+
+// Profile captures a Backtracker's solves with runtime/pprof. while one
+// runs, the goroutine is labeled csp.phase=preprocessing, search or
+// propagation, the last while OrderValues narrows the values to try, so
+// that a profile, whether these writers or some other tool take it,
+// tells the phases apart with pprof's -tagfocus
+type Profile struct {
+	// CPU receives the CPU profile of each solve, if not nil. only one
+	// can run in the process at a time
+	CPU io.Writer
+	// Allocs receives the allocation profile as a solve ends, if not nil.
+	// like pprof's, it counts every allocation since the process started
+	Allocs io.Writer
+	// Err holds the first error capturing or writing a profile, such as
+	// another CPU profile already running
+	Err error
+}
+
+// the phases of a search its goroutine is labeled with
+type phase int
+
+const (
+	phasePreprocessing phase = iota
+	phaseSearch
+	phasePropagation
+)
+
+var phaseNames = [...]string{"preprocessing", "search", "propagation"}
+
+// the labels of each phase, made once so that switching between them
+// doesn't allocate at every node
+type phases [len(phaseNames)]context.Context
+
+func newPhases() *phases {
+	var out phases
+	for ndx, name := range phaseNames {
+		out[ndx] = pprof.WithLabels(context.Background(), pprof.Labels("csp.phase", name))
+	}
+	return &out
+}
+
+// label the goroutine as in the phase, if the search is profiled
+func (ph *phases) enter(p phase) {
+	if ph != nil {
+		pprof.SetGoroutineLabels(ph[p])
+	}
+}
+
+// start capturing a solve, returning the func to end it with
+func (p *Profile) start() func() {
+	cpu := false
+	if p.CPU != nil {
+		if err := pprof.StartCPUProfile(p.CPU); err != nil {
+			p.fail(err)
+		} else {
+			cpu = true
+		}
+	}
+
+	return func() {
+		pprof.SetGoroutineLabels(context.Background())
+		if cpu {
+			pprof.StopCPUProfile()
+		}
+		if p.Allocs != nil {
+			// as go test -memprofile does, to bring the profile up to date
+			runtime.GC()
+			if err := pprof.Lookup("allocs").WriteTo(p.Allocs, 0); err != nil {
+				p.fail(err)
+			}
+		}
+	}
+}
+
+func (p *Profile) fail(err error) {
+	if p.Err == nil {
+		p.Err = err
+	}
+}
@@ -0,0 +1,98 @@
+package csp
+
+import "fmt"
+
+// Automaton is a deterministic finite automaton over the values of a
+// sequence of variables: starting from the Start state, each value moves
+// it along its Transition, and the sequence is accepted if it ends in one
+// of the Accept states. a value without a transition from the current
+// state rejects the sequence
+type Automaton struct {
+	Start       int
+	Accept      []int
+	Transitions []Transition
+}
+
+// Transition moves an Automaton in state From, given Value, to state To
+type Transition struct {
+	From  int
+	Value int
+	To    int
+}
+
+// require the values of the variables, in order, to be accepted by the
+// automaton, e.g. a nurse's shifts never having a day shift straight
+// after a night
+func Regular[V comparable](variables []V, a Automaton) Constraint[V] {
+	args := []int{a.Start, len(a.Accept)}
+	args = append(args, a.Accept...)
+	for _, t := range a.Transitions {
+		args = append(args, t.From, t.Value, t.To)
+	}
+
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationRegular,
+		Args:      args,
+	}
+}
+
+// decode the automaton of a regular constraint's arguments
+func automatonOf(args []int) Automaton {
+	a := Automaton{Start: args[0], Accept: args[2 : 2+args[1]]}
+	for start := 2 + args[1]; start < len(args); start += 3 {
+		a.Transitions = append(a.Transitions, Transition{args[start], args[start+1], args[start+2]})
+	}
+	return a
+}
+
+// report whether the values assigned so far can still be completed to a
+// sequence the automaton accepts, by following every state the sequence
+// could be in: an assigned value takes each along its transition, and an
+// unassigned one along any of them
+func checkRegular(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	a := automatonOf(args)
+	next := map[int][]Transition{}
+	for _, t := range a.Transitions {
+		next[t.From] = append(next[t.From], t)
+	}
+
+	states := map[int]bool{a.Start: true}
+	for ndx := 0; ndx < arity; ndx++ {
+		v, assigned := value(ndx)
+		reached := map[int]bool{}
+		for state := range states {
+			for _, t := range next[state] {
+				if !assigned || t.Value == v {
+					reached[t.To] = true
+				}
+			}
+		}
+		if len(reached) == 0 {
+			return false
+		}
+		states = reached
+	}
+
+	for _, state := range a.Accept {
+		if states[state] {
+			return true
+		}
+	}
+	return false
+}
+
+func validRegular(args []int, arity int) error {
+	if len(args) < 2 || args[1] < 0 || len(args) < 2+args[1] || (len(args)-2-args[1])%3 != 0 {
+		return fmt.Errorf("regular takes a start state, accepting states and transition triples, got %d arguments", len(args))
+	}
+
+	moves := map[[2]int]bool{}
+	for _, t := range automatonOf(args).Transitions {
+		if moves[[2]int{t.From, t.Value}] {
+			return fmt.Errorf("regular automaton has two transitions from state %d on %d", t.From, t.Value)
+		}
+		moves[[2]int{t.From, t.Value}] = true
+	}
+	return nil
+}
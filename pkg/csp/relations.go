@@ -0,0 +1,374 @@
+package csp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Relation names a built-in kind of constraint. built-in constraints
+// are only available for problems with integer-valued domains
+type Relation string
+
+const (
+	// RelationTable allows only the listed combinations of values. Args
+	// holds the allowed tuples one after another, each giving a value
+	// for every constrained variable in order
+	RelationTable Relation = "table"
+	// RelationAllDifferent requires every variable to take a distinct value.
+	// Args is either empty or holds an offset per variable, added to its
+	// value before comparing
+	RelationAllDifferent Relation = "alldifferent"
+	// the linear relations compare the weighted sum of the variables to a
+	// constant. Args holds one coefficient per variable, then the constant
+	RelationLinearEq Relation = "linear_eq"
+	RelationLinearNe Relation = "linear_ne"
+	RelationLinearLe Relation = "linear_le"
+	// the absolute difference relations compare |a - b| for their two
+	// variables to the constant held in Args
+	RelationAbsDiffEq Relation = "absdiff_eq"
+	RelationAbsDiffNe Relation = "absdiff_ne"
+	RelationAbsDiffLe Relation = "absdiff_le"
+	RelationAbsDiffGe Relation = "absdiff_ge"
+	// RelationProduct requires the product of the variables to equal the
+	// constant held in Args
+	RelationProduct Relation = "product"
+	// RelationQuotient requires one of its two variables, divided by the
+	// other, to equal the constant held in Args exactly
+	RelationQuotient Relation = "quotient"
+	// RelationCircuit requires its variables to form a single cycle, each
+	// holding the position of its successor in the constraint
+	RelationCircuit Relation = "circuit"
+	// RelationDisjunctive requires the tasks starting at its variables, each
+	// lasting the duration held for it in Args, to never overlap
+	RelationDisjunctive Relation = "disjunctive"
+	// RelationGlobalCardinality bounds how many of its variables take each
+	// of the values listed in Args, a value, lower and upper bound each
+	RelationGlobalCardinality Relation = "gcc"
+	// RelationRegular requires the values of its variables, in order, to
+	// be accepted by the automaton encoded in Args
+	RelationRegular Relation = "regular"
+	// RelationAmong bounds how many of its variables take one of a set of
+	// values. Args holds the lower and upper bound, then the values
+	RelationAmong Relation = "among"
+	// RelationSequence bounds, as among does, every window of consecutive
+	// variables. Args holds the window's length, the lower and upper
+	// bound, then the values
+	RelationSequence Relation = "sequence"
+	// RelationBinPacking requires the items whose bins are its variables
+	// to fit the bins they're in. Args holds the bins' capacity, then the
+	// size of each item
+	RelationBinPacking Relation = "binpacking"
+	// RelationDiffn requires the rectangles at its variables, the xs of
+	// their corners and then the ys, to never overlap. Args holds the
+	// width of each rectangle, then the height of each
+	RelationDiffn Relation = "diffn"
+)
+
+// Operator compares the two sides of a linear constraint
+type Operator string
+
+const (
+	Eq Operator = "=="
+	Ne Operator = "!="
+	Lt Operator = "<"
+	Le Operator = "<="
+	Gt Operator = ">"
+	Ge Operator = ">="
+)
+
+// a built-in relation's checker, given its arguments and a lookup
+// of the current value, if any, held by each constrained variable
+type relationFn func(args []int, arity int, value func(ndx int) (int, bool)) bool
+
+var relations = map[Relation]struct {
+	check relationFn
+	// report whether args are well-formed for the given arity
+	valid func(args []int, arity int) error
+}{
+	RelationTable:             {check: checkTable, valid: validTable},
+	RelationAllDifferent:      {check: checkAllDifferent, valid: validAllDifferent},
+	RelationLinearEq:          {check: checkLinear(func(sum, c int) bool { return sum == c }), valid: validLinear},
+	RelationLinearNe:          {check: checkLinear(func(sum, c int) bool { return sum != c }), valid: validLinear},
+	RelationLinearLe:          {check: checkLinear(func(sum, c int) bool { return sum <= c }), valid: validLinear},
+	RelationAbsDiffEq:         {check: checkAbsDiff(func(diff, c int) bool { return diff == c }), valid: validAbsDiff},
+	RelationAbsDiffNe:         {check: checkAbsDiff(func(diff, c int) bool { return diff != c }), valid: validAbsDiff},
+	RelationAbsDiffLe:         {check: checkAbsDiff(func(diff, c int) bool { return diff <= c }), valid: validAbsDiff},
+	RelationAbsDiffGe:         {check: checkAbsDiff(func(diff, c int) bool { return diff >= c }), valid: validAbsDiff},
+	RelationProduct:           {check: checkProduct, valid: validProduct},
+	RelationQuotient:          {check: checkQuotient, valid: validQuotient},
+	RelationCircuit:           {check: checkCircuit, valid: validCircuit},
+	RelationDisjunctive:       {check: checkDisjunctive, valid: validDisjunctive},
+	RelationGlobalCardinality: {check: checkGlobalCardinality, valid: validGlobalCardinality},
+	RelationRegular:           {check: checkRegular, valid: validRegular},
+	RelationAmong:             {check: checkAmong, valid: validAmong},
+	RelationSequence:          {check: checkSequence, valid: validSequence},
+	RelationBinPacking:        {check: checkBinPacking, valid: validBinPacking},
+	RelationDiffn:             {check: checkDiffn, valid: validDiffn},
+}
+
+// constrain the variables to take one of the given combinations of values
+func Table[V comparable](variables []V, tuples [][]int) Constraint[V] {
+	var args []int
+	for _, tuple := range tuples {
+		args = append(args, tuple...)
+	}
+
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationTable,
+		Args:      args,
+	}
+}
+
+// require the variables to take pairwise distinct values
+func AllDifferent[V comparable](variables ...V) Constraint[V] {
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationAllDifferent,
+	}
+}
+
+// require the variables, each shifted by its offset, to take pairwise
+// distinct values, e.g. the diagonals of N-Queens, where no two of
+// queen[i] + i may be equal
+func AllDifferentOffset[V comparable](variables []V, offsets []int) Constraint[V] {
+	if len(variables) != len(offsets) {
+		panic(fmt.Sprintf("error: alldifferent constraint over %d variables given %d offsets", len(variables), len(offsets)))
+	}
+
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationAllDifferent,
+		Args:      append([]int{}, offsets...),
+	}
+}
+
+// require the weighted sum of the variables to compare to the constant
+// as the operator specifies, e.g. 2x + 3y <= 12
+func Linear[V comparable](variables []V, coefficients []int, operator Operator, constant int) Constraint[V] {
+	if len(variables) != len(coefficients) {
+		panic(fmt.Sprintf("error: linear constraint over %d variables given %d coefficients", len(variables), len(coefficients)))
+	}
+
+	// strict and reversed inequalities are rewritten in terms of <=
+	relation := RelationLinearLe
+	negate := false
+	switch operator {
+	case Eq:
+		relation = RelationLinearEq
+	case Ne:
+		relation = RelationLinearNe
+	case Le:
+	case Lt:
+		constant--
+	case Ge:
+		negate = true
+	case Gt:
+		negate = true
+		constant++
+	default:
+		panic(fmt.Sprintf("error: unknown operator %q", operator))
+	}
+
+	args := make([]int, 0, len(coefficients)+1)
+	for _, c := range coefficients {
+		if negate {
+			c = -c
+		}
+		args = append(args, c)
+	}
+	if negate {
+		constant = -constant
+	}
+
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  relation,
+		Args:      append(args, constant),
+	}
+}
+
+// require the plain sum of the variables to compare to the constant
+func Sum[V comparable](variables []V, operator Operator, constant int) Constraint[V] {
+	coefficients := make([]int, len(variables))
+	for ndx := range coefficients {
+		coefficients[ndx] = 1
+	}
+
+	return Linear(variables, coefficients, operator, constant)
+}
+
+// require a and b to take the same value
+func Equal[V comparable](a, b V) Constraint[V] {
+	return Linear([]V{a, b}, []int{1, -1}, Eq, 0)
+}
+
+// require a and b to take different values
+func NotEqual[V comparable](a, b V) Constraint[V] {
+	return Linear([]V{a, b}, []int{1, -1}, Ne, 0)
+}
+
+// require a to take a smaller value than b
+func LessThan[V comparable](a, b V) Constraint[V] {
+	return Linear([]V{a, b}, []int{1, -1}, Lt, 0)
+}
+
+// report whether any allowed tuple agrees with every assigned value,
+// so that partial assignments are rejected as soon as they can't succeed
+func checkTable(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	if arity == 0 {
+		return len(args) == 0
+	}
+
+	for start := 0; start < len(args); start += arity {
+		match := true
+		for ndx := 0; ndx < arity; ndx++ {
+			if v, assigned := value(ndx); assigned && v != args[start+ndx] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validTable(args []int, arity int) error {
+	if arity == 0 || len(args)%arity != 0 {
+		return fmt.Errorf("table of %d values doesn't divide into tuples of %d", len(args), arity)
+	}
+	return nil
+}
+
+// report whether the values assigned so far, plus their offsets if
+// any, are pairwise distinct. they're compared against one another in
+// a buffer on the stack, for the usual handful of variables, rather than
+// a set, which would be made on the heap at every check
+func checkAllDifferent(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	var buf [64]int
+	seen := buf[:0]
+	for ndx := 0; ndx < arity; ndx++ {
+		if v, assigned := value(ndx); assigned {
+			if len(args) > 0 {
+				v += args[ndx]
+			}
+			if hasValue(seen, v) {
+				return false
+			}
+			seen = append(seen, v)
+		}
+	}
+
+	return true
+}
+
+// report whether the value is among the values
+func hasValue(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// compare the weighted sum once every variable is assigned; until then
+// the constraint can't be ruled out without knowing the remaining domains
+func checkLinear(compare func(sum, constant int) bool) relationFn {
+	return func(args []int, arity int, value func(ndx int) (int, bool)) bool {
+		sum := 0
+		for ndx := 0; ndx < arity; ndx++ {
+			v, assigned := value(ndx)
+			if !assigned {
+				return true
+			}
+			sum += args[ndx] * v
+		}
+
+		return compare(sum, args[arity])
+	}
+}
+
+func validAllDifferent(args []int, arity int) error {
+	if len(args) != 0 && len(args) != arity {
+		return fmt.Errorf("alldifferent over %d variables needs no offsets or %d, got %d", arity, arity, len(args))
+	}
+	return nil
+}
+
+func validLinear(args []int, arity int) error {
+	if len(args) != arity+1 {
+		return fmt.Errorf("linear relation over %d variables needs %d arguments, got %d", arity, arity+1, len(args))
+	}
+	return nil
+}
+
+// ensure the relation is known, its arguments are well-formed, and the
+// domain values are integers the relation can be evaluated against
+func validateRelation[D any](relation Relation, arity int, args []int) error {
+	r, found := relations[relation]
+	if !found {
+		return fmt.Errorf("unknown relation %q", relation)
+	}
+	if kind := kindOf[D](); !isIntegerKind(kind) {
+		return fmt.Errorf("relation %q requires integer domain values, not %s", relation, kind)
+	}
+
+	return r.valid(args, arity)
+}
+
+// wrap a user-supplied Satisfied func so that built-in relations are
+// evaluated by the package, and all other constraints by the user
+func withRelations[V comparable, D any](user Satisfied[V, D]) Satisfied[V, D] {
+	return func(constraint Constraint[V], candidate map[V]D) bool {
+		if constraint.Relation == "" {
+			return user(constraint, candidate)
+		}
+
+		return relations[constraint.Relation].check(constraint.Args, len(constraint.Variables), func(ndx int) (int, bool) {
+			value, found := candidate[constraint.Variables[ndx]]
+			if !found {
+				return 0, false
+			}
+			return asInt(value), true
+		})
+	}
+}
+
+// the kind of the domain value type, even if it's an interface
+func kindOf[D any]() reflect.Kind {
+	return reflect.TypeOf((*D)(nil)).Elem().Kind()
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// convert an integer-valued domain value to int, taking the fast path
+// for plain integer types and falling back on reflection for named ones
+func asInt[D any](value D) int {
+	switch v := any(value).(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case int32:
+		return int(v)
+	case uint8:
+		return int(v)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.CanInt() {
+		return int(rv.Int())
+	}
+	return int(rv.Uint())
+}
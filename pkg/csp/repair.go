@@ -0,0 +1,51 @@
+package csp
+
+// the solution extending the assignment that's nearest the reference,
+// changing the fewest of the variables the reference assigns, e.g. to
+// mend a roster a nurse's illness broke with as little disruption as
+// possible. it's found by branch and bound, as Minimize does, with each
+// variable's reference value tried first, ahead of those OrderValues
+// gives, so the first solutions found are near ones already. the
+// reference needn't be a solution, nor complete. the result is the
+// solution and the number of variables changed, or nil if there is none;
+// if the Timeout expires first, it's the nearest found so far. values
+// are compared with ==, so D must be comparable
+func Repair[V comparable, D comparable](b *Backtracker[V, D], assignment, reference map[V]D) (map[V]D, int) {
+	order := b.OrderValues
+	defer func() { b.OrderValues = order }()
+	b.OrderValues = func(variable V, assignment map[V]D) []D {
+		values := b.Problem.Domain[variable]
+		if order != nil {
+			values = order(variable, assignment)
+		}
+		want, found := reference[variable]
+		if !found {
+			return values
+		}
+
+		out := make([]D, 0, len(values))
+		for _, value := range values {
+			if value == want {
+				out = append(out, value)
+			}
+		}
+		for _, value := range values {
+			if value != want {
+				out = append(out, value)
+			}
+		}
+		return out
+	}
+
+	// the changes so far, which no extension can undo
+	changed := func(candidate map[V]D) int {
+		n := 0
+		for v, value := range candidate {
+			if want, found := reference[v]; found && want != value {
+				n++
+			}
+		}
+		return n
+	}
+	return b.Minimize(assignment, changed)
+}
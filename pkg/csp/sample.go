@@ -0,0 +1,194 @@
+package csp
+
+import (
+	"math/bits"
+	"math/rand"
+	"time"
+)
+
+const (
+	// the most solutions of a cell a sample is drawn from: the larger,
+	// the nearer uniform the sampling, and the longer a cell takes to list
+	sampleCell = 16
+
+	// the cells a sample tries before settling for the last one not empty
+	sampleTries = 64
+
+	// the nodes listing a cell may visit, by default, before it's cut short
+	sampleNodes = 1 << 16
+)
+
+// Sampler draws solutions near uniformly at random from all those of a
+// problem, unlike the first solution of a randomized search, which
+// favors those down the few branches that lead to the most dead ends.
+// it cuts the solutions into cells by random parity constraints, XORs
+// of the bits of each variable's value, as its position in its domain,
+// adding one at a time until a random cell holds few enough to list, and
+// then picks one of those. each XOR halves the solutions, whichever they
+// are, so every solution is about as likely to be drawn.
+//
+// a sample lists a cell of solutions, from a search that can only check
+// an XOR once its variables are assigned, and so must search the whole
+// tree to show a cell holds few enough: on a problem of millions of
+// solutions, such as 16-queens, that is as costly as listing them all.
+// listing a cell is cut short past CellNodes nodes, and the sample drawn
+// from the solutions the cell had turned up by then, which bounds the
+// work of each sample but favors those the search reaches first. the
+// Backtracker's Timeout bounds the whole of a sample
+type Sampler[V comparable, D comparable] struct {
+	Backtracker *Backtracker[V, D]
+	// Rand draws the XORs and the solution; if nil, one seeded from the
+	// clock is used
+	Rand *rand.Rand
+	// CellNodes bounds the nodes listing a cell under any XORs may visit;
+	// if zero, it's sampleNodes, and if negative, there's no limit, for
+	// samples as near uniform as they can be at whatever cost
+	CellNodes int
+
+	// the number of XORs the last sample took, to start the next from
+	xors  int
+	stats Stats
+}
+
+// a random parity constraint: the XOR of the bits of the variables'
+// positions the masks pick must be odd, or even
+type xor[V comparable] struct {
+	vars  []V
+	masks []int
+	odd   bool
+}
+
+// construct a Sampler of the solutions the Backtracker searches
+func NewSampler[V comparable, D comparable](b *Backtracker[V, D]) *Sampler[V, D] {
+	return &Sampler[V, D]{Backtracker: b}
+}
+
+// the work done by the most recent call to Sample, summed over the cells
+// it listed
+func (s *Sampler[V, D]) Stats() Stats {
+	return s.stats
+}
+
+// draw a solution extending the given assignment, or nil if there is
+// none, or if the Backtracker's Timeout expired first
+func (s *Sampler[V, D]) Sample(assignment map[V]D) map[V]D {
+	if s.Rand == nil {
+		s.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	b := s.Backtracker
+	s.stats = Stats{}
+	timeout := b.Timeout
+	defer func() { b.Timeout = timeout }()
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	positions := map[V]map[D]int{}
+	for v, values := range b.Problem.Domain {
+		positions[v] = make(map[D]int, len(values))
+		for ndx, value := range values {
+			positions[v][value] = ndx
+		}
+	}
+
+	var last []map[V]D
+	for try := 0; try < sampleTries; try++ {
+		if !deadline.IsZero() {
+			if b.Timeout = time.Until(deadline); b.Timeout <= 0 {
+				s.stats.TimedOut = true
+				return nil
+			}
+		}
+		cell, cut := s.list(assignment, s.xorsOf(s.xors), positions)
+		if s.stats.TimedOut || s.stats.Canceled {
+			return nil
+		}
+
+		switch {
+		case len(cell) > sampleCell:
+			s.xors++
+		case len(cell) == 0 && cut:
+			// too many XORs to find any solution soon
+			s.xors--
+		case len(cell) == 0:
+			if s.xors == 0 {
+				return nil
+			}
+			s.xors--
+		default:
+			return cell[s.Rand.Intn(len(cell))]
+		}
+		if len(cell) > 0 {
+			last = cell
+		}
+	}
+
+	// the cells swung between empty and too full; take what there was
+	if len(last) == 0 {
+		return nil
+	}
+	return last[s.Rand.Intn(len(last))]
+}
+
+// n random XORs over the Problem's variables
+func (s *Sampler[V, D]) xorsOf(n int) []xor[V] {
+	out := make([]xor[V], n)
+	for ndx := range out {
+		for v, values := range s.Backtracker.Problem.Domain {
+			width := bits.Len(uint(len(values) - 1))
+			if width == 0 {
+				continue
+			}
+			if mask := s.Rand.Intn(1 << width); mask != 0 {
+				out[ndx].vars = append(out[ndx].vars, v)
+				out[ndx].masks = append(out[ndx].masks, mask)
+			}
+		}
+		out[ndx].odd = s.Rand.Intn(2) == 1
+	}
+	return out
+}
+
+// list the solutions extending the assignment that satisfy the XORs, up
+// to one more than a cell may hold, and whether the listing was cut short
+// at CellNodes before it could finish. with no XORs, it's never cut, as
+// the search is no more than a Solve
+func (s *Sampler[V, D]) list(assignment map[V]D, xors []xor[V], positions map[V]map[D]int) ([]map[V]D, bool) {
+	b := s.Backtracker
+	limit := s.CellNodes
+	if limit == 0 {
+		limit = sampleNodes
+	}
+	cut := false
+	b.prune = func(assignment map[V]D) bool {
+		// past the limit, every branch is cut, ending the search
+		if cut = cut || (len(xors) > 0 && limit > 0 && b.stats.Nodes > limit); cut {
+			return true
+		}
+		for _, x := range xors {
+			parity, complete := 0, true
+			for ndx, v := range x.vars {
+				value, found := assignment[v]
+				if !found {
+					complete = false
+					break
+				}
+				parity ^= bits.OnesCount(uint(positions[v][value]&x.masks[ndx])) & 1
+			}
+			if complete && (parity == 1) != x.odd {
+				return true
+			}
+		}
+		return false
+	}
+	defer func() { b.prune = nil }()
+
+	var cell []map[V]D
+	b.run(assignment, func(solution map[V]D) bool {
+		cell = append(cell, dup(solution))
+		return len(cell) > sampleCell
+	})
+	s.stats = sumStats(s.stats, b.stats)
+	return cell, cut
+}
@@ -0,0 +1,309 @@
+package csp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Hooks observes a backtracking search as it runs; nil callbacks are skipped
+type Hooks[V comparable, D any] struct {
+	// OnAssign is called as value is tentatively assigned to variable
+	OnAssign func(variable V, value D, depth int)
+	// OnReject is called when constraint rules out the value just assigned to variable
+	OnReject func(variable V, value D, constraint Constraint[V])
+	// OnBacktrack is called when no remaining value of variable leads to a
+	// solution, and the search returns to the previous variable
+	OnBacktrack func(variable V, depth int)
+	// OnSolution is called with each solution as it's found. the search
+	// goes on to reuse the map, so copy it to retain it
+	OnSolution func(solution map[V]D)
+}
+
+// Stats counts the work done by a search
+type Stats struct {
+	// Nodes is the number of values tentatively assigned
+	Nodes int
+	// Backtracks is the number of times every value of a variable failed
+	Backtracks int
+	// Rejections is the number of values ruled out by a constraint
+	Rejections int
+	Solutions  int
+	Duration   time.Duration
+	// TimedOut is set if the search gave up before finishing
+	TimedOut bool
+	// Canceled is set if Cancel stopped the search before it finished
+	Canceled bool
+}
+
+// Backtracker is the depth-first search engine behind Problem.Solve,
+// exposed so that callers can observe and tune the search
+type Backtracker[V comparable, D any] struct {
+	Problem Problem[V, D]
+	// Timeout bounds how long Solve may run, or zero for no limit
+	Timeout time.Duration
+	// SelectVariable picks the next variable to assign; if nil, the
+	// search takes the unassigned variables in map order
+	SelectVariable VariableOrder[V, D]
+	// OrderValues orders the values tried for each variable; if nil,
+	// they are tried in domain order
+	OrderValues ValueOrder[V, D]
+	// ParallelThreshold is the number of constraints a variable must have
+	// for them to be checked concurrently, across Workers goroutines, as
+	// each of its values is tried; if zero, they're always checked in
+	// turn. the SatFn must then be safe to call from several goroutines
+	// at once. problems of built-in relations alone, searched on the
+	// faster path, are checked in turn regardless
+	ParallelThreshold int
+	// Workers bounds the goroutines checking a variable's constraints;
+	// if zero, it's GOMAXPROCS
+	Workers int
+	// Profile, if set, captures each solve with runtime/pprof
+	Profile *Profile
+	// Symmetries, if any, make SolveAll return one solution of each class
+	// of those they send each other to: the lexicographically least, as
+	// SolveCanonical orders them. Stats and the hooks still count and see
+	// every solution found
+	Symmetries []Symmetry[V, D]
+
+	hooks []Hooks[V, D]
+	stats Stats
+	// cuts off the partial assignments that can't lead anywhere better,
+	// during a Minimize
+	prune    func(assignment map[V]D) bool
+	deadline time.Time
+	// recycles the copies of solutions a Minimize drops, shared with the
+	// engine running it, if any
+	pool *assignmentPool[V, D]
+	// without a SelectVariable, the variables left to assign, the next
+	// last: a depth-first search takes and puts them back as a stack, so
+	// choosing one doesn't rescan the Domain at every node
+	pending []V
+	// set once by Cancel, from any goroutine
+	canceled int32
+	// the labels of the phases of a profiled search, or nil
+	phases *phases
+}
+
+// construct a Backtracker for the given Problem
+func NewBacktracker[V comparable, D any](p Problem[V, D]) *Backtracker[V, D] {
+	return &Backtracker[V, D]{
+		Problem: p,
+	}
+}
+
+// the pool of assignment maps, made the first time it's needed
+func (b *Backtracker[V, D]) assignments() *assignmentPool[V, D] {
+	if b.pool == nil {
+		b.pool = &assignmentPool[V, D]{}
+	}
+	return b.pool
+}
+
+// register another set of Hooks to be notified as the search runs
+func (b *Backtracker[V, D]) Observe(hooks Hooks[V, D]) {
+	b.hooks = append(b.hooks, hooks)
+}
+
+// backtracking recursive search through the domain of problem
+// variables and all their possible values. the first valid
+// solution obtained in this brute-force effort is returned,
+// or nil if none exists or the Timeout expired first. the
+// assignment passed in is left as it was
+func (b *Backtracker[V, D]) Solve(assignment map[V]D) map[V]D {
+	var result map[V]D
+	b.run(assignment, func(solution map[V]D) bool {
+		result = solution
+		return true
+	})
+
+	return result
+}
+
+// exhaustively enumerate every solution extending the given assignment,
+// each as a separate copy, or only one of each class if Symmetries are
+// declared. if the Timeout expires first, the solutions found so far are
+// returned and Stats reports the timeout
+func (b *Backtracker[V, D]) SolveAll(assignment map[V]D) []map[V]D {
+	var results []map[V]D
+	if len(b.Symmetries) > 0 {
+		distinct := newDistinctSolutions(b.Problem, b.Symmetries)
+		b.run(assignment, func(solution map[V]D) bool {
+			if representative, fresh := distinct.add(solution); fresh {
+				results = append(results, representative)
+			}
+			return false
+		})
+		return results
+	}
+
+	b.run(assignment, func(solution map[V]D) bool {
+		results = append(results, dup(solution))
+		return false
+	})
+
+	return results
+}
+
+// stop the running search as soon as possible, from any goroutine: it
+// returns what it has found, as though its Timeout had expired, and
+// Stats reports the cancellation. a canceled Backtracker stays canceled,
+// so later searches with it return at once
+func (b *Backtracker[V, D]) Cancel() {
+	atomic.StoreInt32(&b.canceled, 1)
+}
+
+// the work done by the most recent call to Solve or SolveAll
+func (b *Backtracker[V, D]) Stats() Stats {
+	return b.stats
+}
+
+// run a search from scratch, reporting each solution to found,
+// which returns true to end the search there
+func (b *Backtracker[V, D]) run(assignment map[V]D, found func(map[V]D) bool) {
+	start := time.Now()
+	// the search fills in its own copy, leaving the caller's as it was
+	assignment = dup(assignment)
+	b.stats = Stats{}
+	b.deadline = time.Time{}
+	if b.Timeout > 0 {
+		b.deadline = start.Add(b.Timeout)
+	}
+	b.phases = nil
+	if b.Profile != nil {
+		b.phases = newPhases()
+		defer b.Profile.start()()
+	}
+
+	b.phases.enter(phasePreprocessing)
+	if d, ok := b.dense(assignment); ok {
+		b.phases.enter(phaseSearch)
+		d.search(assignment, 0, found)
+		b.stats.Duration = time.Since(start)
+		return
+	}
+	b.pending = b.pending[:0]
+	if b.SelectVariable == nil {
+		for v := range b.Problem.Domain {
+			if _, found := assignment[v]; !found {
+				b.pending = append(b.pending, v)
+			}
+		}
+	}
+
+	b.phases.enter(phaseSearch)
+	b.search(assignment, 0, found)
+	b.stats.Duration = time.Since(start)
+}
+
+// returns true once the search should stop, leaving the
+// final solution in place within the assignment
+func (b *Backtracker[V, D]) search(assignment map[V]D, depth int, found func(map[V]D) bool) bool {
+	// checking the clock at every node would be wasteful, unlike the flag.
+	// both are checked before recording a solution, so that a Cancel from
+	// OnSolution, such as at a limit on the solutions, records no more
+	if b.stats.Nodes%1024 == 0 && !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		b.stats.TimedOut = true
+	}
+	if atomic.LoadInt32(&b.canceled) == 1 {
+		b.stats.Canceled = true
+	}
+	if b.stats.TimedOut || b.stats.Canceled {
+		return true
+	}
+
+	// base case: all variables are assigned, a solution has been found
+	if len(assignment) == len(b.Problem.Domain) {
+		b.stats.Solutions++
+		for _, h := range b.hooks {
+			if h.OnSolution != nil {
+				h.OnSolution(assignment)
+			}
+		}
+		return found(assignment)
+	}
+
+	// pick the next currently-unassigned variable
+	var nextVar V
+	if b.SelectVariable != nil {
+		nextVar = b.SelectVariable(assignment)
+	} else {
+		if len(b.pending) == 0 {
+			return false
+		}
+		nextVar = b.pending[len(b.pending)-1]
+		b.pending = b.pending[:len(b.pending)-1]
+	}
+
+	values := b.Problem.Domain[nextVar]
+	if b.OrderValues != nil {
+		b.phases.enter(phasePropagation)
+		values = b.OrderValues(nextVar, assignment)
+		b.phases.enter(phaseSearch)
+	}
+
+	// test the current solution, augmented by the next
+	// unassigned variable and a candidate value, against
+	// all the constraints
+	for _, candidateValue := range values {
+		assignment[nextVar] = candidateValue
+		b.stats.Nodes++
+		for _, h := range b.hooks {
+			if h.OnAssign != nil {
+				h.OnAssign(nextVar, candidateValue, depth)
+			}
+		}
+
+		if b.consistent(nextVar, candidateValue, assignment) && (b.prune == nil || !b.prune(assignment)) {
+			if b.search(assignment, depth+1, found) {
+				return true
+			}
+		}
+	}
+
+	// no candidate value is a component of another valid
+	// solution; ditch the variable and try again higher up
+	delete(assignment, nextVar)
+	if b.SelectVariable == nil {
+		b.pending = append(b.pending, nextVar)
+	}
+	b.stats.Backtracks++
+	for _, h := range b.hooks {
+		if h.OnBacktrack != nil {
+			h.OnBacktrack(nextVar, depth)
+		}
+	}
+
+	return false
+}
+
+// determine if this variable and assignment satisfy the
+// constraints applied to the problem space for that variable
+func (b *Backtracker[V, D]) consistent(variable V, value D, assignment map[V]D) bool {
+	constraints := b.Problem.Constraints[variable]
+	if workers := b.workers(len(constraints)); workers > 1 {
+		constraint, violated := b.Problem.violatedConcurrently(constraints, assignment, workers)
+		if violated {
+			b.reject(variable, value, constraint)
+		}
+		return !violated
+	}
+
+	for _, constraint := range constraints {
+		if !b.Problem.SatFn(constraint, assignment) {
+			b.reject(variable, value, constraint)
+			return false
+		}
+	}
+
+	return true
+}
+
+// count the value as ruled out by the constraint, and report it to the hooks
+func (b *Backtracker[V, D]) reject(variable V, value D, constraint Constraint[V]) {
+	b.stats.Rejections++
+	for _, h := range b.hooks {
+		if h.OnReject != nil {
+			h.OnReject(variable, value, constraint)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package csp
+
+import "fmt"
+
+// require between low and high of the variables, inclusive, to take one
+// of the given values, e.g. two or three of a day's shifts being nights
+func Among[V comparable](variables []V, values []int, low, high int) Constraint[V] {
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationAmong,
+		Args:      append([]int{low, high}, values...),
+	}
+}
+
+// require every window of q consecutive variables to have between low and
+// high of them, inclusive, taking one of the given values, e.g. no more
+// than two of any five cars down a production line needing a sunroof
+func Sequence[V comparable](variables []V, values []int, q, low, high int) Constraint[V] {
+	return Constraint[V]{
+		Variables: variables,
+		Relation:  RelationSequence,
+		Args:      append([]int{q, low, high}, values...),
+	}
+}
+
+// report whether the variables from ndx up to end can still have between
+// low and high of them taking one of the values: no more than high have
+// so far, and enough are unassigned to make up any shortfall from low
+func among(values []int, low, high, ndx, end int, value func(ndx int) (int, bool)) bool {
+	n, unassigned := 0, 0
+	for ; ndx < end; ndx++ {
+		if v, assigned := value(ndx); !assigned {
+			unassigned++
+		} else if hasValue(values, v) {
+			n++
+		}
+	}
+	return n <= high && n+unassigned >= low
+}
+
+func checkAmong(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	return among(args[2:], args[0], args[1], 0, arity, value)
+}
+
+// check each window in turn, as an among constraint of its own
+func checkSequence(args []int, arity int, value func(ndx int) (int, bool)) bool {
+	values := args[3:]
+	q, low, high := args[0], args[1], args[2]
+	for start := 0; start+q <= arity; start++ {
+		if !among(values, low, high, start, start+q, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func validAmong(args []int, arity int) error {
+	if len(args) < 2 {
+		return fmt.Errorf("among takes a lower and upper bound and then the values, got %d arguments", len(args))
+	}
+	if low, high := args[0], args[1]; low < 0 || high < low {
+		return fmt.Errorf("among bounds are out of order: %d to %d", low, high)
+	}
+	return nil
+}
+
+func validSequence(args []int, arity int) error {
+	if len(args) < 3 {
+		return fmt.Errorf("sequence takes a window, lower and upper bound and then the values, got %d arguments", len(args))
+	}
+	if q := args[0]; q < 1 || q > arity {
+		return fmt.Errorf("sequence window %d doesn't fit %d variables", q, arity)
+	}
+	if low, high := args[1], args[2]; low < 0 || high < low {
+		return fmt.Errorf("sequence bounds are out of order: %d to %d", low, high)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// write the problem as an SMT-LIB v2 script in the QF_LIA logic, so that
+// hard instances can be verified with Z3 or CVC5. every variable becomes an
+// Int restricted to its domain, built-in relations are translated directly,
+// and user-defined constraints are written as disjunctions of the value
+// tuples satisfying them. domain values must be integers
+func (p Problem[V, D]) ExportSMTLIB(w io.Writer) error {
+	en := p.exportNames()
+
+	lines := []string{"(set-logic QF_LIA)"}
+	var asserts []string
+	for _, v := range en.ordered {
+		values, err := p.exportDomain(v)
+		if err != nil {
+			return err
+		}
+		name := en.names[v]
+		lines = append(lines, fmt.Sprintf("(declare-const %s Int)", name))
+		asserts = append(asserts, fmt.Sprintf("(assert %s)", smtDomain(name, values)))
+	}
+
+	var constraints []string
+	for _, constraint := range p.exportConstraints() {
+		vars := make([]string, len(constraint.Variables))
+		for ndx, v := range constraint.Variables {
+			vars[ndx] = en.names[v]
+		}
+
+		// products aren't linear, so state them by their tuples
+		if constraint.Relation == RelationProduct || constraint.Relation == RelationQuotient {
+			constraint = p.tabulate(constraint)
+			vars = vars[:0]
+			for _, v := range constraint.Variables {
+				vars = append(vars, en.names[v])
+			}
+		}
+
+		var expr string
+		switch constraint.Relation {
+		case RelationTable:
+			var rows []string
+			for _, tuple := range tuples(constraint.Args, len(vars)) {
+				var eqs []string
+				for ndx, value := range tuple {
+					eqs = append(eqs, fmt.Sprintf("(= %s %s)", vars[ndx], smtInt(value)))
+				}
+				rows = append(rows, smtJoin("and", eqs))
+			}
+			expr = smtJoin("or", rows)
+
+		case RelationAllDifferent:
+			expr = "true"
+			if len(vars) > 1 {
+				terms := vars
+				if len(constraint.Args) > 0 {
+					terms = make([]string, len(vars))
+					for ndx, v := range vars {
+						terms[ndx] = fmt.Sprintf("(+ %s %s)", v, smtInt(constraint.Args[ndx]))
+					}
+				}
+				expr = fmt.Sprintf("(distinct %s)", strings.Join(terms, " "))
+			}
+
+		case RelationLinearEq, RelationLinearNe, RelationLinearLe:
+			var terms []string
+			for ndx, v := range vars {
+				terms = append(terms, fmt.Sprintf("(* %s %s)", smtInt(constraint.Args[ndx]), v))
+			}
+			sum := "0"
+			if len(terms) == 1 {
+				sum = terms[0]
+			} else if len(terms) > 1 {
+				sum = fmt.Sprintf("(+ %s)", strings.Join(terms, " "))
+			}
+
+			constant := smtInt(constraint.Args[len(vars)])
+			switch constraint.Relation {
+			case RelationLinearEq:
+				expr = fmt.Sprintf("(= %s %s)", sum, constant)
+			case RelationLinearNe:
+				expr = fmt.Sprintf("(not (= %s %s))", sum, constant)
+			default:
+				expr = fmt.Sprintf("(<= %s %s)", sum, constant)
+			}
+
+		case RelationAbsDiffEq, RelationAbsDiffNe, RelationAbsDiffLe, RelationAbsDiffGe:
+			diff := fmt.Sprintf("(abs (- %s %s))", vars[0], vars[1])
+			constant := smtInt(constraint.Args[0])
+			switch constraint.Relation {
+			case RelationAbsDiffEq:
+				expr = fmt.Sprintf("(= %s %s)", diff, constant)
+			case RelationAbsDiffNe:
+				expr = fmt.Sprintf("(not (= %s %s))", diff, constant)
+			case RelationAbsDiffLe:
+				expr = fmt.Sprintf("(<= %s %s)", diff, constant)
+			default:
+				expr = fmt.Sprintf("(>= %s %s)", diff, constant)
+			}
+
+		default:
+			return fmt.Errorf("no SMT-LIB translation for relation %q", constraint.Relation)
+		}
+		constraints = append(constraints, fmt.Sprintf("(assert %s)", expr))
+	}
+	sort.Strings(constraints)
+
+	out := append(lines, asserts...)
+	out = append(out, constraints...)
+	out = append(out, "(check-sat)", "(get-model)")
+
+	_, err := io.WriteString(w, strings.Join(out, "\n")+"\n")
+	return err
+}
+
+// restrict the variable to its domain: a range check if the
+// values are contiguous, or else a disjunction of equalities
+func smtDomain(name string, values []int) string {
+	sorted := append([]int{}, values...)
+	sort.Ints(sorted)
+
+	contiguous := len(sorted) > 0
+	for ndx := 1; ndx < len(sorted); ndx++ {
+		if sorted[ndx] != sorted[ndx-1]+1 {
+			contiguous = false
+			break
+		}
+	}
+	if contiguous {
+		return fmt.Sprintf("(and (<= %s %s) (<= %s %s))", smtInt(sorted[0]), name, name, smtInt(sorted[len(sorted)-1]))
+	}
+
+	var eqs []string
+	for _, value := range sorted {
+		eqs = append(eqs, fmt.Sprintf("(= %s %s)", name, smtInt(value)))
+	}
+	return smtJoin("or", eqs)
+}
+
+// SMT-LIB has no negative literals, only negation
+func smtInt(n int) string {
+	if n < 0 {
+		return fmt.Sprintf("(- %d)", -n)
+	}
+	return fmt.Sprint(n)
+}
+
+// combine terms under a boolean connective, which needs at least two
+func smtJoin(op string, terms []string) string {
+	switch len(terms) {
+	case 0:
+		if op == "and" {
+			return "true"
+		}
+		return "false"
+	case 1:
+		return terms[0]
+	}
+
+	return fmt.Sprintf("(%s %s)", op, strings.Join(terms, " "))
+}
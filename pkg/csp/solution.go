@@ -0,0 +1,121 @@
+package csp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Solution is the outcome of a solve along with how it was obtained,
+// for handing results to other programs. it marshals to JSON as
+//
+//	{
+//	  "solved": true,
+//	  "strategy": "backtracking",
+//	  "seed": 1,
+//	  "timestamp": "2024-01-02T15:04:05Z",
+//	  "stats": {"nodes": 12, "backtracks": 3, ...},
+//	  "assignment": [{"variable": "x", "value": 1}, ...]
+//	}
+//
+// with the assignment as a list, since variables need not be strings,
+// ordered by the printed form of each variable. it also encodes with
+// encoding/gob as is, provided V and D do
+type Solution[V comparable, D any] struct {
+	// Assignment is nil if no solution was found
+	Assignment map[V]D
+	Stats      Stats
+	// Strategy names the solver or heuristic that produced the solution
+	Strategy string
+	// Seed is the random seed the solve used, if any
+	Seed      int64
+	Timestamp time.Time
+}
+
+type solutionJSON[V comparable, D any] struct {
+	Solved     bool                   `json:"solved"`
+	Strategy   string                 `json:"strategy,omitempty"`
+	Seed       int64                  `json:"seed"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Stats      statsJSON              `json:"stats"`
+	Assignment []assignmentJSON[V, D] `json:"assignment"`
+}
+
+type statsJSON struct {
+	Nodes      int     `json:"nodes"`
+	Backtracks int     `json:"backtracks"`
+	Rejections int     `json:"rejections"`
+	Solutions  int     `json:"solutions"`
+	Seconds    float64 `json:"seconds"`
+	TimedOut   bool    `json:"timed_out"`
+	Canceled   bool    `json:"canceled"`
+}
+
+type assignmentJSON[V comparable, D any] struct {
+	Variable V `json:"variable"`
+	Value    D `json:"value"`
+}
+
+// encode the solution as JSON
+func (s Solution[V, D]) MarshalJSON() ([]byte, error) {
+	out := solutionJSON[V, D]{
+		Solved:    s.Assignment != nil,
+		Strategy:  s.Strategy,
+		Seed:      s.Seed,
+		Timestamp: s.Timestamp,
+		Stats: statsJSON{
+			Nodes:      s.Stats.Nodes,
+			Backtracks: s.Stats.Backtracks,
+			Rejections: s.Stats.Rejections,
+			Solutions:  s.Stats.Solutions,
+			Seconds:    s.Stats.Duration.Seconds(),
+			TimedOut:   s.Stats.TimedOut,
+			Canceled:   s.Stats.Canceled,
+		},
+		Assignment: []assignmentJSON[V, D]{},
+	}
+
+	for v, value := range s.Assignment {
+		out.Assignment = append(out.Assignment, assignmentJSON[V, D]{Variable: v, Value: value})
+	}
+	sort.Slice(out.Assignment, func(i, j int) bool {
+		return fmt.Sprintf("%+v", out.Assignment[i].Variable) < fmt.Sprintf("%+v", out.Assignment[j].Variable)
+	})
+
+	return json.Marshal(out)
+}
+
+// decode a solution encoded by MarshalJSON
+func (s *Solution[V, D]) UnmarshalJSON(data []byte) error {
+	var in solutionJSON[V, D]
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*s = Solution[V, D]{
+		Strategy:  in.Strategy,
+		Seed:      in.Seed,
+		Timestamp: in.Timestamp,
+		Stats: Stats{
+			Nodes:      in.Stats.Nodes,
+			Backtracks: in.Stats.Backtracks,
+			Rejections: in.Stats.Rejections,
+			Solutions:  in.Stats.Solutions,
+			Duration:   time.Duration(in.Stats.Seconds * float64(time.Second)),
+			TimedOut:   in.Stats.TimedOut,
+			Canceled:   in.Stats.Canceled,
+		},
+	}
+	if in.Solved {
+		s.Assignment = make(map[V]D, len(in.Assignment))
+		for _, entry := range in.Assignment {
+			if _, found := s.Assignment[entry.Variable]; found {
+				return fmt.Errorf("variable %+v is assigned more than once", entry.Variable)
+			}
+			s.Assignment[entry.Variable] = entry.Value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package csp
+
+// Solver is what the search engines have in common, a Backtracker, a
+// Parallel search, and the MinConflicts and LNS local searches, and what
+// any other backend need only implement, so that an application can
+// choose an engine, e.g. by a flag, or swap one for another, without
+// changing the code that calls it. engines differ in what they can
+// prove: a local search can't show that no solution exists, list them
+// all, or show that its best is optimal, so its SolveAll returns the one
+// solution it finds, and its SolveOptimal the best it finds within its
+// limits. the assignment passed in is left as it was
+type Solver[V comparable, D any] interface {
+	// search for a solution extending the assignment, or nil if there is
+	// none, or none was found
+	Solve(assignment map[V]D) map[V]D
+	// search for the solutions extending the assignment
+	SolveAll(assignment map[V]D) []map[V]D
+	// search for the solution extending the assignment of least cost, as
+	// Backtracker.Minimize does, returning it and its cost
+	SolveOptimal(assignment map[V]D, cost Cost[V, D]) (map[V]D, int)
+	// stop the running search as soon as possible, from any goroutine
+	Cancel()
+	// the work done by the most recent search
+	Stats() Stats
+}
+
+// the engines implementing Solver
+var (
+	_ Solver[string, int] = (*Backtracker[string, int])(nil)
+	_ Solver[string, int] = (*Parallel[string, int])(nil)
+	_ Solver[string, int] = (*MinConflicts[string, int])(nil)
+	_ Solver[string, int] = (*LNS[string, int])(nil)
+)
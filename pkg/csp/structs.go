@@ -0,0 +1,304 @@
+package csp
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// the operators of the comparisons a struct tag can make
+var tagOperators = map[string]Operator{
+	"eq": Eq, "ne": Ne, "lt": Lt, "le": Le, "gt": Gt, "ge": Ge,
+}
+
+// an integer field of a struct given a csp tag, and its variables: one for
+// a single integer, named for the field, or one per element for an array
+// or slice of them, named e.g. Rooms[0]
+type structField struct {
+	name   string
+	index  int
+	scalar bool
+	exprs  []Expr
+	tag    []tagOption
+}
+
+// one key=value option of a csp tag
+type tagOption struct {
+	key, value string
+}
+
+// build a Model of the struct v points to, from the csp tags of its
+// fields, for small models written as the data they fill in:
+//
+//	type Plan struct {
+//		Keynote  int    `csp:"min=9,max=16,alldifferent=slots"`
+//		Workshop int    `csp:"min=9,max=16,alldifferent=slots,gt=Keynote"`
+//		Lunch    int    `csp:"domain=12|13,alldifferent=slots"`
+//		Rooms    [3]int `csp:"min=1,max=3,alldifferent=rooms"`
+//	}
+//
+// each field with a tag is an integer, or an array or slice of them, and
+// declares a variable per integer. its options, separated by commas, are
+//
+//	min=N, max=N       the least and greatest values, inclusive
+//	domain=A|B|...     the values, instead of min and max
+//	alldifferent=G     join the group G, whose variables all differ
+//	eq=X, ne=X, lt=X,
+//	le=X, gt=X, ge=X   compare the field to the field X, or the number X
+//	sum=X+Y+...        equal the sum of the fields, and of every element
+//	                   of an array or slice among them
+//
+// comparisons only apply to single integers, and a slice's length is
+// whatever it has when the struct is read. more constraints can be posted
+// to the Model before it's built; see FillStruct to write a solution back
+func ModelOfStruct(v any) (*Model, error) {
+	fields, err := structFields(v)
+	if err != nil {
+		return nil, err
+	}
+	m := NewModel()
+	byName := map[string]*structField{}
+	for _, f := range fields {
+		byName[f.name] = f
+		if err := f.declare(m); err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+
+	// the groups to keep different, in the order they first appear
+	groups := map[string][]Expr{}
+	var order []string
+	for _, f := range fields {
+		for _, opt := range f.tag {
+			var err error
+			switch operator, compare := tagOperators[opt.key]; {
+			case opt.key == "alldifferent":
+				if _, found := groups[opt.value]; !found {
+					order = append(order, opt.value)
+				}
+				groups[opt.value] = append(groups[opt.value], f.exprs...)
+			case compare:
+				err = f.compare(m, operator, opt.value, byName)
+			case opt.key == "sum":
+				err = f.sum(m, opt.value, byName)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s: %w", f.name, opt.key, err)
+			}
+		}
+	}
+	for _, group := range order {
+		m.AllDifferent(groups[group]...)
+	}
+	return m, m.err
+}
+
+// write a solution of a Model from ModelOfStruct back into the fields of
+// the struct v points to
+func FillStruct(v any, solution map[string]int) error {
+	fields, err := structFields(v)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for _, f := range fields {
+		field := rv.Field(f.index)
+		targets := []reflect.Value{field}
+		if !f.scalar {
+			targets = targets[:0]
+			for ndx := 0; ndx < field.Len(); ndx++ {
+				targets = append(targets, field.Index(ndx))
+			}
+		}
+		for ndx, target := range targets {
+			name, _ := f.exprs[ndx].variable()
+			value, found := solution[name]
+			if !found {
+				return fmt.Errorf("no value for %s", name)
+			}
+			if err := setInt(target, value); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// build the Model of the struct v points to, search it with MRV and
+// maintained arc consistency, and fill the struct in with the solution.
+// the result is false, and the struct left as it was, if there is none
+func SolveStruct(v any) (bool, error) {
+	m, err := ModelOfStruct(v)
+	if err != nil {
+		return false, err
+	}
+	problem, err := m.Build()
+	if err != nil {
+		return false, err
+	}
+	bt := NewBacktracker(problem)
+	bt.SelectVariable = MinRemainingValues(problem)
+	bt.OrderValues = MaintainArcConsistency(problem)
+	solution := bt.Solve(map[string]int{})
+	if solution == nil {
+		return false, nil
+	}
+	return true, FillStruct(v, solution)
+}
+
+// the fields of the struct v points to with a csp tag, and the names of
+// their variables, before any are declared
+func structFields(v any) ([]*structField, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T isn't a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var out []*structField
+	for ndx := 0; ndx < rt.NumField(); ndx++ {
+		sf := rt.Field(ndx)
+		tag, found := sf.Tag.Lookup("csp")
+		if !found || tag == "-" {
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("field %s: unexported", sf.Name)
+		}
+		f := &structField{name: sf.Name, index: ndx}
+		var err error
+		if f.tag, err = parseTag(tag); err != nil {
+			return nil, fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+
+		switch kind := sf.Type.Kind(); {
+		case isIntegerKind(kind):
+			f.scalar = true
+			f.exprs = []Expr{variableExpr(sf.Name)}
+		case (kind == reflect.Array || kind == reflect.Slice) && isIntegerKind(sf.Type.Elem().Kind()):
+			for el := 0; el < rv.Field(ndx).Len(); el++ {
+				f.exprs = append(f.exprs, variableExpr(fmt.Sprintf("%s[%d]", sf.Name, el)))
+			}
+		default:
+			return nil, fmt.Errorf("field %s: %s isn't an integer, or an array or slice of them", sf.Name, sf.Type)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// split a csp tag into its options, checking their keys
+func parseTag(tag string) ([]tagOption, error) {
+	var out []tagOption
+	for _, part := range strings.Split(tag, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("option %q isn't of the form key=value", part)
+		}
+		switch _, compare := tagOperators[key]; {
+		case compare, key == "min", key == "max", key == "domain", key == "alldifferent", key == "sum":
+		default:
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+		out = append(out, tagOption{key: key, value: value})
+	}
+	return out, nil
+}
+
+// declare the field's variables, with the domain its tag gives
+func (f *structField) declare(m *Model) error {
+	var min, max *int
+	var domain []int
+	for _, opt := range f.tag {
+		switch opt.key {
+		case "min", "max":
+			n, err := strconv.Atoi(opt.value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", opt.key, err)
+			}
+			if opt.key == "min" {
+				min = &n
+			} else {
+				max = &n
+			}
+		case "domain":
+			for _, value := range strings.Split(opt.value, "|") {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("domain: %w", err)
+				}
+				domain = append(domain, n)
+			}
+		}
+	}
+	if domain == nil && (min == nil || max == nil) {
+		return fmt.Errorf("needs a min and max, or a domain")
+	}
+
+	for _, e := range f.exprs {
+		name, _ := e.variable()
+		if domain != nil {
+			m.IntVarIn(name, domain...)
+		} else {
+			m.IntVar(name, *min, *max)
+		}
+	}
+	return m.err
+}
+
+// post the comparison of the field to another, or to a number
+func (f *structField) compare(m *Model, operator Operator, target string, byName map[string]*structField) error {
+	if !f.scalar {
+		return fmt.Errorf("comparisons only apply to single integers")
+	}
+	other, found := byName[target]
+	switch {
+	case found && other.scalar:
+		m.Post(f.exprs[0].compare(operator, other.exprs[0]))
+	case found:
+		return fmt.Errorf("%s isn't a single integer", target)
+	default:
+		n, err := strconv.Atoi(target)
+		if err != nil {
+			return fmt.Errorf("%q is neither a field nor a number", target)
+		}
+		m.Post(f.exprs[0].compare(operator, Const(n)))
+	}
+	return nil
+}
+
+// post that the field equals the sum of the others
+func (f *structField) sum(m *Model, targets string, byName map[string]*structField) error {
+	if !f.scalar {
+		return fmt.Errorf("sums only apply to single integers")
+	}
+	var terms []Expr
+	for _, target := range strings.Split(targets, "+") {
+		other, found := byName[target]
+		if !found {
+			return fmt.Errorf("unknown field %q", target)
+		}
+		terms = append(terms, other.exprs...)
+	}
+	m.Post(f.exprs[0].Eq(SumOf(terms...)))
+	return nil
+}
+
+// set an integer field, signed or not, to the value, if it fits
+func setInt(field reflect.Value, value int) error {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.OverflowInt(int64(value)) {
+			return fmt.Errorf("%d overflows %s", value, field.Type())
+		}
+		field.SetInt(int64(value))
+	default:
+		if value < 0 || field.OverflowUint(uint64(value)) {
+			return fmt.Errorf("%d overflows %s", value, field.Type())
+		}
+		field.SetUint(uint64(value))
+	}
+	return nil
+}
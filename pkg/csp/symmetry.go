@@ -0,0 +1,99 @@
+package csp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Symmetry maps a solution to the equivalent one it's sent to, e.g. the
+// board of an n-queens solution turned a quarter, leaving the solution
+// it's given as it was. the solutions symmetries send each other to form
+// a class, of which SolveAll keeps one if they're declared
+type Symmetry[V comparable, D any] func(solution map[V]D) map[V]D
+
+// the members of the class of the solution, under the symmetries and all
+// their compositions: its orbit, by the printed form of each member
+func orbit[V comparable, D any](solution map[V]D, symmetries []Symmetry[V, D], vars []V) map[string]map[V]D {
+	out := map[string]map[V]D{solutionKey(solution, vars): solution}
+	queue := []map[V]D{solution}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, symmetry := range symmetries {
+			image := symmetry(next)
+			key := solutionKey(image, vars)
+			if _, found := out[key]; !found {
+				out[key] = image
+				queue = append(queue, image)
+			}
+		}
+	}
+	return out
+}
+
+// the printed form of a solution, its variables in the given order
+func solutionKey[V comparable, D any](solution map[V]D, vars []V) string {
+	var sb strings.Builder
+	for _, v := range vars {
+		fmt.Fprintf(&sb, "%+v ", solution[v])
+	}
+	return sb.String()
+}
+
+// keeps one canonical representative of each class of solutions, under
+// the declared symmetries: the lexicographically least member, as
+// SolveCanonical orders them, whichever member the search finds first
+type distinctSolutions[V comparable, D any] struct {
+	symmetries []Symmetry[V, D]
+	vars       []V
+	less       func(a, b D) bool
+	seen       map[string]bool
+}
+
+func newDistinctSolutions[V comparable, D any](p Problem[V, D], symmetries []Symmetry[V, D]) *distinctSolutions[V, D] {
+	d := &distinctSolutions[V, D]{
+		symmetries: symmetries,
+		less:       defaultLess[D](),
+		seen:       map[string]bool{},
+	}
+	for v := range p.Domain {
+		d.vars = append(d.vars, v)
+	}
+	sort.Slice(d.vars, func(i, j int) bool {
+		return fmt.Sprintf("%+v", d.vars[i]) < fmt.Sprintf("%+v", d.vars[j])
+	})
+	return d
+}
+
+// the representative of the solution's class, or false if its class has
+// been seen already
+func (d *distinctSolutions[V, D]) add(solution map[V]D) (map[V]D, bool) {
+	members := orbit(dup(solution), d.symmetries, d.vars)
+	var least map[V]D
+	for key, member := range members {
+		if d.seen[key] {
+			return nil, false
+		}
+		if least == nil || d.precedes(member, least) {
+			least = member
+		}
+	}
+	for key := range members {
+		d.seen[key] = true
+	}
+	return least, true
+}
+
+// whether one solution is lexicographically less than another
+func (d *distinctSolutions[V, D]) precedes(a, b map[V]D) bool {
+	for _, v := range d.vars {
+		switch {
+		case d.less(a[v], b[v]):
+			return true
+		case d.less(b[v], a[v]):
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package csp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// TraceKind identifies the step of the search a TraceEvent records
+type TraceKind string
+
+const (
+	TraceAssign    TraceKind = "a"
+	TraceReject    TraceKind = "r"
+	TraceBacktrack TraceKind = "b"
+	TraceSolution  TraceKind = "s"
+)
+
+// TraceEvent is a single step of a recorded search. variables and values
+// are recorded in their printed form, or the variables by the name their
+// Label gives them, so that a trace can be read back without knowing the
+// types of the Problem that produced it
+type TraceEvent struct {
+	Kind  TraceKind `json:"k"`
+	Depth int       `json:"d"`
+	// Variable and Value are set on all but TraceSolution events,
+	// which omit the Value for TraceBacktrack
+	Variable string `json:"v,omitempty"`
+	Value    string `json:"x,omitempty"`
+	// Scope holds the Variables of the rejecting constraint of a TraceReject,
+	// Constraint its name, as Labels.Constraint gives it, and Relation its
+	// built-in relation, if any. ConstraintID numbers the constraints from
+	// 1 in the order they first reject, so that two sharing a name and
+	// scope, such as the diagonals of a queen, are still told apart
+	Scope        []string `json:"c,omitempty"`
+	Constraint   string   `json:"n,omitempty"`
+	Relation     Relation `json:"rel,omitempty"`
+	ConstraintID int      `json:"i,omitempty"`
+	// Solution holds the complete assignment of a TraceSolution
+	Solution map[string]string `json:"s,omitempty"`
+}
+
+// Tracer observes a Backtracker, recording every step of the search as
+// one line of JSON per TraceEvent. traces of long searches grow large,
+// so events are buffered: call Flush once the search is done
+type Tracer[V comparable, D any] struct {
+	// Labels, if set, name the variables and constraints in the trace in
+	// place of their printed form, e.g. the Problem's
+	Labels Labels[V]
+
+	w     *bufio.Writer
+	enc   *json.Encoder
+	depth map[V]int
+	ids   map[ConstraintKey[V]]int
+	err   error
+}
+
+// construct a Tracer writing to w
+func NewTracer[V comparable, D any](w io.Writer) *Tracer[V, D] {
+	buf := bufio.NewWriter(w)
+	return &Tracer[V, D]{
+		w:     buf,
+		enc:   json.NewEncoder(buf),
+		depth: map[V]int{},
+		ids:   map[ConstraintKey[V]]int{},
+	}
+}
+
+// obtain the Hooks that feed this Tracer, for use with Backtracker.Observe
+func (t *Tracer[V, D]) Hooks() Hooks[V, D] {
+	return Hooks[V, D]{
+		OnAssign: func(variable V, value D, depth int) {
+			t.depth[variable] = depth
+			t.write(TraceEvent{Kind: TraceAssign, Depth: depth, Variable: t.Labels.Name(variable), Value: printed(value)})
+		},
+		OnReject: func(variable V, value D, constraint Constraint[V]) {
+			scope := make([]string, len(constraint.Variables))
+			for ndx, v := range constraint.Variables {
+				scope[ndx] = t.Labels.Name(v)
+			}
+			key := constraint.Key()
+			id, found := t.ids[key]
+			if !found {
+				id = len(t.ids) + 1
+				t.ids[key] = id
+			}
+			t.write(TraceEvent{
+				Kind:         TraceReject,
+				Depth:        t.depth[variable],
+				Variable:     t.Labels.Name(variable),
+				Value:        printed(value),
+				Scope:        scope,
+				Constraint:   t.Labels.Constraint(constraint),
+				Relation:     constraint.Relation,
+				ConstraintID: id,
+			})
+		},
+		OnBacktrack: func(variable V, depth int) {
+			t.write(TraceEvent{Kind: TraceBacktrack, Depth: depth, Variable: t.Labels.Name(variable)})
+		},
+		OnSolution: func(solution map[V]D) {
+			out := make(map[string]string, len(solution))
+			for v, value := range solution {
+				out[t.Labels.Name(v)] = printed(value)
+			}
+			t.write(TraceEvent{Kind: TraceSolution, Depth: len(solution), Solution: out})
+		},
+	}
+}
+
+// keep the first error, skipping all writes after it
+func (t *Tracer[V, D]) write(event TraceEvent) {
+	if t.err == nil {
+		t.err = t.enc.Encode(event)
+	}
+}
+
+// write out any buffered events, reporting the first error
+// encountered while recording the trace
+func (t *Tracer[V, D]) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+
+	return t.w.Flush()
+}
+
+// TraceReader reads back the events recorded by a Tracer, one at a time
+// so that traces too large to hold in memory can still be examined
+type TraceReader struct {
+	dec   *json.Decoder
+	event int
+}
+
+// construct a TraceReader reading from r
+func NewTraceReader(r io.Reader) *TraceReader {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	dec.DisallowUnknownFields()
+	return &TraceReader{dec: dec}
+}
+
+// read the next event, or return io.EOF at the end of the trace
+func (tr *TraceReader) Next() (TraceEvent, error) {
+	var event TraceEvent
+	if err := tr.dec.Decode(&event); err != nil {
+		if err == io.EOF {
+			return event, err
+		}
+		return event, fmt.Errorf("trace event %d: %s", tr.event+1, err)
+	}
+	tr.event++
+
+	switch event.Kind {
+	case TraceAssign, TraceReject, TraceBacktrack, TraceSolution:
+	default:
+		return event, fmt.Errorf("trace event %d: unknown kind %q", tr.event, event.Kind)
+	}
+	return event, nil
+}
+
+func printed(value any) string {
+	return fmt.Sprintf("%+v", value)
+}
@@ -0,0 +1,415 @@
+package csp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TreeDecomposition arranges the variables of a Problem into overlapping
+// clusters ("bags") linked together as a tree, or a forest when the
+// constraint graph is disconnected. Every constraint's variables must fall
+// within at least one bag, and the bags holding any single variable must
+// form a connected subtree
+type TreeDecomposition[V comparable] struct {
+	Bags [][]V
+	// Parent holds the index of each bag's parent bag, or -1 for a root
+	Parent []int
+}
+
+// the size of the largest bag, minus one. search effort when solving
+// over the decomposition grows exponentially in the width, not in the
+// total number of variables
+func (td TreeDecomposition[V]) Width() int {
+	width := 0
+	for _, bag := range td.Bags {
+		if len(bag) > width {
+			width = len(bag)
+		}
+	}
+
+	return width - 1
+}
+
+// compute a tree decomposition of the constraint graph using the greedy
+// min-degree elimination ordering. the result is not guaranteed to be of
+// minimal width, but is usually close on sparse graphs like map borders
+func (p Problem[V, D]) TreeDecomposition() TreeDecomposition[V] {
+	remaining := p.adjacency()
+
+	// eliminate variables one at a time, each time choosing the one with the
+	// fewest remaining neighbors; the variable and those neighbors form a bag
+	var eliminated []V
+	var bags [][]V
+	for len(remaining) > 0 {
+		var next V
+		best := -1
+		for v, neighbors := range remaining {
+			if best < 0 || len(neighbors) < best {
+				next, best = v, len(neighbors)
+			}
+		}
+
+		bag := []V{next}
+		for neighbor := range remaining[next] {
+			bag = append(bag, neighbor)
+		}
+
+		// the neighbors of an eliminated variable become a clique
+		for _, a := range bag[1:] {
+			delete(remaining[a], next)
+			for _, b := range bag[1:] {
+				if a != b {
+					remaining[a][b] = struct{}{}
+				}
+			}
+		}
+		delete(remaining, next)
+
+		eliminated = append(eliminated, next)
+		bags = append(bags, bag)
+	}
+
+	// each bag hangs off the bag of whichever of its neighbors
+	// was eliminated soonest after the bag's own variable
+	order := make(map[V]int, len(eliminated))
+	for ndx, v := range eliminated {
+		order[v] = ndx
+	}
+	parent := make([]int, len(bags))
+	for ndx, bag := range bags {
+		parent[ndx] = -1
+		for _, neighbor := range bag[1:] {
+			if parent[ndx] < 0 || order[neighbor] < parent[ndx] {
+				parent[ndx] = order[neighbor]
+			}
+		}
+	}
+
+	return compactTree(bags, parent)
+}
+
+// fold together neighboring bags where one wholly contains the other, since
+// the smaller adds a node to the tree without adding any information. parents
+// always appear later in the slice than their children
+func compactTree[V comparable](bags [][]V, parent []int) TreeDecomposition[V] {
+	merged := make([]int, len(bags))
+	for ndx := range bags {
+		merged[ndx] = ndx
+		if parent[ndx] < 0 {
+			continue
+		}
+		if contains(bags[parent[ndx]], bags[ndx]) {
+			merged[ndx] = parent[ndx]
+		} else if contains(bags[ndx], bags[parent[ndx]]) {
+			// the parent takes over the larger bag, keeping its place in the tree
+			bags[parent[ndx]] = bags[ndx]
+			merged[ndx] = parent[ndx]
+		}
+	}
+
+	// resolve each bag to the surviving bag it was folded into
+	resolve := func(ndx int) int {
+		for merged[ndx] != ndx {
+			ndx = merged[ndx]
+		}
+		return ndx
+	}
+
+	td := TreeDecomposition[V]{}
+	renumbered := map[int]int{}
+	for ndx, bag := range bags {
+		if merged[ndx] == ndx {
+			renumbered[ndx] = len(td.Bags)
+			td.Bags = append(td.Bags, bag)
+		}
+	}
+	for ndx := range bags {
+		if merged[ndx] != ndx {
+			continue
+		}
+		if parent[ndx] < 0 {
+			td.Parent = append(td.Parent, -1)
+		} else {
+			td.Parent = append(td.Parent, renumbered[resolve(parent[ndx])])
+		}
+	}
+
+	return td
+}
+
+// report whether every element of subset is also present in set
+func contains[V comparable](set, subset []V) bool {
+	for _, s := range subset {
+		found := false
+		for _, v := range set {
+			if v == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// drop repeated variables, keeping the first occurrence of each
+func distinct[V comparable](variables []V) []V {
+	var out []V
+	for _, v := range variables {
+		if !contains(out, []V{v}) {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
+
+// solve the problem cluster-by-cluster over a freshly computed tree
+// decomposition. see SolveDecomposed for details
+func (p Problem[V, D]) SolveTree(assignment map[V]D) map[V]D {
+	return p.SolveDecomposed(p.TreeDecomposition(), assignment)
+}
+
+// solve the problem by dynamic programming over the given tree decomposition.
+// each bag is solved in turn only for the distinct values of the variables
+// it shares with its parent, and the outcome is memoized, so the search is
+// exponential in the width of the decomposition rather than the number of
+// variables. constraints must only inspect their own Variables for this to be
+// sound. variables already present in assignment keep their values; a
+// completed copy of it is returned, or nil if no solution exists
+func (p Problem[V, D]) SolveDecomposed(td TreeDecomposition[V], assignment map[V]D) map[V]D {
+	p.validate(td)
+
+	ts := newTreeSolver(p, td, assignment)
+	for b, parent := range td.Parent {
+		if parent < 0 && !ts.solve(b) {
+			return nil
+		}
+	}
+	for b, parent := range td.Parent {
+		if parent < 0 {
+			ts.reconstruct(b)
+		}
+	}
+
+	out := dup(assignment)
+	for v, value := range ts.work {
+		out[v] = value
+	}
+	return out
+}
+
+// ensure the decomposition covers every variable and constraint
+// of the problem, and that it really is shaped like a tree
+func (p Problem[V, D]) validate(td TreeDecomposition[V]) {
+	if len(td.Bags) != len(td.Parent) {
+		panic(fmt.Sprintf("error: tree decomposition has %d bags but %d parents", len(td.Bags), len(td.Parent)))
+	}
+
+	holders := map[V]int{}
+	linked := map[V]int{}
+	for b, bag := range td.Bags {
+		parent := td.Parent[b]
+		if parent >= len(td.Bags) || parent == b {
+			panic(fmt.Sprintf("error: tree decomposition bag %d has invalid parent %d", b, parent))
+		}
+		for _, v := range bag {
+			holders[v]++
+			if parent >= 0 && contains(td.Bags[parent], []V{v}) {
+				linked[v]++
+			}
+		}
+	}
+
+	for v := range p.Domain {
+		if holders[v] == 0 {
+			panic(fmt.Sprintf("error: variable %+v not found in tree decomposition", v))
+		}
+		// the bags holding v are connected iff all but one of them link to a parent also holding v
+		if holders[v]-linked[v] != 1 {
+			panic(fmt.Sprintf("error: bags holding variable %+v do not form a connected subtree", v))
+		}
+	}
+
+	for _, constraints := range p.Constraints {
+		for _, constraint := range constraints {
+			covered := false
+			for _, bag := range td.Bags {
+				if contains(bag, constraint.Variables) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				panic(fmt.Sprintf("error: constraint over %+v not covered by any bag", constraint.Variables))
+			}
+		}
+	}
+}
+
+// bookkeeping for a single dynamic programming pass over a tree decomposition
+type treeSolver[V comparable, D any] struct {
+	problem Problem[V, D]
+	domains map[V][]D
+	// per bag: variables shared with the parent bag, and the remainder
+	separator [][]V
+	fresh     [][]V
+	children  [][]int
+	// per bag and fresh variable: the constraints that become
+	// fully assigned once that variable receives its value
+	checks [][][]Constraint[V]
+	// per bag: separator value indices => winning fresh value indices,
+	// or nil if the subtree has no solution for that separator
+	memo []map[string][]int
+
+	work    map[V]D
+	current map[V]int
+}
+
+func newTreeSolver[V comparable, D any](p Problem[V, D], td TreeDecomposition[V], assignment map[V]D) *treeSolver[V, D] {
+	ts := &treeSolver[V, D]{
+		problem:   p,
+		domains:   make(map[V][]D, len(p.Domain)),
+		separator: make([][]V, len(td.Bags)),
+		fresh:     make([][]V, len(td.Bags)),
+		children:  make([][]int, len(td.Bags)),
+		checks:    make([][][]Constraint[V], len(td.Bags)),
+		memo:      make([]map[string][]int, len(td.Bags)),
+		work:      make(map[V]D, len(p.Domain)),
+		current:   make(map[V]int, len(p.Domain)),
+	}
+
+	// pre-assigned variables are restricted to the single value given
+	for v, values := range p.Domain {
+		ts.domains[v] = values
+		if value, found := assignment[v]; found {
+			ts.domains[v] = []D{value}
+		}
+	}
+
+	for b, bag := range td.Bags {
+		ts.memo[b] = map[string][]int{}
+		if parent := td.Parent[b]; parent >= 0 {
+			ts.children[parent] = append(ts.children[parent], b)
+		}
+
+		position := map[V]int{}
+		for _, v := range bag {
+			if td.Parent[b] >= 0 && contains(td.Bags[td.Parent[b]], []V{v}) {
+				ts.separator[b] = append(ts.separator[b], v)
+				position[v] = -1
+			} else {
+				position[v] = len(ts.fresh[b])
+				ts.fresh[b] = append(ts.fresh[b], v)
+			}
+		}
+
+		ts.checks[b] = make([][]Constraint[V], len(ts.fresh[b]))
+		for ndx, v := range ts.fresh[b] {
+			for _, constraint := range p.Constraints[v] {
+				last, inside := -1, true
+				for _, cv := range constraint.Variables {
+					pos, found := position[cv]
+					if !found {
+						inside = false
+						break
+					}
+					if pos > last {
+						last = pos
+					}
+				}
+				if inside && last == ndx {
+					ts.checks[b][ndx] = append(ts.checks[b][ndx], constraint)
+				}
+			}
+		}
+	}
+
+	return ts
+}
+
+// identify the current values of a bag's separator variables
+func (ts *treeSolver[V, D]) key(b int) string {
+	var buf []byte
+	for _, v := range ts.separator[b] {
+		buf = strconv.AppendInt(buf, int64(ts.current[v]), 10)
+		buf = append(buf, ',')
+	}
+
+	return string(buf)
+}
+
+// report whether the subtree rooted at bag b can be solved given the
+// values currently held by its separator variables
+func (ts *treeSolver[V, D]) solve(b int) bool {
+	key := ts.key(b)
+	if tuple, found := ts.memo[b][key]; found {
+		return tuple != nil
+	}
+
+	ok := ts.extend(b, 0, key)
+	for _, v := range ts.fresh[b] {
+		delete(ts.work, v)
+		delete(ts.current, v)
+	}
+	if !ok {
+		ts.memo[b][key] = nil
+	}
+
+	return ok
+}
+
+// backtrack through the values of bag b's fresh variables, starting
+// with the one at ndx, until the whole subtree is consistent
+func (ts *treeSolver[V, D]) extend(b, ndx int, key string) bool {
+	fresh := ts.fresh[b]
+	if ndx == len(fresh) {
+		for _, child := range ts.children[b] {
+			if !ts.solve(child) {
+				return false
+			}
+		}
+
+		tuple := make([]int, len(fresh))
+		for i, v := range fresh {
+			tuple[i] = ts.current[v]
+		}
+		ts.memo[b][key] = tuple
+		return true
+	}
+
+	v := fresh[ndx]
+	for i, value := range ts.domains[v] {
+		ts.work[v] = value
+		ts.current[v] = i
+
+		consistent := true
+		for _, constraint := range ts.checks[b][ndx] {
+			if !ts.problem.SatFn(constraint, ts.work) {
+				consistent = false
+				break
+			}
+		}
+		if consistent && ts.extend(b, ndx+1, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replay the memoized winning values from bag b downward
+func (ts *treeSolver[V, D]) reconstruct(b int) {
+	tuple := ts.memo[b][ts.key(b)]
+	for i, v := range ts.fresh[b] {
+		ts.work[v] = ts.domains[v][tuple[i]]
+		ts.current[v] = tuple[i]
+	}
+
+	for _, child := range ts.children[b] {
+		ts.reconstruct(child)
+	}
+}
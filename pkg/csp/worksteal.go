@@ -0,0 +1,381 @@
+package csp
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Parallel is a backtracking search run by several workers at once, each
+// depth-first over its own part of the search tree. rather than split
+// the tree once, at the top, where one part may hold nearly all the work,
+// a worker that runs out steals the untried values of the shallowest
+// open choice of another, so the work keeps spreading as irregular
+// subtrees turn out to be big. the SatFn, SelectVariable and OrderValues
+// are called from several goroutines at once, each with its own
+// assignment, so must keep no state of their own outside of it
+type Parallel[V comparable, D any] struct {
+	Problem Problem[V, D]
+	// Workers is the number of goroutines searching; if zero, it's GOMAXPROCS
+	Workers int
+	// Timeout bounds how long Solve may run, or zero for no limit
+	Timeout time.Duration
+	// SelectVariable and OrderValues tune each worker's search, as they
+	// do a Backtracker's. without a SelectVariable, the variables are
+	// assigned in one order fixed at the start
+	SelectVariable VariableOrder[V, D]
+	OrderValues    ValueOrder[V, D]
+
+	stats Stats
+	// set once by Cancel, from any goroutine
+	canceled int32
+}
+
+// construct a Parallel search for the given Problem, with a worker per
+// processor
+func NewParallel[V comparable, D any](p Problem[V, D]) *Parallel[V, D] {
+	return &Parallel[V, D]{
+		Problem: p,
+	}
+}
+
+// search for a solution extending the given assignment, returning the
+// first found by any worker, or nil if none exists or the Timeout expired
+// first
+func (s *Parallel[V, D]) Solve(assignment map[V]D) map[V]D {
+	var result map[V]D
+	s.run(assignment, nil, func(solution map[V]D) bool {
+		result = dup(solution)
+		return true
+	})
+
+	return result
+}
+
+// exhaustively enumerate every solution extending the given assignment,
+// in no particular order. if the Timeout expires first, the solutions
+// found so far are returned and Stats reports the timeout
+func (s *Parallel[V, D]) SolveAll(assignment map[V]D) []map[V]D {
+	var results []map[V]D
+	s.run(assignment, nil, func(solution map[V]D) bool {
+		results = append(results, dup(solution))
+		return false
+	})
+
+	return results
+}
+
+// search for the solution extending the given assignment of least cost,
+// by branch and bound as Backtracker.Minimize does, with the best cost
+// found by any worker the bound for them all. the cost is called from
+// several goroutines at once, as the SatFn is. if the Timeout expires
+// first, the result is the best found so far, and Stats reports the
+// timeout
+func (s *Parallel[V, D]) SolveOptimal(assignment map[V]D, cost Cost[V, D]) (map[V]D, int) {
+	var best map[V]D
+	s.run(assignment, cost, func(solution map[V]D) bool {
+		best = dup(solution)
+		return false
+	})
+	if best == nil {
+		return nil, 0
+	}
+	return best, cost(best)
+}
+
+// stop the running search as soon as possible, from any goroutine, as
+// Backtracker.Cancel does
+func (s *Parallel[V, D]) Cancel() {
+	atomic.StoreInt32(&s.canceled, 1)
+}
+
+// the work done by the most recent search, summed over
+// the workers. Nodes and Backtracks count every worker's, so with more
+// workers exploring more of the tree than the one a Solve needed, they
+// may exceed a Backtracker's
+func (s *Parallel[V, D]) Stats() Stats {
+	return s.stats
+}
+
+// a choice point of a worker's search: the variable being assigned and
+// the values to try, of which those from next on are still untried. the
+// value assigned is the one before next
+type choice[V comparable, D any] struct {
+	variable V
+	values   []D
+	next     int
+}
+
+// a worker of a Parallel search, and the open choices another may steal
+type stealer[V comparable, D any] struct {
+	s *Parallel[V, D]
+	// guards base and choices, which a thief reads and splits
+	mu sync.Mutex
+	// the assignment the current task started from, and the choices made
+	// since, shallowest first
+	base    map[V]D
+	choices []choice[V, D]
+
+	assignment map[V]D
+	stats      Stats
+	rand       *rand.Rand
+}
+
+// what the workers of a search share
+type stealSearch[V comparable, D any] struct {
+	workers []*stealer[V, D]
+	// without a SelectVariable, the unassigned variables, in the order
+	// they're assigned
+	order []V
+	// the number of workers with a task, so that the search is over once
+	// it's none
+	busy     int32
+	stopped  int32
+	deadline time.Time
+
+	// serializes the calls to found
+	mu    sync.Mutex
+	found func(map[V]D) bool
+
+	// for a branch and bound search, the cost of a partial assignment,
+	// which bounds those of its solutions, and the least cost of any
+	// solution so far
+	cost Cost[V, D]
+	best int64
+}
+
+// run a search from scratch with all the workers, reporting each solution
+// to found, which returns true to end the search there. given a cost, only
+// solutions cheaper than the best so far are reported
+func (s *Parallel[V, D]) run(assignment map[V]D, cost Cost[V, D], found func(map[V]D) bool) {
+	start := time.Now()
+	s.stats = Stats{}
+	n := s.Workers
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+
+	search := &stealSearch[V, D]{found: found, cost: cost, best: math.MaxInt64}
+	if s.Timeout > 0 {
+		search.deadline = start.Add(s.Timeout)
+	}
+	if s.SelectVariable == nil {
+		for v := range s.Problem.Domain {
+			if _, found := assignment[v]; !found {
+				search.order = append(search.order, v)
+			}
+		}
+	}
+	for ndx := 0; ndx < n; ndx++ {
+		search.workers = append(search.workers, &stealer[V, D]{
+			s:    s,
+			rand: rand.New(rand.NewSource(start.UnixNano() + int64(ndx))),
+		})
+	}
+
+	// the first worker starts on the whole tree, and the rest steal from it
+	search.busy = 1
+	first := search.workers[0]
+	first.base, first.assignment = dup(assignment), dup(assignment)
+	var wg sync.WaitGroup
+	for ndx, w := range search.workers {
+		wg.Add(1)
+		go func(w *stealer[V, D], root bool) {
+			defer wg.Done()
+			w.work(search, root)
+		}(w, ndx == 0)
+	}
+	wg.Wait()
+
+	for _, w := range search.workers {
+		s.stats.Nodes += w.stats.Nodes
+		s.stats.Backtracks += w.stats.Backtracks
+		s.stats.Rejections += w.stats.Rejections
+		s.stats.Solutions += w.stats.Solutions
+		s.stats.TimedOut = s.stats.TimedOut || w.stats.TimedOut
+		s.stats.Canceled = s.stats.Canceled || w.stats.Canceled
+	}
+	s.stats.Duration = time.Since(start)
+}
+
+// search the tasks the worker starts with or steals, until none are left
+// to steal or the search stops
+func (w *stealer[V, D]) work(search *stealSearch[V, D], root bool) {
+	if root {
+		w.explore(search, true)
+		atomic.AddInt32(&search.busy, -1)
+	}
+	for atomic.LoadInt32(&search.stopped) == 0 {
+		if w.steal(search) {
+			w.explore(search, false)
+			atomic.AddInt32(&search.busy, -1)
+			continue
+		}
+		if atomic.LoadInt32(&search.busy) == 0 {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+// take half the untried values of the shallowest open choice of another
+// worker, and the assignment leading to it, as a task of its own,
+// reporting whether there was one to take
+func (w *stealer[V, D]) steal(search *stealSearch[V, D]) bool {
+	workers := search.workers
+	offset := w.rand.Intn(len(workers))
+	for ndx := range workers {
+		victim := workers[(offset+ndx)%len(workers)]
+		if victim == w {
+			continue
+		}
+
+		victim.mu.Lock()
+		for depth := range victim.choices {
+			c := &victim.choices[depth]
+			left := len(c.values) - c.next
+			if left == 0 {
+				continue
+			}
+			split := c.next + left/2
+			stolen := choice[V, D]{variable: c.variable, values: c.values[split:]}
+			c.values = c.values[:split:split]
+
+			base := dup(victim.base)
+			for _, made := range victim.choices[:depth] {
+				base[made.variable] = made.values[made.next-1]
+			}
+			// counted busy while the victim still is, so the search can't
+			// be seen to be over in between
+			atomic.AddInt32(&search.busy, 1)
+			victim.mu.Unlock()
+
+			w.mu.Lock()
+			w.base, w.choices = base, append(w.choices[:0], stolen)
+			w.mu.Unlock()
+			w.assignment = dup(base)
+			return true
+		}
+		victim.mu.Unlock()
+	}
+	return false
+}
+
+// search depth-first below the worker's base assignment, starting with a
+// new choice at the root, or else trying the values of the stolen one,
+// until its choices are exhausted or the search stops
+func (w *stealer[V, D]) explore(search *stealSearch[V, D], descend bool) {
+	p := w.s.Problem
+	for {
+		if atomic.LoadInt32(&search.stopped) == 1 || w.halted(search) {
+			return
+		}
+
+		if descend {
+			if len(w.assignment) == len(p.Domain) {
+				w.stats.Solutions++
+				search.mu.Lock()
+				done := atomic.LoadInt32(&search.stopped) == 0 && search.improves(w.assignment) && search.found(w.assignment)
+				search.mu.Unlock()
+				if done {
+					atomic.StoreInt32(&search.stopped, 1)
+					return
+				}
+			} else {
+				variable := w.nextVariable(search)
+				values := p.Domain[variable]
+				if w.s.OrderValues != nil {
+					values = w.s.OrderValues(variable, w.assignment)
+				}
+				w.mu.Lock()
+				w.choices = append(w.choices, choice[V, D]{variable: variable, values: values})
+				w.mu.Unlock()
+			}
+		}
+
+		// try the next value of the deepest choice, or else backtrack
+		if len(w.choices) == 0 {
+			return
+		}
+		w.mu.Lock()
+		c := &w.choices[len(w.choices)-1]
+		variable, more := c.variable, c.next < len(c.values)
+		var value D
+		if more {
+			value = c.values[c.next]
+			c.next++
+		} else {
+			w.choices = w.choices[:len(w.choices)-1]
+		}
+		w.mu.Unlock()
+
+		if !more {
+			delete(w.assignment, variable)
+			w.stats.Backtracks++
+			descend = false
+			continue
+		}
+		w.assignment[variable] = value
+		w.stats.Nodes++
+		descend = w.consistent(variable) && !search.bounded(w.assignment)
+	}
+}
+
+// whether a solution costs less than the best so far, making it the best
+// if so. without a cost, every solution counts
+func (search *stealSearch[V, D]) improves(solution map[V]D) bool {
+	if search.cost == nil {
+		return true
+	}
+	c := int64(search.cost(solution))
+	if c >= atomic.LoadInt64(&search.best) {
+		return false
+	}
+	atomic.StoreInt64(&search.best, c)
+	return true
+}
+
+// whether a partial assignment costs at least as much as the best
+// solution so far, so that none of its solutions can improve on it
+func (search *stealSearch[V, D]) bounded(assignment map[V]D) bool {
+	return search.cost != nil && int64(search.cost(assignment)) >= atomic.LoadInt64(&search.best)
+}
+
+// the next variable to assign
+func (w *stealer[V, D]) nextVariable(search *stealSearch[V, D]) V {
+	if w.s.SelectVariable != nil {
+		return w.s.SelectVariable(w.assignment)
+	}
+	// every task's assignment holds a prefix of the order
+	return search.order[len(w.assignment)-(len(w.s.Problem.Domain)-len(search.order))]
+}
+
+// determine whether the variable's constraints hold with its value
+func (w *stealer[V, D]) consistent(variable V) bool {
+	for _, constraint := range w.s.Problem.Constraints[variable] {
+		if !w.s.Problem.SatFn(constraint, w.assignment) {
+			w.stats.Rejections++
+			return false
+		}
+	}
+	return true
+}
+
+// whether the Timeout has expired or the search been canceled, stopping
+// every worker if so
+func (w *stealer[V, D]) halted(search *stealSearch[V, D]) bool {
+	if w.stats.Nodes%1024 == 0 && !search.deadline.IsZero() && time.Now().After(search.deadline) {
+		w.stats.TimedOut = true
+	}
+	if atomic.LoadInt32(&w.s.canceled) == 1 {
+		w.stats.Canceled = true
+	}
+	if w.stats.TimedOut || w.stats.Canceled {
+		atomic.StoreInt32(&search.stopped, 1)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,483 @@
+package csp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// the largest table built for an extension constraint given by its conflicts
+const maxConflictTuples = 1 << 20
+
+// read an XCSP3 instance and build the Problem it describes, by way of a
+// Model. the subset supported covers integer var and array declarations,
+// and the allDifferent, extension (supports or conflicts, with * in
+// supports) and sum constraints, with conditions against a constant
+// or a variable, along with blocks and groups of them.
+// array elements are named as in the instance, e.g. "x[2][0]"
+func LoadXCSP3(r io.Reader) (Problem[string, int], error) {
+	var root xnode
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return Problem[string, int]{}, fmt.Errorf("invalid XCSP3 instance: %w", err)
+	}
+	if root.XMLName.Local != "instance" {
+		return Problem[string, int]{}, fmt.Errorf("invalid XCSP3 instance: root element is <%s>, not <instance>", root.XMLName.Local)
+	}
+	if t := root.attr("type"); t != "" && t != "CSP" {
+		return Problem[string, int]{}, fmt.Errorf("unsupported XCSP3 instance type %q", t)
+	}
+
+	x := xcsp{arrays: map[string][]int{}, domains: map[string][]int{}}
+	for _, section := range root.Children {
+		var err error
+		switch section.XMLName.Local {
+		case "variables":
+			err = x.variables(section)
+		case "constraints":
+			err = x.constraints(section)
+		default:
+			err = fmt.Errorf("unsupported section <%s>", section.XMLName.Local)
+		}
+		if err != nil {
+			return Problem[string, int]{}, err
+		}
+	}
+
+	return x.model.Build()
+}
+
+// a generic XML element, since constraints are distinguished by element name
+type xnode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Content  string     `xml:",chardata"`
+	Children []xnode    `xml:",any"`
+}
+
+func (n xnode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// the child element with the given name, if any
+func (n xnode) child(name string) (xnode, bool) {
+	for _, c := range n.Children {
+		if c.XMLName.Local == name {
+			return c, true
+		}
+	}
+	return xnode{}, false
+}
+
+type xcsp struct {
+	model Model
+	// the dimensions of each array, and the domain of each variable
+	arrays  map[string][]int
+	domains map[string][]int
+}
+
+var xcspSize = regexp.MustCompile(`\[(\d+)\]`)
+
+func (x *xcsp) variables(section xnode) error {
+	for _, decl := range section.Children {
+		id := decl.attr("id")
+		if id == "" {
+			return fmt.Errorf("<%s> without an id", decl.XMLName.Local)
+		}
+		if len(decl.Children) > 0 {
+			return fmt.Errorf("%s: per-element domains are unsupported", id)
+		}
+		values, err := xcspValues(decl.Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+
+		switch decl.XMLName.Local {
+		case "var":
+			x.declare(id, values)
+		case "array":
+			var dims []int
+			for _, m := range xcspSize.FindAllStringSubmatch(decl.attr("size"), -1) {
+				size, _ := strconv.Atoi(m[1])
+				dims = append(dims, size)
+			}
+			if len(dims) == 0 {
+				return fmt.Errorf("%s: malformed size %q", id, decl.attr("size"))
+			}
+			x.arrays[id] = dims
+			for _, name := range x.elements(id, dims, nil) {
+				x.declare(name, values)
+			}
+		default:
+			return fmt.Errorf("unsupported declaration <%s>", decl.XMLName.Local)
+		}
+	}
+	return nil
+}
+
+func (x *xcsp) declare(name string, values []int) {
+	x.domains[name] = values
+	x.model.Variables = append(x.model.Variables, ModelVariable{Name: name, Domain: values})
+}
+
+// name the elements of an array, each index of which is either fixed by
+// a range from the given selection, or left to range over the whole
+// dimension where the selection is nil
+func (x *xcsp) elements(id string, dims []int, selection [][2]int) []string {
+	names := []string{id}
+	for d, size := range dims {
+		lo, hi := 0, size-1
+		if selection != nil && selection[d][0] >= 0 {
+			lo, hi = selection[d][0], selection[d][1]
+		}
+
+		var next []string
+		for _, name := range names {
+			for i := lo; i <= hi; i++ {
+				next = append(next, fmt.Sprintf("%s[%d]", name, i))
+			}
+		}
+		names = next
+	}
+	return names
+}
+
+var xcspIndex = regexp.MustCompile(`\[([^\]]*)\]`)
+
+// expand a list of variables, such as "x y[] z[1..2][0]", to their names
+func (x *xcsp) list(text string) ([]string, error) {
+	var out []string
+	for _, token := range strings.Fields(text) {
+		bracket := strings.Index(token, "[")
+		if bracket < 0 {
+			if _, found := x.domains[token]; !found {
+				return nil, fmt.Errorf("unknown variable %q", token)
+			}
+			out = append(out, token)
+			continue
+		}
+
+		id := token[:bracket]
+		dims, found := x.arrays[id]
+		if !found {
+			return nil, fmt.Errorf("unknown array %q", id)
+		}
+		indices := xcspIndex.FindAllStringSubmatch(token[bracket:], -1)
+		if len(indices) != len(dims) {
+			return nil, fmt.Errorf("%q doesn't index all %d dimensions of %s", token, len(dims), id)
+		}
+
+		selection := make([][2]int, len(dims))
+		for d, m := range indices {
+			selection[d] = [2]int{-1, -1}
+			if m[1] == "" {
+				continue
+			}
+			lo, hi, err := xcspRange(m[1])
+			if err != nil || lo < 0 || hi >= dims[d] || lo > hi {
+				return nil, fmt.Errorf("bad index %q in %q", m[1], token)
+			}
+			selection[d] = [2]int{lo, hi}
+		}
+		out = append(out, x.elements(id, dims, selection)...)
+	}
+	return out, nil
+}
+
+func (x *xcsp) constraints(section xnode) error {
+	for _, c := range section.Children {
+		if err := x.constraint(c); err != nil {
+			if id := c.attr("id"); id != "" {
+				return fmt.Errorf("constraint %s: %w", id, err)
+			}
+			return fmt.Errorf("<%s>: %w", c.XMLName.Local, err)
+		}
+	}
+	return nil
+}
+
+func (x *xcsp) constraint(c xnode) error {
+	switch c.XMLName.Local {
+	case "block":
+		return x.constraints(c)
+
+	case "group":
+		return x.group(c)
+
+	case "allDifferent":
+		text := c.Content
+		if list, found := c.child("list"); found {
+			text = list.Content
+		}
+		vars, err := x.list(text)
+		if err != nil {
+			return err
+		}
+		x.model.Constraints = append(x.model.Constraints, ModelConstraint{Type: "alldifferent", Variables: vars})
+		return nil
+
+	case "extension":
+		return x.extension(c)
+
+	case "sum":
+		return x.sum(c)
+	}
+
+	return fmt.Errorf("unsupported constraint")
+}
+
+// a group instantiates its template constraint once per <args>,
+// substituting %0, %1, ... with the arguments, and %... with all of them
+func (x *xcsp) group(c xnode) error {
+	if len(c.Children) == 0 {
+		return fmt.Errorf("group without a template")
+	}
+	template := c.Children[0]
+	for _, args := range c.Children[1:] {
+		if args.XMLName.Local != "args" {
+			return fmt.Errorf("unexpected <%s> in group", args.XMLName.Local)
+		}
+		values := strings.Fields(args.Content)
+		if err := x.constraint(substitute(template, values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var xcspParam = regexp.MustCompile(`%(\d+|\.\.\.)`)
+
+func substitute(n xnode, args []string) xnode {
+	out := n
+	out.Content = xcspParam.ReplaceAllStringFunc(n.Content, func(param string) string {
+		if param == "%..." {
+			return strings.Join(args, " ")
+		}
+		ndx, _ := strconv.Atoi(param[1:])
+		if ndx < len(args) {
+			return args[ndx]
+		}
+		return param
+	})
+	out.Children = make([]xnode, len(n.Children))
+	for i, child := range n.Children {
+		out.Children[i] = substitute(child, args)
+	}
+	return out
+}
+
+func (x *xcsp) extension(c xnode) error {
+	list, found := c.child("list")
+	if !found {
+		return fmt.Errorf("extension without a list")
+	}
+	vars, err := x.list(list.Content)
+	if err != nil {
+		return err
+	}
+
+	if supports, found := c.child("supports"); found {
+		tuples, err := x.tuples(supports.Content, vars)
+		if err != nil {
+			return err
+		}
+		x.model.Constraints = append(x.model.Constraints, ModelConstraint{Type: "table", Variables: vars, Tuples: tuples})
+		return nil
+	}
+
+	conflicts, found := c.child("conflicts")
+	if !found {
+		return fmt.Errorf("extension without supports or conflicts")
+	}
+	forbidden, err := x.tuples(conflicts.Content, vars)
+	if err != nil {
+		return err
+	}
+	banned := map[string]bool{}
+	for _, tuple := range forbidden {
+		banned[fmt.Sprint(tuple)] = true
+	}
+
+	size := 1
+	for _, v := range vars {
+		if size *= len(x.domains[v]); size > maxConflictTuples {
+			return fmt.Errorf("conflicts over more than %d tuples are unsupported", maxConflictTuples)
+		}
+	}
+	var tuples [][]int
+	for _, tuple := range x.product(vars, nil) {
+		if !banned[fmt.Sprint(tuple)] {
+			tuples = append(tuples, tuple)
+		}
+	}
+	x.model.Constraints = append(x.model.Constraints, ModelConstraint{Type: "table", Variables: vars, Tuples: tuples})
+	return nil
+}
+
+// parse the tuples of an extension: "(0,1)(1,*)" for several variables,
+// or plain values for one. a * stands for every value of its variable
+func (x *xcsp) tuples(text string, vars []string) ([][]int, error) {
+	if len(vars) == 1 {
+		values, err := xcspValues(text)
+		if err != nil {
+			return nil, err
+		}
+		var out [][]int
+		for _, v := range values {
+			out = append(out, []int{v})
+		}
+		return out, nil
+	}
+
+	var out [][]int
+	for _, group := range strings.Split(text, ")") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+		if !strings.HasPrefix(group, "(") {
+			return nil, fmt.Errorf("malformed tuple %q", group)
+		}
+		fields := strings.Split(group[1:], ",")
+		if len(fields) != len(vars) {
+			return nil, fmt.Errorf("tuple (%s) doesn't have a value for each of %d variables", group[1:], len(vars))
+		}
+
+		pattern := make([]*int, len(fields))
+		for ndx, field := range fields {
+			field = strings.TrimSpace(field)
+			if field == "*" {
+				continue
+			}
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("malformed tuple (%s)", group[1:])
+			}
+			pattern[ndx] = &value
+		}
+		out = append(out, x.product(vars, pattern)...)
+	}
+	return out, nil
+}
+
+// enumerate the tuples of the variables' domains matching the pattern,
+// where nil entries match any value
+func (x *xcsp) product(vars []string, pattern []*int) [][]int {
+	out := [][]int{{}}
+	for ndx, v := range vars {
+		values := x.domains[v]
+		if pattern != nil && pattern[ndx] != nil {
+			values = []int{*pattern[ndx]}
+		}
+
+		var next [][]int
+		for _, prefix := range out {
+			for _, value := range values {
+				next = append(next, append(append([]int{}, prefix...), value))
+			}
+		}
+		out = next
+	}
+	return out
+}
+
+var xcspCondition = regexp.MustCompile(`^\(\s*(lt|le|ge|gt|eq|ne)\s*,\s*([^)\s]+)\s*\)$`)
+
+var xcspOperators = map[string]Operator{"lt": Lt, "le": Le, "ge": Ge, "gt": Gt, "eq": Eq, "ne": Ne}
+
+func (x *xcsp) sum(c xnode) error {
+	list, found := c.child("list")
+	if !found {
+		return fmt.Errorf("sum without a list")
+	}
+	vars, err := x.list(list.Content)
+	if err != nil {
+		return err
+	}
+
+	coeffs := make([]int, len(vars))
+	for ndx := range coeffs {
+		coeffs[ndx] = 1
+	}
+	if node, found := c.child("coeffs"); found {
+		fields := strings.Fields(node.Content)
+		if len(fields) != len(vars) {
+			return fmt.Errorf("%d coeffs for %d variables", len(fields), len(vars))
+		}
+		for ndx, field := range fields {
+			if coeffs[ndx], err = strconv.Atoi(field); err != nil {
+				return fmt.Errorf("malformed coefficient %q", field)
+			}
+		}
+	}
+
+	condition, found := c.child("condition")
+	if !found {
+		return fmt.Errorf("sum without a condition")
+	}
+	m := xcspCondition.FindStringSubmatch(strings.TrimSpace(condition.Content))
+	if m == nil {
+		return fmt.Errorf("unsupported condition %q", strings.TrimSpace(condition.Content))
+	}
+
+	constant, err := strconv.Atoi(m[2])
+	if err != nil {
+		// compare against a variable: move it to the left-hand side
+		operand, err := x.list(m[2])
+		if err != nil || len(operand) != 1 {
+			return fmt.Errorf("unsupported condition operand %q", m[2])
+		}
+		vars = append(vars, operand[0])
+		coeffs = append(coeffs, -1)
+		constant = 0
+	}
+
+	x.model.Constraints = append(x.model.Constraints, ModelConstraint{
+		Type:         "linear",
+		Variables:    vars,
+		Coefficients: coeffs,
+		Operator:     xcspOperators[m[1]],
+		Constant:     constant,
+	})
+	return nil
+}
+
+// parse a domain such as "1 3 5..7"
+func xcspValues(text string) ([]int, error) {
+	var out []int
+	for _, token := range strings.Fields(text) {
+		lo, hi, err := xcspRange(token)
+		if err != nil {
+			return nil, err
+		}
+		for v := lo; v <= hi; v++ {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// parse "n" or "lo..hi"
+func xcspRange(token string) (int, int, error) {
+	if lo, hi, found := strings.Cut(token, ".."); found {
+		a, err1 := strconv.Atoi(lo)
+		b, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil {
+			return 0, 0, fmt.Errorf("malformed range %q", token)
+		}
+		return a, b, nil
+	}
+
+	v, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed value %q", token)
+	}
+	return v, v, nil
+}
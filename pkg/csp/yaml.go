@@ -0,0 +1,29 @@
+package csp
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// read a Model from a YAML document and build the Problem it describes.
+// the schema is the same as for LoadJSON, which is easier to write by
+// hand for large models:
+//
+//	variables:
+//	  - {name: x, min: 1, max: 9}
+//	  - {name: y, domain: [2, 4, 6]}
+//	constraints:
+//	  - {type: notequal, variables: [x, y]}
+func LoadYAML(r io.Reader) (Problem[string, int], error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var m Model
+	if err := decoder.Decode(&m); err != nil {
+		return Problem[string, int]{}, fmt.Errorf("invalid model: %w", err)
+	}
+
+	return m.Build()
+}
@@ -0,0 +1,90 @@
+package csptest
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// SolveFunc is any solver entry point, such as Problem.Solve, Problem.SolveTree
+// or Backtracker.Solve, which returns a solution extending the given
+// assignment or nil if there is none
+type SolveFunc[V comparable, D any] func(assignment map[V]D) map[V]D
+
+// fail the test unless assignment is a complete and valid solution to the problem
+func AssertSatisfies[V comparable, D any](t testing.TB, problem csp.Problem[V, D], assignment map[V]D) {
+	t.Helper()
+
+	if assignment == nil {
+		t.Fatalf("expected a solution, got nil")
+	}
+	if err := problem.Verify(assignment); err != nil {
+		t.Fatalf("invalid solution %+v: %s", assignment, err)
+	}
+}
+
+// fail the test unless the problem has exactly want solutions, each valid
+func AssertSolutionCount[V comparable, D any](t testing.TB, problem csp.Problem[V, D], want int) []map[V]D {
+	t.Helper()
+
+	solutions := problem.SolveAll(map[V]D{})
+	for _, solution := range solutions {
+		AssertSatisfies(t, problem, solution)
+	}
+	if len(solutions) != want {
+		t.Fatalf("expected %d solutions, found %d", want, len(solutions))
+	}
+
+	return solutions
+}
+
+// fail the test unless every solver agrees on whether the problem can be
+// solved, and every solution they return is valid. the solvers are free to
+// return different solutions
+func AssertAgree[V comparable, D any](t testing.TB, problem csp.Problem[V, D], solvers ...SolveFunc[V, D]) {
+	t.Helper()
+
+	var solvable []bool
+	for _, solve := range solvers {
+		solution := solve(map[V]D{})
+		if solution != nil {
+			AssertSatisfies(t, problem, solution)
+		}
+		solvable = append(solvable, solution != nil)
+	}
+
+	for ndx := 1; ndx < len(solvable); ndx++ {
+		if solvable[ndx] != solvable[0] {
+			t.Fatalf("solver %d found a solution: %t, but solver 0 found a solution: %t", ndx, solvable[ndx], solvable[0])
+		}
+	}
+}
+
+// fail the test unless both sets of solutions hold exactly the same
+// assignments, regardless of order
+func AssertSameSolutions[V comparable, D any](t testing.TB, want, got []map[V]D) {
+	t.Helper()
+
+	wantKeys, gotKeys := keys(want), keys(got)
+	if len(wantKeys) != len(gotKeys) {
+		t.Fatalf("expected %d solutions, got %d", len(wantKeys), len(gotKeys))
+	}
+	for ndx := range wantKeys {
+		if wantKeys[ndx] != gotKeys[ndx] {
+			t.Fatalf("expected solution %s, got %s", wantKeys[ndx], gotKeys[ndx])
+		}
+	}
+}
+
+// render each solution canonically; fmt prints map keys in sorted order
+func keys[V comparable, D any](solutions []map[V]D) []string {
+	out := make([]string, len(solutions))
+	for ndx, solution := range solutions {
+		out[ndx] = fmt.Sprintf("%+v", solution)
+	}
+	sort.Strings(out)
+
+	return out
+}
@@ -0,0 +1,110 @@
+package csptest_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/csptest"
+)
+
+// the n-queens problem: a queen per column, each variable holding the row
+// of its column's queen, with no two sharing a row or diagonal
+func queens(n int) csp.Problem[int, int] {
+	domain := map[int][]int{}
+	cols := make([]int, n)
+	up, down := make([]int, n), make([]int, n)
+	for col := range cols {
+		cols[col] = col
+		up[col], down[col] = col, -col
+		for row := 0; row < n; row++ {
+			domain[col] = append(domain[col], row)
+		}
+	}
+
+	problem := csp.New(domain, nil)
+	problem.AddConstraint(csp.AllDifferent(cols...))
+	problem.AddConstraint(csp.AllDifferentOffset(cols, up))
+	problem.AddConstraint(csp.AllDifferentOffset(cols, down))
+	return problem
+}
+
+// a testing.TB recording a failure rather than failing the test, to check
+// that the assertions fail when they should
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(string, ...any) {
+	r.failed = true
+	runtime.Goexit()
+}
+
+// report whether the assertion fails, run on a goroutine of its own for
+// Fatalf to end, as testing.T's does
+func fails(t *testing.T, assert func(testing.TB)) bool {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(r)
+	}()
+	<-done
+	return r.failed
+}
+
+func TestAssertSatisfies(t *testing.T) {
+	problem := queens(8)
+	csptest.AssertSatisfies(t, problem, problem.Solve(map[int]int{}))
+
+	if !fails(t, func(tb testing.TB) {
+		csptest.AssertSatisfies(tb, problem, map[int]int{0: 0, 1: 0, 2: 0, 3: 0, 4: 0, 5: 0, 6: 0, 7: 0})
+	}) {
+		t.Fatalf("expected every queen on one row to fail")
+	}
+}
+
+func TestAssertSolutionCount(t *testing.T) {
+	for n, want := range map[int]int{1: 1, 3: 0, 4: 2, 6: 4, 8: 92} {
+		t.Run(fmt.Sprintf("%d-queens", n), func(t *testing.T) {
+			csptest.AssertSolutionCount(t, queens(n), want)
+		})
+	}
+
+	if !fails(t, func(tb testing.TB) { csptest.AssertSolutionCount(tb, queens(8), 91) }) {
+		t.Fatalf("expected a wrong count to fail")
+	}
+}
+
+func TestAssertAgree(t *testing.T) {
+	for _, n := range []int{3, 8} {
+		t.Run(fmt.Sprintf("%d-queens", n), func(t *testing.T) {
+			problem := queens(n)
+			bt := csp.NewBacktracker(problem)
+			bt.SelectVariable = csp.MinRemainingValues(problem)
+			bt.OrderValues = csp.MaintainArcConsistency(problem)
+			csptest.AssertAgree(t, problem, problem.Solve, problem.SolveTree, problem.SolveComponents, bt.Solve)
+		})
+	}
+
+	never := func(map[int]int) map[int]int { return nil }
+	if !fails(t, func(tb testing.TB) { csptest.AssertAgree[int, int](tb, queens(8), queens(8).Solve, never) }) {
+		t.Fatalf("expected solvers disagreeing to fail")
+	}
+}
+
+func TestAssertSameSolutions(t *testing.T) {
+	problem := queens(6)
+	bt := csp.NewBacktracker(problem)
+	bt.SelectVariable = csp.MinRemainingValues(problem)
+	csptest.AssertSameSolutions(t, problem.SolveAll(map[int]int{}), bt.SolveAll(map[int]int{}))
+
+	solutions := problem.SolveAll(map[int]int{})
+	if !fails(t, func(tb testing.TB) { csptest.AssertSameSolutions(tb, solutions, solutions[1:]) }) {
+		t.Fatalf("expected a missing solution to fail")
+	}
+}
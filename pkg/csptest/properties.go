@@ -0,0 +1,138 @@
+package csptest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Property is an invariant a constraint should uphold, checked against a
+// random sample assigning every variable of the problem. it reports a
+// violation as an error
+type Property[V comparable, D any] func(problem csp.Problem[V, D], constraint csp.Constraint[V], sample map[V]D, rng *rand.Rand) error
+
+// fail the test if the constraint violates any of the properties
+// for any of the given number of random samples
+func CheckConstraint[V comparable, D any](t testing.TB, problem csp.Problem[V, D], constraint csp.Constraint[V], samples int, rng *rand.Rand, properties ...Property[V, D]) {
+	t.Helper()
+
+	for i := 0; i < samples; i++ {
+		sample := RandomAssignment(problem, rng)
+		for _, property := range properties {
+			if err := property(problem, constraint, sample, rng); err != nil {
+				t.Fatalf("constraint over %+v: %s", constraint.Variables, err)
+			}
+		}
+	}
+}
+
+// fail the test if any constraint of the problem violates any of the
+// properties for any of the given number of random samples
+func CheckConstraints[V comparable, D any](t testing.TB, problem csp.Problem[V, D], samples int, rng *rand.Rand, properties ...Property[V, D]) {
+	t.Helper()
+
+	for v, constraints := range problem.Constraints {
+		for _, constraint := range constraints {
+			if constraint.Variables[0] == v {
+				CheckConstraint(t, problem, constraint, samples, rng, properties...)
+			}
+		}
+	}
+}
+
+// assign every variable of the problem a value drawn at random from its domain
+func RandomAssignment[V comparable, D any](problem csp.Problem[V, D], rng *rand.Rand) map[V]D {
+	out := make(map[V]D, len(problem.Domain))
+	for v, values := range problem.Domain {
+		if len(values) > 0 {
+			out[v] = values[rng.Intn(len(values))]
+		}
+	}
+
+	return out
+}
+
+// restrict the sample to the constraint's own variables
+func scope[V comparable, D any](constraint csp.Constraint[V], sample map[V]D) map[V]D {
+	out := make(map[V]D, len(constraint.Variables))
+	for _, v := range constraint.Variables {
+		if value, found := sample[v]; found {
+			out[v] = value
+		}
+	}
+
+	return out
+}
+
+// the constraint gives the same verdict every time it checks the same assignment
+func Deterministic[V comparable, D any]() Property[V, D] {
+	return func(problem csp.Problem[V, D], constraint csp.Constraint[V], sample map[V]D, _ *rand.Rand) error {
+		assignment := scope(constraint, sample)
+		first := problem.SatFn(constraint, assignment)
+		for i := 0; i < 3; i++ {
+			if problem.SatFn(constraint, assignment) != first {
+				return fmt.Errorf("verdict changed between checks of %+v", assignment)
+			}
+		}
+		return nil
+	}
+}
+
+// the constraint only inspects its own Variables: values assigned to
+// other variables never change its verdict. solving via SolveTree,
+// SolveComponents and Dual relies on this
+func Local[V comparable, D any]() Property[V, D] {
+	return func(problem csp.Problem[V, D], constraint csp.Constraint[V], sample map[V]D, _ *rand.Rand) error {
+		assignment := scope(constraint, sample)
+		if problem.SatFn(constraint, assignment) != problem.SatFn(constraint, sample) {
+			return fmt.Errorf("verdict for %+v depends on variables outside the constraint", assignment)
+		}
+		return nil
+	}
+}
+
+// the constraint only rejects a partial assignment if no extension of it
+// could satisfy the constraint: if a complete assignment of its variables
+// is accepted, so is every part of it. otherwise the search would prune
+// away valid solutions
+func Monotone[V comparable, D any]() Property[V, D] {
+	return func(problem csp.Problem[V, D], constraint csp.Constraint[V], sample map[V]D, rng *rand.Rand) error {
+		assignment := scope(constraint, sample)
+		if !problem.SatFn(constraint, assignment) {
+			return nil
+		}
+
+		partial := map[V]D{}
+		for v, value := range assignment {
+			if rng.Intn(2) == 0 {
+				partial[v] = value
+			}
+		}
+		if !problem.SatFn(constraint, partial) {
+			return fmt.Errorf("rejects partial assignment %+v, though it accepts its extension %+v", partial, assignment)
+		}
+		return nil
+	}
+}
+
+// the constraint treats its variables interchangeably: shuffling the
+// values among them never changes its verdict. not every constraint is
+// symmetric, so only check this where it's meant to hold. variables are
+// expected to share a domain
+func Symmetric[V comparable, D any]() Property[V, D] {
+	return func(problem csp.Problem[V, D], constraint csp.Constraint[V], sample map[V]D, rng *rand.Rand) error {
+		assignment := scope(constraint, sample)
+
+		shuffled := map[V]D{}
+		perm := rng.Perm(len(constraint.Variables))
+		for ndx, v := range constraint.Variables {
+			shuffled[v] = assignment[constraint.Variables[perm[ndx]]]
+		}
+		if problem.SatFn(constraint, assignment) != problem.SatFn(constraint, shuffled) {
+			return fmt.Errorf("verdict differs between %+v and its permutation %+v", assignment, shuffled)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package csptest_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+	"github.com/elireisman/generic-csp-go/pkg/csptest"
+)
+
+// every built-in relation, over variables 0 to 3 each taking 0 to 3, and
+// whether it treats its variables interchangeably
+var relations = []struct {
+	name       string
+	constraint csp.Constraint[int]
+	symmetric  bool
+}{
+	{"table", csp.Table([]int{0, 1}, [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 3}}), false},
+	{"alldifferent", csp.AllDifferent(0, 1, 2, 3), true},
+	{"alldifferent offsets", csp.AllDifferentOffset([]int{0, 1, 2, 3}, []int{0, 1, 2, 3}), false},
+	{"linear ==", csp.Linear([]int{0, 1, 2}, []int{1, 2, -1}, csp.Eq, 3), false},
+	{"linear !=", csp.Linear([]int{0, 1, 2}, []int{1, 2, -1}, csp.Ne, 3), false},
+	{"linear <", csp.Linear([]int{0, 1, 2}, []int{1, 2, -1}, csp.Lt, 3), false},
+	{"linear >=", csp.Linear([]int{0, 1, 2}, []int{1, 2, -1}, csp.Ge, 3), false},
+	{"sum", csp.Sum([]int{0, 1, 2, 3}, csp.Le, 6), true},
+	{"equal", csp.Equal(0, 1), true},
+	{"notequal", csp.NotEqual(0, 1), true},
+	{"less", csp.LessThan(0, 1), false},
+	{"absdiff ==", csp.AbsDiff(0, 1, csp.Eq, 1), true},
+	{"absdiff !=", csp.AbsDiff(0, 1, csp.Ne, 1), true},
+	{"absdiff <=", csp.AbsDiff(0, 1, csp.Le, 1), true},
+	{"absdiff >=", csp.AbsDiff(0, 1, csp.Ge, 2), true},
+	{"product", csp.Product([]int{0, 1}, 2), true},
+	{"quotient", csp.Quotient(0, 1, 3), true},
+	{"circuit", csp.Circuit(0, 1, 2, 3), false},
+	{"disjunctive", csp.Disjunctive([]int{0, 1, 2}, []int{1, 2, 1}), false},
+	{"gcc", csp.GlobalCardinality([]int{0, 1, 2, 3}, []int{0, 1}, []int{1, 0}, []int{2, 1}), true},
+	{"count", csp.Count([]int{0, 1, 2, 3}, 2, 1), true},
+	{"regular", csp.Regular([]int{0, 1, 2, 3}, csp.Automaton{
+		// no two 0s in a row
+		Start:  0,
+		Accept: []int{0, 1},
+		Transitions: []csp.Transition{
+			{From: 0, Value: 0, To: 1}, {From: 0, Value: 1, To: 0}, {From: 0, Value: 2, To: 0}, {From: 0, Value: 3, To: 0},
+			{From: 1, Value: 1, To: 0}, {From: 1, Value: 2, To: 0}, {From: 1, Value: 3, To: 0},
+		},
+	}), false},
+	{"among", csp.Among([]int{0, 1, 2, 3}, []int{0, 3}, 1, 2), true},
+	{"sequence", csp.Sequence([]int{0, 1, 2, 3}, []int{0}, 2, 0, 1), false},
+	{"binpacking", csp.BinPacking([]int{0, 1, 2, 3}, []int{1, 2, 2, 1}, 3), false},
+	{"diffn", csp.Diffn([]int{0, 1}, []int{2, 3}, []int{2, 1}, []int{1, 2}), false},
+}
+
+func TestBuiltInRelations(t *testing.T) {
+	for _, r := range relations {
+		t.Run(r.name, func(t *testing.T) {
+			problem := csp.New(map[int][]int{0: {0, 1, 2, 3}, 1: {0, 1, 2, 3}, 2: {0, 1, 2, 3}, 3: {0, 1, 2, 3}}, nil)
+			problem.AddConstraint(r.constraint)
+
+			properties := []csptest.Property[int, int]{csptest.Deterministic[int, int](), csptest.Local[int, int](), csptest.Monotone[int, int]()}
+			if r.symmetric {
+				properties = append(properties, csptest.Symmetric[int, int]())
+			}
+			csptest.CheckConstraints(t, problem, 500, rand.New(rand.NewSource(1)), properties...)
+		})
+	}
+}
+
+func TestPropertiesCatchViolations(t *testing.T) {
+	domain := map[int][]int{0: {0, 1, 2, 3}, 1: {0, 1, 2, 3}, 2: {0, 1, 2, 3}}
+	cases := []struct {
+		name     string
+		satFn    csp.Satisfied[int, int]
+		property csptest.Property[int, int]
+	}{
+		// rejects any partial assignment, though some extend to 0 < 1
+		{"monotone", func(c csp.Constraint[int], candidate map[int]int) bool {
+			a, foundA := candidate[0]
+			b, foundB := candidate[1]
+			return foundA && foundB && a < b
+		}, csptest.Monotone[int, int]()},
+		// reads variable 2, outside its scope
+		{"local", func(c csp.Constraint[int], candidate map[int]int) bool {
+			return candidate[0] != candidate[2]
+		}, csptest.Local[int, int]()},
+		{"symmetric", func(c csp.Constraint[int], candidate map[int]int) bool {
+			a, foundA := candidate[0]
+			b, foundB := candidate[1]
+			return !foundA || !foundB || a <= b
+		}, csptest.Symmetric[int, int]()},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			problem := csp.New(domain, c.satFn)
+			problem.AddConstraint(csp.Constraint[int]{Variables: []int{0, 1}})
+			if !fails(t, func(tb testing.TB) {
+				csptest.CheckConstraints(tb, problem, 500, rand.New(rand.NewSource(1)), c.property)
+			}) {
+				t.Fatalf("expected the %s property to be violated", c.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Package dashboard serves a small live web page reporting the progress
+// of a running search, streamed to the browser as server-sent events, so
+// that long solves can be watched from anywhere
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Progress is a snapshot of a search, as sent to the dashboard page
+type Progress struct {
+	Depth          int     `json:"depth"`
+	MaxDepth       int     `json:"max_depth"`
+	Nodes          int64   `json:"nodes"`
+	Backtracks     int64   `json:"backtracks"`
+	Solutions      int64   `json:"solutions"`
+	NodesPerSecond float64 `json:"nodes_per_second"`
+	Elapsed        float64 `json:"elapsed_seconds"`
+	// Objective is the best objective value reported so far, if any
+	Objective *int  `json:"objective,omitempty"`
+	Restarts  int64 `json:"restarts"`
+	Done      bool  `json:"done"`
+}
+
+// Dashboard observes a search and serves its progress over HTTP. the
+// search's hooks only update counters, so observing stays cheap; the
+// counters are sampled and sent to each connected browser every Interval
+type Dashboard[V comparable, D any] struct {
+	Title    string
+	Interval time.Duration
+
+	// updated atomically from the search
+	depth, maxDepth                        int64
+	nodes, backtracks, solutions, restarts int64
+	done                                   int32
+
+	mu        sync.Mutex
+	started   time.Time
+	objective *int
+}
+
+// construct a Dashboard, counting elapsed time from now
+func New[V comparable, D any](title string) *Dashboard[V, D] {
+	return &Dashboard[V, D]{
+		Title:    title,
+		Interval: 500 * time.Millisecond,
+		started:  time.Now(),
+	}
+}
+
+// obtain the Hooks that feed this Dashboard, for use with Backtracker.Observe
+func (d *Dashboard[V, D]) Hooks() csp.Hooks[V, D] {
+	return csp.Hooks[V, D]{
+		OnAssign: func(_ V, _ D, depth int) {
+			atomic.AddInt64(&d.nodes, 1)
+			atomic.StoreInt64(&d.depth, int64(depth))
+			if int64(depth) > atomic.LoadInt64(&d.maxDepth) {
+				atomic.StoreInt64(&d.maxDepth, int64(depth))
+			}
+		},
+		OnBacktrack: func(_ V, _ int) {
+			atomic.AddInt64(&d.backtracks, 1)
+		},
+		OnSolution: func(_ map[V]D) {
+			atomic.AddInt64(&d.solutions, 1)
+		},
+	}
+}
+
+// record the objective value of the best solution found so far, for
+// searches that optimize
+func (d *Dashboard[V, D]) ReportObjective(objective int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.objective = &objective
+}
+
+// record that the search restarted
+func (d *Dashboard[V, D]) ReportRestart() {
+	atomic.AddInt64(&d.restarts, 1)
+}
+
+// record that the search is over, so that the page stops updating
+func (d *Dashboard[V, D]) Finish() {
+	atomic.StoreInt32(&d.done, 1)
+}
+
+// take a snapshot of the search. the rate is left for the caller to fill in
+func (d *Dashboard[V, D]) snapshot() Progress {
+	d.mu.Lock()
+	objective := d.objective
+	elapsed := time.Since(d.started)
+	d.mu.Unlock()
+
+	return Progress{
+		Depth:      int(atomic.LoadInt64(&d.depth)),
+		MaxDepth:   int(atomic.LoadInt64(&d.maxDepth)),
+		Nodes:      atomic.LoadInt64(&d.nodes),
+		Backtracks: atomic.LoadInt64(&d.backtracks),
+		Solutions:  atomic.LoadInt64(&d.solutions),
+		Elapsed:    elapsed.Seconds(),
+		Objective:  objective,
+		Restarts:   atomic.LoadInt64(&d.restarts),
+		Done:       atomic.LoadInt32(&d.done) == 1,
+	}
+}
+
+// serve the dashboard page, and the event stream feeding it at the
+// events path below it. mount it at a path ending in a slash, e.g.
+//
+//	http.Handle("/solve/", http.StripPrefix("/solve", dashboard))
+func (d *Dashboard[V, D]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/events") {
+		d.stream(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, page, html.EscapeString(d.Title), html.EscapeString(d.Title))
+}
+
+// send a snapshot every Interval until the search is done or the
+// browser goes away
+func (d *Dashboard[V, D]) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	interval := d.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := d.snapshot()
+	for {
+		progress := d.snapshot()
+		if dt := progress.Elapsed - last.Elapsed; dt > 0 {
+			progress.NodesPerSecond = float64(progress.Nodes-last.Nodes) / dt
+		}
+		last = progress
+
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		flusher.Flush()
+		if progress.Done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+td { text-align: right; font-family: monospace; }
+canvas { border: 1px solid #ccc; margin-top: 1em; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p id="status">connecting...</p>
+<table id="progress"></table>
+<canvas id="rate" width="600" height="150"></canvas>
+<script>
+const rows = [
+  ["elapsed", p => p.elapsed_seconds.toFixed(1) + "s"],
+  ["depth", p => p.depth + " (max " + p.max_depth + ")"],
+  ["nodes", p => p.nodes.toLocaleString()],
+  ["nodes/sec", p => Math.round(p.nodes_per_second).toLocaleString()],
+  ["backtracks", p => p.backtracks.toLocaleString()],
+  ["solutions", p => p.solutions.toLocaleString()],
+  ["best objective", p => p.objective === undefined ? "-" : p.objective],
+  ["restarts", p => p.restarts.toLocaleString()],
+];
+const table = document.getElementById("progress");
+const cells = rows.map(([name]) => {
+  const tr = table.insertRow();
+  tr.insertCell().outerHTML = "<th>" + name + "</th>";
+  return tr.insertCell();
+});
+const rates = [];
+const canvas = document.getElementById("rate");
+const ctx = canvas.getContext("2d");
+function plot() {
+  ctx.clearRect(0, 0, canvas.width, canvas.height);
+  const top = Math.max(1, ...rates);
+  ctx.beginPath();
+  rates.forEach((rate, i) => {
+    const x = i * canvas.width / 120, y = canvas.height * (1 - rate / top);
+    i ? ctx.lineTo(x, y) : ctx.moveTo(x, y);
+  });
+  ctx.stroke();
+}
+const events = new EventSource("events");
+events.onmessage = e => {
+  const p = JSON.parse(e.data);
+  rows.forEach(([, render], i) => cells[i].textContent = render(p));
+  rates.push(p.nodes_per_second);
+  if (rates.length > 120) rates.shift();
+  plot();
+  document.getElementById("status").textContent = p.done ? "finished" : "running";
+  if (p.done) events.close();
+};
+events.onerror = () => document.getElementById("status").textContent = "disconnected";
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,139 @@
+package gen
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// ModelB generates random binary CSPs with exactly the requested density
+// and tightness: variables 0..Variables-1 each take values
+// 0..DomainSize-1, round(Density*n*(n-1)/2) distinct variable pairs are
+// constrained, and each constraint forbids round(Tightness*d*d) value pairs.
+// Density and Tightness are fractions, clamped to 0 to 1, and negative
+// sizes are taken as 0
+type ModelB struct {
+	Variables  int
+	DomainSize int
+	Density    float64
+	Tightness  float64
+}
+
+// ModelRB generates random binary CSPs that stay hard as they grow, with
+// a phase transition at a known point: for n variables the domains hold
+// round(n^Alpha) values, round(R*n*ln(n)) constraints are placed on randomly
+// chosen variable pairs, and each forbids round(P*d*d) value pairs. P is a
+// fraction, clamped to 0 to 1, and a negative number of variables is
+// taken as 0
+type ModelRB struct {
+	Variables int
+	Alpha     float64
+	R         float64
+	P         float64
+}
+
+// generate a random instance
+func (m ModelB) Generate(rng *rand.Rand) csp.Problem[int, int] {
+	m.Variables, m.DomainSize = atLeastZero(m.Variables), atLeastZero(m.DomainSize)
+	m.Density, m.Tightness = fraction(m.Density), fraction(m.Tightness)
+
+	var pairs [][2]int
+	for i := 0; i < m.Variables; i++ {
+		for j := i + 1; j < m.Variables; j++ {
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+	rng.Shuffle(len(pairs), func(i, j int) {
+		pairs[i], pairs[j] = pairs[j], pairs[i]
+	})
+	pairs = pairs[:round(m.Density*float64(len(pairs)))]
+
+	forbidden := round(m.Tightness * float64(m.DomainSize*m.DomainSize))
+	return build(m.Variables, m.DomainSize, pairs, forbidden, rng)
+}
+
+// generate a random instance
+func (m ModelRB) Generate(rng *rand.Rand) csp.Problem[int, int] {
+	m.Variables, m.P = atLeastZero(m.Variables), fraction(m.P)
+	n := float64(m.Variables)
+	d := round(math.Pow(n, m.Alpha))
+
+	var pairs [][2]int
+	if m.Variables > 1 {
+		for c := round(m.R * n * math.Log(n)); c > 0; c-- {
+			i := rng.Intn(m.Variables)
+			j := rng.Intn(m.Variables - 1)
+			if j >= i {
+				j++
+			}
+			if i > j {
+				i, j = j, i
+			}
+			pairs = append(pairs, [2]int{i, j})
+		}
+	}
+
+	forbidden := round(m.P * float64(d*d))
+	return build(m.Variables, d, pairs, forbidden, rng)
+}
+
+// assemble a Problem in which each listed variable pair forbids
+// a distinct random set of value pairs. pairs listed more than
+// once forbid the union of their value pairs
+func build(n, d int, pairs [][2]int, forbidden int, rng *rand.Rand) csp.Problem[int, int] {
+	domain := map[int][]int{}
+	for v := 0; v < n; v++ {
+		values := make([]int, d)
+		for ndx := range values {
+			values[ndx] = ndx
+		}
+		domain[v] = values
+	}
+
+	nogoods := map[[2]int]map[[2]int]bool{}
+	for _, pair := range pairs {
+		if _, found := nogoods[pair]; !found {
+			nogoods[pair] = map[[2]int]bool{}
+		}
+		for _, tuple := range rng.Perm(d * d)[:forbidden] {
+			nogoods[pair][[2]int{tuple / d, tuple % d}] = true
+		}
+	}
+
+	problem := csp.New(domain, func(c csp.Constraint[int], candidate map[int]int) bool {
+		left, foundLeft := candidate[c.Variables[0]]
+		right, foundRight := candidate[c.Variables[1]]
+		if !foundLeft || !foundRight {
+			return true
+		}
+
+		return !nogoods[[2]int{c.Variables[0], c.Variables[1]}][[2]int{left, right}]
+	})
+	for pair := range nogoods {
+		problem.AddConstraint(csp.Constraint[int]{
+			Variables: []int{pair[0], pair[1]},
+		})
+	}
+
+	return problem
+}
+
+func round(f float64) int {
+	return int(math.Round(f))
+}
+
+// the fraction f, between 0 and 1, or 0 if it's NaN
+func fraction(f float64) float64 {
+	if !(f > 0) {
+		return 0
+	}
+	return math.Min(f, 1)
+}
+
+func atLeastZero(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
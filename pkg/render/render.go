@@ -0,0 +1,136 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Color is the ANSI SGR parameters a cell is drawn with, e.g. "0;41"
+type Color string
+
+// background colors, as the examples use to pick out cells
+const (
+	Plain   Color = ""
+	Red     Color = "0;41"
+	Green   Color = "0;42"
+	Yellow  Color = "0;43"
+	Blue    Color = "0;44"
+	Magenta Color = "0;45"
+	Cyan    Color = "0;46"
+	White   Color = "0;47"
+)
+
+// the sequence that returns the terminal to its default colors
+const reset = "\x1b[0;0m"
+
+// the color in bold
+func (c Color) Bold() Color {
+	if c == Plain {
+		return "1"
+	}
+	return "1;" + Color(strings.TrimPrefix(string(c), "0;"))
+}
+
+// Cell is what a single grid cell shows
+type Cell struct {
+	Text  string
+	Color Color
+}
+
+// Grid draws a Rows by Cols grid in the terminal, formatting each cell
+// with Cell. cells are padded to the widest one, right-aligned so that
+// numbers line up
+type Grid struct {
+	Rows, Cols int
+	// Cell formats the cell at row and col, counting from 0
+	Cell func(row, col int) Cell
+	// BoxRows and BoxCols, if set, rule the grid into boxes of that
+	// many rows and columns, like the 3 by 3 boxes of a sudoku
+	BoxRows, BoxCols int
+	// NoColor draws the cells without ANSI escapes, e.g. for a file
+	NoColor bool
+}
+
+// draw the grid as a string, one line per row
+func (g Grid) String() string {
+	cells := make([][]Cell, g.Rows)
+	width := 1
+	for row := range cells {
+		cells[row] = make([]Cell, g.Cols)
+		for col := range cells[row] {
+			cell := g.Cell(row, col)
+			if n := utf8.RuneCountInString(cell.Text); n > width {
+				width = n
+			}
+			cells[row][col] = cell
+		}
+	}
+
+	var b strings.Builder
+	for row := range cells {
+		if row > 0 && g.BoxRows > 0 && row%g.BoxRows == 0 {
+			b.WriteString(g.rule(width))
+		}
+		for col, cell := range cells[row] {
+			if col > 0 {
+				b.WriteString(" ")
+				if g.BoxCols > 0 && col%g.BoxCols == 0 {
+					b.WriteString("| ")
+				}
+			}
+
+			text := strings.Repeat(" ", width-utf8.RuneCountInString(cell.Text)) + cell.Text
+			if g.NoColor || cell.Color == Plain {
+				b.WriteString(text)
+			} else {
+				fmt.Fprintf(&b, "\x1b[%sm%s%s", cell.Color, text, reset)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// draw the horizontal rule between boxes, crossing the vertical ones
+func (g Grid) rule(width int) string {
+	step := g.BoxCols
+	if step <= 0 {
+		step = g.Cols
+	}
+
+	var parts []string
+	for col := 0; col < g.Cols; col += step {
+		cols := step
+		if col+cols > g.Cols {
+			cols = g.Cols - col
+		}
+		parts = append(parts, strings.Repeat("-", cols*(width+1)-1))
+	}
+	return strings.Join(parts, "-+-") + "\n"
+}
+
+// draw the grid to w
+func (g Grid) Write(w io.Writer) error {
+	_, err := io.WriteString(w, g.String())
+	return err
+}
+
+// show the cells of a grid whose variables are located by at, formatting
+// the value of each assigned one with format and leaving the rest as
+// empty, like a puzzle's blanks. at reports false for cells without a
+// variable
+func Assigned[V comparable, D any](assignment map[V]D, at func(row, col int) (V, bool), format func(value D) Cell, empty Cell) func(row, col int) Cell {
+	return func(row, col int) Cell {
+		v, found := at(row, col)
+		if !found {
+			return empty
+		}
+		value, assigned := assignment[v]
+		if !assigned {
+			return empty
+		}
+		return format(value)
+	}
+}
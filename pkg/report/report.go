@@ -0,0 +1,450 @@
+// Package report renders the outcome of a solve as a single self-contained
+// HTML page: a summary of the model, the search statistics, a heatmap of
+// the constraints and variables the search failed on, and the solution,
+// drawn as a grid or a graph where the shape of the problem allows
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+// Reporter observes a search and then renders its report
+type Reporter[V comparable, D any] struct {
+	Title     string
+	problem   csp.Problem[V, D]
+	conflicts *csp.ConflictStats[V, D]
+}
+
+// construct a Reporter for the given problem
+func New[V comparable, D any](title string, problem csp.Problem[V, D]) *Reporter[V, D] {
+	conflicts := csp.NewConflictStats[V, D]()
+	conflicts.Labels = problem.Labels
+	return &Reporter[V, D]{
+		Title:     title,
+		problem:   problem,
+		conflicts: conflicts,
+	}
+}
+
+// obtain the Hooks that collect failures for the heatmap, for use with
+// Backtracker.Observe. a report written without them has no heatmap
+func (r *Reporter[V, D]) Hooks() csp.Hooks[V, D] {
+	return r.conflicts.Hooks()
+}
+
+// at most this many rows of each heatmap table are shown
+const heatmapRows = 50
+
+// problems with more variables than this aren't drawn as a graph
+const maxGraphVariables = 200
+
+// the colors given to distinct solution values, after ColorBrewer's Set3
+var palette = []string{
+	"#8dd3c7", "#ffffb3", "#bebada", "#fb8072", "#80b1d3", "#fdb462",
+	"#b3de69", "#fccde5", "#d9d9d9", "#bc80bd", "#ccebc5", "#ffed6f",
+}
+
+type page struct {
+	Title      string
+	Generated  string
+	Status     string
+	Summary    []field
+	Stats      []field
+	Hardness   string
+	Heatmap    bool
+	Conflicts  []heat
+	Variables  []heat
+	Grid       [][]cell
+	Graph      template.HTML
+	Assignment []field
+}
+
+type field struct {
+	Name, Value string
+}
+
+type heat struct {
+	Label string
+	Count int
+	Extra string
+	Color string
+}
+
+type cell struct {
+	Text, Color string
+}
+
+// render the report for the solution, which carries the search statistics
+func (r *Reporter[V, D]) Write(w io.Writer, solution csp.Solution[V, D]) error {
+	p := page{
+		Title:     r.Title,
+		Generated: solution.Timestamp.Format(time.RFC1123),
+		Status:    "solved",
+	}
+	if solution.Timestamp.IsZero() {
+		p.Generated = time.Now().Format(time.RFC1123)
+	}
+	switch {
+	case solution.Assignment != nil:
+	case solution.Stats.TimedOut:
+		p.Status = "timed out"
+	case solution.Stats.Canceled:
+		p.Status = "canceled"
+	default:
+		p.Status = "no solution"
+	}
+
+	g := r.problem.Graph()
+	p.Summary = []field{
+		{"variables", fmt.Sprint(len(r.problem.Domain))},
+		{"constraints", fmt.Sprint(len(g.Constraints))},
+		{"edges", fmt.Sprint(g.Edges)},
+		{"density", fmt.Sprintf("%.3f", g.Density)},
+		{"degree", fmt.Sprintf("%d-%d (mean %.1f)", g.Degree.Min, g.Degree.Max, g.Degree.Mean)},
+		{"components", fmt.Sprint(len(g.Components))},
+		{"treewidth estimate", fmt.Sprint(g.TreewidthEstimate)},
+	}
+	p.Hardness = r.problem.EstimateHardness(200, rand.New(rand.NewSource(1))).String()
+
+	if solution.Strategy != "" {
+		p.Stats = append(p.Stats, field{"strategy", solution.Strategy})
+	}
+	if solution.Seed != 0 {
+		p.Stats = append(p.Stats, field{"seed", fmt.Sprint(solution.Seed)})
+	}
+	p.Stats = append(p.Stats,
+		field{"duration", solution.Stats.Duration.String()},
+		field{"nodes", fmt.Sprint(solution.Stats.Nodes)},
+		field{"backtracks", fmt.Sprint(solution.Stats.Backtracks)},
+		field{"rejections", fmt.Sprint(solution.Stats.Rejections)},
+		field{"solutions", fmt.Sprint(solution.Stats.Solutions)},
+	)
+
+	report := r.conflicts.Report()
+	p.Heatmap = len(report.Constraints) > 0 || len(report.Variables) > 0
+	failures := map[csp.ConstraintKey[V]]int{}
+	most := 0
+	for _, c := range report.Constraints {
+		failures[c.Constraint.Key()] = c.Failures
+		if c.Failures > most {
+			most = c.Failures
+		}
+	}
+	for ndx, c := range report.Constraints {
+		if ndx == heatmapRows {
+			break
+		}
+		label := report.Labels.Constraint(c.Constraint)
+		p.Conflicts = append(p.Conflicts, heat{Label: label, Count: c.Failures, Color: heatColor(c.Failures, most)})
+	}
+	if len(report.Variables) > 0 {
+		worst := report.Variables[0].Backtracks
+		for ndx, v := range report.Variables {
+			if ndx == heatmapRows {
+				break
+			}
+			p.Variables = append(p.Variables, heat{
+				Label: report.Labels.Name(v.Variable),
+				Count: v.Backtracks,
+				Extra: fmt.Sprint(v.Rejections),
+				Color: heatColor(v.Backtracks, worst),
+			})
+		}
+	}
+
+	colors := valueColors(solution.Assignment)
+	if grid, ok := drawGrid(r.problem, solution.Assignment, colors); ok {
+		p.Grid = grid
+	} else if len(g.Variables) <= maxGraphVariables {
+		p.Graph = drawGraph(g, r.problem.Labels, solution.Assignment, colors, failures, most)
+	}
+
+	for v, value := range solution.Assignment {
+		p.Assignment = append(p.Assignment, field{r.problem.Labels.Name(v), fmt.Sprintf("%+v", value)})
+	}
+	sort.Slice(p.Assignment, func(i, j int) bool {
+		return p.Assignment[i].Name < p.Assignment[j].Name
+	})
+
+	return pageTemplate.Execute(w, p)
+}
+
+// shade from white for no failures to red for the most failures seen
+func heatColor(count, most int) string {
+	ratio := 0.0
+	if most > 0 {
+		ratio = float64(count) / float64(most)
+	}
+	fade := int(math.Round(215 * ratio))
+	return fmt.Sprintf("#%02x%02x%02x", 255-fade/6, 255-fade, 255-fade)
+}
+
+// color each distinct value, keyed by its printed form
+func valueColors[V comparable, D any](assignment map[V]D) map[string]string {
+	var values []string
+	seen := map[string]bool{}
+	for _, value := range assignment {
+		s := fmt.Sprintf("%+v", value)
+		if !seen[s] {
+			seen[s] = true
+			values = append(values, s)
+		}
+	}
+	sort.Strings(values)
+
+	colors := map[string]string{}
+	for ndx, s := range values {
+		colors[s] = palette[ndx%len(palette)]
+	}
+	return colors
+}
+
+// the row and column of a variable identifying a grid cell: a struct of
+// two integer fields, such as Point{Row, Col}, or an array of two integers
+func gridCell(v any) (int, int, bool) {
+	rv := reflect.ValueOf(v)
+	var a, b reflect.Value
+	switch {
+	case rv.Kind() == reflect.Struct && rv.NumField() == 2:
+		a, b = rv.Field(0), rv.Field(1)
+	case rv.Kind() == reflect.Array && rv.Len() == 2:
+		a, b = rv.Index(0), rv.Index(1)
+	default:
+		return 0, 0, false
+	}
+
+	row, ok1 := integer(a)
+	col, ok2 := integer(b)
+	return row, col, ok1 && ok2
+}
+
+func integer(rv reflect.Value) (int, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), true
+	}
+	return 0, false
+}
+
+// grids larger than this on either side are drawn as a graph instead
+const maxGridSide = 64
+
+// draw the solution as a grid, for the two shapes recognized: variables
+// naming grid cells, which hold their values, and integer variables with
+// integer values, as in N queens, where each variable is a row with a
+// mark in the column given by its value
+func drawGrid[V comparable, D any](problem csp.Problem[V, D], assignment map[V]D, colors map[string]string) ([][]cell, bool) {
+	if len(problem.Domain) == 0 {
+		return nil, false
+	}
+
+	type mark struct {
+		row, col int
+		text     string
+	}
+	var marks []mark
+	for v, values := range problem.Domain {
+		if row, col, ok := gridCell(v); ok {
+			text := ""
+			if value, found := assignment[v]; found {
+				text = fmt.Sprintf("%+v", value)
+			}
+			marks = append(marks, mark{row, col, text})
+			continue
+		}
+
+		row, ok := integer(reflect.ValueOf(v))
+		if !ok {
+			return nil, false
+		}
+		for _, value := range values {
+			col, ok := integer(reflect.ValueOf(value))
+			if !ok {
+				return nil, false
+			}
+			text := ""
+			if assigned, found := assignment[v]; found && reflect.DeepEqual(assigned, value) {
+				text = "♛"
+			}
+			marks = append(marks, mark{row, col, text})
+		}
+	}
+
+	if len(marks) == 0 {
+		return nil, false
+	}
+
+	minRow, minCol := marks[0].row, marks[0].col
+	maxRow, maxCol := minRow, minCol
+	for _, m := range marks {
+		minRow, maxRow = imin(minRow, m.row), imax(maxRow, m.row)
+		minCol, maxCol = imin(minCol, m.col), imax(maxCol, m.col)
+	}
+	if maxRow-minRow >= maxGridSide || maxCol-minCol >= maxGridSide {
+		return nil, false
+	}
+
+	grid := make([][]cell, maxRow-minRow+1)
+	for ndx := range grid {
+		grid[ndx] = make([]cell, maxCol-minCol+1)
+	}
+	for _, m := range marks {
+		c := &grid[m.row-minRow][m.col-minCol]
+		// board marks for the same cell only add to one another
+		if m.text != "" {
+			c.Text = m.text
+			c.Color = colors[m.text]
+		}
+		if c.Color == "" {
+			c.Color = "#ffffff"
+		}
+	}
+	for _, row := range grid {
+		for ndx := range row {
+			if row[ndx].Color == "" {
+				// not a variable at all: a hole in the grid
+				row[ndx].Color = "#444444"
+			}
+		}
+	}
+
+	return grid, true
+}
+
+// draw the constraint graph as an SVG with the variables around a circle,
+// filled by their values, and constraints shaded by how often they failed
+func drawGraph[V comparable, D any](g csp.Graph[V], labels csp.Labels[V], assignment map[V]D, colors map[string]string, failures map[csp.ConstraintKey[V]]int, most int) template.HTML {
+	variables := append([]V{}, g.Variables...)
+	sort.Slice(variables, func(i, j int) bool {
+		return labels.Name(variables[i]) < labels.Name(variables[j])
+	})
+
+	radius := math.Max(150, float64(len(variables))*12)
+	size := 2*radius + 160
+	center := size / 2
+	position := map[V][2]float64{}
+	for ndx, v := range variables {
+		angle := 2 * math.Pi * float64(ndx) / float64(len(variables))
+		position[v] = [2]float64{center + radius*math.Cos(angle), center + radius*math.Sin(angle)}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`, size, size, size, size)
+	for _, constraint := range g.Constraints {
+		stroke := "#999999"
+		if most > 0 {
+			stroke = heatColor(failures[constraint.Key()], most)
+			if failures[constraint.Key()] == 0 {
+				stroke = "#cccccc"
+			}
+		}
+
+		// join every variable of the constraint to its centroid, which
+		// for a binary constraint draws a plain line between the two
+		var x, y float64
+		for _, v := range constraint.Variables {
+			x, y = x+position[v][0], y+position[v][1]
+		}
+		x, y = x/float64(len(constraint.Variables)), y/float64(len(constraint.Variables))
+		for _, v := range constraint.Variables {
+			fmt.Fprintf(&sb, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="2"/>`,
+				position[v][0], position[v][1], x, y, stroke)
+		}
+	}
+	for _, v := range variables {
+		fill := "#ffffff"
+		label := labels.Name(v)
+		if value, found := assignment[v]; found {
+			s := fmt.Sprintf("%+v", value)
+			fill = colors[s]
+			label += " = " + s
+		}
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="10" fill="%s" stroke="#333333"/>`, position[v][0], position[v][1], fill)
+		fmt.Fprintf(&sb, `<text x="%.1f" y="%.1f" font-size="11" text-anchor="middle">%s</text>`,
+			position[v][0], position[v][1]-14, template.HTMLEscapeString(label))
+	}
+	sb.WriteString(`</svg>`)
+
+	return template.HTML(sb.String())
+}
+
+func imin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func imax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+var pageTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+td.n { text-align: right; }
+table.grid td { width: 2em; height: 2em; text-align: center; padding: 0; }
+.status { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}}: <span class="status">{{.Status}}</span></p>
+
+<h2>Model</h2>
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+<p>{{.Hardness}}</p>
+
+<h2>Search</h2>
+<table>
+{{range .Stats}}<tr><th>{{.Name}}</th><td class="n">{{.Value}}</td></tr>
+{{end}}</table>
+
+{{if .Heatmap}}<h2>Failures</h2>
+<table>
+<tr><th>constraint</th><th>rejections</th></tr>
+{{range .Conflicts}}<tr><td>{{.Label}}</td><td class="n" style="background: {{.Color}}">{{.Count}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>variable</th><th>backtracks</th><th>rejections</th></tr>
+{{range .Variables}}<tr><td>{{.Label}}</td><td class="n" style="background: {{.Color}}">{{.Count}}</td><td class="n">{{.Extra}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Assignment}}<h2>Solution</h2>
+{{if .Grid}}<table class="grid">
+{{range .Grid}}<tr>{{range .}}<td style="background: {{.Color}}">{{.Text}}</td>{{end}}</tr>
+{{end}}</table>
+{{else if .Graph}}{{.Graph}}
+{{end}}
+<details><summary>assignment</summary>
+<table>
+{{range .Assignment}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+</body>
+</html>
+`))
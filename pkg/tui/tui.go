@@ -0,0 +1,242 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/elireisman/generic-csp-go/pkg/csp"
+)
+
+const (
+	// the pause after each assignment when a Visualizer starts
+	DefaultDelay = 50 * time.Millisecond
+	// the slowest the search can be paced
+	maxDelay = 2 * time.Second
+	// how often the screen is redrawn
+	frameInterval = time.Second / 30
+	// how many of the latest events are listed below the board
+	logLength = 8
+)
+
+// Visualizer animates a Backtracker's search in the terminal, redrawing
+// the board as each value is assigned, rejected or backtracked over. the
+// search is paced so that it can be followed: space pauses it, n steps
+// it one assignment at a time while paused, + and - speed it up and slow
+// it down, and q stops it
+type Visualizer[V comparable, D any] struct {
+	// Title is shown above the board
+	Title string
+	// Render draws the board for an assignment, usually a partial one
+	Render func(assignment map[V]D) string
+	// Delay is the pause after each assignment, until changed with + and -
+	Delay time.Duration
+
+	mu         sync.Mutex
+	wake       *sync.Cond
+	assignment map[V]D
+	depths     map[V]int
+	stats      csp.Stats
+	depth      int
+	log        []string
+	paused     bool
+	step       bool
+	quit       bool
+	done       bool
+	solved     bool
+}
+
+// construct a Visualizer drawing the board with render
+func New[V comparable, D any](title string, render func(assignment map[V]D) string) *Visualizer[V, D] {
+	v := &Visualizer[V, D]{
+		Title:      title,
+		Render:     render,
+		Delay:      DefaultDelay,
+		assignment: map[V]D{},
+		depths:     map[V]int{},
+	}
+	v.wake = sync.NewCond(&v.mu)
+	return v
+}
+
+// obtain the Hooks that drive this Visualizer, for use with Backtracker.Observe
+func (v *Visualizer[V, D]) Hooks() csp.Hooks[V, D] {
+	return csp.Hooks[V, D]{
+		OnAssign: func(variable V, value D, depth int) {
+			v.mu.Lock()
+			// anything assigned at this depth or deeper has been undone
+			for other, d := range v.depths {
+				if d >= depth {
+					delete(v.assignment, other)
+					delete(v.depths, other)
+				}
+			}
+			v.assignment[variable] = value
+			v.depths[variable] = depth
+			v.depth = depth
+			v.stats.Nodes++
+			v.record("assign %+v = %+v", variable, value)
+			v.mu.Unlock()
+			v.pace()
+		},
+		OnReject: func(variable V, value D, constraint csp.Constraint[V]) {
+			v.mu.Lock()
+			v.stats.Rejections++
+			v.record("  rejected by the constraint over %+v", constraint.Variables)
+			v.mu.Unlock()
+		},
+		OnBacktrack: func(variable V, depth int) {
+			v.mu.Lock()
+			delete(v.assignment, variable)
+			delete(v.depths, variable)
+			v.stats.Backtracks++
+			v.record("backtrack from %+v", variable)
+			v.mu.Unlock()
+		},
+		OnSolution: func(solution map[V]D) {
+			v.mu.Lock()
+			v.stats.Solutions++
+			v.record("solution found")
+			v.mu.Unlock()
+		},
+	}
+}
+
+// add an event to the log, with the lock held
+func (v *Visualizer[V, D]) record(format string, args ...any) {
+	v.log = append(v.log, fmt.Sprintf(format, args...))
+	if len(v.log) > logLength {
+		v.log = v.log[len(v.log)-logLength:]
+	}
+}
+
+// hold the search back after an assignment: for the delay, and for as
+// long as it's paused, unless stepping
+func (v *Visualizer[V, D]) pace() {
+	v.mu.Lock()
+	for v.paused && !v.step && !v.quit {
+		v.wake.Wait()
+	}
+	v.step = false
+	delay, quit := v.Delay, v.quit
+	v.mu.Unlock()
+
+	if delay > 0 && !quit {
+		time.Sleep(delay)
+	}
+}
+
+// run the search for a solution extending the assignment, animating it
+// until the user quits. the search stops early if they quit before it
+// finishes, returning what it had found, as Backtracker.Solve does when
+// canceled
+func (v *Visualizer[V, D]) Run(bt *csp.Backtracker[V, D], assignment map[V]D) (map[V]D, error) {
+	bt.Observe(v.Hooks())
+	program := tea.NewProgram(model[V, D]{v: v}, tea.WithAltScreen())
+
+	var result map[V]D
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		result = bt.Solve(assignment)
+
+		v.mu.Lock()
+		v.done, v.solved = true, result != nil
+		v.stats.Duration = bt.Stats().Duration
+		v.record("search finished, press q to exit")
+		v.mu.Unlock()
+	}()
+
+	_, err := program.Run()
+
+	// release a paced search so that it sees the cancellation
+	v.mu.Lock()
+	v.quit = true
+	v.wake.Broadcast()
+	v.mu.Unlock()
+	bt.Cancel()
+	<-finished
+
+	return result, err
+}
+
+// model adapts a Visualizer to bubbletea
+type model[V comparable, D any] struct {
+	v *Visualizer[V, D]
+}
+
+type frameMsg struct{}
+
+func frame() tea.Cmd {
+	return tea.Tick(frameInterval, func(time.Time) tea.Msg { return frameMsg{} })
+}
+
+func (m model[V, D]) Init() tea.Cmd {
+	return frame()
+}
+
+func (m model[V, D]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case frameMsg:
+		return m, frame()
+
+	case tea.KeyMsg:
+		v := m.v
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		switch msg.String() {
+		case "q", "ctrl+c", "esc":
+			return m, tea.Quit
+		case " ":
+			v.paused = !v.paused
+			v.wake.Broadcast()
+		case "n":
+			v.step = true
+			v.wake.Broadcast()
+		case "+", "=":
+			v.Delay /= 2
+		case "-", "_":
+			switch {
+			case v.Delay == 0:
+				v.Delay = time.Millisecond
+			case v.Delay*2 > maxDelay:
+				v.Delay = maxDelay
+			default:
+				v.Delay *= 2
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m model[V, D]) View() string {
+	v := m.v
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", v.Title)
+	b.WriteString(v.Render(v.assignment))
+	b.WriteString("\n\n")
+
+	state := fmt.Sprintf("searching, %s per assignment", v.Delay)
+	switch {
+	case v.done && v.solved:
+		state = fmt.Sprintf("solved in %s", v.stats.Duration)
+	case v.done:
+		state = "no solution"
+	case v.paused:
+		state = "paused"
+	}
+	fmt.Fprintf(&b, "%s · depth %d · %d nodes · %d backtracks · %d rejections\n\n",
+		state, v.depth, v.stats.Nodes, v.stats.Backtracks, v.stats.Rejections)
+
+	for _, line := range v.log {
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+	b.WriteString("\nspace pause · n step · + faster · - slower · q quit\n")
+	return b.String()
+}